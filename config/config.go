@@ -1,6 +1,9 @@
 package config
 
-import "os"
+import (
+	"os"
+	"time"
+)
 
 // Config holds all configuration for the application
 type Config struct {
@@ -11,43 +14,244 @@ type Config struct {
 	Environment       string
 	OllamaBaseURL     string
 	OllamaModel       string
+	OllamaModels      string // Comma-separated models uploads and regenerations may select besides OLLAMA_MODEL
+	OllamaChunkTokens int
+	OllamaRetries     int    // Extra attempts of annotation requests after network errors, timeouts and 5xx
+	OllamaBackoffMS   int    // Delay before the first retry, doubled for each further one
+	OllamaBreakerMax  int    // Consecutive failures after which Ollama isn't called for a while, 0 disables
+	OllamaBreakerCool int    // seconds Ollama isn't called once the breaker opens
+	OllamaFixtures    string // "record" or "replay" Ollama responses, empty calls Ollama directly
+	OllamaFixtureDir  string
+	EmbeddingModel    string
+	VisionModel       string
+	ClusterInterval   int // minutes, 0 disables the clustering job
+	ClusterThreshold  float64
+	LinkCheckInterval int // hours, 0 disables the link check job
+	WatchDir          string
+	WatchUserEmail    string
+	WatchInterval     int // seconds
+	SFTPListenAddr    string
+	SFTPRootDir       string
+	SFTPHostKeyFile   string
+	SFTPUsers         string // login:password:email entries separated by commas
 	UploadDir         string
+	MaxUploadMB       int  // Largest accepted request body, in megabytes
+	BulkUploadMaxMB   int  // Largest accepted bulk upload request body, in megabytes
+	StrictOwnership   bool // Only owners and admins may update or delete annotations
+	ShutdownTimeout   int  // Seconds a shutdown waits for running requests and background work
+	CollabSaveSeconds int  // How often collaborative editing sessions are saved
+	ReadyCheckOllama  bool // /readyz also requires Ollama to be reachable
+	ReadyCheckAWS     bool // /readyz also requires S3 and Polly to be reachable
+	ReadyCacheSeconds int  // How long the Ollama and AWS results of /readyz are reused
+	RateLimitPerMin   int  // Requests per client and minute, 0 disables rate limiting
+	PublicCatalog     bool // Serve /public/annotations without authentication
+	PublicRatePerMin  int  // Requests per client and minute to /public, 0 for no limit beyond RateLimitPerMin
+	UploadQuotaPerDay int  // Documents each non-admin user may upload per UTC day, 0 for no limit
+	AllowSimulated    bool // Accept simulate=true uploads for load testing
+	SimulateExtractMs int  // Fabricated durations of the pipeline steps of simulated uploads
+	SimulateLLMMs     int
+	SimulateTTSMs     int
+	TrustedProxies    string // Proxy addresses or CIDR ranges allowed to set X-Forwarded-For, separated by commas
+	OTLPEndpoint      string // OTLP/HTTP trace collector, e.g. http://localhost:4318; empty disables tracing
+	OTLPHeaders       string // key=value pairs sent to the collector, separated by commas
+	ServiceName       string // service.name of exported spans
 	TTSOutputDir      string
 	JWTSecret         string
+	AdminEmail        string // User promoted to admin at startup
+	RequireConsent    bool   // Uploads through the API must attest the uploader may upload the document
+	UploadTerms       string // Version of the upload terms recorded with each attestation
+	TakedownRepublish int    // Days after a counter-notice until a taken down annotation is republished
+	SMTPHost          string // Mail server for notifications; empty only logs them
+	SMTPPort          string
+	SMTPUsername      string
+	SMTPPassword      string
+	MailFrom          string
+	ContentReports    string // "daily", "weekly" or "off": how often admins are emailed a content report
+	FeatureFlags      string // Rollout percentage per experimental feature, e.g. "explain=100,clusters=25"
+	SafetyMinScore    int    // Minimum confidence, in percent, for a safety label to be stored
+	SafetyRestrict    string // Safety labels hidden per role, e.g. "public=adult_themes,violence;basic=adult_themes"
+	RequireReview     bool   // Generated annotations must be approved by a reviewer before students see them
+	ShedQueueDepth    int    // Documents in the upload pipeline from which new uploads get 503, 0 disables
+	ShedOllamaMS      int    // Average Ollama request duration from which new uploads get 503, 0 disables
+	ShedRetryAfter    int    // seconds, sent in Retry-After while uploads are shed
+	EmbedProvider     string // "ollama" or "openai"
+	OpenAIKey         string
+	OpenAIEmbedModel  string
+	VectorBackend     string // "atlas" for Atlas Vector Search, empty compares embeddings in the API
+	VectorIndex       string // Atlas Vector Search index on annotations.embedding
+	ParseSandbox      bool   // Parse PDFs in a resource-limited worker process
+	ParseTimeout      int    // seconds a PDF parse may take
+	ParseMemoryMB     int    // Memory limit of the parse worker
+	OCREnabled        bool   // Read scanned PDFs with Tesseract when they have little or no text layer
+	OCRLanguages      string // Tesseract languages, e.g. eng+deu
+	OCRMaxPages       int    // Pages of a scanned PDF that are read
+	OCRTimeout        int    // seconds OCR of a document may take
 	AWSAccessKeyID    string
 	AWSSecretKey      string
 	AWSRegion         string
 	AWSS3BucketName   string
 	AWSPollyVoiceID   string
 	AWSPollyEngine    string
-}
+	S3ReplicaBucket   string // Optional second bucket, usually in another region, holding a copy of every object
+	S3ReplicaRegion   string
+	S3ReplicaCountry  string // Comma-separated ISO country codes served from the replica
+	TTSProvider       string // "polly" (default), "google", "azure" or "elevenlabs"
+	GoogleTTSAPIKey   string
+	GoogleTTSVoice    string
+	AzureSpeechKey    string
+	AzureSpeechRegion string
+	AzureTTSVoice     string
+	ElevenLabsAPIKey  string
+	ElevenLabsVoiceID string
+	ElevenLabsModelID string
+	TTSCostPerMillion float64 // USD per million characters of speech, for processing estimates
+	StorageBackend    string  // "s3", "minio" or "local"; defaults to S3 when AWS is configured, else local
+	StorageLocalDir   string
+	StoragePublicURL  string // Public URL of the local storage route, defaults to http://localhost:{PORT}/storage
+	MinIOEndpoint     string
+	MinIOAccessKey    string
+	MinIOSecretKey    string
+	MinIOBucket       string
+	SearchBackend     string // "meilisearch", empty searches MongoDB only
+	MeilisearchURL    string
+	MeilisearchKey    string
+	SearchIndexName   string
+	ShareBaseURL      string // Web app URL share links point to, e.g. https://notes.example.com
+	CDNCacheSeconds   int    // Shared cache lifetime of published annotations, 0 disables CDN headers
+	CDNPurgeURL       string
+	CDNPurgeToken     string
+	CDNPurgeHeader    string // Header carrying CDNPurgeToken, e.g. Fastly-Key
+	Compression       string // "gzip" or "off"
+	CompressionLevel  int    // gzip level 1-9, -1 for the default
+	CompressionMin    int    // Smallest response body compressed, in bytes
 
-// Load loads configuration from environment variables
-func Load() *Config {
-	return &Config{
-		MongoURI:          getEnv("MONGODB_URI", "mongodb://localhost:27017"),
-		DatabaseName:      getEnv("MONGODB_DATABASE", "auto_annotation_db"),
-		Port:              getEnv("PORT", "8080"),
-		GinMode:           getEnv("GIN_MODE", "debug"),
-		Environment:       getEnv("ENVIRONMENT", "development"),
-		OllamaBaseURL:     getEnv("OLLAMA_BASE_URL", "http://localhost:11434"),
-		OllamaModel:       getEnv("OLLAMA_MODEL", "mistral"),
-		UploadDir:         getEnv("UPLOAD_DIR", "uploads"),
-		TTSOutputDir:      getEnv("TTS_OUTPUT_DIR", "uploads/audio"),
-		JWTSecret:         getEnv("JWT_SECRET", "your-super-secret-jwt-key-change-this-in-production"),
-		AWSAccessKeyID:    getEnv("AWS_ACCESS_KEY_ID", ""),
-		AWSSecretKey:      getEnv("AWS_SECRET_ACCESS_KEY", ""),
-		AWSRegion:         getEnv("AWS_REGION", "us-east-1"),
-		AWSS3BucketName:   getEnv("AWS_S3_BUCKET_NAME", ""),
-		AWSPollyVoiceID:   getEnv("AWS_POLLY_VOICE_ID", "Joanna"),
-		AWSPollyEngine:    getEnv("AWS_POLLY_ENGINE", "neural"),
-	}
+	settings []setting // Where each setting came from, for LogEffective
 }
 
-// getEnv gets an environment variable with a fallback default value
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+// Load loads configuration from command-line flags and environment variables. Each setting can be
+// given as a flag named after its variable, e.g. --mongodb-uri for MONGODB_URI, or as the variable
+// with or without the APP_ prefix, in that order of precedence. Invalid values stop the program.
+func Load() *Config {
+	l := newLoader(os.Args[1:])
+	cfg := &Config{
+		MongoURI:          l.getSecret("MONGODB_URI", "mongodb://localhost:27017"),
+		DatabaseName:      l.getEnv("MONGODB_DATABASE", "auto_annotation_db"),
+		Port:              l.getEnv("PORT", "8080"),
+		GinMode:           l.getEnv("GIN_MODE", "debug"),
+		Environment:       l.getEnv("ENVIRONMENT", "development"),
+		OllamaBaseURL:     l.getEnv("OLLAMA_BASE_URL", "http://localhost:11434"),
+		OllamaModel:       l.getEnv("OLLAMA_MODEL", "mistral"),
+		OllamaModels:      l.getEnv("OLLAMA_ALLOWED_MODELS", ""),
+		OllamaChunkTokens: l.getEnvInt("OLLAMA_CHUNK_TOKENS", 2000),
+		OllamaRetries:     l.getEnvInt("OLLAMA_RETRIES", 2),
+		OllamaBackoffMS:   l.getEnvDuration("OLLAMA_RETRY_BACKOFF_MS", time.Millisecond, 1000),
+		OllamaBreakerMax:  l.getEnvInt("OLLAMA_BREAKER_FAILURES", 5),
+		OllamaBreakerCool: l.getEnvDuration("OLLAMA_BREAKER_COOLDOWN_SECONDS", time.Second, 30),
+		OllamaFixtures:    l.getEnv("OLLAMA_FIXTURE_MODE", ""),
+		OllamaFixtureDir:  l.getEnv("OLLAMA_FIXTURE_DIR", "testdata/ollama"),
+		EmbeddingModel:    l.getEnv("OLLAMA_EMBEDDING_MODEL", "nomic-embed-text"),
+		VisionModel:       l.getEnv("OLLAMA_VISION_MODEL", ""),
+		ClusterInterval:   l.getEnvDuration("CLUSTER_INTERVAL_MINUTES", time.Minute, 60),
+		ClusterThreshold:  l.getEnvFloat("CLUSTER_SIMILARITY_THRESHOLD", 0.8),
+		LinkCheckInterval: l.getEnvDuration("LINK_CHECK_INTERVAL_HOURS", time.Hour, 24),
+		WatchDir:          l.getEnv("WATCH_DIR", ""),
+		WatchUserEmail:    l.getEnv("WATCH_USER_EMAIL", ""),
+		WatchInterval:     l.getEnvDuration("WATCH_INTERVAL_SECONDS", time.Second, 30),
+		SFTPListenAddr:    l.getEnv("SFTP_LISTEN_ADDR", ""),
+		SFTPRootDir:       l.getEnv("SFTP_ROOT_DIR", "uploads/sftp"),
+		SFTPHostKeyFile:   l.getEnv("SFTP_HOST_KEY_FILE", "sftp_host_key"),
+		SFTPUsers:         l.getSecret("SFTP_USERS", ""),
+		UploadDir:         l.getEnv("UPLOAD_DIR", "uploads"),
+		MaxUploadMB:       l.getEnvInt("MAX_UPLOAD_MB", 50),
+		BulkUploadMaxMB:   l.getEnvInt("BULK_UPLOAD_MAX_MB", 500),
+		StrictOwnership:   l.getEnvBool("STRICT_OWNERSHIP", false),
+		ShutdownTimeout:   l.getEnvDuration("SHUTDOWN_TIMEOUT_SECONDS", time.Second, 120),
+		CollabSaveSeconds: l.getEnvDuration("COLLAB_SAVE_SECONDS", time.Second, 10),
+		ReadyCheckOllama:  l.getEnvBool("READY_CHECK_OLLAMA", false),
+		ReadyCheckAWS:     l.getEnvBool("READY_CHECK_AWS", false),
+		ReadyCacheSeconds: l.getEnvDuration("READY_CACHE_SECONDS", time.Second, 30),
+		RateLimitPerMin:   l.getEnvInt("RATE_LIMIT_PER_MINUTE", 0),
+		TrustedProxies:    l.getEnv("TRUSTED_PROXIES", ""),
+		PublicCatalog:     l.getEnvBool("PUBLIC_CATALOG", false),
+		PublicRatePerMin:  l.getEnvInt("PUBLIC_RATE_LIMIT_PER_MINUTE", 60),
+		UploadQuotaPerDay: l.getEnvInt("UPLOAD_QUOTA_PER_DAY", 0),
+		AllowSimulated:    l.getEnvBool("ALLOW_SIMULATED_UPLOADS", false),
+		SimulateExtractMs: l.getEnvDuration("SIMULATE_EXTRACT_MS", time.Millisecond, 500),
+		SimulateLLMMs:     l.getEnvDuration("SIMULATE_LLM_MS", time.Millisecond, 30000),
+		SimulateTTSMs:     l.getEnvDuration("SIMULATE_TTS_MS", time.Millisecond, 5000),
+		OTLPEndpoint:      l.getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", ""),
+		OTLPHeaders:       l.getSecret("OTEL_EXPORTER_OTLP_HEADERS", ""),
+		ServiceName:       l.getEnv("OTEL_SERVICE_NAME", "auto-annotation-api"),
+		TTSOutputDir:      l.getEnv("TTS_OUTPUT_DIR", "uploads/audio"),
+		JWTSecret:         l.getSecret("JWT_SECRET", "your-super-secret-jwt-key-change-this-in-production"),
+		AdminEmail:        l.getEnv("ADMIN_EMAIL", ""),
+		RequireConsent:    l.getEnvBool("REQUIRE_UPLOAD_CONSENT", false),
+		UploadTerms:       l.getEnv("UPLOAD_TERMS_VERSION", ""),
+		TakedownRepublish: l.getEnvInt("TAKEDOWN_REPUBLISH_DAYS", 14),
+		SMTPHost:          l.getEnv("SMTP_HOST", ""),
+		SMTPPort:          l.getEnv("SMTP_PORT", "587"),
+		SMTPUsername:      l.getEnv("SMTP_USERNAME", ""),
+		SMTPPassword:      l.getSecret("SMTP_PASSWORD", ""),
+		MailFrom:          l.getEnv("MAIL_FROM", "noreply@localhost"),
+		ContentReports:    l.getEnv("CONTENT_REPORTS", "weekly"),
+		FeatureFlags:      l.getEnv("FEATURE_FLAGS", ""),
+		SafetyMinScore:    l.getEnvInt("SAFETY_LABEL_MIN_CONFIDENCE", 50),
+		SafetyRestrict:    l.getEnv("SAFETY_RESTRICTIONS", ""),
+		RequireReview:     l.getEnvBool("REQUIRE_REVIEW", false),
+		ShedQueueDepth:    l.getEnvInt("LOAD_SHED_QUEUE_DEPTH", 0),
+		ShedOllamaMS:      l.getEnvDuration("LOAD_SHED_OLLAMA_MS", time.Millisecond, 0),
+		ShedRetryAfter:    l.getEnvDuration("LOAD_SHED_RETRY_AFTER", time.Second, 60),
+		EmbedProvider:     l.getEnv("EMBEDDING_PROVIDER", "ollama"),
+		OpenAIKey:         l.getSecret("OPENAI_API_KEY", ""),
+		OpenAIEmbedModel:  l.getEnv("OPENAI_EMBEDDING_MODEL", "text-embedding-3-small"),
+		VectorBackend:     l.getEnv("VECTOR_BACKEND", ""),
+		VectorIndex:       l.getEnv("VECTOR_INDEX", "annotation_embeddings"),
+		ParseSandbox:      l.getEnvBool("PARSE_SANDBOX", true),
+		ParseTimeout:      l.getEnvDuration("PARSE_TIMEOUT_SECONDS", time.Second, 120),
+		ParseMemoryMB:     l.getEnvInt("PARSE_MEMORY_MB", 1024),
+		OCREnabled:        l.getEnvBool("OCR_ENABLED", true),
+		OCRLanguages:      l.getEnv("OCR_LANGUAGES", "eng"),
+		OCRMaxPages:       l.getEnvInt("OCR_MAX_PAGES", 200),
+		OCRTimeout:        l.getEnvDuration("OCR_TIMEOUT_SECONDS", time.Second, 600),
+		AWSAccessKeyID:    l.getEnv("AWS_ACCESS_KEY_ID", ""),
+		AWSSecretKey:      l.getSecret("AWS_SECRET_ACCESS_KEY", ""),
+		AWSRegion:         l.getEnv("AWS_REGION", "us-east-1"),
+		AWSS3BucketName:   l.getEnv("AWS_S3_BUCKET_NAME", ""),
+		AWSPollyVoiceID:   l.getEnv("AWS_POLLY_VOICE_ID", "Joanna"),
+		AWSPollyEngine:    l.getEnv("AWS_POLLY_ENGINE", "neural"),
+		S3ReplicaBucket:   l.getEnv("AWS_S3_REPLICA_BUCKET", ""),
+		S3ReplicaRegion:   l.getEnv("AWS_S3_REPLICA_REGION", ""),
+		S3ReplicaCountry:  l.getEnv("AWS_S3_REPLICA_COUNTRIES", ""),
+		TTSProvider:       l.getEnv("TTS_PROVIDER", "polly"),
+		GoogleTTSAPIKey:   l.getSecret("GOOGLE_TTS_API_KEY", ""),
+		GoogleTTSVoice:    l.getEnv("GOOGLE_TTS_VOICE", "en-US-Neural2-F"),
+		AzureSpeechKey:    l.getSecret("AZURE_SPEECH_KEY", ""),
+		AzureSpeechRegion: l.getEnv("AZURE_SPEECH_REGION", ""),
+		AzureTTSVoice:     l.getEnv("AZURE_TTS_VOICE", "en-US-JennyNeural"),
+		ElevenLabsAPIKey:  l.getSecret("ELEVENLABS_API_KEY", ""),
+		ElevenLabsVoiceID: l.getEnv("ELEVENLABS_VOICE_ID", ""),
+		ElevenLabsModelID: l.getEnv("ELEVENLABS_MODEL_ID", "eleven_multilingual_v2"),
+		TTSCostPerMillion: l.getEnvFloat("TTS_COST_PER_MILLION_CHARS", 16),
+		StorageBackend:    l.getEnv("STORAGE_BACKEND", ""),
+		StorageLocalDir:   l.getEnv("STORAGE_LOCAL_DIR", "uploads/storage"),
+		StoragePublicURL:  l.getEnv("STORAGE_PUBLIC_URL", ""),
+		MinIOEndpoint:     l.getEnv("MINIO_ENDPOINT", ""),
+		MinIOAccessKey:    l.getEnv("MINIO_ACCESS_KEY", ""),
+		MinIOSecretKey:    l.getSecret("MINIO_SECRET_KEY", ""),
+		MinIOBucket:       l.getEnv("MINIO_BUCKET", ""),
+		SearchBackend:     l.getEnv("SEARCH_BACKEND", ""),
+		MeilisearchURL:    l.getEnv("MEILISEARCH_URL", ""),
+		MeilisearchKey:    l.getSecret("MEILISEARCH_API_KEY", ""),
+		SearchIndexName:   l.getEnv("SEARCH_INDEX", "annotations"),
+		ShareBaseURL:      l.getEnv("SHARE_BASE_URL", "http://localhost:3000"),
+		CDNCacheSeconds:   l.getEnvDuration("CDN_CACHE_SECONDS", time.Second, 0),
+		CDNPurgeURL:       l.getEnv("CDN_PURGE_URL", ""),
+		CDNPurgeToken:     l.getSecret("CDN_PURGE_TOKEN", ""),
+		CDNPurgeHeader:    l.getEnv("CDN_PURGE_HEADER", "Authorization"),
+		Compression:       l.getEnv("COMPRESSION", "gzip"),
+		CompressionLevel:  l.getEnvInt("COMPRESSION_LEVEL", 5),
+		CompressionMin:    l.getEnvInt("COMPRESSION_MIN_BYTES", 1024),
 	}
-	return defaultValue
+	cfg.settings = l.finish()
+	return cfg
 }
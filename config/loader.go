@@ -0,0 +1,238 @@
+package config
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// envPrefix is the optional prefix of environment variables, e.g. APP_PORT for PORT. Prefixed
+// variables win over unprefixed ones, so deployments can namespace their settings.
+const envPrefix = "APP_"
+
+// setting records the value a configuration setting ended up with, for LogEffective
+type setting struct {
+	Key    string
+	Value  string
+	Source string // "flag", the environment variable's name, or "default"
+	Secret bool
+}
+
+// loader resolves settings from command-line flags and the environment, collecting invalid values
+// so they can all be reported at once
+type loader struct {
+	flags    map[string]string // Flag values by setting key
+	used     map[string]bool
+	settings []setting
+	errs     []string
+	help     bool
+}
+
+// newLoader parses args as --name=value or --name value flags. A flag without a value, or followed
+// by another flag, is set to "true".
+func newLoader(args []string) *loader {
+	l := &loader{flags: make(map[string]string), used: make(map[string]bool)}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") {
+			l.errs = append(l.errs, fmt.Sprintf("unexpected argument %q", arg))
+			continue
+		}
+		name, value, ok := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if name == "h" || name == "help" {
+			l.help = true
+			continue
+		}
+		if !ok {
+			value = "true"
+			if i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+				value = args[i+1]
+				i++
+			}
+		}
+		l.flags[flagKey(name)] = value
+	}
+	return l
+}
+
+// flagKey converts a flag name to the key of its setting, e.g. mongodb-uri to MONGODB_URI
+func flagKey(name string) string {
+	return strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// flagName converts a setting key to its flag name, e.g. MONGODB_URI to mongodb-uri
+func flagName(key string) string {
+	return strings.ToLower(strings.ReplaceAll(key, "_", "-"))
+}
+
+// lookup returns the raw value of a setting and where it came from, or ok false when it isn't set
+func (l *loader) lookup(key string) (value, source string, ok bool) {
+	l.used[key] = true
+	if value, ok := l.flags[key]; ok {
+		return value, "flag", true
+	}
+	if value := os.Getenv(envPrefix + key); value != "" {
+		return value, envPrefix + key, true
+	}
+	if value := os.Getenv(key); value != "" {
+		return value, key, true
+	}
+	return "", "default", false
+}
+
+// record remembers the effective value of a setting
+func (l *loader) record(key, value, source string, secret bool) {
+	l.settings = append(l.settings, setting{Key: key, Value: value, Source: source, Secret: secret})
+}
+
+// invalid reports a setting whose value can't be parsed
+func (l *loader) invalid(key, source, value, expected string) {
+	l.errs = append(l.errs, fmt.Sprintf("%s (from %s): %q is not %s", key, source, value, expected))
+}
+
+// getEnv gets a string setting with a fallback default value
+func (l *loader) getEnv(key, defaultValue string) string {
+	value, source, ok := l.lookup(key)
+	if !ok {
+		value = defaultValue
+	}
+	l.record(key, value, source, false)
+	return value
+}
+
+// getSecret gets a string setting whose value is never logged
+func (l *loader) getSecret(key, defaultValue string) string {
+	value, source, ok := l.lookup(key)
+	if !ok {
+		value = defaultValue
+	}
+	l.record(key, value, source, true)
+	return value
+}
+
+// getEnvInt gets an integer setting with a fallback default value
+func (l *loader) getEnvInt(key string, defaultValue int) int {
+	value, source, ok := l.lookup(key)
+	if !ok {
+		l.record(key, strconv.Itoa(defaultValue), source, false)
+		return defaultValue
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		l.invalid(key, source, value, "an integer")
+		return defaultValue
+	}
+	l.record(key, value, source, false)
+	return parsed
+}
+
+// getEnvDuration gets a duration setting as a whole number of unit. The value is either a plain
+// number of unit, e.g. 90 for a setting in seconds, or a Go duration such as 1m30s.
+func (l *loader) getEnvDuration(key string, unit time.Duration, defaultValue int) int {
+	value, source, ok := l.lookup(key)
+	if !ok {
+		l.record(key, strconv.Itoa(defaultValue), source, false)
+		return defaultValue
+	}
+	if parsed, err := strconv.Atoi(value); err == nil {
+		l.record(key, value, source, false)
+		return parsed
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d%unit != 0 {
+		l.invalid(key, source, value, "a whole number of "+unitName(unit))
+		return defaultValue
+	}
+	parsed := int(d / unit)
+	l.record(key, strconv.Itoa(parsed), source, false)
+	return parsed
+}
+
+// unitName names a duration unit in error messages
+func unitName(unit time.Duration) string {
+	switch unit {
+	case time.Millisecond:
+		return "milliseconds"
+	case time.Minute:
+		return "minutes"
+	case time.Hour:
+		return "hours"
+	default:
+		return "seconds"
+	}
+}
+
+// getEnvBool gets a boolean setting with a fallback default value
+func (l *loader) getEnvBool(key string, defaultValue bool) bool {
+	value, source, ok := l.lookup(key)
+	if !ok {
+		l.record(key, strconv.FormatBool(defaultValue), source, false)
+		return defaultValue
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		l.invalid(key, source, value, "a boolean")
+		return defaultValue
+	}
+	l.record(key, strconv.FormatBool(parsed), source, false)
+	return parsed
+}
+
+// getEnvFloat gets a float setting with a fallback default value
+func (l *loader) getEnvFloat(key string, defaultValue float64) float64 {
+	value, source, ok := l.lookup(key)
+	if !ok {
+		l.record(key, strconv.FormatFloat(defaultValue, 'g', -1, 64), source, false)
+		return defaultValue
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		l.invalid(key, source, value, "a number")
+		return defaultValue
+	}
+	l.record(key, value, source, false)
+	return parsed
+}
+
+// finish prints the usage for --help, and stops the program on unknown flags and invalid values
+func (l *loader) finish() []setting {
+	if l.help {
+		fmt.Fprintf(os.Stderr, "Usage: %s [--name=value ...]\n\nEach setting is a flag or an environment variable, with or without the %s prefix:\n\n", os.Args[0], envPrefix)
+		for _, s := range l.settings {
+			fmt.Fprintf(os.Stderr, "  --%-30s %s\n", flagName(s.Key), s.Key)
+		}
+		os.Exit(0)
+	}
+
+	for key := range l.flags {
+		if !l.used[key] {
+			l.errs = append(l.errs, fmt.Sprintf("unknown flag --%s", flagName(key)))
+		}
+	}
+	if len(l.errs) > 0 {
+		log.Fatalf("Invalid configuration:\n  %s", strings.Join(l.errs, "\n  "))
+	}
+	return l.settings
+}
+
+// LogEffective logs every setting with its value and where it came from. Secrets are only shown
+// as set or not, and passwords in URLs are masked.
+func (c *Config) LogEffective() {
+	log.Println("Effective configuration:")
+	for _, s := range c.settings {
+		value := s.Value
+		switch {
+		case s.Secret && value != "":
+			value = "[redacted]"
+		case strings.Contains(value, "://"):
+			if u, err := url.Parse(value); err == nil {
+				value = u.Redacted()
+			}
+		}
+		log.Printf("  %s=%s (%s)", s.Key, value, s.Source)
+	}
+}
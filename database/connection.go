@@ -1,6 +1,7 @@
 package database
 
 import (
+	"auto-annotation-api/utils"
 	"context"
 	"crypto/tls"
 	"log"
@@ -27,6 +28,11 @@ func Connect(mongoURI, databaseName string) (*mongo.Database, error) {
 	}
 	clientOptions.SetTLSConfig(tlsConfig)
 
+	// Record a span for every command run on behalf of a traced request
+	if utils.TracingEnabled() {
+		clientOptions.SetMonitor(utils.MongoTracingMonitor(databaseName))
+	}
+
 	// Set timeout - increase for Atlas
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -49,6 +55,14 @@ func Connect(mongoURI, databaseName string) (*mongo.Database, error) {
 	// Get database instance
 	database = client.Database(databaseName)
 
+	// Failures are only logged: duplicate emails from before the unique index, for example, keep
+	// it from being built until they are cleaned up, but the API still works without it
+	indexCtx, cancelIndexes := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancelIndexes()
+	if err := EnsureIndexes(indexCtx, database); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+
 	return database, nil
 }
 
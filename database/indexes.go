@@ -0,0 +1,62 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// indexes are the indexes every collection needs, by collection. Creating an index that already
+// exists with the same options is a no-op, so they are ensured on every start.
+var indexes = map[string][]mongo.IndexModel{
+	"users": {
+		// Registration checks for the email first, the index closes the race between check and insert
+		{Keys: bson.D{{Key: "email", Value: 1}}, Options: options.Index().SetName("email_unique").SetUnique(true)},
+	},
+	"annotations": {
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "content_hash", Value: 1}}}, // Duplicate upload check, not unique: failed, merged and simulated annotations may share a hash
+		{Keys: bson.D{{Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "pinned", Value: -1}, {Key: "position", Value: 1}, {Key: "created_at", Value: -1}}}, // List order
+		{Keys: bson.D{{Key: "review_state", Value: 1}, {Key: "created_at", Value: -1}}},                         // Review queue
+		{
+			Keys:    bson.D{{Key: "title", Value: "text"}, {Key: "tags", Value: "text"}, {Key: "annotation", Value: "text"}},
+			Options: options.Index().SetName("search_text").SetWeights(bson.D{{Key: "title", Value: 10}, {Key: "tags", Value: 5}, {Key: "annotation", Value: 1}}),
+		},
+	},
+	"annotation_links": {
+		{Keys: bson.D{{Key: "source_id", Value: 1}}},
+		{Keys: bson.D{{Key: "target_id", Value: 1}}},
+	},
+	"quizzes": {
+		{Keys: bson.D{{Key: "annotation_id", Value: 1}, {Key: "created_at", Value: -1}}},
+	},
+	"takedowns": {
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "uploader_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "annotation_id", Value: 1}}},
+	},
+	// Expired entries are purged by MongoDB's TTL monitor
+	"revoked_tokens": {
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	},
+	"upload_quotas": {
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	},
+}
+
+// EnsureIndexes creates the missing indexes of every collection. A failing collection doesn't stop
+// the others; the errors are returned together.
+func EnsureIndexes(ctx context.Context, db *mongo.Database) error {
+	var failed []error
+	for collection, models := range indexes {
+		if _, err := db.Collection(collection).Indexes().CreateMany(ctx, models); err != nil {
+			failed = append(failed, fmt.Errorf("failed to create %s indexes: %w", collection, err))
+		}
+	}
+	return errors.Join(failed...)
+}
@@ -0,0 +1,9 @@
+// Package docs holds the OpenAPI description of the API
+package docs
+
+import _ "embed"
+
+// OpenAPI is the OpenAPI 3 document of the API, in YAML. Keep it in step with the routes in main.go.
+//
+//go:embed openapi.yaml
+var OpenAPI []byte
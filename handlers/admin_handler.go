@@ -0,0 +1,621 @@
+package handlers
+
+import (
+	"auto-annotation-api/models"
+	"auto-annotation-api/services"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AdminHandler struct {
+	userService       *services.UserService
+	linkChecker       *services.LinkCheckService
+	annotationService *services.AnnotationService // Rebuilds the search index
+	reportService     *services.ReportService
+}
+
+// NewAdminHandler creates a new admin handler
+func NewAdminHandler(userService *services.UserService, linkChecker *services.LinkCheckService, annotationService *services.AnnotationService, reportService *services.ReportService) *AdminHandler {
+	return &AdminHandler{
+		userService:       userService,
+		linkChecker:       linkChecker,
+		annotationService: annotationService,
+		reportService:     reportService,
+	}
+}
+
+// ListUsers handles GET /admin/users?role=content&limit=20&offset=0
+func (h *AdminHandler) ListUsers(c *gin.Context) {
+	limit, err := strconv.ParseInt(c.DefaultQuery("limit", "20"), 10, 64)
+	if err != nil || limit <= 0 {
+		limit = 20
+	}
+
+	offset, err := strconv.ParseInt(c.DefaultQuery("offset", "0"), 10, 64)
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	role := c.Query("role")
+	users, err := h.userService.ListUsers(c.Request.Context(), role, limit+1, offset)
+	hasMore := len(users) > int(limit)
+	if hasMore {
+		users = users[:limit]
+	}
+	var pagination models.Pagination
+	if err == nil {
+		pagination, err = newPagination(c, limit, offset, len(users), hasMore, func() (int64, error) {
+			return h.userService.CountUsers(c.Request.Context(), role)
+		})
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to get users",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	responses := make([]models.UserResponse, len(users))
+	for i, user := range users {
+		responses[i] = user.ToUserResponse()
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Users retrieved successfully",
+		"data": gin.H{
+			"users":      responses,
+			"pagination": pagination,
+		},
+	})
+}
+
+// GetUser handles GET /admin/users/:id
+func (h *AdminHandler) GetUser(c *gin.Context) {
+	user, err := h.userService.GetUser(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondUserError(c, "Failed to get user", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "User retrieved successfully",
+		"data":    user.ToUserResponse(),
+	})
+}
+
+// UpdateUserRole handles PUT /admin/users/:id/role
+func (h *AdminHandler) UpdateUserRole(c *gin.Context) {
+	admin, ok := contextUser(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateUserRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid request body",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	user, err := h.userService.UpdateRole(c.Request.Context(), admin.ID, c.Param("id"), req.Role)
+	if err != nil {
+		respondUserError(c, "Failed to update user role", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "User role updated successfully",
+		"data":    user.ToUserResponse(),
+	})
+}
+
+// UpdateUserStatus handles PUT /admin/users/:id/status ({"disabled": true} disables the account)
+func (h *AdminHandler) UpdateUserStatus(c *gin.Context) {
+	admin, ok := contextUser(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateUserStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid request body",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	user, err := h.userService.SetDisabled(c.Request.Context(), admin.ID, c.Param("id"), *req.Disabled)
+	if err != nil {
+		respondUserError(c, "Failed to update user status", err)
+		return
+	}
+
+	message := "User enabled successfully"
+	if user.Disabled {
+		message = "User disabled successfully"
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": message,
+		"data":    user.ToUserResponse(),
+	})
+}
+
+// UpdateUserContentRestrictions handles PUT /admin/users/:id/content-restrictions, replacing the
+// safety labels of annotations hidden from the user
+func (h *AdminHandler) UpdateUserContentRestrictions(c *gin.Context) {
+	var req models.UpdateContentRestrictionsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid request body",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	user, err := h.userService.SetContentRestrictions(c.Request.Context(), c.Param("id"), req.Labels)
+	if err != nil {
+		respondUserError(c, "Failed to update content restrictions", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Content restrictions updated successfully",
+		"data":    user.ToUserResponse(),
+	})
+}
+
+// DeleteUser handles DELETE /admin/users/:id (the user's annotations are kept)
+func (h *AdminHandler) DeleteUser(c *gin.Context) {
+	admin, ok := contextUser(c)
+	if !ok {
+		return
+	}
+
+	if err := h.userService.DeleteUser(c.Request.Context(), admin.ID, c.Param("id")); err != nil {
+		respondUserError(c, "Failed to delete user", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "User deleted successfully",
+	})
+}
+
+// GetBrokenLinkReport handles GET /admin/reports/broken-links
+func (h *AdminHandler) GetBrokenLinkReport(c *gin.Context) {
+	reports, err := h.linkChecker.BrokenLinkReport(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to get broken link report",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Broken link report retrieved successfully",
+		"data": gin.H{
+			"annotations": reports,
+			"count":       len(reports),
+		},
+	})
+}
+
+// RunLinkCheck handles POST /admin/reports/broken-links/run (starts a check in the background)
+func (h *AdminHandler) RunLinkCheck(c *gin.Context) {
+	started := services.RunInBackground(func(ctx context.Context) {
+		if err := h.linkChecker.RunLinkCheck(ctx); err != nil {
+			log.Printf("Link check failed: %v", err)
+		}
+	})
+	if !started {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"success": false,
+			"message": "Failed to start link check",
+			"error":   "server is shutting down, retry shortly",
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"success": true,
+		"message": "Link check started",
+	})
+}
+
+// ExportUploadConsents handles GET /admin/reports/upload-consents?format=csv|json&from=...&to=..., the
+// copyright attestations recorded with uploads. from and to are inclusive dates.
+func (h *AdminHandler) ExportUploadConsents(c *gin.Context) {
+	format := c.DefaultQuery("format", "csv")
+	exportFormat, ok := services.ExportFormats[format]
+	if !ok || format == "md" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid export format, expected csv or json",
+		})
+		return
+	}
+
+	var from, to time.Time
+	if fromStr := c.Query("from"); fromStr != "" {
+		date, err := time.Parse(exportDateLayout, fromStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Invalid from date, expected YYYY-MM-DD",
+			})
+			return
+		}
+		from = date
+	}
+	if toStr := c.Query("to"); toStr != "" {
+		date, err := time.Parse(exportDateLayout, toStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Invalid to date, expected YYYY-MM-DD",
+			})
+			return
+		}
+		to = date.AddDate(0, 0, 1)
+	}
+
+	filename := fmt.Sprintf("upload-consents-%s.%s", time.Now().UTC().Format(exportDateLayout), exportFormat.Extension)
+	c.Header("Content-Type", exportFormat.ContentType)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Status(http.StatusOK)
+
+	err := h.annotationService.ExportUploadConsents(c.Request.Context(), from, to, format, c.Writer)
+	if err != nil {
+		// Once the file has started, the status can't change anymore
+		if c.Writer.Written() {
+			log.Printf("Warning: upload consent export aborted: %v", err)
+			return
+		}
+
+		c.Header("Content-Type", "")
+		c.Header("Content-Disposition", "")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to export upload consents",
+			"error":   err.Error(),
+		})
+	}
+}
+
+// DeleteSimulatedAnnotations handles DELETE /admin/load-test/annotations (cleanup after a load test)
+func (h *AdminHandler) DeleteSimulatedAnnotations(c *gin.Context) {
+	deleted, err := h.annotationService.DeleteSimulatedAnnotations(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to delete simulated annotations",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Simulated annotations deleted successfully",
+		"data":    gin.H{"deleted": deleted},
+	})
+}
+
+// StartReindex handles POST /admin/search/reindex
+func (h *AdminHandler) StartReindex(c *gin.Context) {
+	status, err := h.annotationService.StartReindex(c.Request.Context())
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if strings.Contains(err.Error(), "not configured") {
+			statusCode = http.StatusServiceUnavailable
+		} else if strings.Contains(err.Error(), "already running") {
+			statusCode = http.StatusConflict
+		} else if strings.Contains(err.Error(), "shutting down") {
+			statusCode = http.StatusServiceUnavailable
+		}
+
+		c.JSON(statusCode, gin.H{
+			"success": false,
+			"message": "Failed to start reindex",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"success": true,
+		"message": "Reindex started",
+		"data":    status,
+	})
+}
+
+// GetReindexStatus handles GET /admin/search/reindex
+func (h *AdminHandler) GetReindexStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Reindex status retrieved successfully",
+		"data":    h.annotationService.ReindexStatus(),
+	})
+}
+
+// GetContentReport handles GET /admin/reports/weekly, the content report of the last 7 days, or of
+// ?from=YYYY-MM-DD&to=YYYY-MM-DD (both inclusive)
+func (h *AdminHandler) GetContentReport(c *gin.Context) {
+	to := time.Now().UTC()
+	from := to.AddDate(0, 0, -7)
+	if fromStr := c.Query("from"); fromStr != "" {
+		date, err := time.Parse(exportDateLayout, fromStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Invalid from date, expected YYYY-MM-DD",
+			})
+			return
+		}
+		from = date
+	}
+	if toStr := c.Query("to"); toStr != "" {
+		date, err := time.Parse(exportDateLayout, toStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Invalid to date, expected YYYY-MM-DD",
+			})
+			return
+		}
+		to = date.AddDate(0, 0, 1)
+	}
+
+	report, err := h.reportService.Generate(c.Request.Context(), from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to generate content report",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Content report generated successfully",
+		"data":    report,
+	})
+}
+
+// PinAnnotation handles POST /admin/annotations/:id/pin, listing the annotation after the already
+// pinned ones at the top of the annotation list
+func (h *AdminHandler) PinAnnotation(c *gin.Context) {
+	annotation, err := h.annotationService.PinAnnotation(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondPinError(c, "Failed to pin annotation", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Annotation pinned successfully",
+		"data":    annotation.ToResponse(),
+	})
+}
+
+// UnpinAnnotation handles DELETE /admin/annotations/:id/pin
+func (h *AdminHandler) UnpinAnnotation(c *gin.Context) {
+	if err := h.annotationService.UnpinAnnotation(c.Request.Context(), c.Param("id")); err != nil {
+		respondPinError(c, "Failed to unpin annotation", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Annotation unpinned successfully",
+	})
+}
+
+// SetPinnedAnnotations handles PUT /admin/annotations/pinned ({"annotation_ids": [...]}), replacing
+// the pinned annotations and their order
+func (h *AdminHandler) SetPinnedAnnotations(c *gin.Context) {
+	var req models.PinnedAnnotationsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid request body",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	if err := h.annotationService.SetPinnedAnnotations(c.Request.Context(), req.AnnotationIDs); err != nil {
+		respondPinError(c, "Failed to reorder pinned annotations", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Pinned annotations updated successfully",
+		"data":    gin.H{"annotation_ids": req.AnnotationIDs},
+	})
+}
+
+// ListLLMModels handles GET /admin/llm/models, the models installed in Ollama
+func (h *AdminHandler) ListLLMModels(c *gin.Context) {
+	installed, err := h.annotationService.ListLLMModels(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{
+			"success": false,
+			"message": "Failed to list models",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Models retrieved successfully",
+		"data":    installed,
+	})
+}
+
+// PullLLMModel handles POST /admin/llm/models ({"name": "..."}), downloading a model into Ollama
+// while sending its progress as server-sent "progress" events. The outcome follows as a final "done"
+// or "error" event; when the pull fails before any progress, the response is a regular JSON error.
+func (h *AdminHandler) PullLLMModel(c *gin.Context) {
+	var req models.PullModelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid request body",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	// Downloads report progress many times a second, only status changes and a few updates a
+	// second are passed on
+	streamed := false
+	var last models.PullProgress
+	var lastSent time.Time
+	err := h.annotationService.PullLLMModel(c.Request.Context(), req.Name, func(progress models.PullProgress) {
+		if streamed && progress.Status == last.Status && progress.Digest == last.Digest && time.Since(lastSent) < 500*time.Millisecond {
+			return
+		}
+		if !streamed {
+			streamed = true
+			c.Header("Cache-Control", "no-cache")
+			c.Header("X-Accel-Buffering", "no")
+		}
+		c.SSEvent("progress", progress)
+		c.Writer.Flush()
+		last, lastSent = progress, time.Now()
+	})
+
+	if !streamed {
+		statusCode := http.StatusBadGateway
+		if strings.Contains(err.Error(), "invalid model name") {
+			statusCode = http.StatusBadRequest
+		}
+		c.JSON(statusCode, gin.H{
+			"success": false,
+			"message": "Failed to pull model",
+			"error":   err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.SSEvent("error", gin.H{
+			"success": false,
+			"message": "Failed to pull model",
+			"error":   err.Error(),
+		})
+	} else {
+		c.SSEvent("done", gin.H{
+			"success": true,
+			"message": "Model pulled successfully",
+		})
+	}
+	c.Writer.Flush()
+}
+
+// DeleteLLMModel handles DELETE /admin/llm/models/*name. Model names contain slashes and colons,
+// such as library/llama3:8b, so the name is the rest of the path.
+func (h *AdminHandler) DeleteLLMModel(c *gin.Context) {
+	name := strings.TrimPrefix(c.Param("name"), "/")
+	if err := h.annotationService.DeleteLLMModel(c.Request.Context(), name); err != nil {
+		statusCode := http.StatusBadGateway
+		if strings.HasPrefix(err.Error(), "cannot ") {
+			statusCode = http.StatusConflict
+		} else if strings.HasSuffix(err.Error(), "not found") {
+			statusCode = http.StatusNotFound
+		}
+		c.JSON(statusCode, gin.H{
+			"success": false,
+			"message": "Failed to delete model",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Model deleted successfully",
+	})
+}
+
+// respondPinError maps pinning errors to status codes
+func respondPinError(c *gin.Context, message string, err error) {
+	statusCode := http.StatusInternalServerError
+	if err.Error() == "annotation not found" {
+		statusCode = http.StatusNotFound
+	} else if strings.HasPrefix(err.Error(), "invalid ") {
+		statusCode = http.StatusBadRequest
+	}
+
+	c.JSON(statusCode, gin.H{
+		"success": false,
+		"message": message,
+		"error":   err.Error(),
+	})
+}
+
+// contextUser returns the authenticated user set by AuthMiddleware, responding with an error if missing
+func contextUser(c *gin.Context) (*models.User, bool) {
+	userInterface, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "User not authenticated",
+		})
+		return nil, false
+	}
+
+	user, ok := userInterface.(*models.User)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Invalid user data",
+		})
+		return nil, false
+	}
+	return user, true
+}
+
+// respondUserError maps user management errors to status codes
+func respondUserError(c *gin.Context, message string, err error) {
+	statusCode := http.StatusInternalServerError
+	if err.Error() == "user not found" {
+		statusCode = http.StatusNotFound
+	} else if strings.HasPrefix(err.Error(), "cannot ") {
+		statusCode = http.StatusBadRequest
+	}
+
+	c.JSON(statusCode, gin.H{
+		"success": false,
+		"message": message,
+		"error":   err.Error(),
+	})
+}
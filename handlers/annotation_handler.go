@@ -1,34 +1,51 @@
 package handlers
 
 import (
+	"auto-annotation-api/config"
 	"auto-annotation-api/models"
 	"auto-annotation-api/services"
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
+// languageCodePattern matches language codes such as "en-US", "arb" or "es-419"
+var languageCodePattern = regexp.MustCompile(`^[a-z]{2,3}(-[A-Z]{2}|-[0-9]{3})?$`)
+
 type AnnotationHandler struct {
 	service   *services.AnnotationService
 	uploadDir string
+	cdnMaxAge int // seconds, 0 disables CDN cache headers
+
+	requireConsent bool   // Reject uploads without the copyright attestation
+	uploadTerms    string // Version of the upload terms recorded with attestations
 }
 
 // NewAnnotationHandler creates a new annotation handler
-func NewAnnotationHandler(db *mongo.Database, ollamaBaseURL, ollamaModel, uploadDir string, awsService *services.AWSService) *AnnotationHandler {
-	if uploadDir == "" {
-		uploadDir = "uploads"
+func NewAnnotationHandler(cfg *config.Config, service *services.AnnotationService) *AnnotationHandler {
+	if cfg.UploadDir == "" {
+		cfg.UploadDir = "uploads"
 	}
 
 	return &AnnotationHandler{
-		service:   services.NewAnnotationService(db, ollamaBaseURL, ollamaModel, uploadDir, awsService),
-		uploadDir: uploadDir,
+		service:   service,
+		uploadDir: cfg.UploadDir,
+		cdnMaxAge: cfg.CDNCacheSeconds,
+
+		requireConsent: cfg.RequireConsent,
+		uploadTerms:    cfg.UploadTerms,
 	}
 }
 
@@ -53,23 +70,28 @@ func (h *AnnotationHandler) UploadAndCreateAnnotation(c *gin.Context) {
 		return
 	}
 
-	// Get title from form
+	// Get title from form (looked up by ISBN or determined from the document when omitted)
 	title := c.PostForm("title")
-	if title == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "Title is required",
-		})
-		return
+	isbn := strings.TrimSpace(c.PostForm("isbn"))
+	if isbn != "" {
+		if _, err := services.NormalizeISBN(isbn); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Invalid ISBN",
+				"error":   err.Error(),
+			})
+			return
+		}
 	}
 	
 	// Handle optional image - can be URL or file upload
 	var imageURL string
+	var image *services.ImageUpload
 	
 	// Check if image file was uploaded
 	imageFile, err := c.FormFile("image")
 	if err == nil {
-		// Image file provided - validate and upload to S3
+		// Image file provided - validate and upload to storage
 		ext := strings.ToLower(filepath.Ext(imageFile.Filename))
 		validExts := map[string]bool{
 			".jpg":  true,
@@ -87,7 +109,7 @@ func (h *AnnotationHandler) UploadAndCreateAnnotation(c *gin.Context) {
 			return
 		}
 
-		// Open and read image file
+		// Open image file, which is streamed to storage
 		imgFile, err := imageFile.Open()
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
@@ -99,17 +121,6 @@ func (h *AnnotationHandler) UploadAndCreateAnnotation(c *gin.Context) {
 		}
 		defer imgFile.Close()
 
-		imageData := make([]byte, imageFile.Size)
-		_, err = imgFile.Read(imageData)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"success": false,
-				"message": "Failed to read uploaded image",
-				"error":   err.Error(),
-			})
-			return
-		}
-
 		// Determine content type
 		contentType := "image/jpeg"
 		switch ext {
@@ -121,27 +132,19 @@ func (h *AnnotationHandler) UploadAndCreateAnnotation(c *gin.Context) {
 			contentType = "image/webp"
 		}
 
-		// We'll pass the image data to the service to upload after annotation is created
-		// For now, generate a temporary ID to use for the S3 key
-		tempID := fmt.Sprintf("temp_%d", time.Now().UnixNano())
-		uploadedURL, err := h.service.UploadImageForAnnotationUpdate(c.Request.Context(), tempID, imageData, contentType)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"success": false,
-				"message": "Failed to upload image",
-				"error":   err.Error(),
-			})
-			return
-		}
-		imageURL = uploadedURL
+		// The service uploads the image once the annotation ID is known, so it is stored under that ID
+		image = &services.ImageUpload{Reader: imgFile, Size: imageFile.Size, ContentType: contentType}
 	} else {
 		// No image file - check if image URL was provided as text
 		imageURL = c.PostForm("image_url")
 	}
 
-	// Handle PDF file upload
+	// Handle the document upload, a PDF or an image such as a photo of a whiteboard
 	fileHeader, err := c.FormFile("file")
 	if err != nil {
+		if respondUploadError(c, "Failed to upload file", err) {
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{
 			"success": false,
 			"message": "File is required",
@@ -152,10 +155,12 @@ func (h *AnnotationHandler) UploadAndCreateAnnotation(c *gin.Context) {
 
 	// Validate file type
 	ext := strings.ToLower(filepath.Ext(fileHeader.Filename))
-	if ext != ".pdf" {
+	fileType := strings.TrimPrefix(ext, ".")
+	if ext != ".pdf" && !services.IsImageDocument(fileType) {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"success": false,
-			"message": "Only PDF files are supported",
+			"message": "Only PDF files and images (jpg, png) are supported",
+			"code":    "invalid_file_type",
 		})
 		return
 	}
@@ -172,25 +177,160 @@ func (h *AnnotationHandler) UploadAndCreateAnnotation(c *gin.Context) {
 	}
 	defer file.Close()
 
+	// Check the content too, since the extension alone says nothing about the file
+	validate := services.ValidatePDF
+	if services.IsImageDocument(fileType) {
+		validate = services.ValidateImage
+	}
+	if err := validate(file, fileHeader.Size); err != nil {
+		respondUploadError(c, "Invalid file", err)
+		return
+	}
+
+	consent, ok := h.uploadConsent(c)
+	if !ok {
+		return
+	}
+
+	// Load tests can ask for the pipeline's work to be faked, if the server allows it
+	simulate, _ := strconv.ParseBool(c.PostForm("simulate"))
+	sections, _ := strconv.ParseBool(c.PostForm("sections"))
+
+	req := &models.CreateAnnotationRequest{
+		Title:        title,
+		FileName:     fileHeader.Filename,
+		Image:        imageURL,
+		ISBN:         isbn,
+		Tags:         c.PostFormArray("tags"),
+		Length:       c.PostForm("length"),
+		License:      c.PostForm("license"),
+		Visibility:   c.PostForm("visibility"),
+		ImageAltText: c.PostForm("image_alt_text"),
+		Metadata:     c.PostFormMap("metadata"),
+		SourceURL:    c.PostForm("source_url"),
+		Author:       c.PostForm("author"),
+		PublishedOn:  c.PostForm("publication_date"),
+		Sections:     sections,
+		Model:        c.PostForm("model"),
+		Simulate:     simulate,
+		Consent:      consent,
+	}
+	if err := h.service.CheckUploadRequest(c.Request.Context(), req); err != nil {
+		respondCreateError(c, err)
+		return
+	}
+
+	// The document counts against the daily quota once it passed validation
+	quota, err := h.service.ReserveUploads(c.Request.Context(), user, 1)
+	setQuotaHeaders(c, quota)
+	if err != nil {
+		respondQuotaError(c, err)
+		return
+	}
+
 	// Create annotation from stream
-	fileType := strings.TrimPrefix(ext, ".")
-	annotation, err := h.service.CreateAnnotationFromStream(
-		c.Request.Context(),
-		user.ID,
-		title,
-		imageURL,
-		file,
-		fileHeader.Size,
-		fileType,
-	)
+	create := func(ctx context.Context) (*models.Annotation, error) {
+		return h.service.CreateAnnotationFromStream(ctx, user.ID, req, file, fileHeader.Size, fileType, image)
+	}
+
+	// With ?stream=true the notes are sent as server-sent events while Ollama writes them
+	var annotation *models.Annotation
+	if stream, _ := strconv.ParseBool(c.Query("stream")); stream {
+		var streamed bool
+		annotation, streamed, err = streamNotes(c, create)
+		if streamed {
+			return
+		}
+	} else {
+		annotation, err = create(c.Request.Context())
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
+		// Only failed processing uses up the upload, not a request that turned out to be invalid
+		if respondCreateError(c, err) != http.StatusInternalServerError {
+			h.service.ReleaseUploads(c.Request.Context(), user, 1)
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "Annotation created successfully",
+		"data":    annotation.ToResponse(),
+	})
+}
+
+// CreateAnnotationFromURL handles POST /annotations/from-url, annotating the web page or PDF at the
+// form field "url". It takes the other fields of POST /annotations/upload, except the image file.
+func (h *AnnotationHandler) CreateAnnotationFromURL(c *gin.Context) {
+	user, ok := contextUser(c)
+	if !ok {
+		return
+	}
+
+	pageURL := strings.TrimSpace(c.PostForm("url"))
+	if pageURL == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
 			"success": false,
-			"message": "Failed to create annotation",
-			"error":   err.Error(),
+			"message": "URL is required",
 		})
 		return
 	}
+	isbn := strings.TrimSpace(c.PostForm("isbn"))
+	if isbn != "" {
+		if _, err := services.NormalizeISBN(isbn); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Invalid ISBN",
+				"error":   err.Error(),
+			})
+			return
+		}
+	}
+
+	consent, ok := h.uploadConsent(c)
+	if !ok {
+		return
+	}
+	sections, _ := strconv.ParseBool(c.PostForm("sections"))
+
+	req := &models.CreateAnnotationRequest{
+		Title:        c.PostForm("title"),
+		Image:        c.PostForm("image_url"),
+		ISBN:         isbn,
+		Tags:         c.PostFormArray("tags"),
+		Length:       c.PostForm("length"),
+		License:      c.PostForm("license"),
+		Visibility:   c.PostForm("visibility"),
+		ImageAltText: c.PostForm("image_alt_text"),
+		Metadata:     c.PostFormMap("metadata"),
+		SourceURL:    c.PostForm("source_url"),
+		Author:       c.PostForm("author"),
+		PublishedOn:  c.PostForm("publication_date"),
+		Sections:     sections,
+		Model:        c.PostForm("model"),
+		Consent:      consent,
+	}
+	if err := h.service.CheckUploadRequest(c.Request.Context(), req); err != nil {
+		respondCreateError(c, err)
+		return
+	}
+
+	// The page counts against the daily quota like an uploaded document
+	quota, err := h.service.ReserveUploads(c.Request.Context(), user, 1)
+	setQuotaHeaders(c, quota)
+	if err != nil {
+		respondQuotaError(c, err)
+		return
+	}
+
+	annotation, err := h.service.CreateAnnotationFromURL(c.Request.Context(), user.ID, req, pageURL)
+	if err != nil {
+		// Only failed processing uses up the upload, not an invalid request or an unreachable URL
+		if respondCreateError(c, err) != http.StatusInternalServerError {
+			h.service.ReleaseUploads(c.Request.Context(), user, 1)
+		}
+		return
+	}
 
 	c.JSON(http.StatusCreated, gin.H{
 		"success": true,
@@ -199,20 +339,123 @@ func (h *AnnotationHandler) UploadAndCreateAnnotation(c *gin.Context) {
 	})
 }
 
-// GetAnnotation handles GET /annotations/:id (any authenticated user can view)
-func (h *AnnotationHandler) GetAnnotation(c *gin.Context) {
-	annotationID := c.Param("id")
-	
-	annotation, err := h.service.GetAnnotationByID(c.Request.Context(), annotationID)
+// streamNotes runs create while sending the notes Ollama writes as server-sent "notes" events. The
+// stream opens with the first piece of text; when create finishes before that, for example because
+// the upload is rejected, nothing was written and its result is returned for a regular response.
+// Otherwise the outcome follows as a final "done" or "error" event and streamed is true.
+func streamNotes(c *gin.Context, create func(ctx context.Context) (*models.Annotation, error)) (annotation *models.Annotation, streamed bool, err error) {
+	type result struct {
+		annotation *models.Annotation
+		err        error
+	}
+	ctx := c.Request.Context()
+	texts := make(chan string)
+	done := make(chan result, 1)
+	go func() {
+		annotation, err := create(services.WithNotesStream(ctx, func(text string) {
+			select {
+			case texts <- text:
+			case <-ctx.Done(): // The client is gone
+			}
+		}))
+		done <- result{annotation, err}
+	}()
+
+	for {
+		select {
+		case text := <-texts:
+			if !streamed {
+				streamed = true
+				c.Header("Cache-Control", "no-cache")
+				c.Header("X-Accel-Buffering", "no") // Keeps proxies such as nginx from buffering the events
+			}
+			c.SSEvent("notes", gin.H{"text": text})
+			c.Writer.Flush()
+		case r := <-done:
+			if !streamed {
+				return r.annotation, false, r.err
+			}
+			if r.err != nil {
+				c.SSEvent("error", gin.H{
+					"success": false,
+					"message": "Failed to create annotation",
+					"error":   r.err.Error(),
+				})
+			} else {
+				c.SSEvent("done", gin.H{
+					"success": true,
+					"message": "Annotation created successfully",
+					"data":    r.annotation.ToResponse(),
+				})
+			}
+			c.Writer.Flush()
+			return nil, true, nil
+		}
+	}
+}
+
+// EstimateProcessing handles POST /annotations/estimate, returning the expected processing time,
+// token usage and TTS cost of a document. It takes a JSON body with file_size and/or page_count,
+// or a PDF in the multipart field "file", which is only measured, not processed.
+func (h *AnnotationHandler) EstimateProcessing(c *gin.Context) {
+	var estimate *models.ProcessingEstimate
+	var err error
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		fileHeader, formErr := c.FormFile("file")
+		if formErr != nil {
+			if respondUploadError(c, "Failed to upload file", formErr) {
+				return
+			}
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "File is required",
+				"error":   formErr.Error(),
+			})
+			return
+		}
+		if strings.ToLower(filepath.Ext(fileHeader.Filename)) != ".pdf" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Only PDF files are supported",
+				"code":    "invalid_file_type",
+			})
+			return
+		}
+		file, openErr := fileHeader.Open()
+		if openErr != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Failed to open uploaded file",
+				"error":   openErr.Error(),
+			})
+			return
+		}
+		defer file.Close()
+		estimate, err = h.service.EstimateUpload(c.Request.Context(), file)
+	} else {
+		var req models.EstimateRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Invalid request body",
+				"error":   err.Error(),
+			})
+			return
+		}
+		estimate, err = h.service.EstimateProcessing(c.Request.Context(), &req)
+	}
+
 	if err != nil {
-		statusCode := http.StatusNotFound
-		if err.Error() != "annotation not found" {
-			statusCode = http.StatusInternalServerError
+		if respondUploadError(c, "Failed to estimate processing", err) {
+			return
+		}
+		statusCode := http.StatusInternalServerError
+		if strings.Contains(err.Error(), "is required") || strings.Contains(err.Error(), "must not be negative") {
+			statusCode = http.StatusBadRequest
 		}
-		
 		c.JSON(statusCode, gin.H{
 			"success": false,
-			"message": "Failed to get annotation",
+			"message": "Failed to estimate processing",
 			"error":   err.Error(),
 		})
 		return
@@ -220,55 +463,868 @@ func (h *AnnotationHandler) GetAnnotation(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"message": "Annotation retrieved successfully",
-		"data":    annotation.ToResponse(),
+		"message": "Processing estimated successfully",
+		"data":    estimate,
 	})
 }
 
-// GetAllAnnotations handles GET /annotations (all annotations for any authenticated user)
-func (h *AnnotationHandler) GetAllAnnotations(c *gin.Context) {
-	// Parse query parameters
-	limitStr := c.DefaultQuery("limit", "10")
-	offsetStr := c.DefaultQuery("offset", "0")
+// SuggestTitle handles POST /annotations/suggest-title with a PDF in "file", returning the title an
+// upload of the document without one would get, so the uploader can confirm or change it first
+func (h *AnnotationHandler) SuggestTitle(c *gin.Context) {
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		if respondUploadError(c, "Failed to upload file", err) {
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "File is required",
+			"error":   err.Error(),
+		})
+		return
+	}
 
-	limit, err := strconv.ParseInt(limitStr, 10, 64)
-	if err != nil || limit <= 0 {
-		limit = 10
+	ext := strings.ToLower(filepath.Ext(fileHeader.Filename))
+	if ext != ".pdf" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Only PDF files are supported",
+			"code":    "invalid_file_type",
+		})
+		return
 	}
 
-	offset, err := strconv.ParseInt(offsetStr, 10, 64)
-	if err != nil || offset < 0 {
-		offset = 0
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to open uploaded file",
+			"error":   err.Error(),
+		})
+		return
 	}
+	defer file.Close()
 
-	// Get all annotations (no user filter)
-	annotations, err := h.service.GetAllAnnotations(c.Request.Context(), limit, offset)
+	suggestion, err := h.service.SuggestTitle(c.Request.Context(), file, strings.TrimPrefix(ext, "."), fileHeader.Filename)
 	if err != nil {
+		if respondUploadError(c, "Failed to suggest a title", err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
-			"message": "Failed to get annotations",
+			"message": "Failed to suggest a title",
 			"error":   err.Error(),
 		})
 		return
 	}
 
-	// Convert to response format
-	responses := make([]models.AnnotationResponse, len(annotations))
-	for i, annotation := range annotations {
-		responses[i] = annotation.ToResponse()
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Title suggested successfully",
+		"data":    suggestion,
+	})
+}
+
+// BulkUpload handles POST /annotations/bulk-upload with several PDFs or ZIP archives of PDFs in
+// "files". Documents are processed in the background; poll GET /annotations/batches/:id.
+func (h *AnnotationHandler) BulkUpload(c *gin.Context) {
+	user, ok := contextUser(c)
+	if !ok {
+		return
+	}
+
+	form, err := c.MultipartForm()
+	if err != nil {
+		if respondUploadError(c, "Failed to upload files", err) {
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Files are required",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	consent, ok := h.uploadConsent(c)
+	if !ok {
+		return
+	}
+
+	var files []services.BatchFile
+	for _, fileHeader := range form.File["files"] {
+		ext := strings.ToLower(filepath.Ext(fileHeader.Filename))
+		if ext != ".pdf" && ext != ".zip" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Only PDF files and ZIP archives are supported",
+				"code":    "invalid_file_type",
+				"error":   fileHeader.Filename,
+			})
+			return
+		}
+
+		file, err := fileHeader.Open()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Failed to open uploaded file",
+				"error":   err.Error(),
+			})
+			return
+		}
+		data, err := io.ReadAll(file)
+		file.Close()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Failed to read uploaded file",
+				"error":   err.Error(),
+			})
+			return
+		}
+
+		files = append(files, services.BatchFile{Name: filepath.Base(fileHeader.Filename), Data: data})
+	}
+
+	simulate, _ := strconv.ParseBool(c.PostForm("simulate"))
+	sections, _ := strconv.ParseBool(c.PostForm("sections"))
+	autoTitle, _ := strconv.ParseBool(c.PostForm("auto_title"))
+	batch, err := h.service.CreateUploadBatch(c.Request.Context(), user, files, &models.CreateAnnotationRequest{
+		Tags:       c.PostFormArray("tags"),
+		Length:     c.PostForm("length"),
+		License:    c.PostForm("license"),
+		Visibility: c.PostForm("visibility"),
+		Metadata:   c.PostFormMap("metadata"),
+		AutoTitle:  autoTitle,
+		Sections:   sections,
+		Model:      c.PostForm("model"),
+		Simulate:   simulate,
+		Consent:    consent,
+	})
+	if quota, quotaErr := h.service.UploadQuota(c.Request.Context(), user); quotaErr == nil {
+		setQuotaHeaders(c, quota)
+	}
+	if err != nil {
+		if strings.Contains(err.Error(), "quota exceeded") {
+			respondQuotaError(c, err)
+			return
+		}
+
+		statusCode := http.StatusInternalServerError
+		if strings.Contains(err.Error(), "invalid batch") || strings.Contains(err.Error(), "invalid length") ||
+			strings.Contains(err.Error(), "invalid license") || strings.Contains(err.Error(), "invalid visibility") ||
+			strings.Contains(err.Error(), "invalid metadata") || strings.Contains(err.Error(), "invalid model") {
+			statusCode = http.StatusBadRequest
+		} else if strings.Contains(err.Error(), "not enabled") {
+			statusCode = http.StatusForbidden
+		} else if strings.Contains(err.Error(), "shutting down") {
+			statusCode = http.StatusServiceUnavailable
+		}
+
+		c.JSON(statusCode, gin.H{
+			"success": false,
+			"message": "Failed to create upload batch",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"success": true,
+		"message": "Upload batch created, documents are processed in the background",
+		"data":    batch,
+	})
+}
+
+// GetUploadBatch handles GET /annotations/batches/:id
+func (h *AnnotationHandler) GetUploadBatch(c *gin.Context) {
+	user, ok := contextUser(c)
+	if !ok {
+		return
+	}
+
+	batch, err := h.service.GetUploadBatch(c.Request.Context(), c.Param("id"), user)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if strings.Contains(err.Error(), "not found") {
+			statusCode = http.StatusNotFound
+		}
+
+		c.JSON(statusCode, gin.H{
+			"success": false,
+			"message": "Failed to get upload batch",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Upload batch retrieved successfully",
+		"data":    batch,
+	})
+}
+
+// MergeAnnotations handles POST /annotations/merge
+func (h *AnnotationHandler) MergeAnnotations(c *gin.Context) {
+	user, ok := contextUser(c)
+	if !ok {
+		return
+	}
+
+	var req models.MergeAnnotationsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid request body",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	annotation, err := h.service.MergeAnnotations(c.Request.Context(), user.ID, &req)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if strings.Contains(err.Error(), "not found") {
+			statusCode = http.StatusNotFound
+		} else if strings.Contains(err.Error(), "cannot merge") || strings.Contains(err.Error(), "invalid length") {
+			statusCode = http.StatusBadRequest
+		}
+
+		c.JSON(statusCode, gin.H{
+			"success": false,
+			"message": "Failed to merge annotations",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "Annotations merged successfully",
+		"data":    annotation.ToResponse(),
+	})
+}
+
+// ImportMetadata handles POST /annotations/metadata-import (multipart "file" CSV, optional ?dry_run=true)
+func (h *AnnotationHandler) ImportMetadata(c *gin.Context) {
+	dryRun, _ := strconv.ParseBool(c.Query("dry_run"))
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		if respondUploadError(c, "Failed to upload file", err) {
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "File is required",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to open uploaded file",
+			"error":   err.Error(),
+		})
+		return
+	}
+	defer file.Close()
+
+	report, err := h.service.ImportMetadataCSV(c.Request.Context(), file, dryRun)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if strings.Contains(err.Error(), "invalid CSV") || strings.Contains(err.Error(), "invalid metadata") {
+			statusCode = http.StatusBadRequest
+		}
+
+		c.JSON(statusCode, gin.H{
+			"success": false,
+			"message": "Failed to import metadata",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	message := "Metadata imported"
+	if dryRun {
+		message = "Metadata import validated, nothing was changed"
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": message,
+		"data":    report,
+	})
+}
+
+// SearchAnnotations handles GET /annotations/search?q=...&tag=...&genre=...
+func (h *AnnotationHandler) SearchAnnotations(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	results, err := h.service.SearchAnnotations(c.Request.Context(), models.SearchQuery{
+		Query:        strings.TrimSpace(c.Query("q")),
+		Tag:          c.Query("tag"),
+		Genre:        c.Query("genre"),
+		Hidden:       h.service.HiddenSafetyLabels(optionalUser(c)),
+		ReviewedOnly: !seesUnreviewed(optionalUser(c)),
+		Limit:        limit,
+		Offset:       offset,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to search annotations",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Search completed successfully",
+		"data":    results,
+	})
+}
+
+// SemanticSearch handles GET /annotations/semantic-search?q=..., finding annotations related in
+// meaning to the query even when they don't contain its words
+func (h *AnnotationHandler) SemanticSearch(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit <= 0 || limit > 50 {
+		limit = 10
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	results, err := h.service.SemanticSearch(c.Request.Context(), models.SearchQuery{
+		Query:        c.Query("q"),
+		Tag:          c.Query("tag"),
+		Genre:        c.Query("genre"),
+		Hidden:       h.service.HiddenSafetyLabels(optionalUser(c)),
+		ReviewedOnly: !seesUnreviewed(optionalUser(c)),
+		Limit:        limit,
+		Offset:       offset,
+	})
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if strings.HasPrefix(err.Error(), "invalid query") {
+			statusCode = http.StatusBadRequest
+		} else if strings.Contains(err.Error(), "not configured") {
+			statusCode = http.StatusServiceUnavailable
+		}
+
+		c.JSON(statusCode, gin.H{
+			"success": false,
+			"message": "Failed to search annotations",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Search completed successfully",
+		"data":    results,
+	})
+}
+
+// SplitAnnotation handles POST /annotations/:id/split
+func (h *AnnotationHandler) SplitAnnotation(c *gin.Context) {
+	user, ok := contextUser(c)
+	if !ok {
+		return
+	}
+
+	var req models.SplitAnnotationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid request body",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	parts, err := h.service.SplitAnnotation(c.Request.Context(), c.Param("id"), user.ID, &req)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if strings.Contains(err.Error(), "not found") {
+			statusCode = http.StatusNotFound
+		} else if strings.Contains(err.Error(), "cannot split") || strings.Contains(err.Error(), "invalid page range") ||
+			strings.Contains(err.Error(), "invalid length") {
+			statusCode = http.StatusBadRequest
+		}
+
+		c.JSON(statusCode, gin.H{
+			"success": false,
+			"message": "Failed to split annotation",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	responses := make([]models.AnnotationResponse, len(parts))
+	for i, part := range parts {
+		responses[i] = part.ToResponse()
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "Annotation split successfully",
+		"data":    responses,
+	})
+}
+
+// GetAnnotation handles GET /annotations/:id (any authenticated user can view), with the linked annotations
+func (h *AnnotationHandler) GetAnnotation(c *gin.Context) {
+	annotationID := c.Param("id")
+	
+	annotation, err := h.service.GetAnnotationByID(c.Request.Context(), annotationID)
+	if err == nil && !annotation.IsReviewed() && !seesUnreviewed(optionalUser(c)) {
+		err = fmt.Errorf("annotation not found")
+	}
+	if err == nil {
+		err = h.service.FilterForDisplay(c.Request.Context(), annotation)
+	}
+	if err != nil {
+		statusCode := http.StatusNotFound
+		if err.Error() != "annotation not found" {
+			statusCode = http.StatusInternalServerError
+		}
+		
+		c.JSON(statusCode, gin.H{
+			"success": false,
+			"message": "Failed to get annotation",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	// Merged duplicates redirect to the annotation they were merged into
+	if annotation.MergedInto != "" {
+		c.Redirect(http.StatusMovedPermanently, "/annotations/"+annotation.MergedInto)
+		return
+	}
+	if !h.allowSafetyLabels(c, annotation) {
+		return
+	}
+
+	response := annotation.ToResponse()
+	h.localizeURLs(c, &response)
+	response.Links, err = h.service.RelatedLinks(c.Request.Context(), annotationID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to get linked annotations",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	// Annotations still processing change without an edit, so only finished ones are cached
+	if annotation.Status == "completed" {
+		h.setCacheHeaders(c, services.AnnotationSurrogateKey(annotationID))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Annotation retrieved successfully",
+		"data":    response,
+	})
+}
+
+// PrintAnnotation handles GET /annotations/:id/print, a self-contained HTML page of the annotation
+// optimized for printing, with a QR code linking to its audio
+func (h *AnnotationHandler) PrintAnnotation(c *gin.Context) {
+	annotationID := c.Param("id")
+
+	annotation, err := h.service.GetAnnotationByID(c.Request.Context(), annotationID)
+	if err == nil && !annotation.IsReviewed() && !seesUnreviewed(optionalUser(c)) {
+		err = fmt.Errorf("annotation not found")
+	}
+	if err == nil {
+		err = h.service.FilterForDisplay(c.Request.Context(), annotation)
+	}
+	if err != nil {
+		statusCode := http.StatusNotFound
+		if err.Error() != "annotation not found" {
+			statusCode = http.StatusInternalServerError
+		}
+
+		c.JSON(statusCode, gin.H{
+			"success": false,
+			"message": "Failed to get annotation",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	if annotation.MergedInto != "" {
+		c.Redirect(http.StatusMovedPermanently, "/annotations/"+annotation.MergedInto+"/print")
+		return
+	}
+	if !h.allowSafetyLabels(c, annotation) {
+		return
+	}
+
+	// Rendered to a buffer first, so a failure can still be reported as JSON
+	var page bytes.Buffer
+	if err := h.service.WritePrintableHTML(c.Request.Context(), annotation, &page); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to render printable page",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	if annotation.Status == "completed" {
+		h.setCacheHeaders(c, services.AnnotationSurrogateKey(annotationID))
+	}
+	// The page embeds everything it shows, so it may load nothing else
+	c.Header("Content-Security-Policy", "default-src 'none'; img-src data:; style-src 'unsafe-inline'")
+	c.Data(http.StatusOK, "text/html; charset=utf-8", page.Bytes())
+}
+
+// SearchAnnotationText handles GET /annotations/:id/search?q=...
+func (h *AnnotationHandler) SearchAnnotationText(c *gin.Context) {
+	annotationID := c.Param("id")
+
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Query parameter q is required",
+		})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	if _, ok := h.readableAnnotation(c); !ok {
+		return
+	}
+
+	matches, err := h.service.SearchAnnotationText(c.Request.Context(), annotationID, query, limit)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if err.Error() == "annotation not found" {
+			statusCode = http.StatusNotFound
+		}
+
+		c.JSON(statusCode, gin.H{
+			"success": false,
+			"message": "Failed to search annotation",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Search completed successfully",
+		"data": gin.H{
+			"query":   query,
+			"matches": matches,
+			"count":   len(matches),
+		},
+	})
+}
+
+// ExplainSelection handles POST /annotations/:id/explain
+func (h *AnnotationHandler) ExplainSelection(c *gin.Context) {
+	annotationID := c.Param("id")
+
+	var req models.ExplainRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid request body",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	if _, ok := h.readableAnnotation(c); !ok {
+		return
+	}
+
+	explanation, err := h.service.ExplainSelection(c.Request.Context(), annotationID, req.Selection)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if err.Error() == "annotation not found" {
+			statusCode = http.StatusNotFound
+		}
+
+		c.JSON(statusCode, gin.H{
+			"success": false,
+			"message": "Failed to explain selection",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Explanation generated successfully",
+		"data": gin.H{
+			"selection":   req.Selection,
+			"explanation": explanation,
+		},
+	})
+}
+
+// GetReaderRendition handles GET /annotations/:id/reader?page=N
+func (h *AnnotationHandler) GetReaderRendition(c *gin.Context) {
+	annotationID := c.Param("id")
+
+	if _, ok := h.readableAnnotation(c); !ok {
+		return
+	}
+
+	rendition, err := h.service.GetReaderRendition(c.Request.Context(), annotationID)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if err.Error() == "annotation not found" {
+			statusCode = http.StatusNotFound
+		}
+
+		c.JSON(statusCode, gin.H{
+			"success": false,
+			"message": "Failed to get reader rendition",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	pages := rendition.Pages
+	if pageStr := c.Query("page"); pageStr != "" {
+		page, err := strconv.Atoi(pageStr)
+		if err != nil || page <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Invalid page number",
+			})
+			return
+		}
+
+		pages = nil
+		for _, p := range rendition.Pages {
+			if p.Number == page {
+				pages = []models.ReaderPage{p}
+				break
+			}
+		}
+		if pages == nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"message": "Page not found",
+			})
+			return
+		}
+	}
+
+	h.setCacheHeaders(c, services.AnnotationSurrogateKey(annotationID))
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Reader rendition retrieved successfully",
+		"data": gin.H{
+			"annotation_id": rendition.AnnotationID,
+			"total_pages":   len(rendition.Pages),
+			"pages":         pages,
+			"generated_at":  rendition.GeneratedAt,
+		},
+	})
+}
+
+// GetAllAnnotations handles GET /annotations (all annotations for any authenticated user)
+func (h *AnnotationHandler) GetAllAnnotations(c *gin.Context) {
+	h.listAnnotations(c, models.AnnotationFilter{})
+}
+
+// GetMyAnnotations handles GET /annotations/mine, the authenticated user's own annotations with the
+// same filters and pagination as GET /annotations
+func (h *AnnotationHandler) GetMyAnnotations(c *gin.Context) {
+	user, ok := contextUser(c)
+	if !ok {
+		return
+	}
+
+	h.listAnnotations(c, models.AnnotationFilter{UserID: user.ID})
+}
+
+// GetPublicAnnotations handles GET /public/annotations, the public annotations with the same filters
+// and pagination as GET /annotations, readable without signing in
+func (h *AnnotationHandler) GetPublicAnnotations(c *gin.Context) {
+	h.listAnnotations(c, models.AnnotationFilter{PublicOnly: true})
+}
+
+// GetPublicAnnotation handles GET /public/annotations/:id. Annotations that aren't public are
+// reported as not found, so their existence isn't revealed.
+func (h *AnnotationHandler) GetPublicAnnotation(c *gin.Context) {
+	annotation, err := h.service.GetAnnotationByID(c.Request.Context(), c.Param("id"))
+	if err == nil && !annotation.IsPublic() {
+		err = fmt.Errorf("annotation not found")
+	}
+	if err == nil {
+		err = h.service.FilterForDisplay(c.Request.Context(), annotation)
+	}
+	if err != nil {
+		statusCode := http.StatusNotFound
+		if err.Error() != "annotation not found" {
+			statusCode = http.StatusInternalServerError
+		}
+
+		c.JSON(statusCode, gin.H{
+			"success": false,
+			"message": "Failed to get annotation",
+			"error":   err.Error(),
+		})
+		return
+	}
+	if !h.allowSafetyLabels(c, annotation) {
+		return
+	}
+
+	response := annotation.ToResponse()
+	h.localizeURLs(c, &response)
+
+	h.setCacheHeaders(c, services.AnnotationSurrogateKey(annotation.ID))
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Annotation retrieved successfully",
+		"data":    response,
+	})
+}
+
+// listAnnotations responds with a page of the annotations matching base and the query filters
+func (h *AnnotationHandler) listAnnotations(c *gin.Context, base models.AnnotationFilter) {
+	// Parse query parameters
+	limitStr := c.DefaultQuery("limit", "10")
+	offsetStr := c.DefaultQuery("offset", "0")
+
+	limit, err := strconv.ParseInt(limitStr, 10, 64)
+	if err != nil || limit <= 0 {
+		limit = 10
+	}
+
+	offset, err := strconv.ParseInt(offsetStr, 10, 64)
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	filter := base
+	filter.Tag = c.Query("tag")
+	filter.Objective = c.Query("objective")
+	filter.Prerequisite = c.Query("prerequisite")
+	filter.Metadata = c.QueryMap("metadata") // e.g. ?metadata[course_code]=CS101
+	filter.SafetyLabel = c.Query("safety_label")
+	if filter.SafetyLabel != "" && !slices.Contains(models.SafetyLabelNames, filter.SafetyLabel) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid safety label filter",
+			"error":   fmt.Sprintf("invalid safety label %q, must be one of %s", filter.SafetyLabel, strings.Join(models.SafetyLabelNames, ", ")),
+		})
+		return
+	}
+	filter.HiddenLabels = h.service.HiddenSafetyLabels(optionalUser(c))
+	filter.ReviewedOnly = !seesUnreviewed(optionalUser(c))
+	filter.ReviewState = c.Query("review_state")
+	if filter.ReviewState != "" && !slices.Contains([]string{models.ReviewDraft, models.ReviewPending, models.ReviewApproved, models.ReviewRejected}, filter.ReviewState) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid review state filter",
+			"error":   fmt.Sprintf("invalid review state %q, must be draft, pending_review, approved or rejected", filter.ReviewState),
+		})
+		return
+	}
+	for key := range filter.Metadata {
+		if !services.IsValidMetadataKey(key) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Invalid metadata filter",
+				"error":   fmt.Sprintf("invalid metadata field key %q", key),
+			})
+			return
+		}
+	}
+
+	// One more than requested to tell whether there are more
+	annotations, err := h.service.GetAllAnnotations(c.Request.Context(), filter, limit+1, offset)
+	hasMore := len(annotations) > int(limit)
+	if hasMore {
+		annotations = annotations[:limit]
+	}
+	if err == nil {
+		err = h.service.FilterForDisplay(c.Request.Context(), annotations...)
+	}
+	var pagination models.Pagination
+	if err == nil {
+		pagination, err = newPagination(c, limit, offset, len(annotations), hasMore, func() (int64, error) {
+			return h.service.CountAnnotations(c.Request.Context(), filter)
+		})
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to get annotations",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	// Convert to response format
+	responses := make([]models.AnnotationResponse, len(annotations))
+	for i, annotation := range annotations {
+		responses[i] = annotation.ToResponse()
+		h.localizeURLs(c, &responses[i])
+	}
+
+	h.setCacheHeaders(c, services.SurrogateKeyAnnotations)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Annotations retrieved successfully",
+		"data": gin.H{
+			"annotations": responses,
+			"pagination":  pagination,
+		},
+	})
+}
+
+// GetTagCounts handles GET /annotations/tags
+func (h *AnnotationHandler) GetTagCounts(c *gin.Context) {
+	tags, err := h.service.GetTagCounts(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to get tags",
+			"error":   err.Error(),
+		})
+		return
 	}
 
+	h.setCacheHeaders(c, services.SurrogateKeyAnnotations)
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"message": "Annotations retrieved successfully",
-		"data": gin.H{
-			"annotations": responses,
-			"pagination": gin.H{
-				"limit":  limit,
-				"offset": offset,
-				"count":  len(responses),
-			},
-		},
+		"message": "Tags retrieved successfully",
+		"data":    tags,
 	})
 }
 
@@ -295,7 +1351,7 @@ func (h *AnnotationHandler) DeleteAnnotation(c *gin.Context) {
 
 	annotationID := c.Param("id")
 	
-	err := h.service.DeleteAnnotation(c.Request.Context(), annotationID, user.ID)
+	err := h.service.DeleteAnnotation(c.Request.Context(), annotationID, user)
 	if err != nil {
 		statusCode := http.StatusInternalServerError
 		if strings.Contains(err.Error(), "not found") {
@@ -356,30 +1412,141 @@ func (h *AnnotationHandler) GetAnnotationStats(c *gin.Context) {
 	})
 }
 
-// DownloadAudio handles GET /annotations/:id/audio (Deprecated - redirects to S3)
+// DownloadAudio handles GET /annotations/:id/audio (Deprecated - redirects to storage)
 func (h *AnnotationHandler) DownloadAudio(c *gin.Context) {
-	annotationID := c.Param("id")
-	
-	annotation, err := h.service.GetAnnotationByID(c.Request.Context(), annotationID)
-	if err != nil {
+	annotation, ok := h.readableAnnotation(c)
+	if !ok {
+		return
+	}
+
+	// TTS files are now kept in storage, redirect to their URL
+	if annotation.TTSURL == "" {
 		c.JSON(http.StatusNotFound, gin.H{
 			"success": false,
-			"message": "Annotation not found",
+			"message": "TTS audio not available. Use POST /annotations/:id/tts to generate it.",
 		})
 		return
 	}
 
-	// TTS files are now stored on S3, redirect to S3 URL
-	if annotation.TTSURL == "" {
+	// Redirect to the stored file, in the nearest region
+	c.Redirect(http.StatusFound, h.service.LocalizeURL(clientRegionHint(c), clientCountry(c), annotation.TTSURL))
+}
+
+// DownloadCaptions handles GET /annotations/:id/tts/captions?format=vtt|srt (redirects to storage)
+func (h *AnnotationHandler) DownloadCaptions(c *gin.Context) {
+	format := c.DefaultQuery("format", "vtt")
+	if format != "vtt" && format != "srt" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Format must be vtt or srt",
+		})
+		return
+	}
+
+	annotation, ok := h.readableAnnotation(c)
+	if !ok {
+		return
+	}
+
+	if annotation.Captions == nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"success": false,
-			"message": "TTS audio not available. Use POST /annotations/:id/tts to generate it.",
+			"message": "Captions not available. Use POST /annotations/:id/tts to generate them.",
+		})
+		return
+	}
+
+	if format == "srt" {
+		c.Redirect(http.StatusFound, annotation.Captions.SRTURL)
+		return
+	}
+	c.Redirect(http.StatusFound, annotation.Captions.VTTURL)
+}
+
+// DownloadSpeechMarks handles GET /annotations/:id/tts/marks (redirects to the word and sentence
+// timings of the TTS audio in storage). Offsets refer to the spoken text, so clients should match marks
+// by their value when highlighting the annotation.
+func (h *AnnotationHandler) DownloadSpeechMarks(c *gin.Context) {
+	annotation, ok := h.readableAnnotation(c)
+	if !ok {
+		return
+	}
+
+	if annotation.TTSMarksURL == "" {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Speech marks not available. Use POST /annotations/:id/tts to generate them.",
+		})
+		return
+	}
+
+	c.Redirect(http.StatusFound, annotation.TTSMarksURL)
+}
+
+// DownloadSource handles GET /annotations/:id/source (redirects to a signed URL of the original document)
+func (h *AnnotationHandler) DownloadSource(c *gin.Context) {
+	annotation, ok := h.readableAnnotation(c)
+	if !ok {
+		return
+	}
+
+	// Annotations created before source files were stored have no source
+	if annotation.SourceFile == "" {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Source document not available for this annotation",
+		})
+		return
+	}
+
+	url, err := h.service.SourceDownloadURL(c.Request.Context(), annotation)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to create download URL",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.Redirect(http.StatusFound, url)
+}
+
+// ListTTSVoices handles GET /system/tts/voices?lang=en-US
+func (h *AnnotationHandler) ListTTSVoices(c *gin.Context) {
+	lang := strings.TrimSpace(c.Query("lang"))
+	if lang != "" && !languageCodePattern.MatchString(lang) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid language code, expected a code such as en-US",
+		})
+		return
+	}
+
+	voices, err := h.service.ListTTSVoices(c.Request.Context(), lang)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if strings.Contains(err.Error(), "not configured") {
+			statusCode = http.StatusServiceUnavailable
+		}
+
+		c.JSON(statusCode, gin.H{
+			"success": false,
+			"message": "Failed to list voices",
+			"error":   err.Error(),
 		})
 		return
 	}
 
-	// Redirect to S3 URL
-	c.Redirect(http.StatusFound, annotation.TTSURL)
+	h.setCacheHeaders(c, services.SurrogateKeyTTSVoices)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Voices retrieved successfully",
+		"data": gin.H{
+			"voices": voices,
+			"count":  len(voices),
+		},
+	})
 }
 
 // CheckServices handles GET /annotations/services/status
@@ -436,6 +1603,81 @@ func (h *AnnotationHandler) GenerateTTSForAnnotation(c *gin.Context) {
 	})
 }
 
+// RegenerateAnnotation handles POST /annotations/:id/regenerate
+func (h *AnnotationHandler) RegenerateAnnotation(c *gin.Context) {
+	annotationID := c.Param("id")
+
+	// The body is optional, without it the annotation is regenerated at its current length
+	var req models.RegenerateAnnotationRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Invalid request body",
+				"error":   err.Error(),
+			})
+			return
+		}
+	}
+
+	annotation, err := h.service.RegenerateAnnotation(c.Request.Context(), annotationID, req.Length, req.Model)
+	if err != nil {
+		if respondUploadError(c, "Failed to regenerate annotation", err) {
+			return
+		}
+		statusCode := http.StatusInternalServerError
+		if strings.Contains(err.Error(), "not found") {
+			statusCode = http.StatusNotFound
+		} else if strings.Contains(err.Error(), "invalid length") || strings.Contains(err.Error(), "invalid model") ||
+			strings.Contains(err.Error(), "no source text") {
+			statusCode = http.StatusBadRequest
+		}
+
+		c.JSON(statusCode, gin.H{
+			"success": false,
+			"message": "Failed to regenerate annotation",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Annotation regenerated successfully",
+		"data":    annotation.ToResponse(),
+	})
+}
+
+// GenerateAudioTour handles POST /annotations/:id/audio-tour
+func (h *AnnotationHandler) GenerateAudioTour(c *gin.Context) {
+	annotationID := c.Param("id")
+
+	annotation, err := h.service.GenerateAudioTour(c.Request.Context(), annotationID)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if strings.Contains(err.Error(), "not found") {
+			statusCode = http.StatusNotFound
+		} else if strings.Contains(err.Error(), "not configured") {
+			statusCode = http.StatusServiceUnavailable
+		} else if strings.Contains(err.Error(), "no source text") {
+			statusCode = http.StatusBadRequest
+		}
+
+		c.JSON(statusCode, gin.H{
+			"success": false,
+			"message": "Failed to generate audio tour",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Audio tour generated successfully",
+		"data":    annotation.ToResponse(),
+	})
+}
+
 // UpdateAnnotation handles PATCH /annotations/:id (accepts FormData)
 func (h *AnnotationHandler) UpdateAnnotation(c *gin.Context) {
 	// Get user from context
@@ -459,6 +1701,23 @@ func (h *AnnotationHandler) UpdateAnnotation(c *gin.Context) {
 
 	annotationID := c.Param("id")
 
+	// Checked before parsing so that an image isn't uploaded for a request that is then refused
+	if err := h.service.CheckOwnership(c.Request.Context(), annotationID, user); err != nil {
+		statusCode := http.StatusInternalServerError
+		if strings.Contains(err.Error(), "not found") {
+			statusCode = http.StatusNotFound
+		} else if strings.Contains(err.Error(), "unauthorized") {
+			statusCode = http.StatusForbidden
+		}
+
+		c.JSON(statusCode, gin.H{
+			"success": false,
+			"message": "Failed to update annotation",
+			"error":   err.Error(),
+		})
+		return
+	}
+
 	req := &models.UpdateAnnotationRequest{}
 	
 	// Check Content-Type to determine how to parse the request
@@ -483,11 +1742,35 @@ func (h *AnnotationHandler) UpdateAnnotation(c *gin.Context) {
 		if genre != "" {
 			req.Genre = &genre
 		}
+		if tags, ok := c.GetPostFormArray("tags"); ok {
+			req.Tags = &tags
+		}
+		if altText, ok := c.GetPostForm("image_alt_text"); ok {
+			req.ImageAltText = &altText
+		}
+		if license, ok := c.GetPostForm("license"); ok {
+			req.License = &license
+		}
+		if visibility, ok := c.GetPostForm("visibility"); ok {
+			req.Visibility = &visibility
+		}
+		if metadata, ok := c.GetPostFormMap("metadata"); ok {
+			req.Metadata = (*models.Metadata)(&metadata)
+		}
+		if sourceURL, ok := c.GetPostForm("source_url"); ok {
+			req.SourceURL = &sourceURL
+		}
+		if author, ok := c.GetPostForm("author"); ok {
+			req.Author = &author
+		}
+		if date, ok := c.GetPostForm("publication_date"); ok {
+			req.PublishedOn = &date
+		}
 		
 		// Handle optional image upload
 		imageFile, err := c.FormFile("image")
 		if err == nil {
-			// Image file provided - validate and upload to S3
+			// Image file provided - validate and upload to storage
 			ext := strings.ToLower(filepath.Ext(imageFile.Filename))
 			validExts := map[string]bool{
 				".jpg":  true,
@@ -505,7 +1788,7 @@ func (h *AnnotationHandler) UpdateAnnotation(c *gin.Context) {
 				return
 			}
 
-			// Open and read image file
+			// Open image file, which is streamed to storage
 			file, err := imageFile.Open()
 			if err != nil {
 				c.JSON(http.StatusInternalServerError, gin.H{
@@ -517,17 +1800,6 @@ func (h *AnnotationHandler) UpdateAnnotation(c *gin.Context) {
 			}
 			defer file.Close()
 
-			imageData := make([]byte, imageFile.Size)
-			_, err = file.Read(imageData)
-			if err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{
-					"success": false,
-					"message": "Failed to read uploaded image",
-					"error":   err.Error(),
-				})
-				return
-			}
-
 			// Determine content type
 			imageContentType := "image/jpeg"
 			switch ext {
@@ -539,8 +1811,8 @@ func (h *AnnotationHandler) UpdateAnnotation(c *gin.Context) {
 				imageContentType = "image/webp"
 			}
 
-			// Upload to S3 and get URL
-			imageURL, err := h.service.UploadImageForAnnotationUpdate(c.Request.Context(), annotationID, imageData, imageContentType)
+			// Upload to storage and get URL
+			imageURL, err := h.service.UploadImageForAnnotationUpdate(c.Request.Context(), annotationID, file, imageFile.Size, imageContentType)
 			if err != nil {
 				c.JSON(http.StatusInternalServerError, gin.H{
 					"success": false,
@@ -577,11 +1849,17 @@ func (h *AnnotationHandler) UpdateAnnotation(c *gin.Context) {
 	// Update annotation
 	var updatedAnnotation *models.Annotation
 	var err error
-	updatedAnnotation, err = h.service.UpdateAnnotation(c.Request.Context(), annotationID, user.ID, req)
+	updatedAnnotation, err = h.service.UpdateAnnotation(c.Request.Context(), annotationID, user, req)
 	if err != nil {
 		statusCode := http.StatusInternalServerError
 		if strings.Contains(err.Error(), "not found") {
 			statusCode = http.StatusNotFound
+		} else if strings.Contains(err.Error(), "unauthorized") {
+			statusCode = http.StatusForbidden
+		} else if strings.Contains(err.Error(), "invalid metadata") || strings.Contains(err.Error(), "invalid license") ||
+			strings.Contains(err.Error(), "invalid visibility") || strings.Contains(err.Error(), "invalid source_url") ||
+			strings.Contains(err.Error(), "invalid author") || strings.Contains(err.Error(), "invalid publication_date") {
+			statusCode = http.StatusBadRequest
 		}
 
 		c.JSON(statusCode, gin.H{
@@ -598,3 +1876,223 @@ func (h *AnnotationHandler) UpdateAnnotation(c *gin.Context) {
 		"data":    updatedAnnotation.ToResponse(),
 	})
 }
+
+// uploadConsent records the "consent" field of an upload, the uploader's attestation that they may
+// upload the document. When consent is required but wasn't given it responds with 400 and returns false.
+func (h *AnnotationHandler) uploadConsent(c *gin.Context) (*models.UploadConsent, bool) {
+	value := strings.TrimSpace(c.PostForm("consent"))
+	accepted, _ := strconv.ParseBool(value)
+	if strings.EqualFold(value, "on") || strings.EqualFold(value, "yes") {
+		accepted = true // What HTML checkboxes send
+	}
+
+	if !accepted && h.requireConsent {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Confirm that you have the right to upload the document",
+			"code":    "consent_required",
+		})
+		return nil, false
+	}
+
+	return &models.UploadConsent{
+		Accepted:     accepted,
+		Value:        value,
+		TermsVersion: h.uploadTerms,
+		IP:           c.ClientIP(),
+		UserAgent:    c.Request.UserAgent(),
+		RecordedAt:   time.Now(),
+	}, true
+}
+
+// respondUploadError writes the response for a rejected upload, with a machine-readable code so
+// clients can tell the cases apart. It reports false when err is not an upload validation error.
+func respondUploadError(c *gin.Context, message string, err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	var duplicateErr *services.DuplicateUploadError
+	var unavailableErr *services.OllamaUnavailableError
+	statusCode, code := 0, ""
+	switch {
+	case errors.As(err, &duplicateErr):
+		statusCode, code = http.StatusConflict, "duplicate_upload"
+	case errors.As(err, &unavailableErr):
+		statusCode, code = http.StatusServiceUnavailable, "ollama_unavailable"
+		c.Header("Retry-After", strconv.Itoa(int(unavailableErr.RetryAfter.Seconds())+1))
+	case errors.As(err, &maxBytesErr), strings.Contains(err.Error(), "maximum upload size"):
+		statusCode, code = http.StatusRequestEntityTooLarge, "file_too_large"
+	case strings.Contains(err.Error(), "file is empty"):
+		statusCode, code = http.StatusBadRequest, "empty_file"
+	case strings.Contains(err.Error(), "not a valid PDF"), strings.Contains(err.Error(), "not a valid PNG or JPEG"),
+		strings.Contains(err.Error(), "not supported by this server"), strings.Contains(err.Error(), "unsupported content type"):
+		statusCode, code = http.StatusUnsupportedMediaType, "invalid_file_type"
+	default:
+		return false
+	}
+
+	response := gin.H{
+		"success": false,
+		"message": message,
+		"error":   err.Error(),
+		"code":    code,
+	}
+	if duplicateErr != nil && duplicateErr.Existing != nil {
+		response["data"] = duplicateErr.Existing.ToResponse() // The client can open it instead
+	}
+	c.JSON(statusCode, response)
+	return true
+}
+
+// respondCreateError writes the response for a failed upload and returns its status code
+func respondCreateError(c *gin.Context, err error) int {
+	if respondUploadError(c, "Failed to create annotation", err) {
+		return c.Writer.Status()
+	}
+
+	statusCode := http.StatusInternalServerError
+	if strings.Contains(err.Error(), "title is required") || strings.Contains(err.Error(), "invalid length") ||
+		strings.Contains(err.Error(), "invalid license") || strings.Contains(err.Error(), "invalid visibility") ||
+		strings.Contains(err.Error(), "invalid metadata") || strings.Contains(err.Error(), "invalid source_url") ||
+		strings.Contains(err.Error(), "invalid author") || strings.Contains(err.Error(), "invalid publication_date") ||
+		strings.Contains(err.Error(), "invalid model") || strings.Contains(err.Error(), "invalid url") {
+		statusCode = http.StatusBadRequest
+	} else if strings.Contains(err.Error(), "not enabled") {
+		statusCode = http.StatusForbidden
+	} else if strings.Contains(err.Error(), "failed to fetch url") {
+		statusCode = http.StatusBadGateway
+	}
+
+	c.JSON(statusCode, gin.H{
+		"success": false,
+		"message": "Failed to create annotation",
+		"error":   err.Error(),
+	})
+	return statusCode
+}
+
+// setQuotaHeaders reports the user's remaining uploads for today, if a quota applies
+func setQuotaHeaders(c *gin.Context, quota *models.UploadQuota) {
+	if quota == nil {
+		return
+	}
+	c.Header("X-Quota-Limit", strconv.Itoa(quota.Limit))
+	c.Header("X-Quota-Remaining", strconv.Itoa(quota.Remaining))
+	c.Header("X-Quota-Reset", strconv.FormatInt(quota.ResetAt.Unix(), 10))
+}
+
+// respondQuotaError writes the response for an upload rejected by the daily quota
+func respondQuotaError(c *gin.Context, err error) {
+	if !strings.Contains(err.Error(), "quota exceeded") {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to check upload quota",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"success": false,
+		"message": "Daily upload quota exceeded",
+		"error":   err.Error(),
+		"code":    "quota_exceeded",
+	})
+}
+
+// optionalUser returns the authenticated user, or nil for visitors without an account
+func optionalUser(c *gin.Context) *models.User {
+	user, _ := c.Get("user")
+	userModel, _ := user.(*models.User)
+	return userModel
+}
+
+// seesUnreviewed reports whether user may see annotations that weren't approved by a reviewer:
+// content creators, reviewers and admins
+func seesUnreviewed(user *models.User) bool {
+	return user != nil && (user.IsContentCreator() || user.IsReviewer() || user.IsAdmin())
+}
+
+// allowSafetyLabels reports whether the requesting user may see annotation, and responds with 403
+// when it has a safety label restricted for them
+func (h *AnnotationHandler) allowSafetyLabels(c *gin.Context, annotation *models.Annotation) bool {
+	if err := h.service.CheckSafetyRestrictions(annotation, optionalUser(c)); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"message": "This annotation is restricted for your account",
+			"error":   err.Error(),
+			"code":    "content_restricted",
+		})
+		return false
+	}
+	return true
+}
+
+// readableAnnotation loads the annotation of the request's :id for an endpoint serving its content.
+// It responds with 404 when the annotation doesn't exist or awaits review, and 403 when it is
+// restricted for the user.
+func (h *AnnotationHandler) readableAnnotation(c *gin.Context) (*models.Annotation, bool) {
+	return h.readableAnnotationByID(c, c.Param("id"))
+}
+
+// readableAnnotationByID is readableAnnotation for an annotation not named by the route, such as
+// the one a quiz was generated from
+func (h *AnnotationHandler) readableAnnotationByID(c *gin.Context, annotationID string) (*models.Annotation, bool) {
+	annotation, err := h.service.GetAnnotationByID(c.Request.Context(), annotationID)
+	if err == nil && !annotation.IsReviewed() && !seesUnreviewed(optionalUser(c)) {
+		err = fmt.Errorf("annotation not found")
+	}
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if err.Error() == "annotation not found" {
+			statusCode = http.StatusNotFound
+		}
+
+		c.JSON(statusCode, gin.H{
+			"success": false,
+			"message": "Failed to get annotation",
+			"error":   err.Error(),
+		})
+		return nil, false
+	}
+	if !h.allowSafetyLabels(c, annotation) {
+		return nil, false
+	}
+	return annotation, true
+}
+
+// localizeURLs points file URLs at the storage region closest to the client
+func (h *AnnotationHandler) localizeURLs(c *gin.Context, responses ...*models.AnnotationResponse) {
+	h.service.LocalizeURLs(clientRegionHint(c), clientCountry(c), responses...)
+}
+
+// clientRegionHint returns the storage region a client asked for with ?region= or the
+// X-Preferred-Region header, e.g. after measuring latency to each region itself
+func clientRegionHint(c *gin.Context) string {
+	if region := c.Query("region"); region != "" {
+		return region
+	}
+	return c.GetHeader("X-Preferred-Region")
+}
+
+// clientCountry returns the client's ISO country code as geolocated by the CDN or load balancer in
+// front of the API; the API has no GeoIP database of its own
+func clientCountry(c *gin.Context) string {
+	for _, header := range []string{"CloudFront-Viewer-Country", "CF-IPCountry", "X-Country-Code"} {
+		if country := c.GetHeader(header); country != "" {
+			return country
+		}
+	}
+	return ""
+}
+
+// setCacheHeaders lets a CDN cache a successful GET response for CDN_CACHE_SECONDS. Browsers
+// revalidate every time, so edits show up as soon as the purge reaches the CDN. Responses vary by
+// Authorization, so the CDN only serves a cached copy to requests with the same token.
+func (h *AnnotationHandler) setCacheHeaders(c *gin.Context, surrogateKeys ...string) {
+	if h.cdnMaxAge <= 0 {
+		return
+	}
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=0, s-maxage=%d", h.cdnMaxAge))
+	c.Header("Surrogate-Control", fmt.Sprintf("max-age=%d", h.cdnMaxAge))
+	c.Header("Surrogate-Key", strings.Join(surrogateKeys, " "))
+	c.Header("Vary", "Authorization")
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"auto-annotation-api/models"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AskQuestion handles POST /annotations/:id/ask ({"question": "..."}), answering a question from the
+// source text of the annotation with the excerpts the answer is based on
+func (h *AnnotationHandler) AskQuestion(c *gin.Context) {
+	var req models.AskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid request body",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	annotation, ok := h.readableAnnotation(c)
+	if !ok {
+		return
+	}
+
+	answer, err := h.service.AskDocument(c.Request.Context(), annotation, req.Question)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if strings.HasPrefix(err.Error(), "invalid question") {
+			statusCode = http.StatusBadRequest
+		} else if err.Error() == "annotation has no source text" {
+			statusCode = http.StatusConflict
+		}
+
+		c.JSON(statusCode, gin.H{
+			"success": false,
+			"message": "Failed to answer question",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Question answered successfully",
+		"data":    answer,
+	})
+}
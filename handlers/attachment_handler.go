@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"auto-annotation-api/services"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AddAttachment handles POST /annotations/:id/attachments (multipart "file")
+func (h *AnnotationHandler) AddAttachment(c *gin.Context) {
+	user, ok := contextUser(c)
+	if !ok {
+		return
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		if respondUploadError(c, "File is too large", err) {
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "File is required",
+			"error":   err.Error(),
+		})
+		return
+	}
+	if fileHeader.Size > services.MaxAttachmentBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"success": false,
+			"message": "Attachment is too large",
+		})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to open uploaded file",
+			"error":   err.Error(),
+		})
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, services.MaxAttachmentBytes+1))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to read uploaded file",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	annotation, err := h.service.AddAttachment(c.Request.Context(), c.Param("id"), user.ID, fileHeader.Filename, data)
+	if err != nil {
+		respondAttachmentError(c, "Failed to add attachment", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "Attachment added successfully",
+		"data":    annotation.ToResponse(),
+	})
+}
+
+// DownloadAttachment handles GET /annotations/:id/attachments/:attachmentId (redirects to a signed URL)
+func (h *AnnotationHandler) DownloadAttachment(c *gin.Context) {
+	if _, ok := h.readableAnnotation(c); !ok {
+		return
+	}
+
+	url, err := h.service.AttachmentDownloadURL(c.Request.Context(), c.Param("id"), c.Param("attachmentId"))
+	if err != nil {
+		respondAttachmentError(c, "Failed to download attachment", err)
+		return
+	}
+
+	c.Redirect(http.StatusFound, url)
+}
+
+// RemoveAttachment handles DELETE /annotations/:id/attachments/:attachmentId
+func (h *AnnotationHandler) RemoveAttachment(c *gin.Context) {
+	annotation, err := h.service.RemoveAttachment(c.Request.Context(), c.Param("id"), c.Param("attachmentId"))
+	if err != nil {
+		respondAttachmentError(c, "Failed to remove attachment", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Attachment removed successfully",
+		"data":    annotation.ToResponse(),
+	})
+}
+
+// respondAttachmentError maps attachment errors to status codes
+func respondAttachmentError(c *gin.Context, message string, err error) {
+	statusCode := http.StatusInternalServerError
+	switch {
+	case strings.Contains(err.Error(), "not found"):
+		statusCode = http.StatusNotFound
+	case strings.Contains(err.Error(), "unsupported attachment type"), strings.Contains(err.Error(), "attachment limit reached"),
+		strings.Contains(err.Error(), "file is empty"):
+		statusCode = http.StatusBadRequest
+	case strings.Contains(err.Error(), "larger than"):
+		statusCode = http.StatusRequestEntityTooLarge
+	case strings.Contains(err.Error(), "not configured"):
+		statusCode = http.StatusServiceUnavailable
+	}
+
+	c.JSON(statusCode, gin.H{
+		"success": false,
+		"message": message,
+		"error":   err.Error(),
+	})
+}
@@ -10,13 +10,15 @@ import (
 )
 
 type AuthHandler struct {
-	authService *services.AuthService
+	authService       *services.AuthService
+	annotationService *services.AnnotationService // Deletes a user's annotations with their account
 }
 
 // NewAuthHandler creates a new auth handler
-func NewAuthHandler(db *mongo.Database) *AuthHandler {
+func NewAuthHandler(db *mongo.Database, annotationService *services.AnnotationService) *AuthHandler {
 	return &AuthHandler{
-		authService: services.NewAuthService(db),
+		authService:       services.NewAuthService(db),
+		annotationService: annotationService,
 	}
 }
 
@@ -71,6 +73,8 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		statusCode := http.StatusInternalServerError
 		if err.Error() == "invalid email or password" {
 			statusCode = http.StatusUnauthorized
+		} else if err.Error() == "account is disabled" {
+			statusCode = http.StatusForbidden
 		}
 
 		c.JSON(statusCode, gin.H{
@@ -115,3 +119,149 @@ func (h *AuthHandler) GetProfile(c *gin.Context) {
 		"data":    user.ToUserResponse(),
 	})
 }
+
+// UpdateProfile handles PATCH /auth/profile (protected route)
+func (h *AuthHandler) UpdateProfile(c *gin.Context) {
+	user, ok := contextUser(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid request payload",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	updated, err := h.authService.UpdateProfile(c.Request.Context(), user, req)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if err.Error() == "current password is incorrect" {
+			statusCode = http.StatusUnauthorized
+		} else if err.Error() == "name cannot be empty" {
+			statusCode = http.StatusBadRequest
+		}
+
+		c.JSON(statusCode, gin.H{
+			"success": false,
+			"message": "Profile update failed",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Profile updated successfully",
+		"data":    updated.ToUserResponse(),
+	})
+}
+
+// DeleteProfile handles DELETE /auth/profile (protected route). The password must be confirmed;
+// the account is removed or anonymized, optionally with the user's annotations.
+func (h *AuthHandler) DeleteProfile(c *gin.Context) {
+	user, ok := contextUser(c)
+	if !ok {
+		return
+	}
+
+	var req models.DeleteProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid request payload",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	if err := h.authService.CheckPassword(user, req.Password); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Account deletion failed",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	deletedAnnotations := 0
+	if req.DeleteAnnotations {
+		var err error
+		deletedAnnotations, err = h.annotationService.DeleteUserAnnotations(c.Request.Context(), user.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Failed to delete annotations",
+				"error":   err.Error(),
+			})
+			return
+		}
+	}
+
+	if err := h.authService.DeleteAccount(c.Request.Context(), user.ID, req.Anonymize); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Account deletion failed",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	message := "Account deleted successfully"
+	if req.Anonymize {
+		message = "Account anonymized successfully"
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": message,
+		"data": gin.H{
+			"deleted_annotations": deletedAnnotations,
+		},
+	})
+}
+
+// Logout handles POST /auth/logout (protected route)
+func (h *AuthHandler) Logout(c *gin.Context) {
+	// Get token claims from context (set by JWT middleware)
+	claimsInterface, exists := c.Get("claims")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Token not found in context",
+		})
+		return
+	}
+
+	claims, ok := claimsInterface.(*models.JWTClaims)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Invalid token data",
+		})
+		return
+	}
+
+	if err := h.authService.RevokeToken(c.Request.Context(), claims); err != nil {
+		statusCode := http.StatusInternalServerError
+		if err.Error() == "token cannot be revoked" {
+			statusCode = http.StatusBadRequest
+		}
+
+		c.JSON(statusCode, gin.H{
+			"success": false,
+			"message": "Logout failed",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Logged out successfully",
+	})
+}
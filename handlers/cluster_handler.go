@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"auto-annotation-api/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ClusterHandler struct {
+	service *services.ClusteringService
+}
+
+// NewClusterHandler creates a new cluster handler
+func NewClusterHandler(service *services.ClusteringService) *ClusterHandler {
+	return &ClusterHandler{
+		service: service,
+	}
+}
+
+// GetClusters handles GET /annotations/clusters
+func (h *ClusterHandler) GetClusters(c *gin.Context) {
+	clusters, err := h.service.GetClusters(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to get clusters",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Clusters retrieved successfully",
+		"data": gin.H{
+			"clusters": clusters,
+			"count":    len(clusters),
+		},
+	})
+}
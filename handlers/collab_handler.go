@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"auto-annotation-api/services"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+)
+
+// collabMaxMessageBytes caps a single message from an editor
+const collabMaxMessageBytes = 1 << 20
+
+type CollabHandler struct {
+	hub *services.CollabHub
+}
+
+// NewCollabHandler creates a new collaborative editing handler
+func NewCollabHandler(hub *services.CollabHub) *CollabHandler {
+	return &CollabHandler{
+		hub: hub,
+	}
+}
+
+// EditAnnotation handles GET /annotations/:id/collab, a WebSocket on which several creators edit the
+// annotation body together. Messages are JSON, see services.CollabMessage; edits are ot.js operations.
+// Browsers can't set the Authorization header on WebSockets, so they may send the token as the
+// subprotocols "bearer", <token> instead.
+func (h *CollabHandler) EditAnnotation(c *gin.Context) {
+	user, ok := contextUser(c)
+	if !ok {
+		return
+	}
+	if !c.IsWebsocket() {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "WebSocket upgrade required",
+		})
+		return
+	}
+
+	client, err := h.hub.Join(c.Request.Context(), c.Param("id"), user)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		switch {
+		case strings.Contains(err.Error(), "not found"):
+			statusCode = http.StatusNotFound
+		case strings.Contains(err.Error(), "unauthorized"):
+			statusCode = http.StatusForbidden
+		case strings.Contains(err.Error(), "invalid"):
+			statusCode = http.StatusBadRequest
+		case strings.Contains(err.Error(), "shutting down"):
+			statusCode = http.StatusServiceUnavailable
+		}
+
+		c.JSON(statusCode, gin.H{
+			"success": false,
+			"message": "Failed to join editing session",
+			"error":   err.Error(),
+		})
+		return
+	}
+	defer client.Leave()
+
+	server := websocket.Server{
+		// The token authenticates the request, not a cookie, so other origins can't hijack the
+		// socket and the origin isn't checked
+		Handshake: func(config *websocket.Config, req *http.Request) error {
+			for _, protocol := range config.Protocol {
+				if protocol == "bearer" {
+					config.Protocol = []string{"bearer"}
+					return nil
+				}
+			}
+			config.Protocol = nil
+			return nil
+		},
+		Handler: func(ws *websocket.Conn) {
+			ws.MaxPayloadBytes = collabMaxMessageBytes
+			serveCollab(ws, client)
+		},
+	}
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+// serveCollab relays messages between an editor's socket and its session until either side closes
+func serveCollab(ws *websocket.Conn, client *services.CollabClient) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for msg := range client.Messages() {
+			if err := websocket.JSON.Send(ws, msg); err != nil {
+				break
+			}
+		}
+		// The session dropped the client or the socket failed, either way the reader below stops
+		ws.Close()
+	}()
+
+	for {
+		var data []byte
+		if err := websocket.Message.Receive(ws, &data); err != nil {
+			break
+		}
+
+		var msg services.CollabMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			client.ReportError(fmt.Errorf("invalid message: %w", err))
+			continue
+		}
+		if msg.Type != "op" {
+			client.ReportError(fmt.Errorf("invalid message type %q, expected op", msg.Type))
+			continue
+		}
+		if err := client.Submit(msg.Revision, msg.Operation); err != nil {
+			log.Printf("Rejected collaborative edit: %v", err)
+		}
+	}
+
+	client.Leave()
+	<-done
+}
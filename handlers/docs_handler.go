@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"auto-annotation-api/docs"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+)
+
+// swaggerUIPage loads Swagger UI from a CDN and points it at the JSON document
+const swaggerUIPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Auto Annotation API</title>
+<link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js" crossorigin></script>
+<script>
+window.ui = SwaggerUIBundle({
+  url: "/docs/openapi.json",
+  dom_id: "#swagger-ui",
+  persistAuthorization: true,
+});
+</script>
+</body>
+</html>
+`
+
+type DocsHandler struct {
+	specJSON []byte
+}
+
+// NewDocsHandler creates a new API documentation handler
+func NewDocsHandler() *DocsHandler {
+	var spec any
+	if err := yaml.Unmarshal(docs.OpenAPI, &spec); err != nil {
+		// The document is embedded, so only a broken build gets here
+		panic(fmt.Sprintf("invalid OpenAPI document: %v", err))
+	}
+	specJSON, err := json.Marshal(spec)
+	if err != nil {
+		panic(fmt.Sprintf("invalid OpenAPI document: %v", err))
+	}
+
+	return &DocsHandler{
+		specJSON: specJSON,
+	}
+}
+
+// SwaggerUI handles GET /docs, an interactive view of the API documentation
+func (h *DocsHandler) SwaggerUI(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(swaggerUIPage))
+}
+
+// OpenAPIJSON handles GET /docs/openapi.json
+func (h *DocsHandler) OpenAPIJSON(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json", h.specJSON)
+}
+
+// OpenAPIYAML handles GET /docs/openapi.yaml
+func (h *DocsHandler) OpenAPIYAML(c *gin.Context) {
+	c.Data(http.StatusOK, "application/yaml", docs.OpenAPI)
+}
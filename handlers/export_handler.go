@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"auto-annotation-api/models"
+	"auto-annotation-api/services"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// exportDateLayout is the format of the from and to query parameters
+const exportDateLayout = "2006-01-02"
+
+// ExportAnnotations handles GET /annotations/export?format=csv|json|md&tag=...&genre=...&from=...&to=...
+// The file is streamed as a download; from and to are inclusive dates.
+func (h *AnnotationHandler) ExportAnnotations(c *gin.Context) {
+	format := c.DefaultQuery("format", "csv")
+	exportFormat, ok := services.ExportFormats[format]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid export format, expected csv, json or md",
+		})
+		return
+	}
+
+	filter := models.AnnotationFilter{
+		Tag:          c.Query("tag"),
+		Genre:        c.Query("genre"),
+		Metadata:     c.QueryMap("metadata"),
+		ReviewedOnly: !seesUnreviewed(optionalUser(c)),
+		HiddenLabels: h.service.HiddenSafetyLabels(optionalUser(c)),
+	}
+	for key := range filter.Metadata {
+		if !services.IsValidMetadataKey(key) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Invalid metadata filter",
+				"error":   fmt.Sprintf("invalid metadata field key %q", key),
+			})
+			return
+		}
+	}
+	if from := c.Query("from"); from != "" {
+		date, err := time.Parse(exportDateLayout, from)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Invalid from date, expected YYYY-MM-DD",
+			})
+			return
+		}
+		filter.CreatedFrom = date
+	}
+	if to := c.Query("to"); to != "" {
+		date, err := time.Parse(exportDateLayout, to)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Invalid to date, expected YYYY-MM-DD",
+			})
+			return
+		}
+		filter.CreatedTo = date.AddDate(0, 0, 1)
+	}
+
+	filename := fmt.Sprintf("annotations-%s.%s", time.Now().UTC().Format(exportDateLayout), exportFormat.Extension)
+	c.Header("Content-Type", exportFormat.ContentType)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Status(http.StatusOK)
+
+	err := h.service.ExportAnnotations(c.Request.Context(), filter, format, c.Writer)
+	if err != nil {
+		// Once the file has started, the status can't change anymore
+		if c.Writer.Written() {
+			log.Printf("Warning: annotation export aborted: %v", err)
+			return
+		}
+
+		c.Header("Content-Type", "")
+		c.Header("Content-Disposition", "")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to export annotations",
+			"error":   err.Error(),
+		})
+	}
+}
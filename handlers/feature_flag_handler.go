@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"auto-annotation-api/models"
+	"auto-annotation-api/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type FeatureFlagHandler struct {
+	service *services.FeatureFlagService
+}
+
+// NewFeatureFlagHandler creates a new feature flag handler
+func NewFeatureFlagHandler(service *services.FeatureFlagService) *FeatureFlagHandler {
+	return &FeatureFlagHandler{
+		service: service,
+	}
+}
+
+// ListFlags handles GET /admin/feature-flags
+func (h *FeatureFlagHandler) ListFlags(c *gin.Context) {
+	flags, err := h.service.List(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to get feature flags",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Feature flags retrieved successfully",
+		"data":    flags,
+	})
+}
+
+// UpdateFlag handles PUT /admin/feature-flags/:name ({"enabled": true, "rollout": 25, "roles": [...], "user_ids": [...]})
+func (h *FeatureFlagHandler) UpdateFlag(c *gin.Context) {
+	admin, ok := contextUser(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateFeatureFlagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid request body",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	flag, err := h.service.Update(c.Request.Context(), c.Param("name"), &req, admin.ID)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if err.Error() == "feature flag not found" {
+			statusCode = http.StatusNotFound
+		}
+
+		c.JSON(statusCode, gin.H{
+			"success": false,
+			"message": "Failed to update feature flag",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Feature flag updated successfully",
+		"data":    flag,
+	})
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"auto-annotation-api/models"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// imageContentTypes maps the supported image extensions to their content types
+var imageContentTypes = map[string]string{
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".png":  "image/png",
+	".gif":  "image/gif",
+	".webp": "image/webp",
+}
+
+// AddImage handles POST /annotations/:id/images (multipart "image" file or "url", optional "caption" and "alt_text")
+func (h *AnnotationHandler) AddImage(c *gin.Context) {
+	annotationID := c.Param("id")
+
+	imageURL := strings.TrimSpace(c.PostForm("url"))
+	if imageFile, err := c.FormFile("image"); err == nil {
+		contentType, ok := imageContentTypes[strings.ToLower(filepath.Ext(imageFile.Filename))]
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Only image files are supported (jpg, png, gif, webp)",
+			})
+			return
+		}
+
+		file, err := imageFile.Open()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Failed to open uploaded image",
+				"error":   err.Error(),
+			})
+			return
+		}
+		defer file.Close()
+
+		imageURL, err = h.service.UploadImageForAnnotationUpdate(c.Request.Context(), annotationID, file, imageFile.Size, contentType)
+		if err != nil {
+			respondGalleryError(c, "Failed to upload image", err)
+			return
+		}
+	} else if respondUploadError(c, "Failed to upload image", err) {
+		return
+	}
+
+	if imageURL == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "An image file or URL is required",
+		})
+		return
+	}
+
+	annotation, err := h.service.AddImage(c.Request.Context(), annotationID, imageURL, c.PostForm("caption"), c.PostForm("alt_text"))
+	if err != nil {
+		respondGalleryError(c, "Failed to add image", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "Image added successfully",
+		"data":    annotation.ToResponse(),
+	})
+}
+
+// UpdateImage handles PATCH /annotations/:id/images/:imageId
+func (h *AnnotationHandler) UpdateImage(c *gin.Context) {
+	var req models.UpdateGalleryImageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid request body",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	annotation, err := h.service.UpdateImage(c.Request.Context(), c.Param("id"), c.Param("imageId"), &req)
+	if err != nil {
+		respondGalleryError(c, "Failed to update image", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Image updated successfully",
+		"data":    annotation.ToResponse(),
+	})
+}
+
+// RemoveImage handles DELETE /annotations/:id/images/:imageId
+func (h *AnnotationHandler) RemoveImage(c *gin.Context) {
+	annotation, err := h.service.RemoveImage(c.Request.Context(), c.Param("id"), c.Param("imageId"))
+	if err != nil {
+		respondGalleryError(c, "Failed to remove image", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Image removed successfully",
+		"data":    annotation.ToResponse(),
+	})
+}
+
+// ReorderImages handles PUT /annotations/:id/images/order
+func (h *AnnotationHandler) ReorderImages(c *gin.Context) {
+	var req models.ReorderImagesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid request body",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	annotation, err := h.service.ReorderImages(c.Request.Context(), c.Param("id"), req.ImageIDs)
+	if err != nil {
+		respondGalleryError(c, "Failed to reorder images", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Images reordered successfully",
+		"data":    annotation.ToResponse(),
+	})
+}
+
+// respondGalleryError maps gallery errors to status codes
+func respondGalleryError(c *gin.Context, message string, err error) {
+	statusCode := http.StatusInternalServerError
+	if strings.Contains(err.Error(), "not found") {
+		statusCode = http.StatusNotFound
+	} else if strings.Contains(err.Error(), "gallery is full") || strings.Contains(err.Error(), "invalid image order") {
+		statusCode = http.StatusBadRequest
+	} else if strings.Contains(err.Error(), "not configured") {
+		statusCode = http.StatusServiceUnavailable
+	}
+
+	c.JSON(statusCode, gin.H{
+		"success": false,
+		"message": message,
+		"error":   err.Error(),
+	})
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"auto-annotation-api/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type HealthHandler struct {
+	readiness *services.ReadinessChecker
+}
+
+// NewHealthHandler creates a new health handler
+func NewHealthHandler(readiness *services.ReadinessChecker) *HealthHandler {
+	return &HealthHandler{
+		readiness: readiness,
+	}
+}
+
+// Liveness handles GET /healthz. It only shows the process is serving requests, so a liveness
+// probe never restarts the API because a dependency is down.
+func (h *HealthHandler) Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "OK",
+	})
+}
+
+// Readiness handles GET /readyz, answering 503 while MongoDB or a checked dependency is unreachable
+func (h *HealthHandler) Readiness(c *gin.Context) {
+	report := h.readiness.Check(c.Request.Context())
+
+	statusCode := http.StatusOK
+	message := "Ready"
+	if !report.Ready {
+		statusCode = http.StatusServiceUnavailable
+		message = "Not ready"
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(statusCode, gin.H{
+		"success": report.Ready,
+		"message": message,
+		"data":    report,
+	})
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"auto-annotation-api/models"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateLink handles POST /annotations/:id/links
+func (h *AnnotationHandler) CreateLink(c *gin.Context) {
+	user, ok := contextUser(c)
+	if !ok {
+		return
+	}
+
+	var req models.CreateLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid request body",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	link, err := h.service.CreateLink(c.Request.Context(), c.Param("id"), user.ID, &req)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		switch {
+		case strings.Contains(err.Error(), "not found"):
+			statusCode = http.StatusNotFound
+		case strings.Contains(err.Error(), "already exists"):
+			statusCode = http.StatusConflict
+		case strings.Contains(err.Error(), "cannot link"):
+			statusCode = http.StatusBadRequest
+		}
+
+		c.JSON(statusCode, gin.H{
+			"success": false,
+			"message": "Failed to create link",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "Link created successfully",
+		"data":    link,
+	})
+}
+
+// DeleteLink handles DELETE /annotations/:id/links/:linkId
+func (h *AnnotationHandler) DeleteLink(c *gin.Context) {
+	if err := h.service.DeleteLink(c.Request.Context(), c.Param("id"), c.Param("linkId")); err != nil {
+		statusCode := http.StatusInternalServerError
+		if strings.Contains(err.Error(), "not found") {
+			statusCode = http.StatusNotFound
+		}
+
+		c.JSON(statusCode, gin.H{
+			"success": false,
+			"message": "Failed to delete link",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Link deleted successfully",
+	})
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"auto-annotation-api/services"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MetricsHandler struct {
+	shedder *services.LoadShedder
+}
+
+// NewMetricsHandler creates a new metrics handler
+func NewMetricsHandler(shedder *services.LoadShedder) *MetricsHandler {
+	return &MetricsHandler{
+		shedder: shedder,
+	}
+}
+
+// GetMetrics handles GET /metrics, the load of the upload pipeline as Prometheus gauges
+func (h *MetricsHandler) GetMetrics(c *gin.Context) {
+	state := h.shedder.State()
+	shedding := 0
+	if state.Shedding {
+		shedding = 1
+	}
+
+	var out strings.Builder
+	writeGauge(&out, "annotation_upload_queue_depth", "Documents being annotated or waiting in bulk uploads", float64(state.QueueDepth))
+	writeGauge(&out, "annotation_ollama_latency_seconds", "Moving average of recent Ollama generate requests", float64(state.OllamaLatencyMS)/1000)
+	writeGauge(&out, "annotation_upload_shedding", "Whether new uploads are turned away, 1 while shedding", float64(shedding))
+
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "text/plain; version=0.0.4; charset=utf-8", []byte(out.String()))
+}
+
+// writeGauge writes a gauge in the Prometheus text format
+func writeGauge(out *strings.Builder, name, help string, value float64) {
+	fmt.Fprintf(out, "# HELP %s %s\n# TYPE %s gauge\n%s %g\n", name, help, name, name, value)
+}
@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"auto-annotation-api/models"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newPagination describes a page of limit items at offset, of which count were returned. Lists
+// fetch one item more than the limit so hasMore is known without counting. The total is only
+// counted when the client asks for it with include_total=true, since counting scans the matches.
+func newPagination(c *gin.Context, limit, offset int64, count int, hasMore bool, countTotal func() (int64, error)) (models.Pagination, error) {
+	pagination := models.Pagination{
+		Limit:   limit,
+		Offset:  offset,
+		Count:   count,
+		HasMore: hasMore,
+	}
+
+	if includeTotal, _ := strconv.ParseBool(c.Query("include_total")); includeTotal {
+		total, err := countTotal()
+		if err != nil {
+			return pagination, err
+		}
+		pagination.TotalCount = &total
+	}
+	return pagination, nil
+}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"auto-annotation-api/models"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateQuiz handles POST /annotations/:id/quiz ({"questions": 5}), multiple-choice questions on the
+// annotated document for studying
+func (h *AnnotationHandler) CreateQuiz(c *gin.Context) {
+	user, ok := contextUser(c)
+	if !ok {
+		return
+	}
+
+	var req models.CreateQuizRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Invalid request body",
+				"error":   err.Error(),
+			})
+			return
+		}
+	}
+
+	annotation, ok := h.readableAnnotation(c)
+	if !ok {
+		return
+	}
+
+	quiz, err := h.service.GenerateQuiz(c.Request.Context(), annotation, user, req.Questions)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if strings.HasPrefix(err.Error(), "invalid quiz") {
+			statusCode = http.StatusConflict
+		}
+
+		c.JSON(statusCode, gin.H{
+			"success": false,
+			"message": "Failed to generate quiz",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "Quiz generated successfully",
+		"data":    quiz,
+	})
+}
+
+// ListQuizzes handles GET /annotations/:id/quizzes?limit=20&offset=0, newest first
+func (h *AnnotationHandler) ListQuizzes(c *gin.Context) {
+	annotation, ok := h.readableAnnotation(c)
+	if !ok {
+		return
+	}
+
+	limit, err := strconv.ParseInt(c.DefaultQuery("limit", "20"), 10, 64)
+	if err != nil || limit <= 0 {
+		limit = 20
+	}
+
+	offset, err := strconv.ParseInt(c.DefaultQuery("offset", "0"), 10, 64)
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	quizzes, err := h.service.ListQuizzes(c.Request.Context(), annotation.ID, limit+1, offset)
+	hasMore := len(quizzes) > int(limit)
+	if hasMore {
+		quizzes = quizzes[:limit]
+	}
+	var pagination models.Pagination
+	if err == nil {
+		pagination, err = newPagination(c, limit, offset, len(quizzes), hasMore, func() (int64, error) {
+			return h.service.CountQuizzes(c.Request.Context(), annotation.ID)
+		})
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to get quizzes",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Quizzes retrieved successfully",
+		"data": gin.H{
+			"quizzes":    quizzes,
+			"pagination": pagination,
+		},
+	})
+}
+
+// GetQuiz handles GET /quizzes/:id
+func (h *AnnotationHandler) GetQuiz(c *gin.Context) {
+	quiz, err := h.service.GetQuiz(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if err.Error() == "quiz not found" {
+			statusCode = http.StatusNotFound
+		}
+
+		c.JSON(statusCode, gin.H{
+			"success": false,
+			"message": "Failed to get quiz",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	// Quizzes quote their document, so they are only shown to those who may read it
+	if _, ok := h.readableAnnotationByID(c, quiz.AnnotationID); !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Quiz retrieved successfully",
+		"data":    quiz,
+	})
+}
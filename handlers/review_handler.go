@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"auto-annotation-api/models"
+	"auto-annotation-api/services"
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ReviewHandler struct {
+	service *services.ReviewService
+}
+
+// NewReviewHandler creates a new review handler
+func NewReviewHandler(service *services.ReviewService) *ReviewHandler {
+	return &ReviewHandler{
+		service: service,
+	}
+}
+
+// SubmitAnnotation handles POST /annotations/:id/submit, asking the reviewers to review an annotation
+func (h *ReviewHandler) SubmitAnnotation(c *gin.Context) {
+	user, ok := contextUser(c)
+	if !ok {
+		return
+	}
+
+	annotation, err := h.service.Submit(c.Request.Context(), c.Param("id"), user)
+	if err != nil {
+		respondReviewError(c, "Failed to submit annotation for review", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Annotation submitted for review",
+		"data":    annotation.ToResponse(),
+	})
+}
+
+// ApproveAnnotation handles POST /annotations/:id/approve ({"comment": "..."} optional), showing
+// the annotation to students
+func (h *ReviewHandler) ApproveAnnotation(c *gin.Context) {
+	h.decide(c, h.service.Approve, "Annotation approved")
+}
+
+// RejectAnnotation handles POST /annotations/:id/reject ({"comment": "..."}), sending the annotation
+// back to its creator
+func (h *ReviewHandler) RejectAnnotation(c *gin.Context) {
+	h.decide(c, h.service.Reject, "Annotation rejected")
+}
+
+// decide applies a reviewer's decision to the annotation in the path
+func (h *ReviewHandler) decide(c *gin.Context, decide func(ctx context.Context, annotationID string, reviewer *models.User, comment string) (*models.Annotation, error), message string) {
+	reviewer, ok := contextUser(c)
+	if !ok {
+		return
+	}
+
+	var req models.ReviewDecisionRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Invalid request body",
+				"error":   err.Error(),
+			})
+			return
+		}
+	}
+
+	annotation, err := decide(c.Request.Context(), c.Param("id"), reviewer, strings.TrimSpace(req.Comment))
+	if err != nil {
+		respondReviewError(c, "Failed to review annotation", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": message,
+		"data":    annotation.ToResponse(),
+	})
+}
+
+// respondReviewError maps review service errors to status codes
+func respondReviewError(c *gin.Context, message string, err error) {
+	statusCode := http.StatusInternalServerError
+	switch {
+	case strings.HasSuffix(err.Error(), "not found"):
+		statusCode = http.StatusNotFound
+	case strings.HasPrefix(err.Error(), "invalid transition"):
+		statusCode = http.StatusConflict
+	case strings.HasPrefix(err.Error(), "invalid review"):
+		statusCode = http.StatusBadRequest
+	case strings.HasPrefix(err.Error(), "unauthorized"), strings.HasPrefix(err.Error(), "cannot "):
+		statusCode = http.StatusForbidden
+	}
+
+	c.JSON(statusCode, gin.H{
+		"success": false,
+		"message": message,
+		"error":   err.Error(),
+	})
+}
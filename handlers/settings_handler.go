@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"auto-annotation-api/models"
+	"auto-annotation-api/services"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SettingsHandler struct {
+	service *services.SettingsService
+}
+
+// NewSettingsHandler creates a new settings handler
+func NewSettingsHandler(service *services.SettingsService) *SettingsHandler {
+	return &SettingsHandler{
+		service: service,
+	}
+}
+
+// GetContentFilter handles GET /settings/content-filter
+func (h *SettingsHandler) GetContentFilter(c *gin.Context) {
+	settings, err := h.service.GetContentFilterSettings(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to get content filter settings",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Content filter settings retrieved successfully",
+		"data":    settings,
+	})
+}
+
+// UpdateContentFilter handles PUT /settings/content-filter
+func (h *SettingsHandler) UpdateContentFilter(c *gin.Context) {
+	userInterface, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "User not authenticated",
+		})
+		return
+	}
+
+	user, ok := userInterface.(*models.User)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Invalid user data",
+		})
+		return
+	}
+
+	var req models.UpdateContentFilterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid request body",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	settings, err := h.service.UpdateContentFilterSettings(c.Request.Context(), &req, user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to update content filter settings",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Content filter settings updated successfully",
+		"data":    settings,
+	})
+}
+
+// GetTTSVoice handles GET /settings/tts-voice
+func (h *SettingsHandler) GetTTSVoice(c *gin.Context) {
+	settings, err := h.service.GetTTSVoiceSettings(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to get TTS voice settings",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "TTS voice settings retrieved successfully",
+		"data":    settings,
+	})
+}
+
+// UpdateTTSVoice handles PUT /settings/tts-voice
+func (h *SettingsHandler) UpdateTTSVoice(c *gin.Context) {
+	userInterface, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "User not authenticated",
+		})
+		return
+	}
+
+	user, ok := userInterface.(*models.User)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Invalid user data",
+		})
+		return
+	}
+
+	var req models.UpdateTTSVoiceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid request body",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	settings, err := h.service.UpdateTTSVoiceSettings(c.Request.Context(), &req, user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to update TTS voice settings",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "TTS voice settings updated successfully",
+		"data":    settings,
+	})
+}
+
+// GetMetadataSchema handles GET /settings/metadata-schema
+func (h *SettingsHandler) GetMetadataSchema(c *gin.Context) {
+	schema, err := h.service.GetMetadataSchema(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to get metadata schema",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Metadata schema retrieved successfully",
+		"data":    schema,
+	})
+}
+
+// UpdateMetadataSchema handles PUT /settings/metadata-schema
+func (h *SettingsHandler) UpdateMetadataSchema(c *gin.Context) {
+	user, ok := contextUser(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateMetadataSchemaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid request body",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	schema, err := h.service.UpdateMetadataSchema(c.Request.Context(), &req, user.ID)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if strings.Contains(err.Error(), "invalid metadata") {
+			statusCode = http.StatusBadRequest
+		}
+
+		c.JSON(statusCode, gin.H{
+			"success": false,
+			"message": "Failed to update metadata schema",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Metadata schema updated successfully",
+		"data":    schema,
+	})
+}
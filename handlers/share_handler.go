@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"auto-annotation-api/models"
+	"auto-annotation-api/services"
+	"auto-annotation-api/utils"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Bounds of the share QR code options
+const (
+	shareQRDefaultSize = 512
+	shareQRMinSize     = 64
+	shareQRMaxSize     = 2048
+	shareQRMaxBorder   = 16
+)
+
+// CreateShareLink handles POST /annotations/:id/share ({"expires_in_days": 7}), a signed link that
+// opens the annotation without an account
+func (h *AnnotationHandler) CreateShareLink(c *gin.Context) {
+	user, ok := contextUser(c)
+	if !ok {
+		return
+	}
+
+	var req models.CreateShareRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Invalid request body",
+				"error":   err.Error(),
+			})
+			return
+		}
+	}
+
+	link, err := h.service.CreateShareLink(c.Request.Context(), c.Param("id"), user, req.ExpiresInDays)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if err.Error() == "annotation not found" {
+			statusCode = http.StatusNotFound
+		}
+
+		c.JSON(statusCode, gin.H{
+			"success": false,
+			"message": "Failed to create share link",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "Share link created successfully",
+		"data":    link,
+	})
+}
+
+// GetSharedAnnotation handles GET /shared/:token, the read-only view of a shared annotation for
+// readers without an account
+func (h *AnnotationHandler) GetSharedAnnotation(c *gin.Context) {
+	annotation, err := h.service.GetSharedAnnotation(c.Request.Context(), c.Param("token"))
+	if err == nil && !annotation.IsReviewed() && !seesUnreviewed(optionalUser(c)) {
+		err = fmt.Errorf("annotation not found")
+	}
+	if err == nil {
+		err = h.service.FilterForDisplay(c.Request.Context(), annotation)
+	}
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		message := "Failed to get shared annotation"
+		switch {
+		case errors.Is(err, utils.ErrShareTokenExpired):
+			statusCode = http.StatusGone
+			message = "Share link has expired"
+		case err.Error() == "annotation not found" || err.Error() == "invalid share link":
+			statusCode = http.StatusNotFound
+		}
+
+		c.JSON(statusCode, gin.H{
+			"success": false,
+			"message": message,
+			"error":   err.Error(),
+		})
+		return
+	}
+	if !h.allowSafetyLabels(c, annotation) {
+		return
+	}
+
+	response := annotation.ToResponse()
+	h.localizeURLs(c, &response)
+
+	// Expiring links must stop working on time, so shared views aren't cached by the CDN
+	c.Header("Cache-Control", "private, no-store")
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Shared annotation retrieved successfully",
+		"data":    response,
+	})
+}
+
+// GetShareQRCode handles GET /annotations/:id/share/qr.png?size=512&format=png|svg&border=4, the
+// share link of the annotation as a QR code for slides and printed handouts
+func (h *AnnotationHandler) GetShareQRCode(c *gin.Context) {
+	size := shareQRDefaultSize
+	if sizeStr := c.Query("size"); sizeStr != "" {
+		var err error
+		size, err = strconv.Atoi(sizeStr)
+		if err != nil || size < shareQRMinSize || size > shareQRMaxSize {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Invalid size, expected pixels between " + strconv.Itoa(shareQRMinSize) + " and " + strconv.Itoa(shareQRMaxSize),
+			})
+			return
+		}
+	}
+
+	border := 4
+	if borderStr := c.Query("border"); borderStr != "" {
+		var err error
+		border, err = strconv.Atoi(borderStr)
+		if err != nil || border < 0 || border > shareQRMaxBorder {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Invalid border, expected modules between 0 and " + strconv.Itoa(shareQRMaxBorder),
+			})
+			return
+		}
+	}
+
+	format := c.DefaultQuery("format", "png")
+	if format != "png" && format != "svg" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid format, expected png or svg",
+		})
+		return
+	}
+
+	// Only annotations the user could open get a code
+	annotation, ok := h.readableAnnotation(c)
+	if !ok {
+		return
+	}
+
+	if annotation.MergedInto != "" {
+		target := "/annotations/" + annotation.MergedInto + "/share/qr.png"
+		if c.Request.URL.RawQuery != "" {
+			target += "?" + c.Request.URL.RawQuery
+		}
+		c.Redirect(http.StatusMovedPermanently, target)
+		return
+	}
+
+	image, contentType, err := h.service.ShareQRCode(annotation.ID, format, size, border)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to render QR code",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	// The link never changes, but a deleted annotation must stop being served
+	h.setCacheHeaders(c, services.AnnotationSurrogateKey(annotation.ID))
+	c.Data(http.StatusOK, contentType, image)
+}
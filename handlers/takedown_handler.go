@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"auto-annotation-api/models"
+	"auto-annotation-api/services"
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TakedownHandler struct {
+	service *services.TakedownService
+}
+
+// NewTakedownHandler creates a new takedown handler
+func NewTakedownHandler(service *services.TakedownService) *TakedownHandler {
+	return &TakedownHandler{
+		service: service,
+	}
+}
+
+// FileClaim handles POST /takedowns, a copyright takedown notice from a rights holder. Claimants
+// need no account.
+func (h *TakedownHandler) FileClaim(c *gin.Context) {
+	var req models.CreateTakedownRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid request body",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	claim, err := h.service.FileClaim(c.Request.Context(), &req)
+	if err != nil {
+		respondTakedownError(c, "Failed to file takedown claim", err)
+		return
+	}
+
+	// The claimant only learns the claim ID, not the uploader
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "Takedown claim filed, it will be reviewed shortly",
+		"data": gin.H{
+			"id":     claim.ID,
+			"status": claim.Status,
+		},
+	})
+}
+
+// ListMyClaims handles GET /takedowns?status=upheld&limit=20&offset=0, the claims against the
+// authenticated user's annotations
+func (h *TakedownHandler) ListMyClaims(c *gin.Context) {
+	user, ok := contextUser(c)
+	if !ok {
+		return
+	}
+
+	h.listClaims(c, models.TakedownFilter{Status: c.Query("status"), UploaderID: user.ID})
+}
+
+// SubmitCounterNotice handles POST /takedowns/:id/counter-notice, the uploader's answer to an
+// upheld claim against one of their annotations
+func (h *TakedownHandler) SubmitCounterNotice(c *gin.Context) {
+	user, ok := contextUser(c)
+	if !ok {
+		return
+	}
+
+	var req models.CounterNoticeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid request body",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	claim, err := h.service.SubmitCounterNotice(c.Request.Context(), c.Param("id"), user, &req)
+	if err != nil {
+		respondTakedownError(c, "Failed to submit counter-notice", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Counter-notice submitted, the annotation is republished at republish_at unless the claimant takes legal action",
+		"data":    claim,
+	})
+}
+
+// ListClaims handles GET /admin/takedowns?status=pending&limit=20&offset=0
+func (h *TakedownHandler) ListClaims(c *gin.Context) {
+	h.listClaims(c, models.TakedownFilter{Status: c.Query("status")})
+}
+
+// listClaims responds with a page of the claims matching filter
+func (h *TakedownHandler) listClaims(c *gin.Context, filter models.TakedownFilter) {
+	limit, err := strconv.ParseInt(c.DefaultQuery("limit", "20"), 10, 64)
+	if err != nil || limit <= 0 {
+		limit = 20
+	}
+
+	offset, err := strconv.ParseInt(c.DefaultQuery("offset", "0"), 10, 64)
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	claims, err := h.service.ListClaims(c.Request.Context(), filter, limit+1, offset)
+	hasMore := len(claims) > int(limit)
+	if hasMore {
+		claims = claims[:limit]
+	}
+	var pagination models.Pagination
+	if err == nil {
+		pagination, err = newPagination(c, limit, offset, len(claims), hasMore, func() (int64, error) {
+			return h.service.CountClaims(c.Request.Context(), filter)
+		})
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to get takedown claims",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Takedown claims retrieved successfully",
+		"data": gin.H{
+			"takedowns":  claims,
+			"pagination": pagination,
+		},
+	})
+}
+
+// GetClaim handles GET /admin/takedowns/:id
+func (h *TakedownHandler) GetClaim(c *gin.Context) {
+	claim, err := h.service.GetClaim(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondTakedownError(c, "Failed to get takedown claim", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Takedown claim retrieved successfully",
+		"data":    claim,
+	})
+}
+
+// UpholdClaim handles POST /admin/takedowns/:id/uphold ({"note": "..."}), unpublishing the annotation
+func (h *TakedownHandler) UpholdClaim(c *gin.Context) {
+	h.review(c, h.service.Uphold, "Takedown claim upheld, the annotation was unpublished")
+}
+
+// RejectClaim handles POST /admin/takedowns/:id/reject ({"note": "..."})
+func (h *TakedownHandler) RejectClaim(c *gin.Context) {
+	h.review(c, h.service.Reject, "Takedown claim rejected")
+}
+
+// KeepDown handles POST /admin/takedowns/:id/keep-down ({"note": "..."}), cancelling the
+// republication of a countered claim when the claimant reported a court action
+func (h *TakedownHandler) KeepDown(c *gin.Context) {
+	h.review(c, h.service.KeepDown, "Republication cancelled, the annotation stays unpublished")
+}
+
+// review applies an admin decision to the claim in the path
+func (h *TakedownHandler) review(c *gin.Context, decide func(ctx context.Context, claimID string, admin *models.User, note string) (*models.TakedownClaim, error), message string) {
+	admin, ok := contextUser(c)
+	if !ok {
+		return
+	}
+
+	var req models.ReviewTakedownRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Invalid request body",
+				"error":   err.Error(),
+			})
+			return
+		}
+	}
+
+	claim, err := decide(c.Request.Context(), c.Param("id"), admin, req.Note)
+	if err != nil {
+		respondTakedownError(c, "Failed to review takedown claim", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": message,
+		"data":    claim,
+	})
+}
+
+// respondTakedownError maps takedown service errors to status codes
+func respondTakedownError(c *gin.Context, message string, err error) {
+	statusCode := http.StatusInternalServerError
+	if strings.HasSuffix(err.Error(), "not found") {
+		statusCode = http.StatusNotFound
+	} else if strings.HasPrefix(err.Error(), "invalid transition") {
+		statusCode = http.StatusConflict
+	}
+
+	c.JSON(statusCode, gin.H{
+		"success": false,
+		"message": message,
+		"error":   err.Error(),
+	})
+}
@@ -5,15 +5,30 @@ import (
 	"auto-annotation-api/database"
 	"auto-annotation-api/handlers"
 	"auto-annotation-api/middleware"
+	"auto-annotation-api/models"
 	"auto-annotation-api/services"
+	"auto-annotation-api/utils"
+	"context"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 func main() {
+	// The same binary parses PDFs in a resource-limited worker process, see services.ParseSandbox
+	if len(os.Args) > 1 && os.Args[1] == services.ParseWorkerCommand {
+		services.RunParseWorker(os.Args[2:])
+		return
+	}
+
 	// Load environment variables
 	if err := godotenv.Load(); err != nil {
 		log.Println("No .env file found, using system environment variables")
@@ -21,6 +36,16 @@ func main() {
 
 	// Initialize configuration
 	cfg := config.Load()
+	cfg.LogEffective()
+	utils.SetJWTSecret(cfg.JWTSecret)
+
+	// Tracing starts first, so the database client is instrumented too
+	utils.InitTracing(cfg.OTLPEndpoint, cfg.OTLPHeaders, cfg.ServiceName)
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		utils.ShutdownTracing(ctx)
+	}()
 
 	// Initialize database connection
 	db, err := database.Connect(cfg.MongoURI, cfg.DatabaseName)
@@ -35,19 +60,50 @@ func main() {
 	// Set Gin mode
 	gin.SetMode(cfg.GinMode)
 
-	// Initialize router
+	// Initialize router. Client addresses, which rate limits are kept by, are only taken from
+	// X-Forwarded-For when the request comes from a trusted proxy.
 	router := gin.Default()
+	var trustedProxies []string
+	if cfg.TrustedProxies != "" {
+		trustedProxies = strings.Split(cfg.TrustedProxies, ",")
+	}
+	if err := router.SetTrustedProxies(trustedProxies); err != nil {
+		log.Fatal("Invalid TRUSTED_PROXIES:", err)
+	}
 
 	// Add CORS middleware
 	router.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:3000", "http://localhost:5173", "http://localhost:5174"}, // Add your frontend URLs
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "traceparent"},
+		ExposeHeaders:    []string{"Content-Length", "Retry-After", "X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset", "X-Quota-Limit", "X-Quota-Remaining", "X-Quota-Reset"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
 
+	// Time every request, the spans of services and the database nest below it
+	if utils.TracingEnabled() {
+		router.Use(middleware.TracingMiddleware())
+	}
+
+	// Reject oversized request bodies before they are parsed. Bulk uploads carry several files and
+	// have a limit of their own.
+	router.Use(middleware.UploadLimitMiddleware(int64(cfg.MaxUploadMB)<<20, "/annotations/bulk-upload"))
+
+	// Limit requests per client, so one client can't starve the others
+	if cfg.RateLimitPerMin > 0 {
+		router.Use(middleware.RateLimitMiddleware(cfg.RateLimitPerMin, time.Minute))
+	}
+
+	// Compress responses, mainly annotation lists with long summaries
+	switch strings.ToLower(cfg.Compression) {
+	case "gzip":
+		router.Use(middleware.CompressionMiddleware(cfg.CompressionLevel, cfg.CompressionMin))
+	case "", "off", "none":
+	default:
+		log.Printf("Warning: unsupported COMPRESSION %q, responses are sent uncompressed", cfg.Compression)
+	}
+
 	// Initialize AWS service (if configured)
 	var awsService *services.AWSService
 	if cfg.AWSAccessKeyID != "" && cfg.AWSSecretKey != "" && cfg.AWSS3BucketName != "" {
@@ -70,9 +126,125 @@ func main() {
 		log.Println("AWS credentials not configured. TTS functionality will not be available")
 	}
 
+	// One annotation service serves the handlers and the watch folders
+	annotationService := services.NewAnnotationService(db, cfg, awsService)
+
+	// Local storage is served by the API itself
+	if local, ok := annotationService.Storage().(*services.LocalStorage); ok {
+		router.Static(services.LocalStorageRoute, local.Dir())
+	}
+
+	// Recorded Ollama responses make the pipeline reproducible in tests and staging
+	if cfg.OllamaFixtures != "" {
+		log.Printf("Ollama fixtures: %s (%s)", cfg.OllamaFixtures, cfg.OllamaFixtureDir)
+	}
+
+	if !cfg.ParseSandbox {
+		log.Println("Warning: PDFs are parsed in the API process (PARSE_SANDBOX=false)")
+	}
+
+	if cfg.AllowSimulated {
+		log.Println("Warning: simulated uploads are enabled (ALLOW_SIMULATED_UPLOADS), for load testing only")
+	}
+
+	// Configure the external search index, if SEARCH_BACKEND selects one
+	if err := annotationService.SetupSearchIndex(); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+
+	// Embeddings power semantic search; annotations missing one of the configured model are embedded
+	// in the background
+	services.RunInBackground(annotationService.BackfillEmbeddings)
+
+	// Promote the configured admin, so user management doesn't require editing the database
+	userService := services.NewUserService(db)
+	if cfg.AdminEmail != "" {
+		if err := userService.EnsureAdmin(context.Background(), cfg.AdminEmail); err != nil {
+			log.Printf("Warning: Failed to grant admin role to %s: %v", cfg.AdminEmail, err)
+		} else {
+			log.Printf("Admin role granted to %s", cfg.AdminEmail)
+		}
+	}
+
 	// Initialize handlers
-	authHandler := handlers.NewAuthHandler(db)
-	annotationHandler := handlers.NewAnnotationHandler(db, cfg.OllamaBaseURL, cfg.OllamaModel, cfg.UploadDir, awsService)
+	authHandler := handlers.NewAuthHandler(db, annotationService)
+	linkChecker := services.NewLinkCheckService(db)
+	mailer := services.NewMailer(cfg)
+	reportService := services.NewReportService(db, cfg, userService, mailer)
+	adminHandler := handlers.NewAdminHandler(userService, linkChecker, annotationService, reportService)
+	annotationHandler := handlers.NewAnnotationHandler(cfg, annotationService)
+
+	// Initialize clustering service and start the background clustering job
+	clusteringService := services.NewClusteringService(db, cfg)
+	clusterHandler := handlers.NewClusterHandler(clusteringService)
+	featureFlags := services.NewFeatureFlagService(db, cfg)
+	featureFlagHandler := handlers.NewFeatureFlagHandler(featureFlags)
+	// Uploads are turned away while the pipeline is saturated, see LOAD_SHED_QUEUE_DEPTH
+	loadShedder := services.NewLoadShedder(cfg)
+	loadShedding := middleware.LoadSheddingMiddleware(loadShedder)
+	settingsHandler := handlers.NewSettingsHandler(annotationService.Settings())
+	healthHandler := handlers.NewHealthHandler(services.NewReadinessChecker(db, cfg, awsService))
+	metricsHandler := handlers.NewMetricsHandler(loadShedder)
+	takedownService := services.NewTakedownService(db, cfg, annotationService, userService, mailer)
+	takedownHandler := handlers.NewTakedownHandler(takedownService)
+	reviewHandler := handlers.NewReviewHandler(services.NewReviewService(annotationService, userService, mailer))
+
+	jobCtx, cancelJobs := context.WithCancel(context.Background())
+	defer cancelJobs()
+
+	// Collaborative editing sessions are saved periodically and once more at shutdown
+	collabHub := services.NewCollabHub(annotationService, time.Duration(cfg.CollabSaveSeconds)*time.Second)
+	collabHandler := handlers.NewCollabHandler(collabHub)
+	services.RunInBackground(func(context.Context) {
+		collabHub.Run(jobCtx)
+	})
+
+	if cfg.ClusterInterval > 0 {
+		go clusteringService.StartBackgroundJob(jobCtx, time.Duration(cfg.ClusterInterval)*time.Minute)
+		log.Printf("Clustering job started (every %d minutes)", cfg.ClusterInterval)
+	}
+
+	// Republish annotations whose counter-notice waiting period has passed
+	go takedownService.StartBackgroundJob(jobCtx)
+
+	switch cfg.ContentReports {
+	case models.ReportsDaily, models.ReportsWeekly:
+		go reportService.StartBackgroundJob(jobCtx)
+		log.Printf("Content reports enabled (%s)", cfg.ContentReports)
+	case models.ReportsOff:
+	default:
+		log.Printf("Warning: unknown CONTENT_REPORTS %q, content reports are disabled", cfg.ContentReports)
+	}
+
+	if cfg.LinkCheckInterval > 0 {
+		go linkChecker.StartBackgroundJob(jobCtx, time.Duration(cfg.LinkCheckInterval)*time.Hour)
+		log.Printf("Link check job started (every %d hours)", cfg.LinkCheckInterval)
+	}
+
+	// Start the watch folder (if configured)
+	watchInterval := time.Duration(cfg.WatchInterval) * time.Second
+	if cfg.WatchDir != "" {
+		startWatchFolder(jobCtx, db, annotationService, cfg.WatchDir, cfg.WatchUserEmail, watchInterval)
+	}
+
+	// Start the SFTP ingestion server (if configured); each account's directory is a watch folder
+	if cfg.SFTPListenAddr != "" {
+		accounts, err := services.ParseSFTPAccounts(cfg.SFTPUsers, cfg.SFTPRootDir)
+		if err != nil {
+			log.Printf("Warning: SFTP ingestion disabled: %v", err)
+		} else if sftpServer, err := services.NewSFTPServer(cfg.SFTPListenAddr, cfg.SFTPHostKeyFile, accounts); err != nil {
+			log.Printf("Warning: SFTP ingestion disabled: %v", err)
+		} else {
+			for _, account := range accounts {
+				startWatchFolder(jobCtx, db, annotationService, account.Dir, account.UserEmail, watchInterval)
+			}
+			go func() {
+				if err := sftpServer.Start(jobCtx); err != nil {
+					log.Printf("Warning: SFTP server stopped: %v", err)
+				}
+			}()
+		}
+	}
 
 	// Basic route
 	router.GET("/", func(c *gin.Context) {
@@ -83,6 +255,30 @@ func main() {
 		})
 	})
 
+	// API documentation (public)
+	docsHandler := handlers.NewDocsHandler()
+	router.GET("/docs", docsHandler.SwaggerUI)
+	router.GET("/docs/openapi.json", docsHandler.OpenAPIJSON)
+	router.GET("/docs/openapi.yaml", docsHandler.OpenAPIYAML)
+
+	// Probes for orchestrators such as Kubernetes: liveness of the process and readiness to serve
+	router.GET("/healthz", healthHandler.Liveness)
+	router.GET("/readyz", healthHandler.Readiness)
+
+	// Load of the upload pipeline for monitoring, in the Prometheus text format
+	router.GET("/metrics", metricsHandler.GetMetrics)
+
+	// Copyright takedown notices can be filed without an account
+	router.POST("/takedowns", takedownHandler.FileClaim)
+
+	// Takedown claims against the user's annotations
+	takedownRoutes := router.Group("/takedowns")
+	takedownRoutes.Use(middleware.AuthMiddleware(db))
+	{
+		takedownRoutes.GET("", takedownHandler.ListMyClaims)
+		takedownRoutes.POST("/:id/counter-notice", takedownHandler.SubmitCounterNotice)
+	}
+
 	// Auth routes (public)
 	authRoutes := router.Group("/auth")
 	{
@@ -95,6 +291,27 @@ func main() {
 	protectedRoutes.Use(middleware.AuthMiddleware(db))
 	{
 		protectedRoutes.GET("/profile", authHandler.GetProfile)
+		protectedRoutes.PATCH("/profile", authHandler.UpdateProfile)
+		protectedRoutes.DELETE("/profile", authHandler.DeleteProfile)
+		protectedRoutes.POST("/logout", authHandler.Logout)
+	}
+
+	// Share links open a single annotation without an account
+	router.GET("/shared/:token", annotationHandler.GetSharedAnnotation)
+
+	// With PUBLIC_CATALOG, public annotations can be read without an account, e.g. when embedded in
+	// a website. Anonymous readers get their own, usually stricter, rate limit.
+	if cfg.PublicCatalog {
+		publicRoutes := router.Group("/public")
+		if cfg.PublicRatePerMin > 0 {
+			publicRoutes.Use(middleware.RateLimitMiddleware(cfg.PublicRatePerMin, time.Minute))
+		}
+		publicRoutes.Use(middleware.OptionalAuthMiddleware(db))
+		{
+			publicRoutes.GET("/annotations", annotationHandler.GetPublicAnnotations)
+			publicRoutes.GET("/annotations/:id", annotationHandler.GetPublicAnnotation)
+		}
+		log.Printf("Public catalog enabled (%d requests per minute)", cfg.PublicRatePerMin)
 	}
 
 	// Annotation routes - viewing is available to all authenticated users
@@ -103,8 +320,32 @@ func main() {
 	{
 		// Public viewing (any authenticated user)
 		annotationRoutes.GET("", annotationHandler.GetAllAnnotations)
+		annotationRoutes.GET("/tags", annotationHandler.GetTagCounts)
+		annotationRoutes.GET("/mine", annotationHandler.GetMyAnnotations)
 		annotationRoutes.GET("/:id", annotationHandler.GetAnnotation)
+		annotationRoutes.GET("/:id/search", annotationHandler.SearchAnnotationText)
+		annotationRoutes.GET("/search", annotationHandler.SearchAnnotations)
+		annotationRoutes.GET("/semantic-search", middleware.FeatureFlagMiddleware(featureFlags, models.FeatureSemanticSearch), annotationHandler.SemanticSearch)
+		annotationRoutes.GET("/export", annotationHandler.ExportAnnotations)
+		annotationRoutes.GET("/:id/reader", annotationHandler.GetReaderRendition)
+		annotationRoutes.GET("/:id/print", annotationHandler.PrintAnnotation)
+		annotationRoutes.GET("/:id/share/qr.png", annotationHandler.GetShareQRCode)
+		annotationRoutes.GET("/:id/source", annotationHandler.DownloadSource)
+		annotationRoutes.POST("/:id/explain", middleware.FeatureFlagMiddleware(featureFlags, models.FeatureExplain), annotationHandler.ExplainSelection)
 		annotationRoutes.GET("/:id/audio", annotationHandler.DownloadAudio) // Deprecated - kept for backward compatibility
+		annotationRoutes.GET("/:id/tts/captions", annotationHandler.DownloadCaptions)
+		annotationRoutes.GET("/:id/tts/marks", annotationHandler.DownloadSpeechMarks)
+		annotationRoutes.GET("/:id/attachments/:attachmentId", annotationHandler.DownloadAttachment)
+		annotationRoutes.POST("/:id/ask", middleware.FeatureFlagMiddleware(featureFlags, models.FeatureAsk), annotationHandler.AskQuestion)
+		annotationRoutes.POST("/:id/quiz", middleware.FeatureFlagMiddleware(featureFlags, models.FeatureQuiz), annotationHandler.CreateQuiz)
+		annotationRoutes.GET("/:id/quizzes", middleware.FeatureFlagMiddleware(featureFlags, models.FeatureQuiz), annotationHandler.ListQuizzes)
+	}
+
+	// Quizzes generated from annotations, for studying
+	quizRoutes := router.Group("/quizzes")
+	quizRoutes.Use(middleware.AuthMiddleware(db))
+	{
+		quizRoutes.GET("/:id", middleware.FeatureFlagMiddleware(featureFlags, models.FeatureQuiz), annotationHandler.GetQuiz)
 	}
 
 	// Annotation creation/modification routes (content creators only)
@@ -112,17 +353,95 @@ func main() {
 	annotationCreatorRoutes.Use(middleware.AuthMiddleware(db))
 	annotationCreatorRoutes.Use(middleware.ContentCreatorMiddleware())
 	{
-		annotationCreatorRoutes.POST("/upload", annotationHandler.UploadAndCreateAnnotation)
+		annotationCreatorRoutes.POST("/upload", loadShedding, annotationHandler.UploadAndCreateAnnotation)
+		annotationCreatorRoutes.POST("/from-url", loadShedding, annotationHandler.CreateAnnotationFromURL)
+		annotationCreatorRoutes.POST("/bulk-upload", loadShedding, middleware.UploadLimitMiddleware(int64(cfg.BulkUploadMaxMB)<<20), annotationHandler.BulkUpload)
+		annotationCreatorRoutes.POST("/suggest-title", annotationHandler.SuggestTitle)
+		annotationCreatorRoutes.POST("/estimate", annotationHandler.EstimateProcessing)
+		annotationCreatorRoutes.GET("/batches/:id", annotationHandler.GetUploadBatch)
+		annotationCreatorRoutes.POST("/merge", annotationHandler.MergeAnnotations)
+		annotationCreatorRoutes.POST("/metadata-import", annotationHandler.ImportMetadata)
 		annotationCreatorRoutes.GET("/stats", annotationHandler.GetAnnotationStats)
+		annotationCreatorRoutes.GET("/clusters", middleware.FeatureFlagMiddleware(featureFlags, models.FeatureClusters), clusterHandler.GetClusters)
 		annotationCreatorRoutes.PATCH("/:id", annotationHandler.UpdateAnnotation)
 		annotationCreatorRoutes.DELETE("/:id", annotationHandler.DeleteAnnotation)
+		annotationCreatorRoutes.POST("/:id/regenerate", annotationHandler.RegenerateAnnotation)
 		annotationCreatorRoutes.POST("/:id/tts", annotationHandler.GenerateTTSForAnnotation)
+		annotationCreatorRoutes.POST("/:id/audio-tour", annotationHandler.GenerateAudioTour)
+		annotationCreatorRoutes.POST("/:id/images", annotationHandler.AddImage)
+		annotationCreatorRoutes.PUT("/:id/images/order", annotationHandler.ReorderImages)
+		annotationCreatorRoutes.PATCH("/:id/images/:imageId", annotationHandler.UpdateImage)
+		annotationCreatorRoutes.DELETE("/:id/images/:imageId", annotationHandler.RemoveImage)
+		annotationCreatorRoutes.POST("/:id/attachments", annotationHandler.AddAttachment)
+		annotationCreatorRoutes.DELETE("/:id/attachments/:attachmentId", annotationHandler.RemoveAttachment)
+		annotationCreatorRoutes.POST("/:id/links", annotationHandler.CreateLink)
+		annotationCreatorRoutes.DELETE("/:id/links/:linkId", annotationHandler.DeleteLink)
+		annotationCreatorRoutes.POST("/:id/split", annotationHandler.SplitAnnotation)
+		annotationCreatorRoutes.POST("/:id/share", annotationHandler.CreateShareLink)
+		annotationCreatorRoutes.POST("/:id/submit", reviewHandler.SubmitAnnotation)
+		annotationCreatorRoutes.GET("/:id/collab", collabHandler.EditAnnotation)
+	}
+
+	// Review decisions on submitted annotations (reviewers and admins only)
+	reviewRoutes := router.Group("/annotations")
+	reviewRoutes.Use(middleware.AuthMiddleware(db))
+	reviewRoutes.Use(middleware.RoleMiddleware("reviewer", "admin"))
+	{
+		reviewRoutes.POST("/:id/approve", reviewHandler.ApproveAnnotation)
+		reviewRoutes.POST("/:id/reject", reviewHandler.RejectAnnotation)
+	}
+
+	// Settings routes (content creators only)
+	settingsRoutes := router.Group("/settings")
+	settingsRoutes.Use(middleware.AuthMiddleware(db))
+	settingsRoutes.Use(middleware.ContentCreatorMiddleware())
+	{
+		settingsRoutes.GET("/content-filter", settingsHandler.GetContentFilter)
+		settingsRoutes.PUT("/content-filter", settingsHandler.UpdateContentFilter)
+		settingsRoutes.GET("/tts-voice", settingsHandler.GetTTSVoice)
+		settingsRoutes.PUT("/tts-voice", settingsHandler.UpdateTTSVoice)
+		settingsRoutes.GET("/metadata-schema", settingsHandler.GetMetadataSchema)
+		settingsRoutes.PUT("/metadata-schema", settingsHandler.UpdateMetadataSchema)
+	}
+
+	// Admin routes (admins only)
+	adminRoutes := router.Group("/admin")
+	adminRoutes.Use(middleware.AuthMiddleware(db))
+	adminRoutes.Use(middleware.RoleMiddleware("admin"))
+	{
+		adminRoutes.GET("/users", adminHandler.ListUsers)
+		adminRoutes.GET("/users/:id", adminHandler.GetUser)
+		adminRoutes.PUT("/users/:id/role", adminHandler.UpdateUserRole)
+		adminRoutes.PUT("/users/:id/status", adminHandler.UpdateUserStatus)
+		adminRoutes.PUT("/users/:id/content-restrictions", adminHandler.UpdateUserContentRestrictions)
+		adminRoutes.DELETE("/users/:id", adminHandler.DeleteUser)
+		adminRoutes.GET("/reports/broken-links", adminHandler.GetBrokenLinkReport)
+		adminRoutes.POST("/reports/broken-links/run", adminHandler.RunLinkCheck)
+		adminRoutes.GET("/reports/upload-consents", adminHandler.ExportUploadConsents)
+		adminRoutes.GET("/reports/weekly", adminHandler.GetContentReport)
+		adminRoutes.GET("/search/reindex", adminHandler.GetReindexStatus)
+		adminRoutes.POST("/search/reindex", adminHandler.StartReindex)
+		adminRoutes.DELETE("/load-test/annotations", adminHandler.DeleteSimulatedAnnotations)
+		adminRoutes.GET("/llm/models", adminHandler.ListLLMModels)
+		adminRoutes.POST("/llm/models", adminHandler.PullLLMModel)
+		adminRoutes.DELETE("/llm/models/*name", adminHandler.DeleteLLMModel)
+		adminRoutes.PUT("/annotations/pinned", adminHandler.SetPinnedAnnotations)
+		adminRoutes.GET("/feature-flags", featureFlagHandler.ListFlags)
+		adminRoutes.PUT("/feature-flags/:name", featureFlagHandler.UpdateFlag)
+		adminRoutes.POST("/annotations/:id/pin", adminHandler.PinAnnotation)
+		adminRoutes.DELETE("/annotations/:id/pin", adminHandler.UnpinAnnotation)
+		adminRoutes.GET("/takedowns", takedownHandler.ListClaims)
+		adminRoutes.GET("/takedowns/:id", takedownHandler.GetClaim)
+		adminRoutes.POST("/takedowns/:id/uphold", takedownHandler.UpholdClaim)
+		adminRoutes.POST("/takedowns/:id/reject", takedownHandler.RejectClaim)
+		adminRoutes.POST("/takedowns/:id/keep-down", takedownHandler.KeepDown)
 	}
 
 	// System routes
 	systemRoutes := router.Group("/system")
 	{
 		systemRoutes.GET("/services/status", annotationHandler.CheckServices)
+		systemRoutes.GET("/tts/voices", annotationHandler.ListTTSVoices)
 	}
 
 
@@ -132,10 +451,59 @@ func main() {
 		port = "8080"
 	}
 
+	server := &http.Server{
+		Addr:    ":" + port,
+		Handler: router,
+	}
+
 	log.Printf("Server starting on port %s", port)
 	log.Printf("Visit http://localhost:%s to test the connection", port)
-	
-	if err := router.Run(":" + port); err != nil {
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- server.ListenAndServe()
+	}()
+
+	stop, stopSignals := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stopSignals()
+
+	select {
+	case err := <-serverErr:
 		log.Fatal("Failed to start server:", err)
+	case <-stop.Done():
 	}
-}
\ No newline at end of file
+	stopSignals() // A second signal kills the process right away
+
+	// Deploys send SIGTERM: stop accepting connections, let running uploads, generations and TTS
+	// finish, and only then disconnect from MongoDB (deferred above)
+	timeout := time.Duration(cfg.ShutdownTimeout) * time.Second
+	log.Printf("Shutting down, waiting up to %s for running requests and background work", timeout)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cancelJobs()
+	if err := server.Shutdown(ctx); err != nil {
+		log.Printf("Warning: requests still running at shutdown were aborted: %v", err)
+	}
+	if err := services.DrainBackgroundWork(ctx); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+	log.Println("Server stopped")
+}
+
+// startWatchFolder starts a folder watcher that creates annotations as the user with the given email
+func startWatchFolder(ctx context.Context, db *mongo.Database, annotationService *services.AnnotationService, dir, userEmail string, interval time.Duration) {
+	serviceAccount, err := services.NewAuthService(db).GetUserByEmail(ctx, userEmail)
+	if err != nil {
+		log.Printf("Warning: Watch folder %s disabled, service account %q not available: %v", dir, userEmail, err)
+		return
+	}
+
+	// Tracked as background work, so a shutdown waits for the file being processed
+	watcher := services.NewFolderWatcher(dir, serviceAccount.ID, interval, annotationService)
+	services.RunInBackground(func(context.Context) {
+		if err := watcher.Start(ctx); err != nil {
+			log.Printf("Warning: Watch folder %s stopped: %v", dir, err)
+		}
+	})
+}
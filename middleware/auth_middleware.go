@@ -19,6 +19,9 @@ func AuthMiddleware(db *mongo.Database) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Get token from Authorization header
 		authHeader := c.GetHeader("Authorization")
+		if authHeader == "" {
+			authHeader = websocketAuthorization(c)
+		}
 		if authHeader == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"success": false,
@@ -53,6 +56,26 @@ func AuthMiddleware(db *mongo.Database) gin.HandlerFunc {
 			return
 		}
 
+		// Reject tokens that were revoked (e.g. on logout)
+		revoked, err := authService.IsTokenRevoked(c.Request.Context(), claims.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Failed to verify token",
+				"error":   err.Error(),
+			})
+			c.Abort()
+			return
+		}
+		if revoked {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": "Token has been revoked",
+			})
+			c.Abort()
+			return
+		}
+
 		// Get user from database
 		user, err := authService.GetUserByID(c.Request.Context(), claims.UserID)
 		if err != nil {
@@ -65,9 +88,20 @@ func AuthMiddleware(db *mongo.Database) gin.HandlerFunc {
 			return
 		}
 
+		// Tokens issued before the account was disabled stop working
+		if user.Disabled {
+			c.JSON(http.StatusForbidden, gin.H{
+				"success": false,
+				"message": "Account is disabled",
+			})
+			c.Abort()
+			return
+		}
+
 		// Add user to context
 		c.Set("user", user)
 		c.Set("userID", user.ID)
+		c.Set("claims", claims)
 
 		// Continue to next handler
 		c.Next()
@@ -101,9 +135,15 @@ func OptionalAuthMiddleware(db *mongo.Database) gin.HandlerFunc {
 			return
 		}
 
+		if revoked, err := authService.IsTokenRevoked(c.Request.Context(), claims.ID); err != nil || revoked {
+			// Revoked token, continue without setting user
+			c.Next()
+			return
+		}
+
 		user, err := authService.GetUserByID(c.Request.Context(), claims.UserID)
-		if err != nil {
-			// User not found, continue without setting user
+		if err != nil || user.Disabled {
+			// User not found or disabled, continue without setting user
 			c.Next()
 			return
 		}
@@ -111,6 +151,7 @@ func OptionalAuthMiddleware(db *mongo.Database) gin.HandlerFunc {
 		// Add user to context
 		c.Set("user", user)
 		c.Set("userID", user.ID)
+		c.Set("claims", claims)
 		c.Next()
 	}
 }
@@ -197,3 +238,18 @@ func RoleMiddleware(allowedRoles ...string) gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// websocketAuthorization returns the token of a WebSocket handshake as an Authorization header.
+// Browsers can't set headers on WebSockets, so clients pass the subprotocols "bearer", <token>.
+func websocketAuthorization(c *gin.Context) string {
+	if !c.IsWebsocket() {
+		return ""
+	}
+	protocols := strings.Split(c.GetHeader("Sec-WebSocket-Protocol"), ",")
+	for i := 0; i+1 < len(protocols); i++ {
+		if strings.TrimSpace(protocols[i]) == "bearer" {
+			return "Bearer " + strings.TrimSpace(protocols[i+1])
+		}
+	}
+	return ""
+}
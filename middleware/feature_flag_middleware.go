@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"auto-annotation-api/models"
+	"auto-annotation-api/services"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FeatureFlagMiddleware rejects requests of users the feature isn't rolled out to. It runs after
+// AuthMiddleware or OptionalAuthMiddleware so the user's rollout can be looked up.
+func FeatureFlagMiddleware(flags *services.FeatureFlagService, feature string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, _ := c.Get("user")
+		userModel, _ := user.(*models.User)
+
+		if !flags.Enabled(c.Request.Context(), feature, userModel) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"success": false,
+				"message": "This feature is not available to you yet",
+				"error":   fmt.Sprintf("feature %q is not enabled for this account", feature),
+				"code":    "feature_disabled",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"auto-annotation-api/services"
+	"auto-annotation-api/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LoadSheddingMiddleware rejects requests with 503 and a Retry-After header while the upload
+// pipeline is saturated, see services.LoadShedder. It does nothing when no threshold is set.
+func LoadSheddingMiddleware(shedder *services.LoadShedder) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !shedder.Enabled() {
+			c.Next()
+			return
+		}
+
+		state := shedder.State()
+		if !state.Shedding {
+			c.Next()
+			return
+		}
+
+		span := utils.SpanFromContext(c.Request.Context())
+		span.SetAttribute("load_shed.reason", state.Reason)
+		span.SetAttribute("load_shed.queue_depth", state.QueueDepth)
+		span.SetAttribute("load_shed.ollama_latency_ms", state.OllamaLatencyMS)
+
+		c.Header("Retry-After", strconv.Itoa(state.RetryAfter))
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"success": false,
+			"message": "The server is too busy to accept uploads, retry later",
+			"error":   state.Reason,
+			"code":    "overloaded",
+		})
+		c.Abort()
+	}
+}
@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"auto-annotation-api/utils"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// rateWindow counts the requests of one client in the current window
+type rateWindow struct {
+	count   int
+	resetAt time.Time
+}
+
+// RateLimitMiddleware allows each client limit requests per window and reports the state in
+// X-RateLimit-Limit, X-RateLimit-Remaining and X-RateLimit-Reset (Unix seconds) on every response,
+// so clients can back off before they are rejected with 429. Clients are told apart by the user of
+// a valid bearer token, or by IP address without one. Counts are kept in memory, per API instance.
+func RateLimitMiddleware(limit int, window time.Duration) gin.HandlerFunc {
+	var mu sync.Mutex
+	windows := make(map[string]*rateWindow)
+	nextSweep := time.Now().Add(window)
+
+	return func(c *gin.Context) {
+		now := time.Now()
+		key := rateLimitKey(c)
+
+		mu.Lock()
+		if now.After(nextSweep) {
+			for k, w := range windows {
+				if now.After(w.resetAt) {
+					delete(windows, k)
+				}
+			}
+			nextSweep = now.Add(window)
+		}
+
+		w, ok := windows[key]
+		if !ok || now.After(w.resetAt) {
+			w = &rateWindow{resetAt: now.Add(window)}
+			windows[key] = w
+		}
+		w.count++
+		count, resetAt := w.count, w.resetAt
+		mu.Unlock()
+
+		c.Header("X-RateLimit-Limit", strconv.Itoa(limit))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(max(limit-count, 0)))
+		c.Header("X-RateLimit-Reset", strconv.FormatInt(resetAt.Unix(), 10))
+
+		if count > limit {
+			retryAfter := int(resetAt.Sub(now).Seconds()) + 1
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"success": false,
+				"message": "Too many requests",
+				"error":   fmt.Sprintf("rate limit of %d requests per %s exceeded, retry in %d seconds", limit, window, retryAfter),
+				"code":    "rate_limited",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// rateLimitKey identifies the client of a request: the user of a valid token, otherwise the IP
+// address. Invalid tokens count against the IP, so clients can't get a fresh limit by sending
+// made-up tokens, and only as many windows are kept as there are users and addresses.
+func rateLimitKey(c *gin.Context) string {
+	if token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok {
+		if claims, err := utils.ValidateToken(token); err == nil {
+			return "user:" + claims.UserID
+		}
+	}
+	return "ip:" + c.ClientIP()
+}
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"auto-annotation-api/utils"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TracingMiddleware starts a server span for every request, named after the matched route, and
+// puts it in the request context so service and database spans become its children. A traceparent
+// header from the caller continues the caller's trace.
+func TracingMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, span := utils.StartServerSpan(c.Request.Context(), c.Request.Method, c.GetHeader("traceparent"))
+		c.Request = c.Request.WithContext(ctx)
+		defer span.End()
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		span.SetName(c.Request.Method + " " + route)
+		span.SetAttribute("http.request.method", c.Request.Method)
+		span.SetAttribute("http.route", route)
+		span.SetAttribute("http.response.status_code", c.Writer.Status())
+		if c.Writer.Status() >= 500 {
+			span.RecordError(fmt.Errorf("status %d", c.Writer.Status()))
+		}
+		if userID, ok := c.Get("userID"); ok {
+			span.SetAttribute("enduser.id", fmt.Sprint(userID))
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UploadLimitMiddleware caps the request body at maxBytes. Requests that announce a larger body are
+// rejected up front; others fail while parsing once the limit is crossed, so nothing oversized is
+// read into memory or spooled to disk. Routes listed in ownLimit are skipped, they set their own
+// limit with another UploadLimitMiddleware.
+func UploadLimitMiddleware(maxBytes int64, ownLimit ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if slices.Contains(ownLimit, c.FullPath()) {
+			c.Next()
+			return
+		}
+
+		if c.Request.ContentLength > maxBytes {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+				"success": false,
+				"message": "Request is too large",
+				"error":   fmt.Sprintf("request body exceeds the maximum upload size of %d MB", maxBytes>>20),
+				"code":    "file_too_large",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes)
+		c.Next()
+	}
+}
@@ -7,13 +7,15 @@ import (
 )
 
 type User struct {
-	ID        string    `json:"id" bson:"_id"`
-	Email     string    `json:"email" bson:"email"`
-	Password  string    `json:"-" bson:"password"` // "-" means this field won't be included in JSON responses
-	Name      string    `json:"name" bson:"name"`
-	Role      string    `json:"role" bson:"role"` // "content", "basic", or empty
-	CreatedAt time.Time `json:"created_at" bson:"created_at"`
-	UpdatedAt time.Time `json:"updated_at" bson:"updated_at"`
+	ID         string    `json:"id" bson:"_id"`
+	Email      string    `json:"email" bson:"email"`
+	Password   string    `json:"-" bson:"password"` // "-" means this field won't be included in JSON responses
+	Name       string    `json:"name" bson:"name"`
+	Role       string    `json:"role" bson:"role"`                                                     // "admin", "content", "reviewer", "basic", or empty
+	Disabled   bool      `json:"disabled" bson:"disabled,omitempty"`                                   // Disabled accounts cannot log in
+	Restricted []string  `json:"content_restrictions,omitempty" bson:"content_restrictions,omitempty"` // Safety labels hidden from the user, on top of those of the role
+	CreatedAt  time.Time `json:"created_at" bson:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at" bson:"updated_at"`
 }
 
 // NewUser creates a new user with a generated UUID
@@ -49,6 +51,16 @@ func (u *User) IsContentCreator() bool {
 	return u.Role == "content"
 }
 
+// IsAdmin checks if user has admin role
+func (u *User) IsAdmin() bool {
+	return u.Role == "admin"
+}
+
+// IsReviewer checks if user has reviewer role
+func (u *User) IsReviewer() bool {
+	return u.Role == "reviewer"
+}
+
 // HasRole checks if user has a specific role
 func (u *User) HasRole(role string) bool {
 	return u.Role == role
@@ -8,41 +8,286 @@ import (
 
 // Annotation represents a generated annotation
 type Annotation struct {
-	ID           string    `json:"id" bson:"_id"`
-	UserID       string    `json:"user_id" bson:"user_id"`
-	Title        string    `json:"title" bson:"title"`
-	Image        string    `json:"image,omitempty" bson:"image,omitempty"` // Image URL/path
-	SourceFile   string    `json:"source_file" bson:"source_file"`
-	SourceType   string    `json:"source_type" bson:"source_type"` // "pdf" only now
-	TextContent  string    `json:"text_content" bson:"text_content"`
-	Annotation   string    `json:"annotation" bson:"annotation"`
-	Genre        string    `json:"genre" bson:"genre"`
-	TTSURL       string    `json:"tts_url,omitempty" bson:"tts_url,omitempty"`
-	Status       string    `json:"status" bson:"status"` // "processing", "completed", "failed"
-	ErrorMessage string    `json:"error_message,omitempty" bson:"error_message,omitempty"`
-	CreatedAt    time.Time `json:"created_at" bson:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at" bson:"updated_at"`
+	ID           string          `json:"id" bson:"_id"`
+	UserID       string          `json:"user_id" bson:"user_id"`
+	Title        string          `json:"title" bson:"title"`
+	TitleSource  string          `json:"title_source,omitempty" bson:"title_source,omitempty"` // TitleProvided or another title source
+	Image        string          `json:"image,omitempty" bson:"image,omitempty"`               // Image URL/path
+	ImageAltText string          `json:"image_alt_text,omitempty" bson:"image_alt_text,omitempty"`
+	SourceFile   string          `json:"source_file" bson:"source_file"`
+	SourceType   string          `json:"source_type" bson:"source_type"` // "pdf", or "png", "jpg" or "jpeg" for an image
+	TextContent  string          `json:"text_content" bson:"text_content"`
+	Annotation   string          `json:"annotation" bson:"annotation"`
+	Genre        string          `json:"genre" bson:"genre"`
+	Model        string          `json:"model,omitempty" bson:"model,omitempty"` // Ollama model that wrote the notes
+	Length       string          `json:"length" bson:"length,omitempty"`         // "short", "medium" or "detailed"
+	License      string          `json:"license" bson:"license,omitempty"`       // One of Licenses, empty for DefaultLicense
+	Visibility   string          `json:"visibility" bson:"visibility,omitempty"` // VisibilityPublic or VisibilityPrivate, empty for private
+	Tags         []string        `json:"tags" bson:"tags"`
+	Book         *BookMetadata   `json:"book,omitempty" bson:"book,omitempty"`
+	SourceURL    string          `json:"source_url,omitempty" bson:"source_url,omitempty"`             // Where the document was originally published
+	Author       string          `json:"author,omitempty" bson:"author,omitempty"`                     // As credited, e.g. "Ada Lovelace, Charles Babbage"
+	PublishedOn  string          `json:"publication_date,omitempty" bson:"publication_date,omitempty"` // YYYY-MM-DD, YYYY-MM or YYYY
+	Metadata     Metadata        `json:"metadata,omitempty" bson:"metadata,omitempty"`                 // Custom fields defined by the metadata schema
+	TTSURL       string          `json:"tts_url,omitempty" bson:"tts_url,omitempty"`
+	TTSKey       string          `json:"-" bson:"tts_key,omitempty"` // Storage key of the TTS audio
+	TTSOpusURL   string          `json:"tts_opus_url,omitempty" bson:"tts_opus_url,omitempty"`
+	TTSOpusKey   string          `json:"-" bson:"tts_opus_key,omitempty"`                        // Storage key of the OGG/Opus TTS audio
+	TTSMarksURL  string          `json:"tts_marks_url,omitempty" bson:"tts_marks_url,omitempty"` // Polly speech marks as a JSON array
+	TTSMarksKey  string          `json:"-" bson:"tts_marks_key,omitempty"`
+	Captions     *TTSCaptions    `json:"captions,omitempty" bson:"captions,omitempty"`
+	ImageKey     string          `json:"-" bson:"image_key,omitempty"`             // Storage key of the image, empty for external URLs
+	Images       []GalleryImage  `json:"images,omitempty" bson:"images,omitempty"` // Gallery in display order, the first entry is mirrored in Image
+	SourceKey    string          `json:"-" bson:"source_key,omitempty"`            // Storage key of the original upload
+	ContentHash  string          `json:"-" bson:"content_hash,omitempty"`          // Hex SHA-256 of the original upload
+	AudioTour    *AudioTour      `json:"audio_tour,omitempty" bson:"audio_tour,omitempty"`
+	Attachments  []Attachment    `json:"attachments,omitempty" bson:"attachments,omitempty"`
+	Status       string          `json:"status" bson:"status"`                                 // "processing", "completed", "failed", "merged"
+	MergedInto   string          `json:"merged_into,omitempty" bson:"merged_into,omitempty"`   // Set when Status is "merged"
+	ReviewState  string          `json:"review_state,omitempty" bson:"review_state,omitempty"` // See ReviewDraft and the other review states
+	Review       *ReviewRecord   `json:"review,omitempty" bson:"review,omitempty"`
+	MergedFrom   []string        `json:"merged_from,omitempty" bson:"merged_from,omitempty"`
+	ErrorMessage string          `json:"error_message,omitempty" bson:"error_message,omitempty"`
+	Figures      []FigureInsight `json:"figures,omitempty" bson:"figures,omitempty"`
+	Formulas     []Formula       `json:"formulas,omitempty" bson:"formulas,omitempty"`
+	CodeBlocks   []CodeBlock     `json:"code_blocks,omitempty" bson:"code_blocks,omitempty"`
+	CodeExamples string          `json:"-" bson:"code_examples,omitempty"`                                   // Generated "Key code examples" section
+	Objectives   []string        `json:"learning_objectives,omitempty" bson:"learning_objectives,omitempty"` // Educational material only
+	Prereqs      []string        `json:"prerequisites,omitempty" bson:"prerequisites,omitempty"`             // Educational material only
+	KeyTerms     []KeyTerm       `json:"key_terms,omitempty" bson:"key_terms,omitempty"`                     // Glossary in order of appearance
+	Sections     []Section       `json:"sections,omitempty" bson:"sections,omitempty"`                       // Per-section summaries of long documents, in document order
+	SafetyLabels []SafetyLabel   `json:"safety_labels,omitempty" bson:"safety_labels,omitempty"`             // Sensitive topics, most confident first
+	BrokenLinks  []BrokenLink    `json:"broken_links,omitempty" bson:"broken_links,omitempty"`               // Found by the link check job
+	LinksChecked *time.Time      `json:"-" bson:"links_checked_at,omitempty"`
+	Embedding    []float64       `json:"-" bson:"embedding,omitempty"`
+	EmbedModel   string          `json:"-" bson:"embedding_model,omitempty"`             // Provider and model that generated Embedding
+	Simulated    bool            `json:"simulated,omitempty" bson:"simulated,omitempty"` // Created by a simulated load-test upload
+	Processing   *UploadStats    `json:"-" bson:"processing,omitempty"`                  // Upload size and pipeline duration, for estimates
+	Consent      *UploadConsent  `json:"-" bson:"consent,omitempty"`                     // Only set for uploads through the API
+	TakenDown    bool            `json:"-" bson:"taken_down,omitempty"`                  // Unpublished by an upheld copyright claim
+	Pinned       bool            `json:"pinned,omitempty" bson:"pinned,omitempty"`       // Listed before unpinned annotations
+	Position     int             `json:"position,omitempty" bson:"position,omitempty"`   // 1-based place among the pinned annotations
+	CreatedAt    time.Time       `json:"created_at" bson:"created_at"`
+	UpdatedAt    time.Time       `json:"updated_at" bson:"updated_at"`
+}
+
+// GalleryImage is one image of an annotation's gallery
+type GalleryImage struct {
+	ID      string `json:"id" bson:"id"`
+	URL     string `json:"url" bson:"url"`
+	Key     string `json:"-" bson:"key,omitempty"` // Storage key of the image, empty for external URLs
+	Caption string `json:"caption,omitempty" bson:"caption,omitempty"`
+	AltText string `json:"alt_text,omitempty" bson:"alt_text,omitempty"`
+}
+
+// NewGalleryImage creates a gallery image with a generated ID
+func NewGalleryImage(url, key, caption, altText string) GalleryImage {
+	return GalleryImage{
+		ID:      uuid.New().String(),
+		URL:     url,
+		Key:     key,
+		Caption: caption,
+		AltText: altText,
+	}
+}
+
+// GalleryImages returns the image gallery. Annotations created before galleries were introduced get
+// a one-image gallery from the legacy image fields, with an ID derived from the image URL so it is
+// stable across requests.
+func (a *Annotation) GalleryImages() []GalleryImage {
+	if len(a.Images) > 0 || a.Image == "" {
+		return a.Images
+	}
+	return []GalleryImage{{
+		ID:      uuid.NewSHA1(uuid.NameSpaceURL, []byte(a.Image)).String(),
+		URL:     a.Image,
+		Key:     a.ImageKey,
+		AltText: a.ImageAltText,
+	}}
+}
+
+// SetImages replaces the gallery and mirrors its first entry into the legacy image fields
+func (a *Annotation) SetImages(images []GalleryImage) {
+	a.Images = images
+	a.Image, a.ImageKey, a.ImageAltText = "", "", ""
+	if len(images) > 0 {
+		a.Image, a.ImageKey, a.ImageAltText = images[0].URL, images[0].Key, images[0].AltText
+	}
+}
+
+// Attachment is a supplementary file attached to an annotation, e.g. a solution sheet or dataset
+type Attachment struct {
+	ID          string    `json:"id" bson:"id"`
+	Name        string    `json:"name" bson:"name"` // Original file name
+	ContentType string    `json:"content_type" bson:"content_type"`
+	Size        int64     `json:"size" bson:"size"`
+	Key         string    `json:"-" bson:"key"` // Storage key of the file
+	UploadedBy  string    `json:"uploaded_by" bson:"uploaded_by"`
+	CreatedAt   time.Time `json:"created_at" bson:"created_at"`
+}
+
+// BrokenLink is an external reference of an annotation that could not be loaded
+type BrokenLink struct {
+	URL    string `json:"url" bson:"url"`
+	Source string `json:"source" bson:"source"`                     // "text" or "image"
+	Status int    `json:"status,omitempty" bson:"status,omitempty"` // HTTP status, 0 when the request failed
+	Error  string `json:"error,omitempty" bson:"error,omitempty"`
+}
+
+// BrokenLinkReport lists the broken references of one annotation
+type BrokenLinkReport struct {
+	AnnotationID string       `json:"annotation_id" bson:"_id"`
+	Title        string       `json:"title" bson:"title"`
+	BrokenLinks  []BrokenLink `json:"broken_links" bson:"broken_links"`
+	CheckedAt    time.Time    `json:"checked_at" bson:"links_checked_at"`
+}
+
+// BookMetadata holds bibliographic data looked up by ISBN
+type BookMetadata struct {
+	ISBN        string   `json:"isbn" bson:"isbn"`
+	Title       string   `json:"title" bson:"title"`
+	Authors     []string `json:"authors,omitempty" bson:"authors,omitempty"`
+	Publisher   string   `json:"publisher,omitempty" bson:"publisher,omitempty"`
+	PublishDate string   `json:"publish_date,omitempty" bson:"publish_date,omitempty"`
+	CoverURL    string   `json:"cover_url,omitempty" bson:"cover_url,omitempty"`
+	Source      string   `json:"source" bson:"source"` // "openlibrary" or "googlebooks"
+}
+
+// FigureInsight is a vision-model description of a figure in the source document
+type FigureInsight struct {
+	Page        int    `json:"page" bson:"page"`
+	Description string `json:"description" bson:"description"`
+}
+
+// Formula is a mathematical expression found in the source document, kept verbatim with a
+// MathML rendering for display
+type Formula struct {
+	Page    int    `json:"page" bson:"page"`
+	Source  string `json:"source" bson:"source"` // LaTeX, or the Unicode text extracted from the PDF
+	Display bool   `json:"display" bson:"display"`
+	MathML  string `json:"mathml" bson:"mathml"`
+}
+
+// CodeBlock is a source code snippet found in the source document, with its formatting preserved
+type CodeBlock struct {
+	Page     int    `json:"page" bson:"page"`
+	Language string `json:"language,omitempty" bson:"language,omitempty"` // Best guess, empty if unknown
+	Code     string `json:"code" bson:"code"`
+}
+
+// KeyTerm is a term the source document introduces, with a one-line definition for a glossary
+type KeyTerm struct {
+	Term       string `json:"term" bson:"term"`
+	Definition string `json:"definition" bson:"definition"`
+}
+
+// TTSCaptions holds the caption files synchronized with the TTS audio
+type TTSCaptions struct {
+	VTTURL string `json:"vtt_url" bson:"vtt_url"`
+	VTTKey string `json:"-" bson:"vtt_key"`
+	SRTURL string `json:"srt_url" bson:"srt_url"`
+	SRTKey string `json:"-" bson:"srt_key"`
+}
+
+// AudioTour is a spoken overview of a document, one chapter per section
+type AudioTour struct {
+	URL         string             `json:"url" bson:"url"`
+	Key         string             `json:"-" bson:"key"` // Storage key of the MP3
+	DurationMs  int64              `json:"duration_ms" bson:"duration_ms"`
+	Chapters    []AudioTourChapter `json:"chapters" bson:"chapters"`
+	GeneratedAt time.Time          `json:"generated_at" bson:"generated_at"`
+}
+
+// AudioTourChapter is one section of an audio tour with its position in the MP3
+type AudioTourChapter struct {
+	Title   string `json:"title" bson:"title"`
+	Summary string `json:"summary" bson:"summary"`
+	StartMs int64  `json:"start_ms" bson:"start_ms"`
+	EndMs   int64  `json:"end_ms" bson:"end_ms"`
+}
+
+// UploadConsent is the uploader's attestation that they may upload the document, e.g. that they hold
+// the copyright or the owner agreed, kept as an audit trail
+type UploadConsent struct {
+	Accepted     bool      `json:"accepted" bson:"accepted"`
+	Value        string    `json:"value" bson:"value"` // The consent form field as sent, empty when it was missing
+	TermsVersion string    `json:"terms_version,omitempty" bson:"terms_version,omitempty"`
+	IP           string    `json:"ip" bson:"ip"`
+	UserAgent    string    `json:"user_agent,omitempty" bson:"user_agent,omitempty"`
+	RecordedAt   time.Time `json:"recorded_at" bson:"recorded_at"`
 }
 
 // CreateAnnotationRequest represents the request to create an annotation
 type CreateAnnotationRequest struct {
-	Title string `form:"title" binding:"required"`
-	Image string `form:"image"` // Optional image URL
+	Title        string         `form:"title"`            // Optional, looked up by ISBN or determined from the document when omitted
+	TitleSource  string         `form:"-"`                // Where Title came from, TitleProvided when empty
+	FileName     string         `form:"-"`                // Name of the uploaded file, the last resort for a title
+	AutoTitle    bool           `form:"auto_title"`       // Bulk uploads: title documents from their content rather than their file names
+	Image        string         `form:"image"`            // Optional image URL
+	ISBN         string         `form:"isbn"`             // Optional ISBN for book uploads
+	Tags         []string       `form:"tags"`             // Optional tags, repeated field or comma-separated
+	Length       string         `form:"length"`           // Optional "short", "medium" (default) or "detailed"
+	License      string         `form:"license"`          // Optional, one of Licenses, defaults to DefaultLicense
+	Visibility   string         `form:"visibility"`       // Optional, "public" or "private" (default)
+	ImageAltText string         `form:"image_alt_text"`   // Optional, generated when omitted
+	Metadata     Metadata       `form:"-"`                // Optional custom fields, sent as metadata[key]=value
+	SourceURL    string         `form:"source_url"`       // Optional address the document was originally published at
+	Author       string         `form:"author"`           // Optional, credited from the ISBN lookup when omitted
+	PublishedOn  string         `form:"publication_date"` // Optional YYYY-MM-DD, YYYY-MM or YYYY
+	Sections     bool           `form:"sections"`         // Optional, also summarize each chapter or section of the document
+	Model        string         `form:"model"`            // Optional Ollama model, see OLLAMA_ALLOWED_MODELS
+	Simulate     bool           `form:"simulate"`         // Load testing: fake extraction, LLM and TTS work, see ALLOW_SIMULATED_UPLOADS
+	Consent      *UploadConsent `form:"-"`                // Recorded from the "consent" field of API uploads
 }
 
 // AnnotationResponse represents the annotation response
 type AnnotationResponse struct {
-	ID          string    `json:"id"`
-	Title       string    `json:"title"`
-	Image       string    `json:"image,omitempty"`
-	SourceFile  string    `json:"source_file"`
-	SourceType  string    `json:"source_type"`
-	Annotation  string    `json:"annotation"`
-	Genre       string    `json:"genre"`
-	TTSURL      string    `json:"tts_url,omitempty"`
-	Status      string    `json:"status"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID           string          `json:"id"`
+	Title        string          `json:"title"`
+	TitleSource  string          `json:"title_source,omitempty"` // Suggested titles are worth confirming with the uploader
+	Image        string          `json:"image,omitempty"`
+	ImageAltText string          `json:"image_alt_text,omitempty"`
+	Images       []GalleryImage  `json:"images"`
+	SourceFile   string          `json:"source_file"`
+	SourceType   string          `json:"source_type"`
+	Annotation   string          `json:"annotation"`
+	Genre        string          `json:"genre"`
+	Model        string          `json:"model,omitempty"`
+	Length       string          `json:"length"`
+	License      License         `json:"license"`
+	Visibility   string          `json:"visibility"`
+	Tags         []string        `json:"tags"`
+	Book         *BookMetadata   `json:"book,omitempty"`
+	SourceURL    string          `json:"source_url,omitempty"`
+	Author       string          `json:"author,omitempty"`
+	PublishedOn  string          `json:"publication_date,omitempty"`
+	Metadata     Metadata        `json:"metadata,omitempty"`
+	TTSURL       string          `json:"tts_url,omitempty"`
+	TTSOpusURL   string          `json:"tts_opus_url,omitempty"`
+	TTSMarksURL  string          `json:"tts_marks_url,omitempty"`
+	Captions     *TTSCaptions    `json:"captions,omitempty"`
+	Figures      []FigureInsight `json:"figures,omitempty"`
+	Formulas     []Formula       `json:"formulas,omitempty"`
+	CodeBlocks   []CodeBlock     `json:"code_blocks,omitempty"`
+	Objectives   []string        `json:"learning_objectives,omitempty"`
+	Prereqs      []string        `json:"prerequisites,omitempty"`
+	KeyTerms     []KeyTerm       `json:"key_terms,omitempty"`
+	Sections     []Section       `json:"sections,omitempty"`
+	SafetyLabels []SafetyLabel   `json:"safety_labels,omitempty"`
+	AudioTour    *AudioTour      `json:"audio_tour,omitempty"`
+	Attachments  []Attachment    `json:"attachments,omitempty"`
+	Links        []RelatedLink   `json:"links,omitempty"` // Only set for single annotations
+	BrokenLinks  []BrokenLink    `json:"broken_links,omitempty"`
+	Status       string          `json:"status"`
+	MergedInto   string          `json:"merged_into,omitempty"`
+	ReviewState  string          `json:"review_state,omitempty"`
+	Review       *ReviewRecord   `json:"review,omitempty"`
+	MergedFrom   []string        `json:"merged_from,omitempty"`
+	Simulated    bool            `json:"simulated,omitempty"`
+	Pinned       bool            `json:"pinned,omitempty"`
+	Position     int             `json:"position,omitempty"`
+	CreatedAt    time.Time       `json:"created_at"`
+	UpdatedAt    time.Time       `json:"updated_at"`
 }
 
 // NewAnnotation creates a new annotation
@@ -54,6 +299,7 @@ func NewAnnotation(userID, title, sourceFile, sourceType string) *Annotation {
 		Title:      title,
 		SourceFile: sourceFile,
 		SourceType: sourceType,
+		Tags:       []string{},
 		Status:     "processing",
 		CreatedAt:  now,
 		UpdatedAt:  now,
@@ -62,25 +308,185 @@ func NewAnnotation(userID, title, sourceFile, sourceType string) *Annotation {
 
 // ToResponse converts Annotation to AnnotationResponse
 func (a *Annotation) ToResponse() AnnotationResponse {
+	tags := a.Tags
+	if tags == nil {
+		tags = []string{} // Annotations created before tags were introduced
+	}
+
+	length := a.Length
+	if length == "" {
+		length = "medium" // Annotations created before lengths were introduced
+	}
+
+	visibility := a.Visibility
+	if visibility == "" {
+		visibility = VisibilityPrivate
+	}
+
+	images := a.GalleryImages()
+	if images == nil {
+		images = []GalleryImage{}
+	}
+
 	return AnnotationResponse{
-		ID:         a.ID,
-		Title:      a.Title,
-		Image:      a.Image,
-		SourceFile: a.SourceFile,
-		SourceType: a.SourceType,
-		Annotation: a.Annotation,
-		Genre:      a.Genre,
-		TTSURL:     a.TTSURL,
-		Status:     a.Status,
-		CreatedAt:  a.CreatedAt,
-		UpdatedAt:  a.UpdatedAt,
+		ID:           a.ID,
+		Title:        a.Title,
+		TitleSource:  a.TitleSource,
+		Image:        a.Image,
+		ImageAltText: a.ImageAltText,
+		Images:       images,
+		SourceFile:   a.SourceFile,
+		SourceType:   a.SourceType,
+		Annotation:   a.Annotation,
+		Genre:        a.Genre,
+		Model:        a.Model,
+		Length:       length,
+		License:      a.LicenseOf(),
+		Visibility:   visibility,
+		Tags:         tags,
+		Book:         a.Book,
+		SourceURL:    a.SourceURL,
+		Author:       a.Author,
+		PublishedOn:  a.PublishedOn,
+		Metadata:     a.Metadata,
+		TTSURL:       a.TTSURL,
+		TTSOpusURL:   a.TTSOpusURL,
+		TTSMarksURL:  a.TTSMarksURL,
+		Captions:     a.Captions,
+		Figures:      a.Figures,
+		Formulas:     a.Formulas,
+		CodeBlocks:   a.CodeBlocks,
+		Objectives:   a.Objectives,
+		Prereqs:      a.Prereqs,
+		KeyTerms:     a.KeyTerms,
+		Sections:     a.Sections,
+		SafetyLabels: a.SafetyLabels,
+		AudioTour:    a.AudioTour,
+		Attachments:  a.Attachments,
+		BrokenLinks:  a.BrokenLinks,
+		Status:       a.Status,
+		MergedInto:   a.MergedInto,
+		ReviewState:  a.ReviewState,
+		Review:       a.Review,
+		MergedFrom:   a.MergedFrom,
+		Simulated:    a.Simulated,
+		Pinned:       a.Pinned,
+		Position:     a.Position,
+		CreatedAt:    a.CreatedAt,
+		UpdatedAt:    a.UpdatedAt,
 	}
 }
 
+// DocumentSearchMatch represents a passage of the source text matching a search query
+type DocumentSearchMatch struct {
+	Page    int    `json:"page"`
+	Offset  int    `json:"offset"` // Character offset within the extracted text
+	Length  int    `json:"length"`
+	Passage string `json:"passage"`
+}
+
+// AskRequest represents a question about the source text of an annotation
+type AskRequest struct {
+	Question string `json:"question" binding:"required,max=1000"`
+}
+
+// DocumentAnswer is the LLM's answer to a question about a document, with the excerpts of the
+// source text it is based on
+type DocumentAnswer struct {
+	Question string          `json:"question"`
+	Answer   string          `json:"answer"`
+	Excerpts []AnswerExcerpt `json:"excerpts"`
+}
+
+// AnswerExcerpt is a passage of the source text given to the LLM to answer a question
+type AnswerExcerpt struct {
+	Page int    `json:"page"` // Where the excerpt starts
+	Text string `json:"text"`
+}
+
+// PinnedAnnotationsRequest sets which annotations are pinned to the top of the annotation list,
+// in display order
+type PinnedAnnotationsRequest struct {
+	AnnotationIDs []string `json:"annotation_ids" binding:"required,max=100,dive,required"` // Empty to unpin all
+}
+
+// MergeAnnotationsRequest represents the request to combine duplicate annotations into one
+type MergeAnnotationsRequest struct {
+	AnnotationIDs []string `json:"annotation_ids" binding:"required,min=2,max=10,dive,required"`
+	Title         string   `json:"title,omitempty"`  // Defaults to the title of the first annotation
+	Length        string   `json:"length,omitempty"` // Defaults to the length of the first annotation
+}
+
+// SplitAnnotationRequest represents the request to split an annotation into separate works by page range
+type SplitAnnotationRequest struct {
+	Parts  []SplitPart `json:"parts" binding:"required,min=2,max=20,dive"`
+	Length string      `json:"length,omitempty"` // Defaults to the length of the parent annotation
+}
+
+// SplitPart is one work within a split annotation, covering pages StartPage to EndPage inclusive
+type SplitPart struct {
+	Title     string `json:"title" binding:"required"`
+	StartPage int    `json:"start_page" binding:"required,min=1"`
+	EndPage   int    `json:"end_page" binding:"required,min=1"`
+}
+
+// RegenerateAnnotationRequest represents the request to regenerate an annotation
+type RegenerateAnnotationRequest struct {
+	Length string `json:"length" binding:"omitempty,oneof=short medium detailed"`
+	Model  string `json:"model,omitempty"` // Ollama model, see OLLAMA_ALLOWED_MODELS; defaults to OLLAMA_MODEL
+}
+
+// ExplainRequest represents the request to explain a selected passage
+type ExplainRequest struct {
+	Selection string `json:"selection" binding:"required,max=2000"`
+}
+
 // UpdateAnnotationRequest represents the request to update an annotation
 type UpdateAnnotationRequest struct {
-	Title      *string `json:"title,omitempty"`
-	Image      *string `json:"image,omitempty"`
-	Annotation *string `json:"annotation,omitempty"`
-	Genre      *string `json:"genre,omitempty"`
+	Title        *string   `json:"title,omitempty"`
+	Image        *string   `json:"image,omitempty"`
+	ImageAltText *string   `json:"image_alt_text,omitempty" binding:"omitempty,max=500"` // Overrides the generated alt text
+	Annotation   *string   `json:"annotation,omitempty"`
+	Genre        *string   `json:"genre,omitempty"`
+	Tags         *[]string `json:"tags,omitempty"`
+	License      *string   `json:"license,omitempty"`    // One of Licenses
+	Visibility   *string   `json:"visibility,omitempty"` // "public" or "private"
+	Metadata     *Metadata `json:"metadata,omitempty"`   // Replaces all custom fields
+	SourceURL    *string   `json:"source_url,omitempty"` // Empty clears it, as for Author and PublishedOn
+	Author       *string   `json:"author,omitempty" binding:"omitempty,max=300"`
+	PublishedOn  *string   `json:"publication_date,omitempty"` // YYYY-MM-DD, YYYY-MM or YYYY
+}
+
+// UpdateGalleryImageRequest represents the request to update a gallery image
+type UpdateGalleryImageRequest struct {
+	Caption *string `json:"caption,omitempty" binding:"omitempty,max=500"`
+	AltText *string `json:"alt_text,omitempty" binding:"omitempty,max=500"`
+}
+
+// ReorderImagesRequest represents the request to reorder a gallery, listing every image ID once
+type ReorderImagesRequest struct {
+	ImageIDs []string `json:"image_ids" binding:"required"`
+}
+
+// AnnotationFilter holds optional filters for listing annotations
+type AnnotationFilter struct {
+	UserID       string   // Only the annotations of this user, empty for everyone's
+	PublicOnly   bool     // Only finished annotations readable without signing in
+	SafetyLabel  string   // Only annotations with this safety label
+	HiddenLabels []string // No annotations with any of these safety labels
+	ReviewState  string   // Only annotations in this review state
+	ReviewedOnly bool     // Only annotations that may be shown to students
+	Tag          string
+	Genre        string
+	Objective    string    // Matches learning objectives containing the text
+	Prerequisite string    // Matches prerequisites containing the text
+	Metadata     Metadata  // Exact matches on custom fields
+	CreatedFrom  time.Time // Inclusive, zero for no bound
+	CreatedTo    time.Time // Exclusive, zero for no bound
+}
+
+// TagCount represents how many annotations use a tag
+type TagCount struct {
+	Tag   string `json:"tag" bson:"_id"`
+	Count int    `json:"count" bson:"count"`
 }
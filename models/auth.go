@@ -28,29 +28,70 @@ type AuthResponse struct {
 
 // UserResponse represents user data in responses (without password)
 type UserResponse struct {
-	ID        string    `json:"id"`
-	Email     string    `json:"email"`
-	Name      string    `json:"name"`
-	Role      string    `json:"role"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID                  string    `json:"id"`
+	Email               string    `json:"email"`
+	Name                string    `json:"name"`
+	Role                string    `json:"role"`
+	Disabled            bool      `json:"disabled"`
+	ContentRestrictions []string  `json:"content_restrictions"` // Safety labels hidden from the user by an admin
+	CreatedAt           time.Time `json:"created_at"`
+	UpdatedAt           time.Time `json:"updated_at"`
 }
 
 // ToUserResponse converts User to UserResponse
 func (u *User) ToUserResponse() UserResponse {
+	restrictions := u.Restricted
+	if restrictions == nil {
+		restrictions = []string{}
+	}
 	return UserResponse{
-		ID:        u.ID,
-		Email:     u.Email,
-		Name:      u.Name,
-		Role:      u.Role,
-		CreatedAt: u.CreatedAt,
-		UpdatedAt: u.UpdatedAt,
+		ID:                  u.ID,
+		Email:               u.Email,
+		Name:                u.Name,
+		Role:                u.Role,
+		Disabled:            u.Disabled,
+		ContentRestrictions: restrictions,
+		CreatedAt:           u.CreatedAt,
+		UpdatedAt:           u.UpdatedAt,
 	}
 }
 
+// UpdateProfileRequest represents a user's request to update their own account. Changing the
+// password requires the current one.
+type UpdateProfileRequest struct {
+	Name            *string `json:"name,omitempty" binding:"omitempty,min=1,max=100"`
+	CurrentPassword string  `json:"current_password,omitempty"`
+	NewPassword     *string `json:"new_password,omitempty" binding:"omitempty,min=6"`
+}
+
+// DeleteProfileRequest represents a user's request to delete their own account
+type DeleteProfileRequest struct {
+	Password          string `json:"password" binding:"required"`
+	Anonymize         bool   `json:"anonymize"`          // Keep a disabled record without personal data instead of removing it
+	DeleteAnnotations bool   `json:"delete_annotations"` // Also delete the annotations the user created
+}
+
+// UpdateUserRoleRequest represents an admin's request to change a user's role
+type UpdateUserRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=basic content reviewer admin"`
+}
+
+// UpdateUserStatusRequest represents an admin's request to disable or re-enable a user
+type UpdateUserStatusRequest struct {
+	Disabled *bool `json:"disabled" binding:"required"`
+}
+
 // JWTClaims represents the JWT token claims
 type JWTClaims struct {
 	UserID string `json:"user_id"`
 	Email  string `json:"email"`
 	jwt.RegisteredClaims
 }
+
+// RevokedToken represents a JWT that was invalidated before its expiry (e.g. on logout)
+type RevokedToken struct {
+	TokenID   string    `json:"token_id" bson:"_id"`
+	UserID    string    `json:"user_id" bson:"user_id"`
+	ExpiresAt time.Time `json:"expires_at" bson:"expires_at"` // TTL index removes the entry once the token expires anyway
+	RevokedAt time.Time `json:"revoked_at" bson:"revoked_at"`
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UploadBatch tracks the annotations created from one bulk upload
+type UploadBatch struct {
+	ID        string      `json:"id" bson:"_id"`
+	UserID    string      `json:"user_id" bson:"user_id"`
+	Status    string      `json:"status" bson:"status"` // "processing", "completed" or "interrupted"
+	Items     []BatchItem `json:"items" bson:"items"`
+	CreatedAt time.Time   `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at" bson:"updated_at"`
+}
+
+// BatchItem is one document of a bulk upload
+type BatchItem struct {
+	FileName     string `json:"file_name" bson:"file_name"` // Prefixed with the ZIP name for files from an archive
+	Status       string `json:"status" bson:"status"`       // "queued", "processing", "completed" or "failed"
+	AnnotationID string `json:"annotation_id,omitempty" bson:"annotation_id,omitempty"`
+	Error        string `json:"error,omitempty" bson:"error,omitempty"`
+}
+
+// NewUploadBatch creates a batch with its items queued
+func NewUploadBatch(userID string, items []BatchItem) *UploadBatch {
+	now := time.Now()
+	return &UploadBatch{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		Status:    "processing",
+		Items:     items,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
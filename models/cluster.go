@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AnnotationCluster represents a group of annotations with similar content
+type AnnotationCluster struct {
+	ID          string          `json:"id" bson:"_id"`
+	Label       string          `json:"label" bson:"label"`
+	Genre       string          `json:"genre,omitempty" bson:"genre,omitempty"` // Most common genre among members
+	Members     []ClusterMember `json:"members" bson:"members"`
+	Size        int             `json:"size" bson:"size"`
+	GeneratedAt time.Time       `json:"generated_at" bson:"generated_at"`
+}
+
+// ClusterMember is an annotation belonging to a cluster
+type ClusterMember struct {
+	AnnotationID string  `json:"annotation_id" bson:"annotation_id"`
+	Title        string  `json:"title" bson:"title"`
+	Similarity   float64 `json:"similarity" bson:"similarity"` // Cosine similarity to the cluster centroid
+}
+
+// NewAnnotationCluster creates a new cluster with a generated UUID
+func NewAnnotationCluster(label, genre string, members []ClusterMember) *AnnotationCluster {
+	return &AnnotationCluster{
+		ID:          uuid.New().String(),
+		Label:       label,
+		Genre:       genre,
+		Members:     members,
+		Size:        len(members),
+		GeneratedAt: time.Now(),
+	}
+}
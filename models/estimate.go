@@ -0,0 +1,31 @@
+package models
+
+// UploadStats records the size of an upload and how long the pipeline took, the history that
+// processing estimates are based on
+type UploadStats struct {
+	FileBytes  int64 `bson:"file_bytes"`
+	Pages      int   `bson:"pages"`
+	TextChars  int   `bson:"text_chars"`
+	DurationMs int64 `bson:"duration_ms"`
+	OCRPages   int   `bson:"ocr_pages,omitempty"` // Pages read with OCR, for scans
+}
+
+// EstimateRequest describes a document to estimate the processing of, by its size, its page count
+// or both
+type EstimateRequest struct {
+	FileSize  int64 `json:"file_size"` // bytes
+	PageCount int   `json:"page_count"`
+}
+
+// ProcessingEstimate is the expected cost of annotating a document
+type ProcessingEstimate struct {
+	FileSize          int64   `json:"file_size,omitempty"`
+	PageCount         int     `json:"page_count,omitempty"`
+	TextChars         int     `json:"text_characters"`
+	InputTokens       int     `json:"input_tokens"`
+	Chunks            int     `json:"chunks"` // Parts summarized separately, 1 when the document fits into one request
+	ProcessingSeconds int     `json:"processing_seconds"`
+	TTSCharacters     int     `json:"tts_characters"`
+	TTSCostUSD        float64 `json:"tts_cost_usd"`
+	BasedOn           int     `json:"based_on"` // Recent uploads the averages come from, 0 for built-in defaults
+}
@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// Feature flags of experimental endpoints
+const (
+	FeatureExplain        = "explain"         // POST /annotations/:id/explain
+	FeatureClusters       = "clusters"        // GET /annotations/clusters, built from embeddings
+	FeatureQuiz           = "quiz"            // Quizzes generated from annotations
+	FeatureAsk            = "ask"             // POST /annotations/:id/ask
+	FeatureSemanticSearch = "semantic-search" // GET /annotations/semantic-search, built from embeddings
+)
+
+// Features are the known feature flags with what they gate
+var Features = map[string]string{
+	FeatureExplain:        "Explaining selected passages with the LLM",
+	FeatureClusters:       "Topic clusters computed from annotation embeddings",
+	FeatureQuiz:           "Multiple-choice quizzes generated from annotations",
+	FeatureAsk:            "Answering questions about a document with the LLM",
+	FeatureSemanticSearch: "Searching annotations by meaning with embeddings",
+}
+
+// FeatureFlag decides who can use an experimental feature. An enabled flag is on for the listed
+// roles and users, and for Rollout percent of everyone else; a disabled flag is off for everyone.
+type FeatureFlag struct {
+	Name        string   `json:"name" bson:"name"`
+	Description string   `json:"description" bson:"-"`
+	Enabled     bool     `json:"enabled" bson:"enabled"`
+	Rollout     int      `json:"rollout" bson:"rollout"` // Percentage of users, 0-100
+	Roles       []string `json:"roles" bson:"roles"`
+	UserIDs     []string `json:"user_ids" bson:"user_ids"`
+}
+
+// FeatureFlagSettings holds the flags changed at runtime, overriding the configured defaults
+type FeatureFlagSettings struct {
+	ID        string                 `json:"-" bson:"_id"`
+	Flags     map[string]FeatureFlag `json:"flags" bson:"flags"`
+	UpdatedBy string                 `json:"updated_by,omitempty" bson:"updated_by,omitempty"`
+	UpdatedAt time.Time              `json:"updated_at" bson:"updated_at"`
+}
+
+// UpdateFeatureFlagRequest represents the request to change a feature flag
+type UpdateFeatureFlagRequest struct {
+	Enabled *bool     `json:"enabled,omitempty"`
+	Rollout *int      `json:"rollout,omitempty" binding:"omitempty,min=0,max=100"`
+	Roles   *[]string `json:"roles,omitempty" binding:"omitempty,dive,oneof=basic content reviewer admin"`
+	UserIDs *[]string `json:"user_ids,omitempty" binding:"omitempty,max=1000,dive,required"`
+}
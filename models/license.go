@@ -0,0 +1,36 @@
+package models
+
+// License identifiers annotations can be published under
+const (
+	LicenseCCBY              = "CC-BY-4.0"
+	LicenseCC0               = "CC0-1.0"
+	LicenseAllRightsReserved = "all-rights-reserved"
+	LicenseInstitutional     = "institutional" // Reuse within the institution only
+)
+
+// DefaultLicense applies to annotations uploaded without a license, including those created
+// before licenses were introduced, since reuse must not be assumed
+const DefaultLicense = LicenseAllRightsReserved
+
+// License describes a license for display and in exports
+type License struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	URL  string `json:"url,omitempty"` // Legal text, if there is a public one
+}
+
+// Licenses are the selectable licenses, by identifier
+var Licenses = map[string]License{
+	LicenseCCBY:              {ID: LicenseCCBY, Name: "Creative Commons Attribution 4.0", URL: "https://creativecommons.org/licenses/by/4.0/"},
+	LicenseCC0:               {ID: LicenseCC0, Name: "CC0 1.0 Public Domain Dedication", URL: "https://creativecommons.org/publicdomain/zero/1.0/"},
+	LicenseAllRightsReserved: {ID: LicenseAllRightsReserved, Name: "All Rights Reserved"},
+	LicenseInstitutional:     {ID: LicenseInstitutional, Name: "Institutional use only"},
+}
+
+// LicenseOf returns the license of an annotation
+func (a *Annotation) LicenseOf() License {
+	if license, ok := Licenses[a.License]; ok {
+		return license
+	}
+	return Licenses[DefaultLicense]
+}
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Relation types between annotations, read as "source <type> target"
+const (
+	LinkPrerequisiteOf = "prerequisite-of"
+	LinkFollows        = "follows"
+	LinkRelatedTo      = "related-to"
+	LinkPartOf         = "part-of" // Set on the parts created by splitting an annotation
+)
+
+// AnnotationLink is a typed relation from one annotation to another
+type AnnotationLink struct {
+	ID        string    `json:"id" bson:"_id"`
+	SourceID  string    `json:"source_id" bson:"source_id"`
+	TargetID  string    `json:"target_id" bson:"target_id"`
+	Type      string    `json:"type" bson:"type"`
+	CreatedBy string    `json:"created_by" bson:"created_by"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+}
+
+// NewAnnotationLink creates a new link
+func NewAnnotationLink(sourceID, targetID, linkType, userID string) *AnnotationLink {
+	return &AnnotationLink{
+		ID:        uuid.New().String(),
+		SourceID:  sourceID,
+		TargetID:  targetID,
+		Type:      linkType,
+		CreatedBy: userID,
+		CreatedAt: time.Now(),
+	}
+}
+
+// CreateLinkRequest represents the request to link an annotation to another
+type CreateLinkRequest struct {
+	TargetID string `json:"target_id" binding:"required"`
+	Type     string `json:"type" binding:"required,oneof=prerequisite-of follows related-to part-of"`
+}
+
+// RelatedLink is a link as seen from one annotation, with a summary of the annotation at the other end
+type RelatedLink struct {
+	LinkID    string `json:"link_id"`
+	Type      string `json:"type"`
+	Direction string `json:"direction"` // "outgoing" when this annotation is the source, "incoming" otherwise
+	ID        string `json:"id"`
+	Title     string `json:"title"`
+	Summary   string `json:"summary"`
+	Image     string `json:"image,omitempty"`
+}
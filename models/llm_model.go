@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// LLMModel is a model installed in Ollama
+type LLMModel struct {
+	Name          string    `json:"name"`
+	Size          int64     `json:"size"` // bytes
+	Digest        string    `json:"digest"`
+	ModifiedAt    time.Time `json:"modified_at"`
+	Family        string    `json:"family,omitempty"`
+	ParameterSize string    `json:"parameter_size,omitempty"` // e.g. "7.2B"
+	Quantization  string    `json:"quantization,omitempty"`   // e.g. "Q4_0"
+	Default       bool      `json:"default"`                  // OLLAMA_MODEL
+	Selectable    bool      `json:"selectable"`               // May be selected by uploads and regenerations
+}
+
+// PullModelRequest represents the request to download a model into Ollama
+type PullModelRequest struct {
+	Name string `json:"name" binding:"required"` // e.g. "llama3.1:8b"
+}
+
+// PullProgress is a progress update of a model download, as reported by Ollama
+type PullProgress struct {
+	Status    string `json:"status"`
+	Digest    string `json:"digest,omitempty"`    // Layer being downloaded
+	Total     int64  `json:"total,omitempty"`     // bytes of the layer
+	Completed int64  `json:"completed,omitempty"` // bytes of the layer downloaded so far
+}
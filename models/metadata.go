@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+// Metadata holds custom catalog attributes of an annotation (course code, semester, edition, ...),
+// keyed by the field keys of the metadata schema
+type Metadata map[string]string
+
+// Metadata field types
+const (
+	MetadataString  = "string"
+	MetadataNumber  = "number"
+	MetadataBoolean = "boolean"
+	MetadataDate    = "date" // YYYY-MM-DD
+	MetadataEnum    = "enum" // One of Options
+)
+
+// MetadataField defines a custom metadata attribute
+type MetadataField struct {
+	Key     string   `json:"key" bson:"key" binding:"required,max=50"` // Lowercase letters, digits and underscores
+	Label   string   `json:"label" bson:"label" binding:"max=100"`
+	Type    string   `json:"type" bson:"type" binding:"required,oneof=string number boolean date enum"`
+	Options []string `json:"options,omitempty" bson:"options,omitempty" binding:"max=100,dive,required,max=100"` // Allowed values of an enum field
+}
+
+// MetadataSchema defines the custom metadata fields annotations may have
+type MetadataSchema struct {
+	ID        string          `json:"-" bson:"_id"`
+	Fields    []MetadataField `json:"fields" bson:"fields"`
+	UpdatedBy string          `json:"updated_by,omitempty" bson:"updated_by,omitempty"`
+	UpdatedAt time.Time       `json:"updated_at" bson:"updated_at"`
+}
+
+// UpdateMetadataSchemaRequest represents the request to replace the metadata schema
+type UpdateMetadataSchemaRequest struct {
+	Fields []MetadataField `json:"fields" binding:"max=50,dive"`
+}
+
+// MetadataImportRow reports the outcome of one CSV row of a bulk metadata import
+type MetadataImportRow struct {
+	Row          int      `json:"row"` // Line number in the CSV, the header being line 1
+	AnnotationID string   `json:"annotation_id"`
+	Status       string   `json:"status"` // "updated", "valid" (dry run) or "failed"
+	Error        string   `json:"error,omitempty"`
+	Metadata     Metadata `json:"metadata,omitempty"`
+}
+
+// MetadataImportReport summarizes a bulk metadata import
+type MetadataImportReport struct {
+	DryRun  bool                `json:"dry_run"`
+	Total   int                 `json:"total"`
+	Updated int                 `json:"updated"` // Rows applied, or that would be applied in a dry run
+	Failed  int                 `json:"failed"`
+	Rows    []MetadataImportRow `json:"rows"`
+}
@@ -0,0 +1,10 @@
+package models
+
+// Pagination describes the page returned by a list endpoint
+type Pagination struct {
+	Limit      int64  `json:"limit"`
+	Offset     int64  `json:"offset"`
+	Count      int    `json:"count"`
+	HasMore    bool   `json:"has_more"`
+	TotalCount *int64 `json:"total_count,omitempty"` // Only counted when requested with include_total=true
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Quiz is a set of multiple-choice questions generated from the source text of an annotation
+type Quiz struct {
+	ID           string         `json:"id" bson:"_id"`
+	AnnotationID string         `json:"annotation_id" bson:"annotation_id"`
+	Title        string         `json:"title" bson:"title"` // Of the annotation when the quiz was generated
+	Questions    []QuizQuestion `json:"questions" bson:"questions"`
+	CreatedBy    string         `json:"created_by" bson:"created_by"`
+	CreatedAt    time.Time      `json:"created_at" bson:"created_at"`
+}
+
+// QuizQuestion is a multiple-choice question with exactly one correct choice
+type QuizQuestion struct {
+	Question    string   `json:"question" bson:"question"`
+	Choices     []string `json:"choices" bson:"choices"`
+	Answer      int      `json:"answer" bson:"answer"` // 0-based index into Choices
+	Explanation string   `json:"explanation,omitempty" bson:"explanation,omitempty"`
+}
+
+// NewQuiz creates a quiz of an annotation
+func NewQuiz(annotation *Annotation, userID string, questions []QuizQuestion) *Quiz {
+	return &Quiz{
+		ID:           uuid.New().String(),
+		AnnotationID: annotation.ID,
+		Title:        annotation.Title,
+		Questions:    questions,
+		CreatedBy:    userID,
+		CreatedAt:    time.Now(),
+	}
+}
+
+// CreateQuizRequest represents the request to generate a quiz
+type CreateQuizRequest struct {
+	Questions int `json:"questions" binding:"omitempty,min=1,max=20"` // Defaults to 5
+}
@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// UploadQuota is a user's daily document upload allowance
+type UploadQuota struct {
+	Limit     int       `json:"limit"`
+	Used      int       `json:"used"`
+	Remaining int       `json:"remaining"`
+	ResetAt   time.Time `json:"reset_at"` // Midnight UTC
+}
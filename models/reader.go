@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// ReaderRendition is the cached reading-mode HTML of an annotation's source text
+type ReaderRendition struct {
+	AnnotationID string       `json:"annotation_id" bson:"_id"`
+	TextHash     string       `json:"-" bson:"text_hash"` // Hash of the text the rendition was built from
+	Pages        []ReaderPage `json:"pages" bson:"pages"`
+	GeneratedAt  time.Time    `json:"generated_at" bson:"generated_at"`
+}
+
+// ReaderPage is the HTML of a single page of the source document
+type ReaderPage struct {
+	Number int    `json:"number" bson:"number"`
+	HTML   string `json:"html" bson:"html"`
+}
@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// Content report frequencies
+const (
+	ReportsDaily  = "daily"
+	ReportsWeekly = "weekly"
+	ReportsOff    = "off"
+)
+
+// ContentReport summarizes the uploads of a period for the admins
+type ContentReport struct {
+	From           time.Time       `json:"from"`
+	To             time.Time       `json:"to"` // Exclusive
+	NewAnnotations int64           `json:"new_annotations"`
+	Completed      int64           `json:"completed"`
+	Failed         int64           `json:"failed"`
+	Processing     int64           `json:"processing"`
+	Failures       []FailureReason `json:"failures"`      // Most frequent first
+	TopTags        []TagCount      `json:"top_tags"`      // Of the new annotations
+	TopUploaders   []UploaderCount `json:"top_uploaders"` // Most new annotations first
+	QuotaBreaches  []QuotaBreach   `json:"quota_breaches"`
+}
+
+// FailureReason counts the failed uploads with the same error
+type FailureReason struct {
+	Reason string `json:"reason" bson:"_id"`
+	Count  int    `json:"count" bson:"count"`
+}
+
+// UploaderCount counts the new annotations of a user
+type UploaderCount struct {
+	UserID      string `json:"user_id" bson:"_id"`
+	Email       string `json:"email" bson:"email"`
+	Name        string `json:"name" bson:"name"`
+	Annotations int    `json:"annotations" bson:"annotations"`
+}
+
+// QuotaBreach records a user who uploaded more documents on a day than the quota allowed
+type QuotaBreach struct {
+	UserID   string    `json:"user_id" bson:"user_id"`
+	Email    string    `json:"email" bson:"email"`
+	Day      time.Time `json:"day" bson:"day"`
+	Rejected int       `json:"rejected" bson:"rejected"` // Documents refused
+}
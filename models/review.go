@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// Review states of a generated annotation. Annotations without a review state, such as those
+// created while reviews weren't required, count as approved.
+const (
+	ReviewDraft    = "draft"          // Generated, not yet submitted for review
+	ReviewPending  = "pending_review" // Waiting for a reviewer
+	ReviewApproved = "approved"       // Shown to students
+	ReviewRejected = "rejected"       // Sent back to the creator, who may edit and resubmit it
+)
+
+// UnreviewedStates are the review states of annotations hidden from students
+var UnreviewedStates = []string{ReviewDraft, ReviewPending, ReviewRejected}
+
+// ReviewRecord records the last submission of an annotation for review and the decision on it
+type ReviewRecord struct {
+	SubmittedBy string     `json:"submitted_by,omitempty" bson:"submitted_by,omitempty"`
+	SubmittedAt *time.Time `json:"submitted_at,omitempty" bson:"submitted_at,omitempty"`
+	ReviewedBy  string     `json:"reviewed_by,omitempty" bson:"reviewed_by,omitempty"`
+	ReviewedAt  *time.Time `json:"reviewed_at,omitempty" bson:"reviewed_at,omitempty"`
+	Comment     string     `json:"comment,omitempty" bson:"comment,omitempty"` // From the reviewer, required to reject
+}
+
+// IsReviewed reports whether an annotation may be shown to students
+func (a *Annotation) IsReviewed() bool {
+	for _, state := range UnreviewedStates {
+		if a.ReviewState == state {
+			return false
+		}
+	}
+	return true
+}
+
+// ReviewDecisionRequest represents a reviewer's decision on an annotation
+type ReviewDecisionRequest struct {
+	Comment string `json:"comment" binding:"max=2000"`
+}
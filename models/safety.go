@@ -0,0 +1,24 @@
+package models
+
+// Sensitive topics annotations are labeled with during generation
+const (
+	SafetyViolence = "violence"
+	SafetyMedical  = "medical"
+	SafetyAdult    = "adult_themes"
+)
+
+// SafetyLabelNames are the known safety labels, sorted
+var SafetyLabelNames = []string{SafetyAdult, SafetyMedical, SafetyViolence}
+
+// SafetyLabel marks an annotation as dealing with a sensitive topic. Only labels at or above the
+// configured minimum confidence are stored.
+type SafetyLabel struct {
+	Label      string  `json:"label" bson:"label"`
+	Confidence float64 `json:"confidence" bson:"confidence"` // 0 to 1, as estimated by the LLM
+}
+
+// UpdateContentRestrictionsRequest represents an admin's request to hide labeled annotations from a
+// user, e.g. a minor, on top of the restrictions of the user's role
+type UpdateContentRestrictionsRequest struct {
+	Labels []string `json:"labels" binding:"required,dive,oneof=violence medical adult_themes"`
+}
@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// SearchQuery represents a search across annotations
+type SearchQuery struct {
+	Query        string
+	Tag          string
+	Genre        string
+	Hidden       []string // Safety labels of annotations left out
+	ReviewedOnly bool     // Only annotations that may be shown to students
+	Limit        int
+	Offset       int
+}
+
+// SearchHit is one annotation matching a search
+type SearchHit struct {
+	ID         string            `json:"id"`
+	Title      string            `json:"title"`
+	Genre      string            `json:"genre"`
+	Tags       []string          `json:"tags"`
+	Image      string            `json:"image,omitempty"`
+	Highlights map[string]string `json:"highlights,omitempty"` // Matching excerpts by field, terms wrapped in <mark>
+	Score      float64           `json:"score,omitempty"`      // Similarity to the query in semantic search, higher is closer
+}
+
+// SearchResults is a page of search hits
+type SearchResults struct {
+	Query   string                    `json:"query"`
+	Hits    []SearchHit               `json:"hits"`
+	Total   int                       `json:"total"`            // Estimated by external search backends
+	Facets  map[string]map[string]int `json:"facets,omitempty"` // Hit counts per tag and genre
+	Backend string                    `json:"backend"`          // "meilisearch", "mongodb" or, in semantic search, "atlas"
+}
+
+// ReindexStatus reports the progress of a search index rebuild
+type ReindexStatus struct {
+	State      string     `json:"state"` // "idle", "running", "completed" or "failed"
+	Total      int        `json:"total"`
+	Indexed    int        `json:"indexed"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	Error      string     `json:"error,omitempty"`
+}
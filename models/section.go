@@ -0,0 +1,14 @@
+package models
+
+// Section is the summary of one section of a long document, such as a chapter
+type Section struct {
+	Title   string    `json:"title" bson:"title"` // The section's heading, or its page range when the document has none
+	Summary string    `json:"summary" bson:"summary"`
+	Pages   PageRange `json:"pages" bson:"pages"`
+}
+
+// PageRange is a range of source pages, Start to End inclusive
+type PageRange struct {
+	Start int `json:"start" bson:"start"`
+	End   int `json:"end" bson:"end"`
+}
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// ContentFilterSettings configures masking of terms in TTS text and annotation display
+type ContentFilterSettings struct {
+	ID        string    `json:"-" bson:"_id"`
+	Enabled   bool      `json:"enabled" bson:"enabled"`
+	Mode      string    `json:"mode" bson:"mode"` // "mask" or "remove"
+	Terms     []string  `json:"terms" bson:"terms"`
+	UpdatedBy string    `json:"updated_by,omitempty" bson:"updated_by,omitempty"`
+	UpdatedAt time.Time `json:"updated_at" bson:"updated_at"`
+}
+
+// UpdateContentFilterRequest represents the request to change the content filter
+type UpdateContentFilterRequest struct {
+	Enabled *bool     `json:"enabled,omitempty"`
+	Mode    *string   `json:"mode,omitempty" binding:"omitempty,oneof=mask remove"`
+	Terms   *[]string `json:"terms,omitempty" binding:"omitempty,max=1000,dive,max=100"`
+}
+
+// TTSVoiceSettings overrides the TTS voice, e.g. with a custom brand voice. The engine only applies to Polly.
+type TTSVoiceSettings struct {
+	ID        string    `json:"-" bson:"_id"`
+	VoiceID   string    `json:"voice_id" bson:"voice_id"` // Empty uses the configured default voice
+	Engine    string    `json:"engine" bson:"engine"`     // Empty uses the configured default engine
+	UpdatedBy string    `json:"updated_by,omitempty" bson:"updated_by,omitempty"`
+	UpdatedAt time.Time `json:"updated_at" bson:"updated_at"`
+}
+
+// UpdateTTSVoiceRequest represents the request to change the TTS voice
+type UpdateTTSVoiceRequest struct {
+	VoiceID *string `json:"voice_id,omitempty" binding:"omitempty,max=100"`
+	Engine  *string `json:"engine,omitempty" binding:"omitempty,oneof=standard neural long-form generative"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ShareTokenAudience marks share tokens, so they can't be used as login tokens and vice versa
+const ShareTokenAudience = "share"
+
+// ShareClaims are the claims of a share token, granting read access to one annotation
+type ShareClaims struct {
+	AnnotationID string `json:"annotation_id"`
+	SharedBy     string `json:"shared_by"` // ID of the user who created the link
+	jwt.RegisteredClaims
+}
+
+// CreateShareRequest represents the request to create a share link. Links without an expiry stay
+// valid as long as the annotation exists.
+type CreateShareRequest struct {
+	ExpiresInDays int `json:"expires_in_days,omitempty" binding:"omitempty,min=1,max=365"`
+}
+
+// ShareLinkResponse is a created share link
+type ShareLinkResponse struct {
+	Token     string     `json:"token"`
+	URL       string     `json:"url"` // Opens the shared annotation in the web app
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
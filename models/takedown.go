@@ -0,0 +1,93 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// States of a copyright takedown claim
+const (
+	TakedownPending    = "pending"    // Awaiting review by an admin
+	TakedownRejected   = "rejected"   // Found invalid, the annotation stayed published
+	TakedownUpheld     = "upheld"     // The annotation was unpublished
+	TakedownCountered  = "countered"  // The uploader sent a counter-notice, republication is scheduled
+	TakedownReinstated = "reinstated" // Republished after the counter-notice waiting period
+	TakedownClosed     = "closed"     // The claimant went to court, the annotation stays unpublished
+)
+
+// TakedownClaim is a copyright (DMCA) takedown notice against an annotation
+type TakedownClaim struct {
+	ID            string         `json:"id" bson:"_id"`
+	AnnotationID  string         `json:"annotation_id" bson:"annotation_id"`
+	Title         string         `json:"title" bson:"title"`             // Of the annotation when the claim was filed
+	UploaderID    string         `json:"uploader_id" bson:"uploader_id"` // Owner of the annotation
+	ClaimantName  string         `json:"claimant_name" bson:"claimant_name"`
+	ClaimantEmail string         `json:"claimant_email" bson:"claimant_email"`
+	Work          string         `json:"work" bson:"work"` // The copyrighted work said to be infringed
+	Statement     string         `json:"statement" bson:"statement"`
+	Signature     string         `json:"signature" bson:"signature"`
+	Status        string         `json:"status" bson:"status"`
+	ReviewNote    string         `json:"review_note,omitempty" bson:"review_note,omitempty"`
+	ReviewedBy    string         `json:"reviewed_by,omitempty" bson:"reviewed_by,omitempty"`
+	ReviewedAt    *time.Time     `json:"reviewed_at,omitempty" bson:"reviewed_at,omitempty"`
+	CounterNotice *CounterNotice `json:"counter_notice,omitempty" bson:"counter_notice,omitempty"`
+	RepublishAt   *time.Time     `json:"republish_at,omitempty" bson:"republish_at,omitempty"` // Set while countered
+	CreatedAt     time.Time      `json:"created_at" bson:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at" bson:"updated_at"`
+}
+
+// CounterNotice is the uploader's answer to an upheld takedown claim
+type CounterNotice struct {
+	Name        string    `json:"name" bson:"name"`
+	Email       string    `json:"email" bson:"email"`
+	Statement   string    `json:"statement" bson:"statement"`
+	Signature   string    `json:"signature" bson:"signature"`
+	SubmittedAt time.Time `json:"submitted_at" bson:"submitted_at"`
+}
+
+// NewTakedownClaim creates a pending claim against an annotation
+func NewTakedownClaim(annotation *Annotation, req *CreateTakedownRequest) *TakedownClaim {
+	now := time.Now()
+	return &TakedownClaim{
+		ID:            uuid.New().String(),
+		AnnotationID:  annotation.ID,
+		Title:         annotation.Title,
+		UploaderID:    annotation.UserID,
+		ClaimantName:  req.ClaimantName,
+		ClaimantEmail: req.ClaimantEmail,
+		Work:          req.Work,
+		Statement:     req.Statement,
+		Signature:     req.Signature,
+		Status:        TakedownPending,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+}
+
+// CreateTakedownRequest represents a takedown notice filed by a rights holder
+type CreateTakedownRequest struct {
+	AnnotationID  string `json:"annotation_id" binding:"required"`
+	ClaimantName  string `json:"claimant_name" binding:"required"`
+	ClaimantEmail string `json:"claimant_email" binding:"required,email"`
+	Work          string `json:"work" binding:"required"`
+	Statement     string `json:"statement" binding:"required"` // Good faith belief that the use is not authorized
+	Signature     string `json:"signature" binding:"required"`
+}
+
+// ReviewTakedownRequest represents an admin decision on a takedown claim
+type ReviewTakedownRequest struct {
+	Note string `json:"note"`
+}
+
+// CounterNoticeRequest represents a counter-notice filed by the uploader
+type CounterNoticeRequest struct {
+	Statement string `json:"statement" binding:"required"` // Good faith belief that the material was removed by mistake
+	Signature string `json:"signature" binding:"required"`
+}
+
+// TakedownFilter restricts a list of takedown claims
+type TakedownFilter struct {
+	Status     string
+	UploaderID string
+}
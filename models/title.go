@@ -0,0 +1,17 @@
+package models
+
+// Sources of annotation titles
+const (
+	TitleProvided    = "provided"     // Entered by the uploader
+	TitleISBN        = "isbn"         // Looked up by ISBN
+	TitlePDFMetadata = "pdf_metadata" // From the document information of the PDF
+	TitleGenerated   = "generated"    // Written by the LLM from the opening of the document
+	TitleFileName    = "file_name"    // Derived from the name of the uploaded file
+	TitleWebPage     = "web_page"     // From the title of the fetched web page
+)
+
+// TitleSuggestion is a title determined from a document, for the uploader to confirm or change
+type TitleSuggestion struct {
+	Title  string `json:"title"`
+	Source string `json:"source"` // TitlePDFMetadata, TitleGenerated or TitleFileName
+}
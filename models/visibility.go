@@ -0,0 +1,13 @@
+package models
+
+// Annotation visibilities
+const (
+	VisibilityPrivate = "private" // Only signed-in users can read the annotation
+	VisibilityPublic  = "public"  // Anyone can read the annotation through the public routes
+)
+
+// IsPublic reports whether an annotation can be read without signing in. Annotations without a
+// visibility, including those created before visibilities were introduced, are private.
+func (a *Annotation) IsPublic() bool {
+	return a.Visibility == VisibilityPublic && a.Status == "completed" && !a.TakenDown && a.IsReviewed()
+}
@@ -0,0 +1,12 @@
+package models
+
+// TTSVoice describes a voice of the TTS provider
+type TTSVoice struct {
+	ID                      string   `json:"id"`
+	Name                    string   `json:"name"`
+	Gender                  string   `json:"gender"`
+	LanguageCode            string   `json:"language_code"`
+	LanguageName            string   `json:"language_name"`
+	AdditionalLanguageCodes []string `json:"additional_language_codes,omitempty"` // Bilingual voices
+	SupportedEngines        []string `json:"supported_engines"`
+}
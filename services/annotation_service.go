@@ -1,11 +1,22 @@
 package services
 
 import (
+	"auto-annotation-api/config"
 	"auto-annotation-api/models"
+	"auto-annotation-api/utils"
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io"
 	"log"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -13,54 +24,410 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// maxImageBytes limits the size of images downloaded for alt text generation
+const maxImageBytes = 10 << 20
+
+// sourceURLExpiry is how long signed source document URLs stay valid
+const sourceURLExpiry = 15 * time.Minute
+
+// genreExcerptLength is how much of the text the genre pre-classification sees
+const genreExcerptLength = 3000
+
 // AnnotationService orchestrates the annotation creation process
 type AnnotationService struct {
 	collection    *mongo.Collection
+	renditions    *mongo.Collection
+	links         *mongo.Collection
+	batches       *mongo.Collection
+	quotas        *mongo.Collection
+	quizzes       *mongo.Collection
+	settings      *SettingsService
 	ollamaClient  *OllamaClient
+	bookLookup    *BookLookupClient
 	awsService    *AWSService
+	tts           TTSProvider    // nil when the selected provider isn't configured
+	storage       StorageBackend // nil when the selected backend isn't configured
+	search        SearchIndex    // nil when no external search backend is configured
+	reindex       searchReindex
+	embedder      Embedder      // nil when the selected provider isn't configured
+	vectors       VectorStore   // nil when the selected backend is unknown
+	sandbox       *ParseSandbox // nil parses PDFs in the API process
+	ocr           *PDFOCR       // nil when scanned PDFs can't be read
+	cdn           *CDNPurger    // nil when no CDN purge URL is configured
+	shedder       *LoadShedder
+	simulation    simulation
 	uploadDir     string
+	maxUpload     int64 // bytes
+	maxBulkUpload int64 // bytes
+	strictOwner   bool  // Only owners and admins may update or delete annotations
+	uploadQuota   int   // Documents per user and day, 0 for no limit
+	chunkTokens   int
+	textModels    []string // Models requests may select besides the default, see selectModel
+	visionModel   string
+	shareBaseURL  string  // Web app URL share links point to
+	safetyMin     float64 // Minimum confidence of stored safety labels
+	safety        safetyPolicy
+	mustReview    bool    // Generated annotations start as drafts, hidden from students until approved
+	ttsCost       float64 // USD per million TTS characters, for processing estimates
+
+	uploadsInFlight sync.Map // "<user ID>:<content hash>" of uploads being processed
 }
 
 // NewAnnotationService creates a new annotation service
-func NewAnnotationService(db *mongo.Database, ollamaBaseURL, ollamaModel, uploadDir string, awsService *AWSService) *AnnotationService {
+func NewAnnotationService(db *mongo.Database, cfg *config.Config, awsService *AWSService) *AnnotationService {
+	// Backends are reported at startup; a missing one again when it is needed
+	tts, err := NewTTSProvider(cfg, awsService)
+	if err != nil {
+		log.Printf("Warning: %v. TTS functionality will not be available", err)
+	} else {
+		log.Printf("TTS provider: %s", tts.Name())
+	}
+	storage, err := NewStorageBackend(cfg, awsService)
+	if err != nil {
+		log.Printf("Warning: %v. Image and TTS storage will not be available", err)
+	} else {
+		log.Printf("Storage backend: %s", storage.Name())
+	}
+	search, err := NewSearchIndex(cfg)
+	if err != nil {
+		log.Printf("Warning: %v. Search will use MongoDB", err)
+	} else if search != nil {
+		log.Printf("Search backend: %s", search.Name())
+	}
+	embedder, err := NewEmbedder(cfg)
+	if err != nil {
+		log.Printf("Warning: %v. Semantic search will not be available", err)
+	}
+	collection := db.Collection("annotations")
+	vectors, err := NewVectorStore(cfg, collection)
+	if err != nil {
+		log.Printf("Warning: %v. Semantic search will not be available", err)
+	} else if embedder != nil {
+		log.Printf("Semantic search: %s embeddings, %s vector search", embedder.Model(), vectors.Name())
+	}
+	configureOllamaBreaker(cfg.OllamaBreakerMax, time.Duration(cfg.OllamaBreakerCool)*time.Second)
+	sandbox, err := NewParseSandbox(cfg)
+	if err != nil {
+		log.Printf("Warning: %v. PDFs will be parsed in the API process", err)
+	}
+	ocr, err := NewPDFOCR(cfg)
+	if err != nil {
+		log.Printf("Warning: %v. Scanned PDFs without a text layer will be rejected", err)
+	}
+
 	return &AnnotationService{
-		collection:   db.Collection("annotations"),
-		ollamaClient: NewOllamaClientWithConfig(ollamaBaseURL, ollamaModel),
-		awsService:   awsService,
-		uploadDir:    uploadDir, // Kept for backward compatibility, but not used
+		collection: collection,
+		renditions: db.Collection("reader_renditions"),
+		links:      db.Collection("annotation_links"),
+		batches:    db.Collection("upload_batches"),
+		quotas:     db.Collection("upload_quotas"),
+		quizzes:    db.Collection("quizzes"),
+		settings:   NewSettingsService(db),
+		ollamaClient: NewOllamaClientWithConfig(cfg.OllamaBaseURL, cfg.OllamaModel).WithFixtures(cfg.OllamaFixtures, cfg.OllamaFixtureDir).
+			WithRetries(cfg.OllamaRetries, time.Duration(cfg.OllamaBackoffMS)*time.Millisecond),
+		bookLookup:    NewBookLookupClient(),
+		awsService:    awsService,
+		tts:           tts,
+		storage:       storage,
+		search:        search,
+		embedder:      embedder,
+		vectors:       vectors,
+		sandbox:       sandbox,
+		ocr:           ocr,
+		cdn:           NewCDNPurger(cfg.CDNPurgeURL, cfg.CDNPurgeToken, cfg.CDNPurgeHeader),
+		shedder:       NewLoadShedder(cfg),
+		uploadDir:     cfg.UploadDir, // Kept for backward compatibility, but not used
+		maxUpload:     int64(cfg.MaxUploadMB) << 20,
+		maxBulkUpload: int64(cfg.BulkUploadMaxMB) << 20,
+		strictOwner:   cfg.StrictOwnership,
+		uploadQuota:   cfg.UploadQuotaPerDay,
+		simulation:    newSimulation(cfg),
+		chunkTokens:   cfg.OllamaChunkTokens,
+		textModels:    parseModelList(cfg.OllamaModels),
+		visionModel:   cfg.VisionModel,
+		shareBaseURL:  cfg.ShareBaseURL,
+		safetyMin:     float64(cfg.SafetyMinScore) / 100,
+		safety:        parseSafetyPolicy(cfg.SafetyRestrict),
+		mustReview:    cfg.RequireReview,
+		ttsCost:       cfg.TTSCostPerMillion,
+	}
+}
+
+// Settings returns the settings service the annotation service reads its content filter from, so
+// settings changes through it apply without waiting for the cache to expire
+func (s *AnnotationService) Settings() *SettingsService {
+	return s.settings
+}
+
+// Storage returns the backend images and audio are stored in, nil when the selected one isn't configured
+func (s *AnnotationService) Storage() StorageBackend {
+	return s.storage
+}
+
+// CheckUploadRequest rejects a request that can't be processed, so uploads are checked before
+// they count against the quota. CreateAnnotationFromStream checks the same again.
+func (s *AnnotationService) CheckUploadRequest(ctx context.Context, req *models.CreateAnnotationRequest) error {
+	if req.Simulate && !s.simulation.enabled {
+		return fmt.Errorf("simulated uploads not enabled")
+	}
+	if _, err := normalizeSummaryLength(req.Length); err != nil {
+		return err
+	}
+	if _, err := normalizeLicense(req.License); err != nil {
+		return err
+	}
+	if _, err := normalizeVisibility(req.Visibility); err != nil {
+		return err
 	}
+	if err := setAttribution(&models.Annotation{}, req); err != nil {
+		return err
+	}
+	if _, err := s.selectModel(req.Model); err != nil {
+		return err
+	}
+	_, err := s.checkMetadata(ctx, req.Metadata)
+	return err
 }
 
-// CreateAnnotationFromStream creates a new annotation from uploaded file stream (synchronous)
-func (s *AnnotationService) CreateAnnotationFromStream(ctx context.Context, userID, title, image string, fileReader io.Reader, fileSize int64, fileType string) (*models.Annotation, error) {
-	// Create annotation record (no source file path)
+// CreateAnnotationFromStream creates a new annotation from uploaded file stream (synchronous).
+// An uploaded image, if any, replaces req.Image and is stored under the new annotation's ID.
+func (s *AnnotationService) CreateAnnotationFromStream(ctx context.Context, userID string, req *models.CreateAnnotationRequest, fileReader io.Reader, fileSize int64, fileType string, imageFile *ImageUpload) (*models.Annotation, error) {
+	if req.Simulate && !s.simulation.enabled {
+		return nil, fmt.Errorf("simulated uploads not enabled")
+	}
+	uploadLoad.processing.Add(1)
+	defer uploadLoad.processing.Add(-1)
+	started := time.Now()
+
+	// Look up bibliographic metadata for books
+	var book *models.BookMetadata
+	if req.ISBN != "" && !req.Simulate {
+		var err error
+		book, err = s.bookLookup.LookupISBN(req.ISBN)
+		if err != nil {
+			log.Printf("Warning: ISBN lookup failed: %v", err)
+		}
+	}
+
+	length, err := normalizeSummaryLength(req.Length)
+	if err != nil {
+		return nil, err
+	}
+
+	license, err := normalizeLicense(req.License)
+	if err != nil {
+		return nil, err
+	}
+
+	visibility, err := normalizeVisibility(req.Visibility)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata, err := s.checkMetadata(ctx, req.Metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	title := strings.TrimSpace(req.Title)
+	titleSource := firstNonEmpty(req.TitleSource, models.TitleProvided)
+	image := req.Image
+	if book != nil {
+		if title == "" && book.Title != "" {
+			title = book.Title
+			titleSource = models.TitleISBN
+		}
+		if image == "" {
+			image = book.CoverURL
+		}
+	}
+
+	// Create annotation record (no source file path). Without a title, one is determined from the
+	// document once its text is extracted.
 	annotation := models.NewAnnotation(userID, title, "", fileType)
+	if title != "" {
+		annotation.TitleSource = titleSource
+	}
 	annotation.Image = image // Set optional image
+	annotation.ImageKey = s.storageKeyFromURL(image)
+	annotation.Book = book
+	annotation.Tags = NormalizeTags(req.Tags)
+	annotation.Metadata = metadata
+	annotation.Length = length
+	annotation.License = license
+	annotation.Visibility = visibility
+	annotation.Consent = req.Consent
+	if err := setAttribution(annotation, req); err != nil {
+		return nil, err
+	}
+
+	// The original upload is kept in storage, so buffer it once for both extraction and upload
+	fileData, err := io.ReadAll(io.LimitReader(fileReader, s.maxUpload+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read uploaded file: %w", err)
+	}
+	if int64(len(fileData)) > s.maxUpload {
+		return nil, fmt.Errorf("file exceeds the maximum upload size of %d MB", s.maxUpload>>20)
+	}
+	if fileType == "pdf" {
+		if err := ValidatePDF(bytes.NewReader(fileData), int64(len(fileData))); err != nil {
+			return nil, err
+		}
+	} else if IsImageDocument(fileType) {
+		if err := ValidateImage(bytes.NewReader(fileData), int64(len(fileData))); err != nil {
+			return nil, err
+		}
+		if err := s.checkImageDocuments(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Simulated uploads stop here, before any external service is called
+	if req.Simulate {
+		if annotation.Title == "" {
+			annotation.Title, annotation.TitleSource = firstNonEmpty(titleFromFilename(req.FileName), untitledDocument), models.TitleFileName
+		}
+		return s.completeSimulatedAnnotation(ctx, annotation, len(fileData))
+	}
+
+	// Uploads fail fast while Ollama is known to be down
+	if err := checkOllamaAvailable(); err != nil {
+		return nil, err
+	}
+	model, err := s.selectModel(req.Model)
+	if err != nil {
+		return nil, err
+	}
+	ctx = withOllamaModel(ctx, model)
+
+	// Re-uploads of a document are rejected before the expensive pipeline runs
+	annotation.ContentHash = contentHash(fileData)
+	release, err := s.claimUpload(ctx, userID, annotation.ContentHash)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	if imageFile != nil {
+		imageURL, err := s.UploadImageForAnnotationUpdate(ctx, annotation.ID, imageFile.Reader, imageFile.Size, imageFile.ContentType)
+		if err != nil {
+			return nil, err
+		}
+		annotation.Image = imageURL
+		annotation.ImageKey = s.storageKeyFromURL(imageURL)
+	}
 
 	// Step 1: Extract text from file stream
 	log.Printf("Extracting text from %s stream", fileType)
-	text, err := s.extractTextFromStream(fileReader, fileSize, fileType)
+	_, span := utils.StartSpan(ctx, "annotation.extract_text")
+	span.SetAttribute("file.type", fileType)
+	span.SetAttribute("file.size", fileSize)
+	var text string
+	if IsImageDocument(fileType) {
+		text, err = s.transcribeImageDocument(ctx, fileData)
+	} else {
+		text, err = s.extractTextFromStream(bytes.NewReader(fileData), fileSize, fileType)
+	}
+	span.SetAttribute("text.length", len(text))
+	span.RecordError(err)
+	span.End()
+	ocrPages := 0
+	if fileType == "pdf" {
+		text, ocrPages, err = s.readScannedPDF(ctx, fileData, text, err)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to extract text: %w", err)
 	}
 	annotation.TextContent = text
-	log.Printf("Extracted %d characters of text from file", len(text))
+	annotation.Formulas = extractFormulas(text)
+	annotation.CodeBlocks = extractCodeBlocks(text)
+	log.Printf("Extracted %d characters of text and %d formulas from file", len(text), len(annotation.Formulas))
+
+	if annotation.Title == "" {
+		_, span = utils.StartSpan(ctx, "annotation.determine_title")
+		suggestion := s.determineTitle(ctx, fileData, fileType, text, req.FileName)
+		annotation.Title, annotation.TitleSource = suggestion.Title, suggestion.Source
+		title = suggestion.Title
+		span.SetAttribute("title.source", suggestion.Source)
+		span.End()
+		log.Printf("Titled the upload %q (%s)", title, suggestion.Source)
+	}
+
+	_, span = utils.StartSpan(ctx, "annotation.store_source")
+	s.uploadSourceFile(ctx, annotation, fileData)
+
+	// Catalog cards without an image look blank, so PDFs default to a thumbnail of their first page
+	// and images to a thumbnail of themselves
+	if annotation.Image == "" && (fileType == "pdf" || IsImageDocument(fileType)) {
+		s.uploadThumbnail(ctx, annotation, fileData)
+	}
+	span.End()
 
 	// Step 2: Generate annotation and genre using Ollama
 	log.Printf("Generating annotation and genre using Ollama for: %s", title)
-	result, err := s.ollamaClient.GenerateAnnotationWithGenre(text, title)
+	result, err := s.generateAnnotation(ctx, text, title, length)
 	if err != nil {
 		annotation.Status = "failed"
 		annotation.ErrorMessage = fmt.Sprintf("Annotation generation failed: %v", err)
 		s.collection.InsertOne(ctx, annotation)
 		return nil, fmt.Errorf("failed to generate annotation: %w", err)
 	}
-	annotation.Annotation = result.Annotation
 	annotation.Genre = result.Genre
-	log.Printf("Generated annotation of %d characters, genre: %s", len(result.Annotation), result.Genre)
+	annotation.Model = model
+	log.Printf("Generated annotation of %d characters with %s, genre: %s", len(result.Annotation), model, result.Genre)
+
+	// Step 3: Describe figures, which body text alone doesn't capture
+	if s.visionModel != "" && fileType == "pdf" {
+		_, span = utils.StartSpan(ctx, "ollama.describe_figures")
+		annotation.Figures = s.analyzeFigures(ctx, fileData, title)
+		span.SetAttribute("figures", len(annotation.Figures))
+		span.End()
+	}
+	if len(annotation.CodeBlocks) > 0 {
+		_, span = utils.StartSpan(ctx, "ollama.explain_code")
+		annotation.CodeExamples = s.explainCodeExamples(ctx, annotation.CodeBlocks, title)
+		span.End()
+	}
+	annotation.Annotation = appendCodeExamples(appendFigureInsights(result.Annotation, annotation.Figures), annotation.CodeExamples)
+	_, span = utils.StartSpan(ctx, "ollama.learning_outline")
+	annotation.Objectives, annotation.Prereqs = s.extractLearningOutline(ctx, result, title)
+	span.End()
+	_, span = utils.StartSpan(ctx, "ollama.key_terms")
+	annotation.KeyTerms = s.extractKeyTerms(ctx, result.Annotation, title)
+	span.SetAttribute("key_terms", len(annotation.KeyTerms))
+	span.End()
+	if req.Sections {
+		ctx, span := utils.StartSpan(ctx, "annotation.sections")
+		annotation.Sections = s.summarizeSections(ctx, text, title, result.Genre)
+		span.SetAttribute("sections", len(annotation.Sections))
+		span.End()
+	}
+	_, span = utils.StartSpan(ctx, "ollama.safety_labels")
+	annotation.SafetyLabels = s.labelSafety(ctx, result.Annotation, title)
+	span.End()
+	_, span = utils.StartSpan(ctx, "embedding")
+	annotation.Embedding, annotation.EmbedModel = s.embedNotes(result.Annotation, title)
+	span.End()
+
+	annotation.ImageAltText = strings.TrimSpace(req.ImageAltText)
+	if annotation.Image != "" && annotation.ImageAltText == "" {
+		_, span = utils.StartSpan(ctx, "ollama.alt_text")
+		annotation.ImageAltText = s.generateImageAltText(ctx, annotation, annotation.Image, annotation.ImageKey)
+		span.End()
+	}
+	annotation.Images = annotation.GalleryImages()
 
 	// Mark as completed (no TTS yet)
+	pages := splitPages(text)
+	annotation.Processing = &models.UploadStats{
+		FileBytes:  int64(len(fileData)),
+		Pages:      pages[len(pages)-1].Page,
+		TextChars:  len(text),
+		DurationMs: time.Since(started).Milliseconds(),
+		OCRPages:   ocrPages,
+	}
 	annotation.Status = "completed"
+	annotation.ReviewState = s.initialReviewState()
 	annotation.UpdatedAt = time.Now()
 
 	// Insert into database
@@ -68,117 +435,893 @@ func (s *AnnotationService) CreateAnnotationFromStream(ctx context.Context, user
 	if err != nil {
 		return nil, fmt.Errorf("failed to create annotation record: %w", err)
 	}
+	s.syncSearch(ctx, annotation.ID)
+	s.purgeCDN(annotation.ID)
+
+	return annotation, nil
+}
+
+// uploadSourceFile stores the original upload under sources/ and records it on the annotation.
+// Failures are only logged, the annotation is still usable without its source document.
+func (s *AnnotationService) uploadSourceFile(ctx context.Context, annotation *models.Annotation, data []byte) {
+	if s.storage == nil {
+		return
+	}
+
+	key := fmt.Sprintf("sources/%s_%d.%s", annotation.ID, time.Now().Unix(), annotation.SourceType)
+	url, err := s.storage.Put(ctx, key, data, sourceContentType(annotation.SourceType))
+	if err != nil {
+		log.Printf("Warning: failed to upload source file for annotation %s: %v", annotation.ID, err)
+		return
+	}
+
+	annotation.SourceFile = url
+	annotation.SourceKey = key
+	log.Printf("Source file uploaded to %s: %s", s.storage.Name(), url)
+}
+
+// uploadThumbnail renders the first page of a PDF, or scales down an image document, and uses it
+// as the annotation image. Failures are only logged, the annotation is created without an image.
+func (s *AnnotationService) uploadThumbnail(ctx context.Context, annotation *models.Annotation, data []byte) {
+	if s.storage == nil {
+		return
+	}
+
+	render := renderPDFThumbnail
+	if IsImageDocument(annotation.SourceType) {
+		render = renderImageThumbnail
+	}
+	thumbnail, err := render(data)
+	if err != nil {
+		log.Printf("Skipping thumbnail for annotation %s: %v", annotation.ID, err)
+		return
+	}
+
+	key := fmt.Sprintf("images/%s_%d_thumb.png", annotation.ID, time.Now().Unix())
+	url, err := s.storage.Put(ctx, key, thumbnail, "image/png")
+	if err != nil {
+		log.Printf("Warning: failed to upload thumbnail for annotation %s: %v", annotation.ID, err)
+		return
+	}
+
+	annotation.Image = url
+	annotation.ImageKey = key
+	log.Printf("Thumbnail uploaded to %s: %s", s.storage.Name(), url)
+}
+
+// sourceContentType returns the MIME type of a source file type
+func sourceContentType(fileType string) string {
+	if contentType, ok := imageDocumentTypes[fileType]; ok {
+		return contentType
+	}
+	switch fileType {
+	case "pdf":
+		return "application/pdf"
+	case "html":
+		// Fetched pages are served as text, so their scripts can't run on the storage origin
+		return "text/plain; charset=utf-8"
+	default:
+		return "application/octet-stream"
+	}
+}
+
+// GenerateTTSForAnnotation generates TTS for an existing annotation and uploads it to storage
+func (s *AnnotationService) GenerateTTSForAnnotation(ctx context.Context, annotationID string) (*models.Annotation, error) {
+	// Get annotation
+	annotation, err := s.GetAnnotationByID(ctx, annotationID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check if annotation text exists
+	if annotation.Annotation == "" {
+		return nil, fmt.Errorf("annotation text is empty")
+	}
+	if annotation.Simulated {
+		return s.simulateTTS(ctx, annotation)
+	}
+
+	// Check if TTS and storage are available
+	if s.tts == nil {
+		return nil, fmt.Errorf("TTS provider not configured")
+	}
+	if s.storage == nil {
+		return nil, fmt.Errorf("storage not configured")
+	}
+
+	log.Printf("Generating TTS with %s for annotation ID: %s", s.tts.Name(), annotationID)
+
+	filter, err := s.settings.ContentFilter(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	voice, err := s.settings.GetTTSVoiceSettings(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Formulas are replaced with a short cue rather than read out symbol by symbol
+	text := speakableText(filter.Apply(annotation.Annotation))
+
+	// Generate TTS and upload to storage
+	ttsURL, err := s.generateAndUploadTTS(ctx, text, annotationID, voice, AudioFormatMP3, "audio/mpeg")
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate TTS: %w", err)
+	}
+
+	log.Printf("TTS generated and uploaded to %s: %s", s.storage.Name(), ttsURL)
+
+	// The Opus rendition is for bandwidth-constrained clients, the MP3 alone is still usable
+	opusURL, err := s.generateAndUploadTTS(ctx, text, annotationID, voice, AudioFormatOgg, "audio/ogg")
+	if err != nil {
+		log.Printf("Warning: failed to generate Opus TTS for annotation %s: %v", annotationID, err)
+	} else {
+		log.Printf("Opus TTS generated and uploaded to %s: %s", s.storage.Name(), opusURL)
+	}
+
+	// Speech marks drive word highlighting and captions, the audio is still usable without them
+	var marksURL string
+	var captions *models.TTSCaptions
+	_, span := utils.StartSpan(ctx, "tts.speech_marks")
+	marks, err := s.tts.SpeechMarks(ctx, text, voice.VoiceID, voice.Engine)
+	span.RecordError(err)
+	span.End()
+	if err == errSpeechMarksUnsupported {
+		log.Printf("Skipping speech marks and captions for annotation %s: %v", annotationID, err)
+	} else if err != nil {
+		log.Printf("Warning: failed to generate speech marks for annotation %s: %v", annotationID, err)
+	} else {
+		marksURL, err = s.uploadSpeechMarks(ctx, marks, annotationID)
+		if err != nil {
+			log.Printf("Warning: failed to upload speech marks for annotation %s: %v", annotationID, err)
+		}
+
+		captions, err = s.generateCaptions(ctx, marks, annotationID)
+		if err != nil {
+			log.Printf("Warning: failed to generate captions for annotation %s: %v", annotationID, err)
+		}
+	}
+
+	// Update annotation with TTS URLs
+	update := bson.M{
+		"$set": bson.M{
+			"tts_url":       ttsURL,
+			"tts_key":       s.storage.KeyFromURL(ttsURL),
+			"tts_opus_url":  opusURL,
+			"tts_opus_key":  s.storage.KeyFromURL(opusURL),
+			"tts_marks_url": marksURL,
+			"tts_marks_key": s.storage.KeyFromURL(marksURL),
+			"captions":      captions,
+			"updated_at":    time.Now(),
+		},
+	}
+
+	_, err = s.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": annotationID},
+		update,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update annotation: %w", err)
+	}
+	s.purgeCDN(annotationID)
+
+	// Return updated annotation
+	return s.GetAnnotationByID(ctx, annotationID)
+}
+
+// generateAndUploadTTS synthesizes the text in one audio format and uploads it under tts/
+func (s *AnnotationService) generateAndUploadTTS(ctx context.Context, text, annotationID string, voice *models.TTSVoiceSettings, format, contentType string) (string, error) {
+	ctx, span := utils.StartSpan(ctx, "tts.synthesize")
+	span.SetAttribute("tts.provider", s.tts.Name())
+	span.SetAttribute("tts.format", format)
+	span.SetAttribute("text.length", len(text))
+	defer span.End()
+
+	audio, err := s.tts.Synthesize(ctx, text, voice.VoiceID, voice.Engine, format)
+	if err != nil {
+		span.RecordError(err)
+		return "", err
+	}
+
+	// Create storage key with timestamp to ensure uniqueness
+	key := fmt.Sprintf("tts/%s_%d.%s", annotationID, time.Now().Unix(), format)
+	_, upload := utils.StartSpan(ctx, "storage.put")
+	upload.SetAttribute("storage.provider", s.storage.Name())
+	upload.SetAttribute("storage.size", len(audio))
+	url, err := s.storage.Put(ctx, key, audio, contentType)
+	upload.RecordError(err)
+	upload.End()
+	return url, err
+}
+
+// uploadSpeechMarks stores the speech marks as a JSON array next to the audio, for word highlighting
+func (s *AnnotationService) uploadSpeechMarks(ctx context.Context, marks []SpeechMark, annotationID string) (string, error) {
+	data, err := json.Marshal(marks)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode speech marks: %w", err)
+	}
+
+	key := fmt.Sprintf("tts/%s_%d.marks.json", annotationID, time.Now().Unix())
+	return s.storage.Put(ctx, key, data, "application/json")
+}
+
+// generateCaptions builds WebVTT and SRT captions from Polly speech marks and uploads them next to the audio
+func (s *AnnotationService) generateCaptions(ctx context.Context, marks []SpeechMark, annotationID string) (*models.TTSCaptions, error) {
+	cues := buildCaptionCues(marks)
+	if len(cues) == 0 {
+		return nil, fmt.Errorf("no speech marks returned")
+	}
+
+	timestamp := time.Now().Unix()
+	captions := &models.TTSCaptions{
+		VTTKey: fmt.Sprintf("tts/%s_%d.vtt", annotationID, timestamp),
+		SRTKey: fmt.Sprintf("tts/%s_%d.srt", annotationID, timestamp),
+	}
+
+	var err error
+	captions.VTTURL, err = s.storage.Put(ctx, captions.VTTKey, []byte(formatWebVTT(cues)), "text/vtt; charset=utf-8")
+	if err != nil {
+		return nil, err
+	}
+	captions.SRTURL, err = s.storage.Put(ctx, captions.SRTKey, []byte(formatSRT(cues)), "application/x-subrip; charset=utf-8")
+	if err != nil {
+		return nil, err
+	}
+
+	return captions, nil
+}
+
+// GenerateAudioTour creates a spoken overview of the document, one short LLM summary per section,
+// stitched into a single MP3 with chapter marks and uploaded to storage
+func (s *AnnotationService) GenerateAudioTour(ctx context.Context, annotationID string) (*models.Annotation, error) {
+	annotation, err := s.GetAnnotationByID(ctx, annotationID)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.tts == nil {
+		return nil, fmt.Errorf("TTS provider not configured")
+	}
+	if s.storage == nil {
+		return nil, fmt.Errorf("storage not configured")
+	}
+
+	sections := extractTourSections(annotation.TextContent)
+	if len(sections) == 0 {
+		return nil, fmt.Errorf("annotation has no source text")
+	}
+
+	filter, err := s.settings.ContentFilter(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	voice, err := s.settings.GetTTSVoiceSettings(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Generating audio tour with %d sections for annotation ID: %s", len(sections), annotationID)
+
+	var audio bytes.Buffer
+	var marks []tourChapterMark
+	chapters := make([]models.AudioTourChapter, 0, len(sections))
+	var position int64
+	for i, section := range sections {
+		text := section.Text
+		if len(text) > maxTourSectionText {
+			text = strings.ToValidUTF8(text[:maxTourSectionText], "")
+		}
+
+		summary, err := s.ollamaClient.SummarizeSectionForAudio(ctx, section.Title, text, annotation.Title)
+		if err != nil {
+			return nil, fmt.Errorf("failed to summarize section %d: %w", i+1, err)
+		}
+
+		summary = filter.Apply(summary)
+
+		speech, err := s.tts.Synthesize(ctx, filter.Apply(section.Title)+". "+speakableText(summary), voice.VoiceID, voice.Engine, AudioFormatMP3)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate audio for section %d: %w", i+1, err)
+		}
+		speech = stripID3(speech)
+
+		duration := mp3DurationMs(speech)
+		audio.Write(speech)
+		marks = append(marks, tourChapterMark{Title: section.Title, StartMs: position, EndMs: position + duration})
+		chapters = append(chapters, models.AudioTourChapter{
+			Title:   section.Title,
+			Summary: summary,
+			StartMs: position,
+			EndMs:   position + duration,
+		})
+		position += duration
+	}
+
+	data := append(buildChapterTag(annotation.Title, marks), audio.Bytes()...)
+	key := fmt.Sprintf("tours/%s_%d.mp3", annotationID, time.Now().Unix())
+	url, err := s.storage.Put(ctx, key, data, "audio/mpeg")
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload audio tour: %w", err)
+	}
+
+	log.Printf("Audio tour uploaded to %s: %s (%d ms)", s.storage.Name(), url, position)
+
+	tour := &models.AudioTour{
+		URL:         url,
+		Key:         key,
+		DurationMs:  position,
+		Chapters:    chapters,
+		GeneratedAt: time.Now(),
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"audio_tour": tour,
+			"updated_at": time.Now(),
+		},
+	}
+
+	_, err = s.collection.UpdateOne(ctx, bson.M{"_id": annotationID}, update)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update annotation: %w", err)
+	}
+	s.purgeCDN(annotationID)
+
+	// The previous tour is replaced, remove its audio
+	if annotation.AudioTour != nil && annotation.AudioTour.Key != "" {
+		if err := s.storage.Delete(ctx, annotation.AudioTour.Key); err != nil {
+			log.Printf("Warning: failed to delete previous audio tour %s: %v", annotation.AudioTour.Key, err)
+		}
+	}
+
+	return s.GetAnnotationByID(ctx, annotationID)
+}
+
+// FilterForDisplay applies the content filter, when enabled, to annotations about to be shown to users
+func (s *AnnotationService) FilterForDisplay(ctx context.Context, annotations ...*models.Annotation) error {
+	filter, err := s.settings.ContentFilter(ctx)
+	if err != nil {
+		return err
+	}
+	if filter == nil {
+		return nil
+	}
+
+	for _, annotation := range annotations {
+		annotation.Title = filter.Apply(annotation.Title)
+		annotation.Annotation = filter.Apply(annotation.Annotation)
+	}
+	return nil
+}
+
+// RegenerateAnnotation generates a new annotation from the stored source text, optionally with a different
+// length or model. An empty model selects the default model, not the one that wrote the current notes.
+func (s *AnnotationService) RegenerateAnnotation(ctx context.Context, annotationID, length, model string) (*models.Annotation, error) {
+	annotation, err := s.GetAnnotationByID(ctx, annotationID)
+	if err != nil {
+		return nil, err
+	}
+
+	if length == "" {
+		length = annotation.Length
+	}
+	length, err = normalizeSummaryLength(length)
+	if err != nil {
+		return nil, err
+	}
+
+	if annotation.TextContent == "" {
+		return nil, fmt.Errorf("annotation has no source text")
+	}
+	model, err = s.selectModel(model)
+	if err != nil {
+		return nil, err
+	}
+	ctx = withOllamaModel(ctx, model)
+
+	log.Printf("Regenerating %s annotation with %s for: %s", length, model, annotation.Title)
+	result, err := s.generateAnnotation(ctx, annotation.TextContent, annotation.Title, length)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate annotation: %w", err)
+	}
+
+	objectives, prereqs := s.extractLearningOutline(ctx, result, annotation.Title)
+	keyTerms := s.extractKeyTerms(ctx, result.Annotation, annotation.Title)
+	safetyLabels := s.labelSafety(ctx, result.Annotation, annotation.Title)
+	// Annotations created with per-section summaries keep them
+	var sections []models.Section
+	if len(annotation.Sections) > 0 {
+		sections = s.summarizeSections(ctx, annotation.TextContent, annotation.Title, result.Genre)
+	}
+	embedding, embedModel := s.embedNotes(result.Annotation, annotation.Title)
+
+	update := bson.M{
+		"$set": bson.M{
+			"embedding":           embedding,
+			"embedding_model":     embedModel,
+			"annotation":          appendCodeExamples(appendFigureInsights(result.Annotation, annotation.Figures), annotation.CodeExamples),
+			"learning_objectives": objectives,
+			"prerequisites":       prereqs,
+			"key_terms":           keyTerms,
+			"sections":            sections,
+			"safety_labels":       safetyLabels,
+			"genre":               result.Genre,
+			"model":               model,
+			"length":              length,
+			"status":              "completed",
+			"error_message":       "",
+			"updated_at":          time.Now(),
+		},
+	}
+	// Regenerated notes haven't been reviewed
+	if s.mustReview {
+		update["$set"].(bson.M)["review_state"] = models.ReviewDraft
+		update["$unset"] = bson.M{"review": ""}
+	}
+
+	_, err = s.collection.UpdateOne(ctx, bson.M{"_id": annotationID}, update)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update annotation: %w", err)
+	}
+	s.syncSearch(ctx, annotationID)
+	s.purgeCDN(annotationID)
+
+	return s.GetAnnotationByID(ctx, annotationID)
+}
+
+// normalizeSummaryLength validates an annotation length, defaulting to "medium"
+func normalizeSummaryLength(length string) (string, error) {
+	switch length = strings.ToLower(strings.TrimSpace(length)); length {
+	case "":
+		return "medium", nil
+	case "short", "medium", "detailed":
+		return length, nil
+	default:
+		return "", fmt.Errorf("invalid length %q, must be short, medium or detailed", length)
+	}
+}
+
+// normalizeLicense validates a license identifier, case-insensitively, defaulting to models.DefaultLicense
+func normalizeLicense(license string) (string, error) {
+	license = strings.TrimSpace(license)
+	if license == "" {
+		return models.DefaultLicense, nil
+	}
+	for id := range models.Licenses {
+		if strings.EqualFold(id, license) {
+			return id, nil
+		}
+	}
+	return "", fmt.Errorf("invalid license %q, must be one of %s", license, strings.Join(licenseIDs(), ", "))
+}
+
+// licenseIDs returns the selectable license identifiers, sorted
+func licenseIDs() []string {
+	ids := make([]string, 0, len(models.Licenses))
+	for id := range models.Licenses {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// normalizeVisibility validates a visibility, case-insensitively, defaulting to models.VisibilityPrivate
+func normalizeVisibility(visibility string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(visibility)) {
+	case "", models.VisibilityPrivate:
+		return models.VisibilityPrivate, nil
+	case models.VisibilityPublic:
+		return models.VisibilityPublic, nil
+	default:
+		return "", fmt.Errorf("invalid visibility %q, must be public or private", visibility)
+	}
+}
+
+// UpdateAnnotation updates an annotation's fields (any content creator can edit, unless STRICT_OWNERSHIP is set)
+func (s *AnnotationService) UpdateAnnotation(ctx context.Context, annotationID string, user *models.User, req *models.UpdateAnnotationRequest) (*models.Annotation, error) {
+	if err := s.CheckOwnership(ctx, annotationID, user); err != nil {
+		return nil, err
+	}
+
+	// Build update query
+	updateFields := bson.M{
+		"updated_at": time.Now(),
+	}
+
+	if req.Title != nil {
+		updateFields["title"] = *req.Title
+		updateFields["title_source"] = models.TitleProvided // Confirmed by the uploader
+	}
+	if req.Image != nil || req.ImageAltText != nil {
+		// The legacy image fields edit the first gallery image
+		annotation, err := s.GetAnnotationByID(ctx, annotationID)
+		if err != nil {
+			return nil, err
+		}
+		images := annotation.GalleryImages()
+		if req.Image != nil {
+			images = s.replaceCoverImage(images, *req.Image)
+		}
+		if req.ImageAltText != nil && len(images) > 0 {
+			images[0].AltText = strings.TrimSpace(*req.ImageAltText)
+		}
+		for field, value := range galleryFields(images) {
+			updateFields[field] = value
+		}
+	}
+	if req.Annotation != nil {
+		updateFields["annotation"] = *req.Annotation
+	}
+	if req.Genre != nil {
+		updateFields["genre"] = *req.Genre
+	}
+	if req.Tags != nil {
+		updateFields["tags"] = NormalizeTags(*req.Tags)
+	}
+	if req.License != nil {
+		license, err := normalizeLicense(*req.License)
+		if err != nil {
+			return nil, err
+		}
+		updateFields["license"] = license
+	}
+	if req.Visibility != nil {
+		visibility, err := normalizeVisibility(*req.Visibility)
+		if err != nil {
+			return nil, err
+		}
+		updateFields["visibility"] = visibility
+	}
+	if req.Metadata != nil {
+		metadata, err := s.checkMetadata(ctx, *req.Metadata)
+		if err != nil {
+			return nil, err
+		}
+		updateFields["metadata"] = metadata
+	}
+	if req.SourceURL != nil {
+		sourceURL, err := normalizeSourceURL(*req.SourceURL)
+		if err != nil {
+			return nil, err
+		}
+		updateFields["source_url"] = sourceURL
+	}
+	if req.Author != nil {
+		author, err := normalizeAuthor(*req.Author)
+		if err != nil {
+			return nil, err
+		}
+		updateFields["author"] = author
+	}
+	if req.PublishedOn != nil {
+		date, err := normalizePublicationDate(*req.PublishedOn)
+		if err != nil {
+			return nil, err
+		}
+		updateFields["publication_date"] = date
+	}
+
+	update := bson.M{"$set": updateFields}
+
+	// Update annotation
+	result, err := s.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": annotationID},
+		update,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update annotation: %w", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return nil, fmt.Errorf("annotation not found")
+	}
+
+	annotation, err := s.GetAnnotationByID(ctx, annotationID)
+	if err != nil {
+		return nil, err
+	}
+
+	// A new image without explicit alt text gets generated alt text
+	if req.Image != nil && *req.Image != "" && req.ImageAltText == nil {
+		annotation.ImageAltText = s.generateImageAltText(ctx, annotation, annotation.Image, annotation.ImageKey)
+		if annotation.ImageAltText != "" {
+			annotation.Images[0].AltText = annotation.ImageAltText
+			_, err = s.collection.UpdateOne(ctx, bson.M{"_id": annotationID}, bson.M{"$set": galleryFields(annotation.Images)})
+			if err != nil {
+				log.Printf("Warning: failed to save alt text for annotation %s: %v", annotationID, err)
+			}
+		}
+	}
+	s.syncSearch(ctx, annotationID)
+	s.purgeCDN(annotationID)
+
+	return annotation, nil
+}
+
+// generateImageAltText describes one of an annotation's images with the vision model when configured,
+// otherwise infers it from the title and summary. Failures are logged and yield no alt text.
+func (s *AnnotationService) generateImageAltText(ctx context.Context, annotation *models.Annotation, url, key string) string {
+	var altText string
+	var err error
+	if s.visionModel != "" {
+		var image []byte
+		image, err = s.loadImage(ctx, url, key)
+		if err == nil {
+			altText, err = s.ollamaClient.DescribeImage(ctx, s.visionModel, image, annotation.Title)
+		}
+	} else {
+		altText, err = s.ollamaClient.GenerateAltText(ctx, annotation.Title, annotation.Annotation)
+	}
+	if err != nil {
+		log.Printf("Warning: failed to generate alt text for annotation %s: %v", annotation.ID, err)
+		return ""
+	}
+
+	return strings.Trim(strings.TrimSpace(altText), `"`)
+}
+
+// loadImage reads an image from storage when it was uploaded, otherwise downloads it
+func (s *AnnotationService) loadImage(ctx context.Context, url, key string) ([]byte, error) {
+	if key == "" || s.storage == nil {
+		return fetchImage(url)
+	}
+
+	data, err := s.storage.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxImageBytes {
+		return nil, fmt.Errorf("image is larger than %d bytes", maxImageBytes)
+	}
+	return data, nil
+}
+
+// fetchImage downloads an image for captioning, limited to maxImageBytes
+func fetchImage(url string) ([]byte, error) {
+	resp, err := webClient.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download image: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download image: status %d", resp.StatusCode)
+	}
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "image/") {
+		return nil, fmt.Errorf("URL is not an image: %s", resp.Header.Get("Content-Type"))
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxImageBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image: %w", err)
+	}
+	if len(data) > maxImageBytes {
+		return nil, fmt.Errorf("image is larger than %d bytes", maxImageBytes)
+	}
+	return data, nil
+}
+
+// ImageUpload is an image file uploaded along with a document
+type ImageUpload struct {
+	Reader      io.Reader
+	Size        int64
+	ContentType string
+}
+
+// UploadImageForAnnotationUpdate streams an image of the given size to storage and returns the URL (doesn't update DB)
+func (s *AnnotationService) UploadImageForAnnotationUpdate(ctx context.Context, annotationID string, image io.Reader, size int64, contentType string) (string, error) {
+	// Check if storage is available
+	if s.storage == nil {
+		return "", fmt.Errorf("storage not configured")
+	}
+
+	log.Printf("Uploading image for annotation ID: %s", annotationID)
+
+	// Create storage key with timestamp to ensure uniqueness
+	key := fmt.Sprintf("images/%s_%d%s", annotationID, time.Now().Unix(), imageExtension(contentType))
+	imageURL, err := s.storage.PutStream(ctx, key, image, size, contentType)
+	if err != nil {
+		return "", fmt.Errorf("failed to upload image: %w", err)
+	}
 
-	return annotation, nil
+	log.Printf("Image uploaded to %s: %s", s.storage.Name(), imageURL)
+
+	return imageURL, nil
 }
 
-// GenerateTTSForAnnotation generates TTS for an existing annotation and uploads to S3
-func (s *AnnotationService) GenerateTTSForAnnotation(ctx context.Context, annotationID string) (*models.Annotation, error) {
-	// Get annotation
-	annotation, err := s.GetAnnotationByID(ctx, annotationID)
-	if err != nil {
-		return nil, err
+// imageExtension returns the file extension for an image content type, defaulting to .jpg
+func imageExtension(contentType string) string {
+	switch contentType {
+	case "image/png":
+		return ".png"
+	case "image/gif":
+		return ".gif"
+	case "image/webp":
+		return ".webp"
+	default:
+		return ".jpg"
 	}
+}
 
-	// Check if annotation text exists
-	if annotation.Annotation == "" {
-		return nil, fmt.Errorf("annotation text is empty")
+// SourceDownloadURL returns a URL for the original upload of an annotation, signed when the storage
+// backend supports it, or "" when the source document wasn't stored
+func (s *AnnotationService) SourceDownloadURL(ctx context.Context, annotation *models.Annotation) (string, error) {
+	if annotation.SourceKey == "" || s.storage == nil {
+		return annotation.SourceFile, nil
 	}
+	return s.storage.SignedURL(ctx, annotation.SourceKey, sourceURLExpiry)
+}
 
-	// Check if AWS service is available
-	if s.awsService == nil {
-		return nil, fmt.Errorf("AWS service not configured")
-	}
+// generateAnnotation generates an annotation of the given length for the text, splitting long documents into
+// token-bounded chunks that are summarized individually and then consolidated
+func (s *AnnotationService) generateAnnotation(ctx context.Context, text, title, length string) (result *AnnotationWithGenre, err error) {
+	ctx, span := utils.StartSpan(ctx, "ollama.generate_annotation")
+	span.SetAttribute("llm.model", s.ollamaClient.model)
+	span.SetAttribute("llm.length", length)
+	span.SetAttribute("text.tokens", estimateTokens(text))
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
 
-	log.Printf("Generating TTS for annotation ID: %s", annotationID)
+	_, classify := utils.StartSpan(ctx, "ollama.classify_genre")
+	genre := s.classifyGenre(ctx, text, title)
+	classify.SetAttribute("genre", genre)
+	classify.End()
 
-	// Generate TTS and upload to S3
-	ttsURL, err := s.awsService.GenerateAndUploadTTS(annotation.Annotation, annotationID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate TTS: %w", err)
+	if s.chunkTokens <= 0 || estimateTokens(text) <= s.chunkTokens {
+		_, call := utils.StartSpan(ctx, "ollama.annotate")
+		defer call.End()
+		return s.ollamaClient.GenerateAnnotationWithGenre(ctx, text, title, length, genre)
 	}
 
-	log.Printf("TTS generated and uploaded to S3: %s", ttsURL)
+	notes := splitTextIntoChunks(text, s.chunkTokens)
+	log.Printf("Text is ~%d tokens, processing in %d chunks", estimateTokens(text), len(notes))
+	span.SetAttribute("chunks", len(notes))
 
-	// Update annotation with TTS URL
-	update := bson.M{
-		"$set": bson.M{
-			"tts_url":    ttsURL,
-			"updated_at": time.Now(),
-		},
-	}
+	// Summarize chunks until the combined notes fit into a single consolidation request
+	for {
+		summaries := make([]string, len(notes))
+		for i, chunk := range notes {
+			log.Printf("Summarizing chunk %d/%d for: %s", i+1, len(notes), title)
+			_, call := utils.StartSpan(ctx, "ollama.summarize_chunk")
+			call.SetAttribute("chunk", i+1)
+			summary, err := s.ollamaClient.SummarizeChunk(ctx, chunk, title, genre, i+1, len(notes))
+			call.RecordError(err)
+			call.End()
+			if err != nil {
+				return nil, fmt.Errorf("failed to summarize chunk %d/%d: %w", i+1, len(notes), err)
+			}
+			summaries[i] = summary
+		}
 
-	_, err = s.collection.UpdateOne(
-		ctx,
-		bson.M{"_id": annotationID},
-		update,
-	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to update annotation: %w", err)
-	}
+		combined := strings.Join(summaries, "\n\n")
+		if estimateTokens(combined) <= s.chunkTokens || len(summaries) == 1 {
+			log.Printf("Consolidating %d chunk summaries for: %s", len(summaries), title)
+			_, call := utils.StartSpan(ctx, "ollama.consolidate")
+			defer call.End()
+			return s.ollamaClient.ConsolidateAnnotations(ctx, summaries, title, length, genre)
+		}
 
-	// Return updated annotation
-	return s.GetAnnotationByID(ctx, annotationID)
+		next := splitTextIntoChunks(combined, s.chunkTokens)
+		if len(next) >= len(notes) {
+			// Summaries are not getting shorter, consolidate what we have
+			_, call := utils.StartSpan(ctx, "ollama.consolidate")
+			defer call.End()
+			return s.ollamaClient.ConsolidateAnnotations(ctx, summaries, title, length, genre)
+		}
+		notes = next
+	}
 }
 
-// UpdateAnnotation updates an annotation's fields (any content creator can edit)
-func (s *AnnotationService) UpdateAnnotation(ctx context.Context, annotationID, userID string, req *models.UpdateAnnotationRequest) (*models.Annotation, error) {
-	// Build update query (no ownership check - CMS style)
-	updateFields := bson.M{
-		"updated_at": time.Now(),
+// classifyGenre picks the genre from the opening of the text so the genre-specific prompt can be
+// used. On failure it returns "", which falls back to the generic prompt with genre detection.
+func (s *AnnotationService) classifyGenre(ctx context.Context, text, title string) string {
+	excerpt := text
+	if len(excerpt) > genreExcerptLength {
+		excerpt = strings.ToValidUTF8(excerpt[:genreExcerptLength], "")
 	}
 
-	if req.Title != nil {
-		updateFields["title"] = *req.Title
-	}
-	if req.Image != nil {
-		updateFields["image"] = *req.Image
+	genre, err := s.ollamaClient.ClassifyGenre(ctx, excerpt, title)
+	if err != nil {
+		log.Printf("Warning: genre pre-classification failed, using the generic prompt: %v", err)
+		return ""
 	}
-	if req.Annotation != nil {
-		updateFields["annotation"] = *req.Annotation
+	log.Printf("Pre-classified genre for %s: %s", title, genre)
+	return genre
+}
+
+// analyzeFigures extracts figures from a PDF and describes them with the vision model.
+// Failures are logged and skipped, figure insights are an optional part of the annotation.
+func (s *AnnotationService) analyzeFigures(ctx context.Context, data []byte, title string) []models.FigureInsight {
+	figures, err := extractPDFFigures(data, maxFiguresPerDocument)
+	if err != nil {
+		log.Printf("Warning: failed to extract figures: %v", err)
+		return nil
 	}
-	if req.Genre != nil {
-		updateFields["genre"] = *req.Genre
+
+	var insights []models.FigureInsight
+	for _, figure := range figures {
+		log.Printf("Describing figure on page %d for: %s", figure.Page, title)
+		description, err := s.ollamaClient.DescribeFigure(ctx, s.visionModel, figure.Image, title, figure.Page)
+		if err != nil {
+			log.Printf("Warning: failed to describe figure on page %d: %v", figure.Page, err)
+			continue
+		}
+		if strings.Contains(strings.ToUpper(description), "DECORATIVE") {
+			continue
+		}
+		insights = append(insights, models.FigureInsight{Page: figure.Page, Description: description})
 	}
+	return insights
+}
 
-	update := bson.M{"$set": updateFields}
+// extractLearningOutline lists learning objectives and prerequisites for educational material.
+// Other genres, and failures, return no outline since it is an optional part of the annotation.
+func (s *AnnotationService) extractLearningOutline(ctx context.Context, result *AnnotationWithGenre, title string) ([]string, []string) {
+	if !strings.EqualFold(result.Genre, "Educational") {
+		return nil, nil
+	}
 
-	// Update annotation
-	result, err := s.collection.UpdateOne(
-		ctx,
-		bson.M{"_id": annotationID},
-		update,
-	)
+	log.Printf("Extracting learning objectives and prerequisites for: %s", title)
+	outline, err := s.ollamaClient.ExtractLearningOutline(ctx, result.Annotation, title)
 	if err != nil {
-		return nil, fmt.Errorf("failed to update annotation: %w", err)
+		log.Printf("Warning: failed to extract learning outline: %v", err)
+		return nil, nil
 	}
+	return outline.Objectives, outline.Prerequisites
+}
 
-	if result.MatchedCount == 0 {
-		return nil, fmt.Errorf("annotation not found")
+// extractKeyTerms builds the glossary of generated notes. Failures leave the annotation without one.
+func (s *AnnotationService) extractKeyTerms(ctx context.Context, notes, title string) []models.KeyTerm {
+	log.Printf("Extracting key terms for: %s", title)
+	terms, err := s.ollamaClient.ExtractKeyTerms(ctx, notes, title)
+	if err != nil {
+		log.Printf("Warning: failed to extract key terms: %v", err)
+		return nil
 	}
-
-	// Return updated annotation
-	return s.GetAnnotationByID(ctx, annotationID)
+	return terms
 }
 
-// UploadImageForAnnotationUpdate uploads an image to S3 and returns the URL (doesn't update DB)
-func (s *AnnotationService) UploadImageForAnnotationUpdate(ctx context.Context, annotationID string, imageData []byte, contentType string) (string, error) {
-	// Check if AWS service is available
-	if s.awsService == nil {
-		return "", fmt.Errorf("AWS service not configured")
+// explainCodeExamples asks the LLM for a "Key code examples" section; failures only skip the section
+func (s *AnnotationService) explainCodeExamples(ctx context.Context, blocks []models.CodeBlock, title string) string {
+	var snippets strings.Builder
+	for i, block := range blocks {
+		snippet := fmt.Sprintf("Snippet %d (page %d):\n%s\n\n", i+1, block.Page, block.Code)
+		if snippets.Len()+len(snippet) > maxCodeExamplesInput {
+			break
+		}
+		snippets.WriteString(snippet)
+	}
+	if snippets.Len() == 0 {
+		return ""
 	}
 
-	log.Printf("Uploading image for annotation ID: %s", annotationID)
-
-	// Upload image to S3
-	imageURL, err := s.awsService.UploadImageToS3(imageData, annotationID, contentType)
+	log.Printf("Explaining %d code snippets for: %s", len(blocks), title)
+	examples, err := s.ollamaClient.ExplainCodeExamples(ctx, snippets.String(), title)
 	if err != nil {
-		return "", fmt.Errorf("failed to upload image: %w", err)
+		log.Printf("Warning: failed to explain code examples: %v", err)
+		return ""
 	}
+	return examples
+}
 
-	log.Printf("Image uploaded to S3: %s", imageURL)
+// appendFigureInsights adds a "Figure insights" section to the annotation text
+func appendFigureInsights(annotation string, figures []models.FigureInsight) string {
+	if len(figures) == 0 {
+		return annotation
+	}
 
-	return imageURL, nil
+	var b strings.Builder
+	b.WriteString(annotation)
+	b.WriteString("\n\nFigure insights:\n")
+	for _, figure := range figures {
+		b.WriteString(fmt.Sprintf("- Page %d: %s\n", figure.Page, figure.Description))
+	}
+	return strings.TrimRight(b.String(), "\n")
 }
 
 // extractTextFromStream extracts text content from uploaded file stream
@@ -187,15 +1330,20 @@ func (s *AnnotationService) extractTextFromStream(reader io.Reader, size int64,
 	if parser == nil {
 		return "", fmt.Errorf("unsupported file type: %s", fileType)
 	}
+	// PDFs are parsed in a resource-limited worker process, see PARSE_SANDBOX
+	if _, ok := parser.(*PDFParser); ok && s.sandbox != nil {
+		parser = s.sandbox
+	}
 
 	return parser.ExtractTextFromReader(reader, size)
 }
 
 
-// GetAnnotationByID retrieves an annotation by ID
+// GetAnnotationByID retrieves an annotation by ID. Annotations unpublished by a copyright claim
+// are not found.
 func (s *AnnotationService) GetAnnotationByID(ctx context.Context, annotationID string) (*models.Annotation, error) {
 	var annotation models.Annotation
-	err := s.collection.FindOne(ctx, bson.M{"_id": annotationID}).Decode(&annotation)
+	err := s.collection.FindOne(ctx, bson.M{"_id": annotationID, "taken_down": bson.M{"$ne": true}}).Decode(&annotation)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return nil, fmt.Errorf("annotation not found")
@@ -205,8 +1353,97 @@ func (s *AnnotationService) GetAnnotationByID(ctx context.Context, annotationID
 	return &annotation, nil
 }
 
-// GetAllAnnotations retrieves all annotations (public access)
-func (s *AnnotationService) GetAllAnnotations(ctx context.Context, limit, offset int64) ([]*models.Annotation, error) {
+// SearchAnnotationText searches the extracted source text of an annotation
+func (s *AnnotationService) SearchAnnotationText(ctx context.Context, annotationID, query string, limit int) ([]models.DocumentSearchMatch, error) {
+	annotation, err := s.GetAnnotationByID(ctx, annotationID)
+	if err != nil {
+		return nil, err
+	}
+
+	return searchDocumentText(annotation.TextContent, query, limit), nil
+}
+
+// GetReaderRendition returns the reading-mode HTML of an annotation's source text,
+// building and caching it on first access or when the text has changed
+func (s *AnnotationService) GetReaderRendition(ctx context.Context, annotationID string) (*models.ReaderRendition, error) {
+	annotation, err := s.GetAnnotationByID(ctx, annotationID)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256([]byte(annotation.TextContent))
+	textHash := hex.EncodeToString(sum[:])
+
+	var cached models.ReaderRendition
+	err = s.renditions.FindOne(ctx, bson.M{"_id": annotationID}).Decode(&cached)
+	if err == nil && cached.TextHash == textHash {
+		return &cached, nil
+	}
+	if err != nil && err != mongo.ErrNoDocuments {
+		return nil, fmt.Errorf("failed to load reader rendition: %w", err)
+	}
+
+	rendition := &models.ReaderRendition{
+		AnnotationID: annotationID,
+		TextHash:     textHash,
+		Pages:        renderReaderPages(annotation.TextContent),
+		GeneratedAt:  time.Now(),
+	}
+	if rendition.Pages == nil {
+		rendition.Pages = []models.ReaderPage{}
+	}
+
+	// A failed cache write only costs a re-render next time
+	_, err = s.renditions.ReplaceOne(ctx, bson.M{"_id": annotationID}, rendition, options.Replace().SetUpsert(true))
+	if err != nil {
+		log.Printf("Warning: failed to cache reader rendition for %s: %v", annotationID, err)
+	}
+
+	return rendition, nil
+}
+
+// ExplainSelection asks the LLM to explain a passage in the context of the annotated document
+func (s *AnnotationService) ExplainSelection(ctx context.Context, annotationID, selection string) (string, error) {
+	annotation, err := s.GetAnnotationByID(ctx, annotationID)
+	if err != nil {
+		return "", err
+	}
+
+	log.Printf("Explaining %d character selection for annotation ID: %s", len(selection), annotationID)
+
+	explanation, err := s.ollamaClient.ExplainSelection(ctx, selection, selectionContext(annotation, selection), annotation.Title)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate explanation: %w", err)
+	}
+
+	return explanation, nil
+}
+
+// selectionContext returns the source text around the selection, or the annotation summary if the
+// selection can't be located in the source text
+func selectionContext(annotation *models.Annotation, selection string) string {
+	const contextChars = 3000
+
+	text := annotation.TextContent
+	loc := regexp.MustCompile(`(?i)` + regexp.QuoteMeta(strings.TrimSpace(selection))).FindStringIndex(text)
+	if loc == nil {
+		return annotation.Annotation
+	}
+
+	start := loc[0] - contextChars/2
+	if start < 0 {
+		start = 0
+	}
+	end := loc[1] + contextChars/2
+	if end > len(text) {
+		end = len(text)
+	}
+
+	return strings.ToValidUTF8(text[start:end], "")
+}
+
+// GetAllAnnotations retrieves all annotations matching the filter (public access)
+func (s *AnnotationService) GetAllAnnotations(ctx context.Context, filter models.AnnotationFilter, limit, offset int64) ([]*models.Annotation, error) {
 	opts := options.Find()
 	if limit > 0 {
 		opts.SetLimit(limit)
@@ -214,10 +1451,11 @@ func (s *AnnotationService) GetAllAnnotations(ctx context.Context, limit, offset
 	if offset > 0 {
 		opts.SetSkip(offset)
 	}
-	opts.SetSort(bson.D{{Key: "created_at", Value: -1}})
+	// Pinned annotations first in their set order, the rest newest first
+	opts.SetSort(bson.D{{Key: "pinned", Value: -1}, {Key: "position", Value: 1}, {Key: "created_at", Value: -1}})
 
-	// No user filter - return all annotations
-	cursor, err := s.collection.Find(ctx, bson.M{}, opts)
+	// No user filter - return all annotations except those merged into another
+	cursor, err := s.collection.Find(ctx, annotationQuery(filter), opts)
 	if err != nil {
 		return nil, err
 	}
@@ -231,24 +1469,258 @@ func (s *AnnotationService) GetAllAnnotations(ctx context.Context, limit, offset
 	return annotations, nil
 }
 
+// CountAnnotations returns how many annotations GetAllAnnotations lists for filter in total
+func (s *AnnotationService) CountAnnotations(ctx context.Context, filter models.AnnotationFilter) (int64, error) {
+	return s.collection.CountDocuments(ctx, annotationQuery(filter))
+}
+
+// annotationQuery builds the query for a list filter. Annotations merged into another or taken
+// down for a copyright claim are never listed.
+func annotationQuery(filter models.AnnotationFilter) bson.M {
+	query := bson.M{"status": bson.M{"$ne": "merged"}, "taken_down": bson.M{"$ne": true}}
+	if filter.UserID != "" {
+		query["user_id"] = filter.UserID
+	}
+	if filter.PublicOnly {
+		query["visibility"] = models.VisibilityPublic
+		query["status"] = "completed"
+	}
+	if filter.Tag != "" {
+		query["tags"] = strings.ToLower(strings.TrimSpace(filter.Tag))
+	}
+	if filter.Genre != "" {
+		query["genre"] = bson.M{"$regex": "^" + regexp.QuoteMeta(strings.TrimSpace(filter.Genre)) + "$", "$options": "i"}
+	}
+	if filter.Objective != "" {
+		query["learning_objectives"] = containsPattern(filter.Objective)
+	}
+	if filter.Prerequisite != "" {
+		query["prerequisites"] = containsPattern(filter.Prerequisite)
+	}
+	for key, value := range filter.Metadata {
+		query["metadata."+key] = strings.TrimSpace(value)
+	}
+
+	safety := bson.M{}
+	if filter.SafetyLabel != "" {
+		safety["$eq"] = filter.SafetyLabel
+	}
+	if len(filter.HiddenLabels) > 0 {
+		safety["$nin"] = filter.HiddenLabels
+	}
+	if len(safety) > 0 {
+		query["safety_labels.label"] = safety
+	}
+
+	review := bson.M{}
+	if filter.ReviewState != "" {
+		review["$eq"] = filter.ReviewState
+	}
+	if filter.ReviewedOnly || filter.PublicOnly {
+		review["$nin"] = models.UnreviewedStates
+	}
+	if len(review) > 0 {
+		query["review_state"] = review
+	}
+
+	created := bson.M{}
+	if !filter.CreatedFrom.IsZero() {
+		created["$gte"] = filter.CreatedFrom
+	}
+	if !filter.CreatedTo.IsZero() {
+		created["$lt"] = filter.CreatedTo
+	}
+	if len(created) > 0 {
+		query["created_at"] = created
+	}
+	return query
+}
+
+// containsPattern matches strings containing text, case-insensitively
+func containsPattern(text string) bson.M {
+	return bson.M{"$regex": regexp.QuoteMeta(strings.TrimSpace(text)), "$options": "i"}
+}
+
+// GetTagCounts returns every tag in use with the number of annotations using it
+func (s *AnnotationService) GetTagCounts(ctx context.Context) ([]models.TagCount, error) {
+	pipeline := []bson.M{
+		{"$unwind": "$tags"},
+		{"$group": bson.M{
+			"_id":   "$tags",
+			"count": bson.M{"$sum": 1},
+		}},
+		{"$sort": bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}},
+	}
+
+	cursor, err := s.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	tags := []models.TagCount{}
+	if err := cursor.All(ctx, &tags); err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+// NormalizeTags splits comma-separated values, lowercases and trims tags, and removes duplicates
+func NormalizeTags(raw []string) []string {
+	tags := []string{}
+	seen := make(map[string]bool)
+	for _, value := range raw {
+		for _, tag := range strings.Split(value, ",") {
+			tag = strings.ToLower(strings.TrimSpace(tag))
+			if tag == "" || seen[tag] {
+				continue
+			}
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
 // DeleteAnnotation deletes an annotation (any content creator can delete)
-func (s *AnnotationService) DeleteAnnotation(ctx context.Context, annotationID, userID string) error {
-	// Delete from database (no ownership check - CMS style)
-	result, err := s.collection.DeleteOne(ctx, bson.M{"_id": annotationID})
+func (s *AnnotationService) DeleteAnnotation(ctx context.Context, annotationID string, user *models.User) error {
+	if err := s.CheckOwnership(ctx, annotationID, user); err != nil {
+		return err
+	}
+	return s.deleteAnnotation(ctx, annotationID)
+}
+
+// deleteAnnotation deletes an annotation with its rendition, links, quizzes and stored files
+func (s *AnnotationService) deleteAnnotation(ctx context.Context, annotationID string) error {
+	var annotation models.Annotation
+	err := s.collection.FindOneAndDelete(ctx, bson.M{"_id": annotationID}).Decode(&annotation)
 	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return fmt.Errorf("annotation not found")
+		}
 		return err
 	}
 
-	if result.DeletedCount == 0 {
-		return fmt.Errorf("annotation not found")
+	if _, err := s.renditions.DeleteOne(ctx, bson.M{"_id": annotationID}); err != nil {
+		log.Printf("Warning: failed to delete reader rendition for %s: %v", annotationID, err)
 	}
+	if _, err := s.quizzes.DeleteMany(ctx, bson.M{"annotation_id": annotationID}); err != nil {
+		log.Printf("Warning: failed to delete quizzes of %s: %v", annotationID, err)
+	}
+	s.deleteLinksOf(ctx, annotationID)
+	s.syncSearch(ctx, annotationID)
+	s.purgeCDN(annotationID)
+
+	s.deleteStoredArtifacts(ctx, &annotation)
 
-	// Note: TTS files are in S3. We're keeping them for now.
-	// If you want to delete from S3, extract the key from annotation.TTSURL and call s.awsService.DeleteFromS3(key)
+	return nil
+}
+
+// CheckOwnership reports whether user may update or delete an annotation. Any content creator may
+// (CMS style), unless STRICT_OWNERSHIP restricts it to the owner and admins.
+func (s *AnnotationService) CheckOwnership(ctx context.Context, annotationID string, user *models.User) error {
+	if !s.strictOwner || user.IsAdmin() {
+		return nil
+	}
 
+	var annotation models.Annotation
+	err := s.collection.FindOne(ctx, bson.M{"_id": annotationID}, options.FindOne().SetProjection(bson.M{"user_id": 1})).Decode(&annotation)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return fmt.Errorf("annotation not found")
+		}
+		return err
+	}
+	if annotation.UserID != user.ID {
+		return fmt.Errorf("unauthorized: only the owner or an admin can modify this annotation")
+	}
 	return nil
 }
 
+// DeleteUserAnnotations deletes every annotation created by a user, with their stored files, and
+// returns how many were deleted
+func (s *AnnotationService) DeleteUserAnnotations(ctx context.Context, userID string) (int, error) {
+	cursor, err := s.collection.Find(ctx, bson.M{"user_id": userID}, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return 0, err
+	}
+
+	var annotations []models.Annotation
+	if err := cursor.All(ctx, &annotations); err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for _, annotation := range annotations {
+		if err := s.deleteAnnotation(ctx, annotation.ID); err != nil && err.Error() != "annotation not found" {
+			return deleted, err
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+// deleteStoredArtifacts removes the audio, caption, gallery image, attachment, source and audio tour files of a deleted annotation.
+// Failures are only logged since the record itself is already gone.
+func (s *AnnotationService) deleteStoredArtifacts(ctx context.Context, annotation *models.Annotation) {
+	if s.storage == nil {
+		return
+	}
+
+	// Records created before keys were tracked only have URLs
+	keys := []string{
+		firstNonEmpty(annotation.TTSKey, s.storageKeyFromURL(annotation.TTSURL)),
+		annotation.TTSOpusKey,
+		annotation.TTSMarksKey,
+		firstNonEmpty(annotation.ImageKey, s.storageKeyFromURL(annotation.Image)),
+		annotation.SourceKey,
+	}
+	for _, attachment := range annotation.Attachments {
+		keys = append(keys, attachment.Key)
+	}
+	for _, image := range annotation.Images {
+		if image.Key != annotation.ImageKey {
+			keys = append(keys, image.Key)
+		}
+	}
+	if annotation.AudioTour != nil {
+		keys = append(keys, annotation.AudioTour.Key)
+	}
+	if annotation.Captions != nil {
+		keys = append(keys, annotation.Captions.VTTKey, annotation.Captions.SRTKey)
+	}
+
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+		if err := s.storage.Delete(ctx, key); err != nil {
+			log.Printf("Warning: failed to delete stored file %s for annotation %s: %v", key, annotation.ID, err)
+			continue
+		}
+		log.Printf("Deleted stored file %s for annotation %s", key, annotation.ID)
+	}
+}
+
+// storageKeyFromURL returns the storage key for a URL of a stored file, or "" for external URLs
+func (s *AnnotationService) storageKeyFromURL(url string) string {
+	if s.storage == nil || url == "" {
+		return ""
+	}
+	return s.storage.KeyFromURL(url)
+}
+
+// firstNonEmpty returns the first non-empty string
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
 // GetAnnotationStats returns statistics about annotations
 func (s *AnnotationService) GetAnnotationStats(ctx context.Context, userID string) (map[string]interface{}, error) {
 	pipeline := []bson.M{
@@ -288,6 +1760,15 @@ func (s *AnnotationService) GetAnnotationStats(ctx context.Context, userID strin
 	return stats, nil
 }
 
+// ListTTSVoices returns the voices of the TTS provider, optionally filtered by language
+func (s *AnnotationService) ListTTSVoices(ctx context.Context, languageCode string) ([]models.TTSVoice, error) {
+	if s.tts == nil {
+		return nil, fmt.Errorf("TTS provider not configured")
+	}
+
+	return s.tts.ListVoices(ctx, languageCode)
+}
+
 // CheckServices verifies that required services are available
 func (s *AnnotationService) CheckServices() map[string]interface{} {
 	status := make(map[string]interface{})
@@ -333,5 +1814,76 @@ func (s *AnnotationService) CheckServices() map[string]interface{} {
 		}
 	}
 
+	// Check the storage backend
+	if s.storage != nil {
+		if err := s.storage.TestConnection(); err != nil {
+			status["storage"] = map[string]interface{}{
+				"status":  "Error",
+				"backend": s.storage.Name(),
+				"error":   err.Error(),
+			}
+		} else {
+			status["storage"] = map[string]interface{}{
+				"status":  "OK",
+				"backend": s.storage.Name(),
+			}
+		}
+	} else {
+		status["storage"] = map[string]interface{}{
+			"status": "Not Configured",
+		}
+	}
+
+	// Check the search backend
+	if s.search != nil {
+		if err := s.search.TestConnection(); err != nil {
+			status["search"] = map[string]interface{}{
+				"status":  "Error",
+				"backend": s.search.Name(),
+				"error":   err.Error(),
+			}
+		} else {
+			status["search"] = map[string]interface{}{
+				"status":  "OK",
+				"backend": s.search.Name(),
+			}
+		}
+	} else {
+		status["search"] = map[string]interface{}{
+			"status":  "Not Configured",
+			"backend": "mongodb",
+		}
+	}
+
+	// Check the TTS provider
+	if s.tts != nil {
+		if err := s.tts.TestConnection(); err != nil {
+			status["tts"] = map[string]interface{}{
+				"status":   "Error",
+				"provider": s.tts.Name(),
+				"error":    err.Error(),
+			}
+		} else {
+			status["tts"] = map[string]interface{}{
+				"status":   "OK",
+				"provider": s.tts.Name(),
+			}
+		}
+	} else {
+		status["tts"] = map[string]interface{}{
+			"status": "Not Configured",
+		}
+	}
+
+	// Report whether new uploads are shed
+	load := s.shedder.State()
+	status["uploads"] = map[string]interface{}{
+		"status": "OK",
+		"load":   load,
+	}
+	if load.Shedding {
+		status["uploads"].(map[string]interface{})["status"] = "Shedding"
+	}
+
 	return status
 }
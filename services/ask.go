@@ -0,0 +1,149 @@
+package services
+
+import (
+	"auto-annotation-api/models"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	askChunkTokens = 500 // Size of the excerpts the source text is split into
+	askExcerpts    = 4   // Excerpts given to the LLM per question
+)
+
+// citationPattern matches the excerpt citations of an answer, e.g. [2]
+var citationPattern = regexp.MustCompile(`\[(\d+)\]`)
+
+// askStopWords are left out when matching a question to the source text
+var askStopWords = map[string]bool{
+	"about": true, "and": true, "are": true, "can": true, "does": true, "did": true, "for": true,
+	"from": true, "has": true, "have": true, "how": true, "that": true, "the": true, "their": true,
+	"there": true, "this": true, "was": true, "were": true, "what": true, "when": true, "where": true,
+	"which": true, "who": true, "why": true, "with": true, "would": true, "you": true, "your": true,
+}
+
+// askChunk is an excerpt of the source text and the page it starts on
+type askChunk struct {
+	text  string
+	page  int
+	score float64
+}
+
+// AskDocument answers a question about the source text of an annotation. The text is split into
+// excerpts; the ones sharing the most words with the question are given to the LLM along with the
+// notes, and the excerpts the answer cites are returned with it.
+func (s *AnnotationService) AskDocument(ctx context.Context, annotation *models.Annotation, question string) (*models.DocumentAnswer, error) {
+	question = strings.TrimSpace(question)
+	if question == "" {
+		return nil, errors.New("invalid question: the question is empty")
+	}
+	if strings.TrimSpace(annotation.TextContent) == "" {
+		return nil, errors.New("annotation has no source text")
+	}
+
+	chunks := relevantChunks(annotation.TextContent, question, askExcerpts)
+	excerpts := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		excerpts[i] = chunk.text
+	}
+
+	log.Printf("Answering a question about annotation ID: %s from %d excerpts", annotation.ID, len(excerpts))
+	answer, err := s.ollamaClient.AnswerQuestion(ctx, question, annotation.Title, annotation.Annotation, excerpts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to answer question: %w", err)
+	}
+
+	// Uncited excerpts are left out, unless the answer cites none
+	cited := make(map[int]bool)
+	for _, match := range citationPattern.FindAllStringSubmatch(answer, -1) {
+		if n, err := strconv.Atoi(match[1]); err == nil && n >= 1 && n <= len(chunks) {
+			cited[n-1] = true
+		}
+	}
+	result := &models.DocumentAnswer{Question: question, Answer: answer, Excerpts: []models.AnswerExcerpt{}}
+	for i, chunk := range chunks {
+		if len(cited) == 0 || cited[i] {
+			result.Excerpts = append(result.Excerpts, models.AnswerExcerpt{Page: chunk.page, Text: chunk.text})
+		}
+	}
+	return result, nil
+}
+
+// relevantChunks splits text into excerpts and returns the limit ones sharing the most words with
+// the question, weighted by how rare the words are in the document, in document order. Documents
+// short enough are returned whole.
+func relevantChunks(text, question string, limit int) []askChunk {
+	pageAt := pageLocator(text)
+	var chunks []askChunk
+	offset := 0
+	for _, chunk := range splitTextIntoChunks(text, askChunkTokens) {
+		if i := strings.Index(text[offset:], chunk); i >= 0 {
+			offset += i
+		}
+		clean := strings.Join(strings.Fields(pageMarkerPattern.ReplaceAllString(chunk, " ")), " ")
+		if clean != "" {
+			chunks = append(chunks, askChunk{text: clean, page: pageAt(offset)})
+		}
+	}
+	if len(chunks) <= limit {
+		return chunks
+	}
+
+	terms := questionTerms(question)
+	counts := make([]map[string]int, len(chunks))
+	documentFrequency := make(map[string]int)
+	for i, chunk := range chunks {
+		counts[i] = make(map[string]int)
+		lower := strings.ToLower(chunk.text)
+		for _, term := range terms {
+			if n := strings.Count(lower, term); n > 0 {
+				counts[i][term] = n
+				documentFrequency[term]++
+			}
+		}
+	}
+	for i := range chunks {
+		for term, n := range counts[i] {
+			idf := math.Log(float64(len(chunks)) / float64(documentFrequency[term]))
+			chunks[i].score += (1 + math.Log(float64(n))) * (idf + 0.1)
+		}
+	}
+
+	order := make([]int, len(chunks))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return chunks[order[a]].score > chunks[order[b]].score })
+	selected := order[:limit]
+	sort.Ints(selected)
+
+	relevant := make([]askChunk, len(selected))
+	for i, index := range selected {
+		relevant[i] = chunks[index]
+	}
+	return relevant
+}
+
+// questionTerms returns the distinct lowercase words of a question worth looking for
+func questionTerms(question string) []string {
+	seen := make(map[string]bool)
+	var terms []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(question), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len([]rune(word)) < 3 || askStopWords[word] || seen[word] {
+			continue
+		}
+		seen[word] = true
+		terms = append(terms, word)
+	}
+	return terms
+}
@@ -0,0 +1,156 @@
+package services
+
+import (
+	"auto-annotation-api/models"
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	MaxAttachmentBytes        = 50 << 20 // Upload limit per attachment, also enforced by the handler while reading
+	maxAttachmentsPerDocument = 20
+	attachmentURLExpiry       = 15 * time.Minute
+)
+
+// attachmentContentTypes maps the accepted attachment extensions to their content types
+var attachmentContentTypes = map[string]string{
+	".pdf":  "application/pdf",
+	".txt":  "text/plain",
+	".md":   "text/markdown",
+	".csv":  "text/csv",
+	".json": "application/json",
+	".zip":  "application/zip",
+	".doc":  "application/msword",
+	".docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	".ppt":  "application/vnd.ms-powerpoint",
+	".pptx": "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+	".xls":  "application/vnd.ms-excel",
+	".xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	".odt":  "application/vnd.oasis.opendocument.text",
+	".odp":  "application/vnd.oasis.opendocument.presentation",
+	".ods":  "application/vnd.oasis.opendocument.spreadsheet",
+	".png":  "image/png",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+}
+
+// AddAttachment stores a supplementary file and attaches it to an annotation
+func (s *AnnotationService) AddAttachment(ctx context.Context, annotationID, userID, fileName string, data []byte) (*models.Annotation, error) {
+	if s.storage == nil {
+		return nil, fmt.Errorf("storage not configured")
+	}
+
+	ext := strings.ToLower(filepath.Ext(fileName))
+	contentType, ok := attachmentContentTypes[ext]
+	if !ok {
+		return nil, fmt.Errorf("unsupported attachment type %q", ext)
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("file is empty")
+	}
+	if len(data) > MaxAttachmentBytes {
+		return nil, fmt.Errorf("attachment is larger than %d MB", MaxAttachmentBytes>>20)
+	}
+
+	annotation, err := s.GetAnnotationByID(ctx, annotationID)
+	if err != nil {
+		return nil, err
+	}
+	if len(annotation.Attachments) >= maxAttachmentsPerDocument {
+		return nil, fmt.Errorf("attachment limit reached: at most %d attachments per annotation", maxAttachmentsPerDocument)
+	}
+
+	attachment := models.Attachment{
+		ID:          uuid.New().String(),
+		Name:        filepath.Base(fileName),
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		UploadedBy:  userID,
+		CreatedAt:   time.Now(),
+	}
+	attachment.Key = fmt.Sprintf("attachments/%s/%s%s", annotationID, attachment.ID, ext)
+
+	if _, err := s.storage.Put(ctx, attachment.Key, data, contentType); err != nil {
+		return nil, fmt.Errorf("failed to upload attachment: %w", err)
+	}
+
+	update := bson.M{
+		"$push": bson.M{"attachments": attachment},
+		"$set":  bson.M{"updated_at": time.Now()},
+	}
+	if _, err := s.collection.UpdateOne(ctx, bson.M{"_id": annotationID}, update); err != nil {
+		s.deleteStoredFile(ctx, attachment.Key)
+		return nil, fmt.Errorf("failed to update annotation: %w", err)
+	}
+	s.purgeCDN(annotationID)
+
+	log.Printf("Attachment %s (%d bytes) added to annotation %s", attachment.Name, attachment.Size, annotationID)
+	return s.GetAnnotationByID(ctx, annotationID)
+}
+
+// AttachmentDownloadURL returns a signed URL for an attachment
+func (s *AnnotationService) AttachmentDownloadURL(ctx context.Context, annotationID, attachmentID string) (string, error) {
+	if s.storage == nil {
+		return "", fmt.Errorf("storage not configured")
+	}
+
+	annotation, err := s.GetAnnotationByID(ctx, annotationID)
+	if err != nil {
+		return "", err
+	}
+
+	for _, attachment := range annotation.Attachments {
+		if attachment.ID == attachmentID {
+			return s.storage.SignedURL(ctx, attachment.Key, attachmentURLExpiry)
+		}
+	}
+	return "", fmt.Errorf("attachment not found")
+}
+
+// RemoveAttachment detaches a file from an annotation and deletes it from storage
+func (s *AnnotationService) RemoveAttachment(ctx context.Context, annotationID, attachmentID string) (*models.Annotation, error) {
+	annotation, err := s.GetAnnotationByID(ctx, annotationID)
+	if err != nil {
+		return nil, err
+	}
+
+	var removed *models.Attachment
+	for i := range annotation.Attachments {
+		if annotation.Attachments[i].ID == attachmentID {
+			removed = &annotation.Attachments[i]
+			break
+		}
+	}
+	if removed == nil {
+		return nil, fmt.Errorf("attachment not found")
+	}
+
+	update := bson.M{
+		"$pull": bson.M{"attachments": bson.M{"id": attachmentID}},
+		"$set":  bson.M{"updated_at": time.Now()},
+	}
+	if _, err := s.collection.UpdateOne(ctx, bson.M{"_id": annotationID}, update); err != nil {
+		return nil, fmt.Errorf("failed to update annotation: %w", err)
+	}
+	s.purgeCDN(annotationID)
+
+	s.deleteStoredFile(ctx, removed.Key)
+	return s.GetAnnotationByID(ctx, annotationID)
+}
+
+// deleteStoredFile removes a file from storage, logging failures
+func (s *AnnotationService) deleteStoredFile(ctx context.Context, key string) {
+	if s.storage == nil || key == "" {
+		return
+	}
+	if err := s.storage.Delete(ctx, key); err != nil {
+		log.Printf("Warning: failed to delete stored file %s: %v", key, err)
+	}
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"auto-annotation-api/models"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	maxSourceURLLength = 2048
+	maxAuthorLength    = 300
+)
+
+// publicationDateLayouts are the accepted precisions of a publication date, since many documents
+// only state the year or month they were published
+var publicationDateLayouts = []string{"2006-01-02", "2006-01", "2006"}
+
+// normalizeSourceURL validates the address a document was originally published at. Empty clears it.
+func normalizeSourceURL(sourceURL string) (string, error) {
+	sourceURL = strings.TrimSpace(sourceURL)
+	if sourceURL == "" {
+		return "", nil
+	}
+	if len(sourceURL) > maxSourceURLLength {
+		return "", fmt.Errorf("invalid source_url: longer than %d characters", maxSourceURLLength)
+	}
+	parsed, err := url.Parse(sourceURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return "", fmt.Errorf("invalid source_url %q, must be an http or https URL", sourceURL)
+	}
+	return parsed.String(), nil
+}
+
+// normalizeAuthor collapses whitespace in an author credit, e.g. "Ada Lovelace, Charles Babbage"
+func normalizeAuthor(author string) (string, error) {
+	author = strings.Join(strings.Fields(author), " ")
+	if utf8.RuneCountInString(author) > maxAuthorLength {
+		return "", fmt.Errorf("invalid author: longer than %d characters", maxAuthorLength)
+	}
+	return author, nil
+}
+
+// normalizePublicationDate validates a publication date of the form YYYY-MM-DD, YYYY-MM or YYYY.
+// Empty clears it.
+func normalizePublicationDate(date string) (string, error) {
+	date = strings.TrimSpace(date)
+	if date == "" {
+		return "", nil
+	}
+	for _, layout := range publicationDateLayouts {
+		if len(date) != len(layout) {
+			continue
+		}
+		if parsed, err := time.Parse(layout, date); err == nil {
+			if parsed.After(time.Now()) {
+				return "", fmt.Errorf("invalid publication_date %q, must not be in the future", date)
+			}
+			return date, nil
+		}
+	}
+	return "", fmt.Errorf("invalid publication_date %q, must be YYYY-MM-DD, YYYY-MM or YYYY", date)
+}
+
+// setAttribution validates the source attribution of a new annotation. Books looked up by ISBN
+// credit their authors and publication date unless the uploader gave them.
+func setAttribution(annotation *models.Annotation, req *models.CreateAnnotationRequest) error {
+	var err error
+	if annotation.SourceURL, err = normalizeSourceURL(req.SourceURL); err != nil {
+		return err
+	}
+	if annotation.Author, err = normalizeAuthor(req.Author); err != nil {
+		return err
+	}
+	if annotation.PublishedOn, err = normalizePublicationDate(req.PublishedOn); err != nil {
+		return err
+	}
+
+	if book := annotation.Book; book != nil {
+		if annotation.Author == "" {
+			annotation.Author, _ = normalizeAuthor(strings.Join(book.Authors, ", "))
+		}
+		if annotation.PublishedOn == "" {
+			annotation.PublishedOn = bookPublicationDate(book.PublishDate)
+		}
+	}
+	return nil
+}
+
+// bookPublicationDate reads the publication date of a book lookup, which may be free text such as
+// "March 1998", keeping only the year in that case
+func bookPublicationDate(publishDate string) string {
+	if date, err := normalizePublicationDate(publishDate); err == nil {
+		return date
+	}
+	for _, field := range strings.FieldsFunc(publishDate, func(r rune) bool { return r < '0' || r > '9' }) {
+		if len(field) == 4 {
+			if date, err := normalizePublicationDate(field); err == nil {
+				return date
+			}
+		}
+	}
+	return ""
+}
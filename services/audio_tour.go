@@ -0,0 +1,254 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+const (
+	maxTourSections    = 8    // Keeps the tour around five minutes
+	minTourSectionText = 200  // Sections shorter than this are treated as noise
+	maxTourSectionText = 8000 // Characters of section text sent to the LLM
+)
+
+// tourSection is a titled section of a document's text
+type tourSection struct {
+	Title string
+	Text  string
+}
+
+// extractTourSections splits document text into sections using the detected headings,
+// falling back to equally sized parts when the document has no usable outline
+func extractTourSections(text string) []tourSection {
+	lines := strings.Split(text, "\n")
+
+	// Running headers repeat on every page, they are not part of the outline
+	headingCounts := make(map[string]int)
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line != "" && isHeading(line) {
+			headingCounts[line]++
+		}
+	}
+
+	var sections []tourSection
+	current := tourSection{Title: "Introduction"}
+	var body strings.Builder
+	flush := func() {
+		current.Text = strings.Join(strings.Fields(body.String()), " ")
+		if len(current.Text) >= minTourSectionText {
+			sections = append(sections, current)
+		}
+		body.Reset()
+	}
+
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || pageMarkerPattern.MatchString(line) {
+			continue
+		}
+
+		if isHeading(line) {
+			if headingCounts[line] > 2 {
+				continue
+			}
+			flush()
+			current = tourSection{Title: line}
+			continue
+		}
+
+		body.WriteString(line)
+		body.WriteString(" ")
+	}
+	flush()
+
+	if len(sections) == 0 {
+		return splitIntoParts(text)
+	}
+	return mergeSections(sections, maxTourSections)
+}
+
+// splitIntoParts divides text without headings into equally sized parts
+func splitIntoParts(text string) []tourSection {
+	text = pageMarkerPattern.ReplaceAllString(text, " ")
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return nil
+	}
+
+	parts := maxTourSections
+	if len(words) < parts*50 {
+		parts = len(words)/50 + 1
+	}
+
+	size := (len(words) + parts - 1) / parts
+	var sections []tourSection
+	for start := 0; start < len(words); start += size {
+		end := min(start+size, len(words))
+		sections = append(sections, tourSection{
+			Title: "Part " + strconv.Itoa(len(sections)+1),
+			Text:  strings.Join(words[start:end], " "),
+		})
+	}
+	return sections
+}
+
+// mergeSections combines adjacent sections until at most limit remain
+func mergeSections(sections []tourSection, limit int) []tourSection {
+	if len(sections) <= limit {
+		return sections
+	}
+
+	merged := make([]tourSection, 0, limit)
+	for i := 0; i < limit; i++ {
+		from := i * len(sections) / limit
+		to := (i + 1) * len(sections) / limit
+
+		group := tourSection{Title: sections[from].Title}
+		texts := make([]string, 0, to-from)
+		for _, section := range sections[from:to] {
+			texts = append(texts, section.Text)
+		}
+		group.Text = strings.Join(texts, " ")
+		merged = append(merged, group)
+	}
+	return merged
+}
+
+// tourChapterMark is the position of a chapter within the stitched MP3
+type tourChapterMark struct {
+	Title   string
+	StartMs int64
+	EndMs   int64
+}
+
+// mp3Bitrates holds the layer III bitrates in kbit/s for MPEG-1 and MPEG-2/2.5
+var mp3Bitrates = [2][16]int{
+	{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 0},
+	{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0},
+}
+
+// mp3SampleRates holds the sample rates for MPEG-1, MPEG-2 and MPEG-2.5
+var mp3SampleRates = [3][3]int{
+	{44100, 48000, 32000},
+	{22050, 24000, 16000},
+	{11025, 12000, 8000},
+}
+
+// stripID3 removes a leading ID3v2 tag from MP3 data
+func stripID3(data []byte) []byte {
+	if len(data) < 10 || string(data[:3]) != "ID3" {
+		return data
+	}
+
+	size := int(data[6])<<21 | int(data[7])<<14 | int(data[8])<<7 | int(data[9])
+	size += 10
+	if data[5]&0x10 != 0 {
+		size += 10 // Footer present
+	}
+	if size > len(data) {
+		return nil
+	}
+	return data[size:]
+}
+
+// mp3DurationMs returns the playing time of MPEG layer III audio by walking its frame headers
+func mp3DurationMs(data []byte) int64 {
+	var samples float64
+	for i := 0; i+4 <= len(data); {
+		if data[i] != 0xFF || data[i+1]&0xE0 != 0xE0 {
+			i++
+			continue
+		}
+
+		versionBits := (data[i+1] >> 3) & 0x03
+		layerBits := (data[i+1] >> 1) & 0x03
+		bitrateIndex := data[i+2] >> 4
+		sampleRateIndex := (data[i+2] >> 2) & 0x03
+		padding := int((data[i+2] >> 1) & 0x01)
+		if versionBits == 1 || layerBits != 1 || sampleRateIndex == 3 {
+			i++
+			continue
+		}
+
+		version := 0 // MPEG-1
+		switch versionBits {
+		case 2:
+			version = 1 // MPEG-2
+		case 0:
+			version = 2 // MPEG-2.5
+		}
+
+		table := 0
+		if version > 0 {
+			table = 1
+		}
+		bitrate := mp3Bitrates[table][bitrateIndex] * 1000
+		sampleRate := mp3SampleRates[version][sampleRateIndex]
+		if bitrate == 0 {
+			i++
+			continue
+		}
+
+		samplesPerFrame, coefficient := 1152, 144
+		if version > 0 {
+			samplesPerFrame, coefficient = 576, 72
+		}
+
+		frameLength := coefficient*bitrate/sampleRate + padding
+		samples += float64(samplesPerFrame) / float64(sampleRate)
+		i += frameLength
+	}
+	return int64(samples * 1000)
+}
+
+// buildChapterTag builds an ID3v2.3 tag with a table of contents and one CHAP frame per chapter
+func buildChapterTag(title string, chapters []tourChapterMark) []byte {
+	var frames bytes.Buffer
+	frames.Write(id3Frame("TIT2", id3Text(title)))
+
+	var toc bytes.Buffer
+	toc.WriteString("toc\x00")
+	toc.WriteByte(0x03) // Top-level, ordered
+	toc.WriteByte(byte(len(chapters)))
+	for i := range chapters {
+		toc.WriteString("chp" + strconv.Itoa(i) + "\x00")
+	}
+	frames.Write(id3Frame("CTOC", toc.Bytes()))
+
+	for i, chapter := range chapters {
+		var chap bytes.Buffer
+		chap.WriteString("chp" + strconv.Itoa(i) + "\x00")
+		binary.Write(&chap, binary.BigEndian, uint32(chapter.StartMs))
+		binary.Write(&chap, binary.BigEndian, uint32(chapter.EndMs))
+		binary.Write(&chap, binary.BigEndian, uint32(0xFFFFFFFF)) // Byte offsets unused
+		binary.Write(&chap, binary.BigEndian, uint32(0xFFFFFFFF))
+		chap.Write(id3Frame("TIT2", id3Text(chapter.Title)))
+		frames.Write(id3Frame("CHAP", chap.Bytes()))
+	}
+
+	size := frames.Len()
+	header := []byte{'I', 'D', '3', 0x03, 0x00, 0x00,
+		byte(size >> 21 & 0x7F), byte(size >> 14 & 0x7F), byte(size >> 7 & 0x7F), byte(size & 0x7F)}
+	return append(header, frames.Bytes()...)
+}
+
+// id3Frame encodes an ID3v2.3 frame
+func id3Frame(id string, body []byte) []byte {
+	frame := make([]byte, 10, 10+len(body))
+	copy(frame, id)
+	binary.BigEndian.PutUint32(frame[4:8], uint32(len(body)))
+	return append(frame, body...)
+}
+
+// id3Text encodes a text frame body as UTF-16 with a byte order mark
+func id3Text(text string) []byte {
+	body := []byte{0x01, 0xFF, 0xFE}
+	for _, unit := range utf16.Encode([]rune(text)) {
+		body = append(body, byte(unit), byte(unit>>8))
+	}
+	return append(body, 0x00, 0x00)
+}
@@ -5,20 +5,25 @@ import (
 	"auto-annotation-api/utils"
 	"context"
 	"errors"
+	"strings"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type AuthService struct {
-	collection *mongo.Collection
+	collection    *mongo.Collection
+	revokedTokens *mongo.Collection
 }
 
 // NewAuthService creates a new auth service
 func NewAuthService(db *mongo.Database) *AuthService {
 	return &AuthService{
-		collection: db.Collection("users"),
+		collection:    db.Collection("users"),
+		revokedTokens: db.Collection("revoked_tokens"),
 	}
 }
 
@@ -48,8 +53,11 @@ func (s *AuthService) Register(ctx context.Context, req models.RegisterRequest)
 		user = models.NewUser(req.Email, string(hashedPassword), req.Name) // Default to "basic"
 	}
 
-	// Insert user into database
+	// Insert user into database; the unique email index catches registrations racing the check above
 	_, err = s.collection.InsertOne(ctx, user)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, errors.New("user with this email already exists")
+	}
 	if err != nil {
 		return nil, errors.New("failed to create user")
 	}
@@ -84,6 +92,11 @@ func (s *AuthService) Login(ctx context.Context, req models.LoginRequest) (*mode
 		return nil, errors.New("invalid email or password")
 	}
 
+	// Disabled accounts are kept but cannot log in
+	if user.Disabled {
+		return nil, errors.New("account is disabled")
+	}
+
 	// Generate JWT token
 	token, err := utils.GenerateToken(&user)
 	if err != nil {
@@ -122,7 +135,112 @@ func (s *AuthService) GetUserByEmail(ctx context.Context, email string) (*models
 	return &user, nil
 }
 
-// isValidRole checks if the provided role is valid
+// UpdateProfile changes a user's own name or password. The password is only changed when the
+// current password is confirmed.
+func (s *AuthService) UpdateProfile(ctx context.Context, user *models.User, req models.UpdateProfileRequest) (*models.User, error) {
+	fields := bson.M{"updated_at": time.Now()}
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			return nil, errors.New("name cannot be empty")
+		}
+		fields["name"] = name
+	}
+	if req.NewPassword != nil {
+		if s.CheckPassword(user, req.CurrentPassword) != nil {
+			return nil, errors.New("current password is incorrect")
+		}
+		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(*req.NewPassword), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, errors.New("failed to hash password")
+		}
+		fields["password"] = string(hashedPassword)
+	}
+
+	var updated models.User
+	err := s.collection.FindOneAndUpdate(ctx, bson.M{"_id": user.ID}, bson.M{"$set": fields},
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&updated)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("user not found")
+		}
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// CheckPassword confirms a user's password before a sensitive change
+func (s *AuthService) CheckPassword(user *models.User, password string) error {
+	if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)) != nil {
+		return errors.New("password is incorrect")
+	}
+	return nil
+}
+
+// DeleteAccount removes a user's account. With anonymize, a disabled record without name, email or
+// password is kept instead, so references to the user remain valid.
+func (s *AuthService) DeleteAccount(ctx context.Context, userID string, anonymize bool) error {
+	if anonymize {
+		_, err := s.collection.UpdateOne(ctx, bson.M{"_id": userID}, bson.M{"$set": bson.M{
+			"email":      "deleted-" + userID + "@deleted.invalid",
+			"name":       "Deleted user",
+			"password":   "",
+			"disabled":   true,
+			"updated_at": time.Now(),
+		}})
+		if err != nil {
+			return errors.New("failed to anonymize user")
+		}
+		return nil
+	}
+
+	if _, err := s.collection.DeleteOne(ctx, bson.M{"_id": userID}); err != nil {
+		return errors.New("failed to delete user")
+	}
+	return nil
+}
+
+// RevokeToken adds a token to the denylist until it expires
+func (s *AuthService) RevokeToken(ctx context.Context, claims *models.JWTClaims) error {
+	if claims.ID == "" {
+		return errors.New("token cannot be revoked")
+	}
+
+	expiresAt := time.Now().Add(24 * time.Hour)
+	if claims.ExpiresAt != nil {
+		expiresAt = claims.ExpiresAt.Time
+	}
+
+	revoked := models.RevokedToken{
+		TokenID:   claims.ID,
+		UserID:    claims.UserID,
+		ExpiresAt: expiresAt,
+		RevokedAt: time.Now(),
+	}
+
+	// Upsert so that logging out twice with the same token is not an error
+	_, err := s.revokedTokens.ReplaceOne(ctx, bson.M{"_id": claims.ID}, revoked, options.Replace().SetUpsert(true))
+	if err != nil {
+		return errors.New("failed to revoke token")
+	}
+	return nil
+}
+
+// IsTokenRevoked checks whether a token ID is on the denylist
+func (s *AuthService) IsTokenRevoked(ctx context.Context, tokenID string) (bool, error) {
+	if tokenID == "" {
+		return false, nil
+	}
+
+	count, err := s.revokedTokens.CountDocuments(ctx, bson.M{"_id": tokenID}, options.Count().SetLimit(1))
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// isValidRole checks if the provided role can be chosen at registration. The admin role is only granted
+// by other admins or ADMIN_EMAIL.
 func isValidRole(role string) bool {
 	validRoles := []string{"basic", "content"}
 	for _, validRole := range validRoles {
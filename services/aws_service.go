@@ -1,11 +1,13 @@
 package services
 
 import (
-	"bytes"
+	"auto-annotation-api/models"
+	"auto-annotation-api/utils"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
-	"time"
+	"sort"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
@@ -15,7 +17,8 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 )
 
-// AWSService handles AWS operations (S3 and Polly)
+// AWSService handles AWS operations (S3 and Polly). Storage goes through StorageBackend, which uses
+// the S3 client for the s3 backend.
 type AWSService struct {
 	s3Client     *s3.Client
 	pollyClient  *polly.Client
@@ -34,6 +37,7 @@ func NewAWSService(accessKeyID, secretKey, region, bucketName, voiceID, engine s
 			secretKey,
 			"",
 		)),
+		config.WithAPIOptions(utils.AWSTracingOptions()),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("unable to load AWS config: %w", err)
@@ -57,26 +61,33 @@ func NewAWSService(accessKeyID, secretKey, region, bucketName, voiceID, engine s
 }
 
 // GenerateTTS generates TTS audio using AWS Polly and returns audio data
-func (a *AWSService) GenerateTTS(text string) ([]byte, error) {
-	// Determine engine type
-	var engineType pollyTypes.Engine
-	if a.pollyEngine == "neural" {
-		engineType = pollyTypes.EngineNeural
-	} else {
-		engineType = pollyTypes.EngineStandard
-	}
+func (a *AWSService) GenerateTTS(ctx context.Context, text string) ([]byte, error) {
+	return a.GenerateTTSWithVoice(ctx, text, "", "")
+}
 
-	// Create Polly input
-	input := &polly.SynthesizeSpeechInput{
-		Text:         aws.String(text),
-		OutputFormat: pollyTypes.OutputFormatMp3,
-		VoiceId:      pollyTypes.VoiceId(a.pollyVoiceID),
-		Engine:       engineType,
-		TextType:     pollyTypes.TextTypeText,
-	}
+// GenerateTTSWithVoice generates TTS audio with a specific voice and engine (e.g. a custom brand voice).
+// Empty values fall back to the configured voice and engine.
+func (a *AWSService) GenerateTTSWithVoice(ctx context.Context, text, voiceID, engine string) ([]byte, error) {
+	return a.synthesize(ctx, text, voiceID, engine, pollyTypes.OutputFormatMp3)
+}
+
+// maxPollyTextChars is Polly's limit on billed characters per SynthesizeSpeech request
+const maxPollyTextChars = 3000
+
+// synthesize calls Polly and returns the audio in the requested output format. Text over Polly's
+// length limit is synthesized in chunks and the audio is concatenated.
+func (a *AWSService) synthesize(ctx context.Context, text, voiceID, engine string, format pollyTypes.OutputFormat) ([]byte, error) {
+	return synthesizeInChunks(text, maxPollyTextChars, format == pollyTypes.OutputFormatMp3, func(chunk string) ([]byte, error) {
+		return a.synthesizeChunk(ctx, chunk, voiceID, engine, format)
+	})
+}
+
+// synthesizeChunk calls Polly for text within its length limit
+func (a *AWSService) synthesizeChunk(ctx context.Context, text, voiceID, engine string, format pollyTypes.OutputFormat) ([]byte, error) {
+	input := a.speechInput(text, voiceID, engine, format)
 
 	// Call Polly API
-	result, err := a.pollyClient.SynthesizeSpeech(context.TODO(), input)
+	result, err := a.pollyClient.SynthesizeSpeech(ctx, input)
 	if err != nil {
 		return nil, fmt.Errorf("failed to synthesize speech: %w", err)
 	}
@@ -91,83 +102,142 @@ func (a *AWSService) GenerateTTS(text string) ([]byte, error) {
 	return audioData, nil
 }
 
-// UploadToS3 uploads data to S3 and returns the public URL
-func (a *AWSService) UploadToS3(key string, data []byte, contentType string) (string, error) {
-	// Upload to S3 (public access controlled by bucket policy, not ACL)
-	_, err := a.s3Client.PutObject(context.TODO(), &s3.PutObjectInput{
-		Bucket:      aws.String(a.bucketName),
-		Key:         aws.String(key),
-		Body:        bytes.NewReader(data),
-		ContentType: aws.String(contentType),
-	})
-	if err != nil {
-		return "", fmt.Errorf("failed to upload to S3: %w", err)
+// speechInput builds a Polly request, falling back to the configured voice and engine
+func (a *AWSService) speechInput(text, voiceID, engine string, format pollyTypes.OutputFormat) *polly.SynthesizeSpeechInput {
+	if voiceID == "" {
+		voiceID = a.pollyVoiceID
+	}
+	if engine == "" {
+		engine = a.pollyEngine
 	}
 
-	// Generate the S3 URL
-	url := fmt.Sprintf("https://%s.s3.amazonaws.com/%s", a.bucketName, key)
-	return url, nil
-}
+	// Determine engine type
+	engineType := pollyTypes.Engine(engine)
+	if engine != "neural" && engine != "long-form" && engine != "generative" {
+		engineType = pollyTypes.EngineStandard
+	}
 
-// GenerateAndUploadTTS generates TTS and uploads to S3, returning the URL
-func (a *AWSService) GenerateAndUploadTTS(text, annotationID string) (string, error) {
-	// Generate TTS
-	audioData, err := a.GenerateTTS(text)
-	if err != nil {
-		return "", err
+	return &polly.SynthesizeSpeechInput{
+		Text:         aws.String(text),
+		OutputFormat: format,
+		VoiceId:      pollyTypes.VoiceId(voiceID),
+		Engine:       engineType,
+		TextType:     pollyTypes.TextTypeText,
 	}
+}
 
-	// Create S3 key with timestamp to ensure uniqueness
-	timestamp := time.Now().Unix()
-	key := fmt.Sprintf("tts/%s_%d.mp3", annotationID, timestamp)
+// SpeechMark is a Polly speech mark: when a sentence or word starts in the audio
+type SpeechMark struct {
+	Time  int64  `json:"time"`  // Milliseconds from the start of the audio
+	Type  string `json:"type"`  // "sentence" or "word"
+	Start int    `json:"start"` // Byte offsets into the input text
+	End   int    `json:"end"`
+	Value string `json:"value"`
+}
 
-	// Upload to S3
-	url, err := a.UploadToS3(key, audioData, "audio/mpeg")
-	if err != nil {
-		return "", err
+// GenerateSpeechMarks returns the sentence and word timings Polly uses for the given text and voice.
+// For text over Polly's length limit, each chunk's marks are shifted by the duration of the audio
+// before it, which costs an extra MP3 synthesis of every chunk but the last.
+func (a *AWSService) GenerateSpeechMarks(ctx context.Context, text, voiceID, engine string) ([]SpeechMark, error) {
+	chunks := splitSpeechText(text, maxPollyTextChars)
+
+	var marks []SpeechMark
+	var elapsedMs int64
+	for i, chunk := range chunks {
+		chunkMarks, err := a.speechMarksForChunk(ctx, chunk.Text, voiceID, engine)
+		if err != nil {
+			return nil, err
+		}
+		for _, mark := range chunkMarks {
+			mark.Time += elapsedMs
+			mark.Start += chunk.Offset
+			mark.End += chunk.Offset
+			marks = append(marks, mark)
+		}
+
+		if i < len(chunks)-1 {
+			audio, err := a.synthesizeChunk(ctx, chunk.Text, voiceID, engine, pollyTypes.OutputFormatMp3)
+			if err != nil {
+				return nil, err
+			}
+			elapsedMs += mp3DurationMs(audio)
+		}
 	}
 
-	return url, nil
+	return marks, nil
 }
 
-// UploadImageToS3 uploads an image to S3 and returns the URL
-func (a *AWSService) UploadImageToS3(imageData []byte, annotationID, contentType string) (string, error) {
-	// Determine file extension from content type
-	ext := ".jpg"
-	switch contentType {
-	case "image/png":
-		ext = ".png"
-	case "image/jpeg", "image/jpg":
-		ext = ".jpg"
-	case "image/gif":
-		ext = ".gif"
-	case "image/webp":
-		ext = ".webp"
-	}
-
-	// Create S3 key with timestamp to ensure uniqueness
-	timestamp := time.Now().Unix()
-	key := fmt.Sprintf("images/%s_%d%s", annotationID, timestamp, ext)
-
-	// Upload to S3
-	url, err := a.UploadToS3(key, imageData, contentType)
+// speechMarksForChunk calls Polly for the speech marks of text within its length limit
+func (a *AWSService) speechMarksForChunk(ctx context.Context, text, voiceID, engine string) ([]SpeechMark, error) {
+	input := a.speechInput(text, voiceID, engine, pollyTypes.OutputFormatJson)
+	input.SpeechMarkTypes = []pollyTypes.SpeechMarkType{pollyTypes.SpeechMarkTypeSentence, pollyTypes.SpeechMarkTypeWord}
+
+	result, err := a.pollyClient.SynthesizeSpeech(ctx, input)
 	if err != nil {
-		return "", err
+		return nil, fmt.Errorf("failed to generate speech marks: %w", err)
+	}
+	defer result.AudioStream.Close()
+
+	// Speech marks are returned as one JSON object per line
+	var marks []SpeechMark
+	decoder := json.NewDecoder(result.AudioStream)
+	for decoder.More() {
+		var mark SpeechMark
+		if err := decoder.Decode(&mark); err != nil {
+			return nil, fmt.Errorf("failed to parse speech marks: %w", err)
+		}
+		marks = append(marks, mark)
 	}
 
-	return url, nil
+	return marks, nil
 }
 
-// DeleteFromS3 deletes a file from S3
-func (a *AWSService) DeleteFromS3(key string) error {
-	_, err := a.s3Client.DeleteObject(context.TODO(), &s3.DeleteObjectInput{
-		Bucket: aws.String(a.bucketName),
-		Key:    aws.String(key),
-	})
-	if err != nil {
-		return fmt.Errorf("failed to delete from S3: %w", err)
+// ListVoices returns the available Polly voices, optionally limited to a language such as "en-US"
+func (a *AWSService) ListVoices(ctx context.Context, languageCode string) ([]models.TTSVoice, error) {
+	input := &polly.DescribeVoicesInput{
+		LanguageCode:                   pollyTypes.LanguageCode(languageCode),
+		IncludeAdditionalLanguageCodes: languageCode != "",
 	}
-	return nil
+
+	voices := []models.TTSVoice{}
+	for {
+		result, err := a.pollyClient.DescribeVoices(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe voices: %w", err)
+		}
+
+		for _, v := range result.Voices {
+			voice := models.TTSVoice{
+				ID:               string(v.Id),
+				Name:             aws.ToString(v.Name),
+				Gender:           string(v.Gender),
+				LanguageCode:     string(v.LanguageCode),
+				LanguageName:     aws.ToString(v.LanguageName),
+				SupportedEngines: make([]string, 0, len(v.SupportedEngines)),
+			}
+			for _, code := range v.AdditionalLanguageCodes {
+				voice.AdditionalLanguageCodes = append(voice.AdditionalLanguageCodes, string(code))
+			}
+			for _, engine := range v.SupportedEngines {
+				voice.SupportedEngines = append(voice.SupportedEngines, string(engine))
+			}
+			voices = append(voices, voice)
+		}
+
+		if result.NextToken == nil {
+			break
+		}
+		input.NextToken = result.NextToken
+	}
+
+	sort.Slice(voices, func(i, j int) bool {
+		if voices[i].LanguageCode != voices[j].LanguageCode {
+			return voices[i].LanguageCode < voices[j].LanguageCode
+		}
+		return voices[i].Name < voices[j].Name
+	})
+
+	return voices, nil
 }
 
 // TestConnection tests AWS connectivity
@@ -188,4 +258,3 @@ func (a *AWSService) TestConnection() error {
 
 	return nil
 }
-
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// backgroundGrace is how long interrupted background work gets to record its state after
+// DrainBackgroundWork gave up waiting
+const backgroundGrace = 5 * time.Second
+
+// background tracks work that outlives the request that started it, such as bulk uploads and
+// search reindexing, so a shutdown can wait for it instead of killing it mid-flight
+var background = struct {
+	mu       sync.Mutex
+	wg       sync.WaitGroup
+	stopping bool
+	ctx      context.Context
+	cancel   context.CancelFunc
+}{}
+
+func init() {
+	background.ctx, background.cancel = context.WithCancel(context.Background())
+}
+
+// RunInBackground runs fn in a goroutine that DrainBackgroundWork waits for. fn's context is
+// canceled when the drain times out; fn should then stop and record where it left off. Once a
+// shutdown has started no new work is accepted and RunInBackground returns false.
+func RunInBackground(fn func(ctx context.Context)) bool {
+	background.mu.Lock()
+	defer background.mu.Unlock()
+	if background.stopping {
+		return false
+	}
+
+	background.wg.Add(1)
+	go func() {
+		defer background.wg.Done()
+		fn(background.ctx)
+	}()
+	return true
+}
+
+// DrainBackgroundWork stops accepting background work and waits until the running work is done
+// or ctx expires. On expiry the work is canceled and gets a few more seconds to save its state.
+func DrainBackgroundWork(ctx context.Context) error {
+	background.mu.Lock()
+	background.stopping = true
+	background.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		background.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+	}
+
+	background.cancel()
+	select {
+	case <-done:
+	case <-time.After(backgroundGrace):
+	}
+	return fmt.Errorf("background work interrupted: %w", ctx.Err())
+}
+
+// errShuttingDown is returned when work can't be started because the API is shutting down
+var errShuttingDown = fmt.Errorf("server is shutting down, retry shortly")
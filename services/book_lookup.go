@@ -0,0 +1,164 @@
+package services
+
+import (
+	"auto-annotation-api/models"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// BookLookupClient fetches bibliographic metadata by ISBN from OpenLibrary,
+// falling back to Google Books
+type BookLookupClient struct {
+	client *http.Client
+}
+
+// NewBookLookupClient creates a new book lookup client
+func NewBookLookupClient() *BookLookupClient {
+	return &BookLookupClient{
+		client: &http.Client{
+			Timeout: 15 * time.Second,
+		},
+	}
+}
+
+// NormalizeISBN strips separators from an ISBN and validates its length
+func NormalizeISBN(isbn string) (string, error) {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(isbn) {
+		if (r >= '0' && r <= '9') || r == 'X' {
+			b.WriteRune(r)
+		}
+	}
+
+	normalized := b.String()
+	if len(normalized) != 10 && len(normalized) != 13 {
+		return "", fmt.Errorf("invalid ISBN: %s", isbn)
+	}
+	return normalized, nil
+}
+
+// LookupISBN returns book metadata for the given ISBN
+func (b *BookLookupClient) LookupISBN(isbn string) (*models.BookMetadata, error) {
+	normalized, err := NormalizeISBN(isbn)
+	if err != nil {
+		return nil, err
+	}
+
+	book, err := b.lookupOpenLibrary(normalized)
+	if err == nil {
+		return book, nil
+	}
+
+	book, googleErr := b.lookupGoogleBooks(normalized)
+	if googleErr != nil {
+		return nil, fmt.Errorf("book not found for ISBN %s (openlibrary: %v, google books: %v)", normalized, err, googleErr)
+	}
+	return book, nil
+}
+
+// lookupOpenLibrary queries the OpenLibrary books API
+func (b *BookLookupClient) lookupOpenLibrary(isbn string) (*models.BookMetadata, error) {
+	key := "ISBN:" + isbn
+	endpoint := "https://openlibrary.org/api/books?format=json&jscmd=data&bibkeys=" + url.QueryEscape(key)
+
+	var result map[string]struct {
+		Title   string `json:"title"`
+		Authors []struct {
+			Name string `json:"name"`
+		} `json:"authors"`
+		Publishers []struct {
+			Name string `json:"name"`
+		} `json:"publishers"`
+		PublishDate string `json:"publish_date"`
+		Cover       struct {
+			Large  string `json:"large"`
+			Medium string `json:"medium"`
+		} `json:"cover"`
+	}
+	if err := b.getJSON(endpoint, &result); err != nil {
+		return nil, err
+	}
+
+	data, ok := result[key]
+	if !ok || data.Title == "" {
+		return nil, fmt.Errorf("no result")
+	}
+
+	book := &models.BookMetadata{
+		ISBN:        isbn,
+		Title:       data.Title,
+		PublishDate: data.PublishDate,
+		CoverURL:    data.Cover.Large,
+		Source:      "openlibrary",
+	}
+	if book.CoverURL == "" {
+		book.CoverURL = data.Cover.Medium
+	}
+	for _, author := range data.Authors {
+		book.Authors = append(book.Authors, author.Name)
+	}
+	if len(data.Publishers) > 0 {
+		book.Publisher = data.Publishers[0].Name
+	}
+
+	return book, nil
+}
+
+// lookupGoogleBooks queries the Google Books volumes API
+func (b *BookLookupClient) lookupGoogleBooks(isbn string) (*models.BookMetadata, error) {
+	endpoint := "https://www.googleapis.com/books/v1/volumes?q=" + url.QueryEscape("isbn:"+isbn)
+
+	var result struct {
+		Items []struct {
+			VolumeInfo struct {
+				Title         string   `json:"title"`
+				Authors       []string `json:"authors"`
+				Publisher     string   `json:"publisher"`
+				PublishedDate string   `json:"publishedDate"`
+				ImageLinks    struct {
+					Thumbnail string `json:"thumbnail"`
+				} `json:"imageLinks"`
+			} `json:"volumeInfo"`
+		} `json:"items"`
+	}
+	if err := b.getJSON(endpoint, &result); err != nil {
+		return nil, err
+	}
+
+	if len(result.Items) == 0 || result.Items[0].VolumeInfo.Title == "" {
+		return nil, fmt.Errorf("no result")
+	}
+
+	info := result.Items[0].VolumeInfo
+	return &models.BookMetadata{
+		ISBN:        isbn,
+		Title:       info.Title,
+		Authors:     info.Authors,
+		Publisher:   info.Publisher,
+		PublishDate: info.PublishedDate,
+		CoverURL:    strings.Replace(info.ImageLinks.Thumbnail, "http://", "https://", 1),
+		Source:      "googlebooks",
+	}, nil
+}
+
+// getJSON performs a GET request and decodes the JSON response
+func (b *BookLookupClient) getJSON(endpoint string, target interface{}) error {
+	resp, err := b.client.Get(endpoint)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(target); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,273 @@
+package services
+
+import (
+	"archive/zip"
+	"auto-annotation-api/models"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// maxBatchDocuments caps the documents of one bulk upload, counting those inside ZIP archives
+const maxBatchDocuments = 50
+
+// BatchFile is an uploaded file of a bulk upload, a PDF or a ZIP archive of PDFs
+type BatchFile struct {
+	Name string
+	Data []byte
+}
+
+// batchDocument is a PDF queued for processing, or the reason it was rejected
+type batchDocument struct {
+	name string
+	data []byte
+	err  error
+}
+
+// CreateUploadBatch queues one annotation per PDF, expanding ZIP archives, and processes them in
+// the background. Each annotation is titled after its file name, or from its content with
+// template.AutoTitle, and uses the tags, length and metadata of template. Invalid files are reported as failed items rather than failing the batch.
+func (s *AnnotationService) CreateUploadBatch(ctx context.Context, user *models.User, files []BatchFile, template *models.CreateAnnotationRequest) (*models.UploadBatch, error) {
+	// The documents are held in memory until they are processed, so together they may not exceed
+	// the request limit of bulk uploads, also once archives are decompressed
+	var documents []batchDocument
+	budget := s.maxBulkUpload
+	for _, file := range files {
+		if strings.ToLower(filepath.Ext(file.Name)) == ".zip" {
+			entries, err := s.zipDocuments(file, maxBatchDocuments-len(documents), &budget)
+			if err != nil {
+				return nil, err
+			}
+			documents = append(documents, entries...)
+		} else {
+			documents = append(documents, batchDocument{name: file.Name, data: file.Data})
+			budget -= int64(len(file.Data))
+		}
+		if len(documents) > maxBatchDocuments {
+			return nil, fmt.Errorf("invalid batch: at most %d documents per upload", maxBatchDocuments)
+		}
+		if budget < 0 {
+			return nil, fmt.Errorf("invalid batch: the documents exceed %d MB in total", s.maxBulkUpload>>20)
+		}
+	}
+	if len(documents) == 0 {
+		return nil, fmt.Errorf("invalid batch: no documents uploaded")
+	}
+
+	// Reject bad input up front, before the request's validation errors are lost in the background
+	if err := s.CheckUploadRequest(ctx, template); err != nil {
+		return nil, err
+	}
+	if !template.Simulate {
+		if _, err := s.selectModel(template.Model); err != nil {
+			return nil, err
+		}
+	}
+
+	items := make([]models.BatchItem, len(documents))
+	for i := range documents {
+		doc := &documents[i]
+		if doc.err == nil && strings.ToLower(filepath.Ext(doc.name)) != ".pdf" {
+			doc.err = fmt.Errorf("only PDF files are supported")
+		}
+		if doc.err == nil && int64(len(doc.data)) > s.maxUpload {
+			doc.err = fmt.Errorf("file exceeds the maximum upload size of %d MB", s.maxUpload>>20)
+		}
+		if doc.err == nil {
+			doc.err = ValidatePDF(bytes.NewReader(doc.data), int64(len(doc.data)))
+		}
+
+		items[i] = models.BatchItem{FileName: doc.name, Status: "queued"}
+		if doc.err != nil {
+			items[i].Status = "failed"
+			items[i].Error = doc.err.Error()
+		}
+	}
+
+	// Valid documents count against the daily quota, all or none
+	valid := 0
+	for _, doc := range documents {
+		if doc.err == nil {
+			valid++
+		}
+	}
+	if _, err := s.ReserveUploads(ctx, user, valid); err != nil {
+		return nil, err
+	}
+
+	batch := models.NewUploadBatch(user.ID, items)
+	if _, err := s.batches.InsertOne(ctx, batch); err != nil {
+		return nil, fmt.Errorf("failed to create batch: %w", err)
+	}
+
+	uploadLoad.queued.Add(int64(valid))
+	started := RunInBackground(func(jobCtx context.Context) {
+		s.processUploadBatch(jobCtx, batch.ID, user.ID, documents, *template)
+	})
+	if !started {
+		uploadLoad.queued.Add(-int64(valid))
+		s.interruptUploadBatch(ctx, batch.ID, documents, 0)
+		return nil, errShuttingDown
+	}
+
+	return batch, nil
+}
+
+// GetUploadBatch returns a bulk upload with the status of each document. Batches of other users
+// are only visible to admins.
+func (s *AnnotationService) GetUploadBatch(ctx context.Context, batchID string, user *models.User) (*models.UploadBatch, error) {
+	var batch models.UploadBatch
+	err := s.batches.FindOne(ctx, bson.M{"_id": batchID}).Decode(&batch)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("batch not found")
+		}
+		return nil, err
+	}
+	if batch.UserID != user.ID && !user.IsAdmin() {
+		return nil, fmt.Errorf("batch not found")
+	}
+	return &batch, nil
+}
+
+// processUploadBatch creates the annotations one at a time, since generation is bound by the LLM anyway.
+// When jobCtx is canceled by a shutdown, the document in progress is finished and the rest are
+// marked as interrupted.
+func (s *AnnotationService) processUploadBatch(jobCtx context.Context, batchID, userID string, documents []batchDocument, template models.CreateAnnotationRequest) {
+	ctx := context.WithoutCancel(jobCtx)
+	log.Printf("Processing upload batch %s with %d documents", batchID, len(documents))
+
+	// Documents leave the queue as they are picked up, or all at once when the batch is interrupted
+	var waiting int64
+	for _, doc := range documents {
+		if doc.err == nil {
+			waiting++
+		}
+	}
+	defer func() { uploadLoad.queued.Add(-waiting) }()
+
+	for i, doc := range documents {
+		if doc.err != nil {
+			continue
+		}
+		if jobCtx.Err() != nil {
+			s.interruptUploadBatch(ctx, batchID, documents, i)
+			return
+		}
+		s.updateBatchItem(ctx, batchID, i, bson.M{"status": "processing"})
+		uploadLoad.queued.Add(-1)
+		waiting--
+
+		req := template
+		req.FileName = path.Base(doc.name)
+		if !template.AutoTitle {
+			req.Title, req.TitleSource = titleFromFilename(req.FileName), models.TitleFileName
+		}
+		annotation, err := s.CreateAnnotationFromStream(ctx, userID, &req, bytes.NewReader(doc.data), int64(len(doc.data)), "pdf", nil)
+		if err != nil {
+			log.Printf("Upload batch %s: %s failed: %v", batchID, doc.name, err)
+			s.updateBatchItem(ctx, batchID, i, bson.M{"status": "failed", "error": err.Error()})
+			continue
+		}
+		s.updateBatchItem(ctx, batchID, i, bson.M{"status": "completed", "annotation_id": annotation.ID})
+	}
+
+	_, err := s.batches.UpdateOne(ctx, bson.M{"_id": batchID}, bson.M{"$set": bson.M{"status": "completed", "updated_at": time.Now()}})
+	if err != nil {
+		log.Printf("Warning: failed to complete upload batch %s: %v", batchID, err)
+	}
+	log.Printf("Upload batch %s completed", batchID)
+}
+
+// interruptUploadBatch fails the documents of a batch from index on, which a shutdown kept from
+// being processed, so the batch doesn't look like it is still running
+func (s *AnnotationService) interruptUploadBatch(ctx context.Context, batchID string, documents []batchDocument, from int) {
+	update := bson.M{"status": "interrupted", "updated_at": time.Now()}
+	for i := from; i < len(documents); i++ {
+		if documents[i].err == nil {
+			update[fmt.Sprintf("items.%d.status", i)] = "failed"
+			update[fmt.Sprintf("items.%d.error", i)] = "interrupted by a server shutdown, upload the document again"
+		}
+	}
+	if _, err := s.batches.UpdateOne(ctx, bson.M{"_id": batchID}, bson.M{"$set": update}); err != nil {
+		log.Printf("Warning: failed to record interrupted upload batch %s: %v", batchID, err)
+	}
+	log.Printf("Upload batch %s interrupted by shutdown", batchID)
+}
+
+// updateBatchItem sets fields of one item of a batch
+func (s *AnnotationService) updateBatchItem(ctx context.Context, batchID string, index int, fields bson.M) {
+	update := bson.M{"updated_at": time.Now()}
+	for field, value := range fields {
+		update[fmt.Sprintf("items.%d.%s", index, field)] = value
+	}
+	if _, err := s.batches.UpdateOne(ctx, bson.M{"_id": batchID}, bson.M{"$set": update}); err != nil {
+		log.Printf("Warning: failed to update upload batch %s: %v", batchID, err)
+	}
+}
+
+// zipDocuments returns the PDFs in a ZIP archive, at most remaining of them. The sizes in the
+// archive's directory can't be trusted, so entries are decompressed with the per-file limit of
+// uploads and what they decompress to is taken from budget. Running out of either fails the whole
+// batch as soon as it happens.
+func (s *AnnotationService) zipDocuments(file BatchFile, remaining int, budget *int64) ([]batchDocument, error) {
+	archive, err := zip.NewReader(bytes.NewReader(file.Data), int64(len(file.Data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid batch: %s is not a valid ZIP archive", file.Name)
+	}
+
+	var documents []batchDocument
+	for _, entry := range archive.File {
+		base := path.Base(entry.Name)
+		if entry.FileInfo().IsDir() || strings.HasPrefix(base, ".") || strings.HasPrefix(entry.Name, "__MACOSX/") {
+			continue
+		}
+		if strings.ToLower(path.Ext(base)) != ".pdf" {
+			continue
+		}
+		if len(documents) >= remaining {
+			return nil, fmt.Errorf("invalid batch: at most %d documents per upload", maxBatchDocuments)
+		}
+
+		doc := batchDocument{name: file.Name + "/" + entry.Name}
+		data, err := readZipEntry(entry, min(s.maxUpload, *budget))
+		switch {
+		case err != nil:
+			doc.err = err
+		case int64(len(data)) > *budget:
+			return nil, fmt.Errorf("invalid batch: the documents exceed %d MB in total", s.maxBulkUpload>>20)
+		case int64(len(data)) > s.maxUpload:
+			doc.err = fmt.Errorf("file exceeds the maximum upload size of %d MB", s.maxUpload>>20)
+		default:
+			doc.data = data
+			*budget -= int64(len(data))
+		}
+		documents = append(documents, doc)
+	}
+	return documents, nil
+}
+
+// readZipEntry decompresses an archive entry, reading at most one byte more than limit so callers
+// can tell an entry exceeds it
+func readZipEntry(entry *zip.File, limit int64) ([]byte, error) {
+	reader, err := entry.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive entry: %w", err)
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(io.LimitReader(reader, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive entry: %w", err)
+	}
+	return data, nil
+}
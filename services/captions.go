@@ -0,0 +1,118 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	maxCaptionWords = 12  // Long sentences are split so a cue fits on two lines
+	minWordMs       = 400 // Assumed duration of the final word, which has no following mark
+	msPerChar       = 70
+)
+
+// captionCue is one timed caption with the word marks it contains
+type captionCue struct {
+	StartMs int64
+	EndMs   int64
+	Words   []SpeechMark
+}
+
+// buildCaptionCues groups word speech marks into cues, one per sentence or per maxCaptionWords words
+func buildCaptionCues(marks []SpeechMark) []captionCue {
+	var sentences, words []SpeechMark
+	for _, mark := range marks {
+		switch mark.Type {
+		case "sentence":
+			sentences = append(sentences, mark)
+		case "word":
+			words = append(words, mark)
+		}
+	}
+	if len(words) == 0 {
+		return nil
+	}
+
+	// sentenceOf returns the index of the sentence containing a word, by byte offset
+	sentenceOf := func(word SpeechMark) int {
+		index := -1
+		for i, sentence := range sentences {
+			if word.Start >= sentence.Start {
+				index = i
+			}
+		}
+		return index
+	}
+
+	var cues []captionCue
+	current := captionCue{}
+	currentSentence := sentenceOf(words[0])
+	for _, word := range words {
+		sentence := sentenceOf(word)
+		if len(current.Words) > 0 && (sentence != currentSentence || len(current.Words) >= maxCaptionWords) {
+			cues = append(cues, current)
+			current = captionCue{}
+		}
+		if len(current.Words) == 0 {
+			current.StartMs = word.Time
+		}
+		current.Words = append(current.Words, word)
+		currentSentence = sentence
+	}
+	cues = append(cues, current)
+
+	// A cue lasts until the next one starts
+	for i := range cues {
+		if i+1 < len(cues) {
+			cues[i].EndMs = cues[i+1].StartMs
+			continue
+		}
+		last := cues[i].Words[len(cues[i].Words)-1]
+		cues[i].EndMs = last.Time + max(minWordMs, int64(len(last.Value))*msPerChar)
+	}
+
+	return cues
+}
+
+// formatWebVTT renders cues as WebVTT with inline word timestamps for karaoke-style highlighting
+func formatWebVTT(cues []captionCue) string {
+	var b strings.Builder
+	b.WriteString("WEBVTT\n\n")
+	for i, cue := range cues {
+		fmt.Fprintf(&b, "%d\n%s --> %s\n", i+1, formatCaptionTime(cue.StartMs, "."), formatCaptionTime(cue.EndMs, "."))
+		for j, word := range cue.Words {
+			if j > 0 {
+				fmt.Fprintf(&b, " <%s>", formatCaptionTime(word.Time, "."))
+			}
+			b.WriteString(escapeVTT(word.Value))
+		}
+		b.WriteString("\n\n")
+	}
+	return b.String()
+}
+
+// formatSRT renders cues as SubRip, which has no word timing
+func formatSRT(cues []captionCue) string {
+	var b strings.Builder
+	for i, cue := range cues {
+		fmt.Fprintf(&b, "%d\n%s --> %s\n", i+1, formatCaptionTime(cue.StartMs, ","), formatCaptionTime(cue.EndMs, ","))
+		for j, word := range cue.Words {
+			if j > 0 {
+				b.WriteString(" ")
+			}
+			b.WriteString(word.Value)
+		}
+		b.WriteString("\n\n")
+	}
+	return b.String()
+}
+
+// formatCaptionTime formats milliseconds as HH:MM:SS.mmm (WebVTT) or HH:MM:SS,mmm (SRT)
+func formatCaptionTime(ms int64, separator string) string {
+	return fmt.Sprintf("%02d:%02d:%02d%s%03d", ms/3600000, ms/60000%60, ms/1000%60, separator, ms%1000)
+}
+
+// escapeVTT escapes the characters WebVTT treats as markup
+func escapeVTT(text string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(text)
+}
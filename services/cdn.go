@@ -0,0 +1,104 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Surrogate keys tag cached responses so a CDN can purge them after edits
+const (
+	SurrogateKeyAnnotations = "annotations" // Lists, searches and tag counts
+	SurrogateKeyTTSVoices   = "tts-voices"
+)
+
+// AnnotationSurrogateKey tags the responses of a single annotation
+func AnnotationSurrogateKey(annotationID string) string {
+	return "annotation-" + annotationID
+}
+
+// CDNPurger purges cached responses by surrogate key. It POSTs {"surrogate_keys": [...]} and the
+// space-separated keys in a Surrogate-Key header, which covers Fastly's bulk purge API and
+// compatible endpoints.
+type CDNPurger struct {
+	client    *http.Client
+	purgeURL  string
+	token     string
+	tokenHead string
+}
+
+// NewCDNPurger creates a purger, or returns nil when no purge URL is configured
+func NewCDNPurger(purgeURL, token, tokenHeader string) *CDNPurger {
+	if purgeURL == "" {
+		return nil
+	}
+	if tokenHeader == "" {
+		tokenHeader = "Authorization"
+	}
+	return &CDNPurger{
+		client:    &http.Client{Timeout: 10 * time.Second},
+		purgeURL:  purgeURL,
+		token:     token,
+		tokenHead: tokenHeader,
+	}
+}
+
+// Purge invalidates every cached response tagged with one of keys
+func (p *CDNPurger) Purge(keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(map[string][]string{"surrogate_keys": keys})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, p.purgeURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create CDN purge request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Surrogate-Key", strings.Join(keys, " "))
+	if p.token != "" {
+		token := p.token
+		if strings.EqualFold(p.tokenHead, "Authorization") {
+			token = "Bearer " + token
+		}
+		req.Header.Set(p.tokenHead, token)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("CDN purge failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("CDN purge failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
+
+// purgeCDN drops the cached responses of changed annotations and of the lists containing them.
+// The purge runs in the background so edits don't wait for the CDN.
+func (s *AnnotationService) purgeCDN(annotationIDs ...string) {
+	if s.cdn == nil {
+		return
+	}
+
+	keys := []string{SurrogateKeyAnnotations}
+	for _, id := range annotationIDs {
+		keys = append(keys, AnnotationSurrogateKey(id))
+	}
+	go func() {
+		if err := s.cdn.Purge(keys...); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+	}()
+}
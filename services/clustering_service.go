@@ -0,0 +1,238 @@
+package services
+
+import (
+	"auto-annotation-api/config"
+	"auto-annotation-api/models"
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ClusteringService groups annotations by embedding similarity
+type ClusteringService struct {
+	annotations *mongo.Collection
+	clusters    *mongo.Collection
+	embedder    Embedder // nil when the selected provider isn't configured
+	threshold   float64
+	mu          sync.Mutex // Prevents overlapping clustering runs
+}
+
+// NewClusteringService creates a new clustering service
+func NewClusteringService(db *mongo.Database, cfg *config.Config) *ClusteringService {
+	threshold := cfg.ClusterThreshold
+	if threshold <= 0 || threshold > 1 {
+		threshold = 0.8
+	}
+
+	embedder, err := NewEmbedder(cfg)
+	if err != nil {
+		log.Printf("Warning: %v. Annotations will not be clustered", err)
+	}
+
+	return &ClusteringService{
+		annotations: db.Collection("annotations"),
+		clusters:    db.Collection("annotation_clusters"),
+		embedder:    embedder,
+		threshold:   threshold,
+	}
+}
+
+// StartBackgroundJob periodically re-clusters annotations until the context is cancelled
+func (s *ClusteringService) StartBackgroundJob(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.RunClustering(ctx); err != nil {
+			log.Printf("Clustering job failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunClustering embeds any annotations missing an embedding and rebuilds the cluster list
+func (s *ClusteringService) RunClustering(ctx context.Context) error {
+	if s.embedder == nil {
+		return fmt.Errorf("embedding provider not configured")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cursor, err := s.annotations.Find(ctx, bson.M{"status": "completed"}, options.Find().SetProjection(bson.M{
+		"_id":             1,
+		"title":           1,
+		"annotation":      1,
+		"genre":           1,
+		"embedding":       1,
+		"embedding_model": 1,
+	}))
+	if err != nil {
+		return fmt.Errorf("failed to load annotations: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var annotations []*models.Annotation
+	if err := cursor.All(ctx, &annotations); err != nil {
+		return fmt.Errorf("failed to decode annotations: %w", err)
+	}
+
+	// Generate embeddings for annotations that don't have one of the configured model yet
+	model := s.embedder.Model()
+	var embedded []*models.Annotation
+	for _, annotation := range annotations {
+		if len(annotation.Embedding) == 0 || annotation.EmbedModel != model {
+			embedding, err := s.embedder.Embed(embeddingText(annotation.Title, annotation.Annotation))
+			if err != nil {
+				log.Printf("Warning: failed to embed annotation %s: %v", annotation.ID, err)
+				continue
+			}
+
+			_, err = s.annotations.UpdateOne(ctx, bson.M{"_id": annotation.ID}, bson.M{"$set": bson.M{
+				"embedding":       embedding,
+				"embedding_model": model,
+			}})
+			if err != nil {
+				log.Printf("Warning: failed to store embedding for annotation %s: %v", annotation.ID, err)
+			}
+			annotation.Embedding = embedding
+		}
+		embedded = append(embedded, annotation)
+	}
+
+	clusters := s.buildClusters(embedded)
+
+	// Replace the previous clustering result
+	if _, err := s.clusters.DeleteMany(ctx, bson.M{}); err != nil {
+		return fmt.Errorf("failed to clear clusters: %w", err)
+	}
+	if len(clusters) > 0 {
+		docs := make([]interface{}, len(clusters))
+		for i, cluster := range clusters {
+			docs[i] = cluster
+		}
+		if _, err := s.clusters.InsertMany(ctx, docs); err != nil {
+			return fmt.Errorf("failed to store clusters: %w", err)
+		}
+	}
+
+	log.Printf("Clustering completed: %d annotations, %d clusters", len(embedded), len(clusters))
+	return nil
+}
+
+// GetClusters returns the most recently generated clusters, largest first
+func (s *ClusteringService) GetClusters(ctx context.Context) ([]*models.AnnotationCluster, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "size", Value: -1}})
+	cursor, err := s.clusters.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	clusters := []*models.AnnotationCluster{}
+	if err := cursor.All(ctx, &clusters); err != nil {
+		return nil, err
+	}
+	return clusters, nil
+}
+
+// buildClusters groups annotations greedily: each annotation joins the most similar
+// existing cluster above the threshold, otherwise it starts a new one
+func (s *ClusteringService) buildClusters(annotations []*models.Annotation) []*models.AnnotationCluster {
+	type group struct {
+		centroid []float64
+		members  []*models.Annotation
+	}
+
+	var groups []*group
+	for _, annotation := range annotations {
+		var best *group
+		bestSimilarity := s.threshold
+		for _, g := range groups {
+			if similarity := cosineSimilarity(annotation.Embedding, g.centroid); similarity >= bestSimilarity {
+				best = g
+				bestSimilarity = similarity
+			}
+		}
+
+		if best == nil {
+			centroid := make([]float64, len(annotation.Embedding))
+			copy(centroid, annotation.Embedding)
+			groups = append(groups, &group{centroid: centroid, members: []*models.Annotation{annotation}})
+			continue
+		}
+
+		// Update the running mean of the centroid
+		best.members = append(best.members, annotation)
+		n := float64(len(best.members))
+		for i := range best.centroid {
+			if i < len(annotation.Embedding) {
+				best.centroid[i] += (annotation.Embedding[i] - best.centroid[i]) / n
+			}
+		}
+	}
+
+	var clusters []*models.AnnotationCluster
+	for _, g := range groups {
+		// Only groups of related material are interesting
+		if len(g.members) < 2 {
+			continue
+		}
+
+		members := make([]models.ClusterMember, len(g.members))
+		genreCounts := make(map[string]int)
+		for i, annotation := range g.members {
+			members[i] = models.ClusterMember{
+				AnnotationID: annotation.ID,
+				Title:        annotation.Title,
+				Similarity:   cosineSimilarity(annotation.Embedding, g.centroid),
+			}
+			genreCounts[annotation.Genre]++
+		}
+		sort.Slice(members, func(i, j int) bool {
+			return members[i].Similarity > members[j].Similarity
+		})
+
+		genre := ""
+		for name, count := range genreCounts {
+			if count > genreCounts[genre] || (count == genreCounts[genre] && name < genre) {
+				genre = name
+			}
+		}
+
+		// Label the cluster after its most central member
+		clusters = append(clusters, models.NewAnnotationCluster(members[0].Title, genre, members))
+	}
+
+	return clusters
+}
+
+// cosineSimilarity returns the cosine similarity of two vectors
+func cosineSimilarity(a, b []float64) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
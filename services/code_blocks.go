@@ -0,0 +1,183 @@
+package services
+
+import (
+	"auto-annotation-api/models"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	maxCodeBlocksPerDocument = 20
+	maxCodeExamplesInput     = 6000 // Characters of code sent to the LLM for the key examples section
+)
+
+// codeKeywordPattern matches lines that start like a statement or declaration in common languages
+var codeKeywordPattern = regexp.MustCompile(`^(def|func|class|import|from\s+\S+\s+import|package|return|const|let|var|public|private|protected|static|void|int|if\s*\(|for\s*\(|for\s+\w+\s+in\s.*:|while\s*\(|switch\s*\(|}\s*else|elif\s.*:|else:|try:|except\b.*:|#include|#define|using|namespace|SELECT|INSERT|UPDATE|DELETE|CREATE)\b`)
+
+// codeStatementPattern matches assignments like "total += x" and calls like "fmt.Println(x)"
+var codeStatementPattern = regexp.MustCompile(`^([A-Za-z_][\w.\[\]]*\s*([-+*/%]?=)\s*[^=\s]|[A-Za-z_][\w.]*\(.*\);?$)`)
+
+// fencedCodePattern matches Markdown fenced code blocks in generated text
+var fencedCodePattern = regexp.MustCompile("(?s)```[^\\n]*\\n.*?```")
+
+// codeSpan is a run of code lines in text, as line indexes [Start, End)
+type codeSpan struct {
+	Start, End int
+}
+
+// isCodeLine guesses whether a line of extracted text is source code
+func isCodeLine(line string) bool {
+	line = strings.TrimSpace(line)
+	if line == "" || len(line) > 160 {
+		return false
+	}
+	if codeKeywordPattern.MatchString(line) || codeStatementPattern.MatchString(line) && !strings.HasSuffix(line, ".") {
+		return true
+	}
+	if strings.HasSuffix(line, ";") || strings.HasSuffix(line, "{") || line == "}" || line == "};" || line == "})" {
+		return !strings.Contains(line, ". ") // Prose sentences joined by semicolons
+	}
+	return strings.HasPrefix(line, "//") || strings.Contains(line, ":=") || strings.Contains(line, "=>") ||
+		strings.Contains(line, "->") && strings.Contains(line, "(")
+}
+
+// findCodeSpans returns runs of at least two code lines. Lines inside a run may look like prose
+// (a closing call argument, a string literal) as long as the run is dense with code lines.
+func findCodeSpans(lines []string) []codeSpan {
+	var spans []codeSpan
+	for i := 0; i < len(lines); {
+		if !isCodeLine(lines[i]) {
+			i++
+			continue
+		}
+
+		end, codeLines := i+1, 1
+		for end < len(lines) && strings.TrimSpace(lines[end]) != "" && !pageMarkerPattern.MatchString(lines[end]) {
+			if isCodeLine(lines[end]) {
+				codeLines++
+			} else if end+1 >= len(lines) || !isCodeLine(lines[end+1]) {
+				break // Two prose lines in a row end the block
+			}
+			end++
+		}
+
+		if codeLines >= 2 {
+			spans = append(spans, codeSpan{Start: i, End: end})
+		}
+		i = end
+	}
+	return spans
+}
+
+// protectCode swaps code blocks for placeholders so text cleanup keeps their indentation and spacing
+func protectCode(text string) (string, []string) {
+	lines := strings.Split(text, "\n")
+	spans := findCodeSpans(lines)
+	if len(spans) == 0 {
+		return text, nil
+	}
+
+	var out []string
+	originals := make([]string, 0, len(spans))
+	last := 0
+	for i, span := range spans {
+		out = append(out, lines[last:span.Start]...)
+		out = append(out, codePlaceholder(i))
+		originals = append(originals, codeBlockText(lines[span.Start:span.End]))
+		last = span.End
+	}
+	out = append(out, lines[last:]...)
+	return strings.Join(out, "\n"), originals
+}
+
+// restoreCode puts back the code blocks removed by protectCode
+func restoreCode(text string, originals []string) string {
+	for i, original := range originals {
+		text = strings.Replace(text, codePlaceholder(i), original, 1)
+	}
+	return text
+}
+
+// codePlaceholder returns a token that survives whitespace cleanup
+func codePlaceholder(i int) string {
+	return fmt.Sprintf("\x00CODE%d\x00", i)
+}
+
+// codeBlockText joins code lines, keeping indentation but dropping trailing whitespace
+func codeBlockText(lines []string) string {
+	trimmed := make([]string, len(lines))
+	for i, line := range lines {
+		trimmed[i] = strings.TrimRight(line, " \t")
+	}
+	return strings.Join(trimmed, "\n")
+}
+
+// extractCodeBlocks collects the code blocks of extracted text with their pages and a guessed language
+func extractCodeBlocks(text string) []models.CodeBlock {
+	blocks := []models.CodeBlock{}
+	for _, section := range splitPages(text) {
+		lines := strings.Split(section.Text, "\n")
+		for _, span := range findCodeSpans(lines) {
+			if len(blocks) >= maxCodeBlocksPerDocument {
+				return blocks
+			}
+			code := codeBlockText(lines[span.Start:span.End])
+			blocks = append(blocks, models.CodeBlock{
+				Page:     section.Page,
+				Language: guessCodeLanguage(code),
+				Code:     code,
+			})
+		}
+	}
+	return blocks
+}
+
+// removeCodeBlocks drops code blocks from text, so other detectors don't mistake code for prose or math
+func removeCodeBlocks(text string) string {
+	lines := strings.Split(text, "\n")
+	spans := findCodeSpans(lines)
+	for i := len(spans) - 1; i >= 0; i-- {
+		lines = append(lines[:spans[i].Start], lines[spans[i].End:]...)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// guessCodeLanguage names the language of a snippet from telltale syntax, or returns ""
+func guessCodeLanguage(code string) string {
+	switch {
+	case strings.Contains(code, "#include"):
+		return "c"
+	case strings.Contains(code, "package ") && strings.Contains(code, "func "), strings.Contains(code, ":= "):
+		return "go"
+	case strings.Contains(code, "def ") || strings.Contains(code, "import ") && !strings.Contains(code, ";"):
+		return "python"
+	case strings.Contains(code, "public class") || strings.Contains(code, "System.out"):
+		return "java"
+	case strings.Contains(code, "function") || strings.Contains(code, "=>") || strings.Contains(code, "const "):
+		return "javascript"
+	case strings.Contains(strings.ToUpper(code), "SELECT ") && strings.Contains(strings.ToUpper(code), " FROM "):
+		return "sql"
+	}
+	return ""
+}
+
+// appendCodeExamples adds the "Key code examples" section to the annotation text
+func appendCodeExamples(annotation, examples string) string {
+	if examples == "" {
+		return annotation
+	}
+	return annotation + "\n\nKey code examples:\n\n" + examples
+}
+
+// speakableCode replaces code with a short spoken cue, since Polly would read it character by character
+func speakableCode(text string) string {
+	text = fencedCodePattern.ReplaceAllString(text, "(code example, see the text)")
+
+	lines := strings.Split(text, "\n")
+	spans := findCodeSpans(lines)
+	for i := len(spans) - 1; i >= 0; i-- {
+		lines = append(append(lines[:spans[i].Start], "(code example, see the text)"), lines[spans[i].End:]...)
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,377 @@
+package services
+
+import (
+	"auto-annotation-api/models"
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+	"unicode/utf16"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	collabHistoryLimit = 1000    // Operations kept for transforming edits of lagging clients
+	collabMaxLength    = 1 << 20 // Largest annotation body a session accepts, in UTF-16 code units
+	collabSendBuffer   = 64      // Messages queued per client before it counts as too slow
+)
+
+// CollabMessage is a message of the collaborative editing protocol. Clients send "op" messages
+// with an operation made on the given revision. The server answers the sender with "ack" and sends
+// the operation, transformed to apply on the new revision, to everyone else as "op". "init"
+// starts a session with the current text, "join" and "leave" report other editors.
+type CollabMessage struct {
+	Type         string              `json:"type"`
+	Revision     int                 `json:"revision"`
+	Text         *string             `json:"text,omitempty"`
+	Operation    TextOperation       `json:"operation,omitempty"`
+	ClientID     string              `json:"client_id,omitempty"`
+	Participant  *CollabParticipant  `json:"participant,omitempty"`
+	Participants []CollabParticipant `json:"participants,omitempty"`
+	Error        string              `json:"error,omitempty"`
+}
+
+// CollabParticipant is one connected editor
+type CollabParticipant struct {
+	ClientID string `json:"client_id"`
+	UserID   string `json:"user_id"`
+	Name     string `json:"name"`
+}
+
+// CollabHub runs the collaborative editing sessions of annotation bodies. The server holds the
+// authoritative text of each session and orders concurrent edits with operational transformation;
+// the merged text is saved periodically and when the last editor leaves. Changes to the body made
+// through PATCH while a session is open are overwritten by its next save.
+type CollabHub struct {
+	service      *AnnotationService
+	saveInterval time.Duration
+
+	mu       sync.Mutex
+	sessions map[string]*collabSession
+	stopped  bool
+}
+
+// collabSession is the shared state of one annotation being edited
+type collabSession struct {
+	annotationID string
+	saving       sync.Mutex // Serializes saves, so an older text never overwrites a newer one
+
+	mu      sync.Mutex
+	text    []uint16
+	base    int             // Revision of the text history[0] applies to
+	history []TextOperation // Applied operations, each advancing the revision by one
+	saved   int             // Revision last written to the database
+	clients map[string]*CollabClient
+}
+
+// CollabClient is one editor connected to a session. Messages for it are read from Messages,
+// which is closed when the client is removed.
+type CollabClient struct {
+	participant CollabParticipant
+	session     *collabSession
+	hub         *CollabHub
+	send        chan CollabMessage
+	closed      bool // Guarded by session.mu
+}
+
+// NewCollabHub creates a hub saving edited sessions through service every saveInterval
+func NewCollabHub(service *AnnotationService, saveInterval time.Duration) *CollabHub {
+	if saveInterval <= 0 {
+		saveInterval = 10 * time.Second
+	}
+	return &CollabHub{
+		service:      service,
+		saveInterval: saveInterval,
+		sessions:     make(map[string]*collabSession),
+	}
+}
+
+// Run saves edited sessions every save interval until ctx is done, then saves every session and
+// disconnects its editors
+func (h *CollabHub) Run(ctx context.Context) {
+	ticker := time.NewTicker(h.saveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			for _, session := range h.activeSessions() {
+				h.save(ctx, session)
+				if h.closeIfIdle(session) {
+					log.Printf("Closed collaborative editing session for annotation %s", session.annotationID)
+				}
+			}
+		case <-ctx.Done():
+			h.mu.Lock()
+			h.stopped = true
+			sessions := h.sessions
+			h.sessions = make(map[string]*collabSession)
+			h.mu.Unlock()
+
+			for _, session := range sessions {
+				h.save(context.WithoutCancel(ctx), session)
+				session.mu.Lock()
+				for _, client := range session.clients {
+					client.closeLocked()
+				}
+				session.clients = nil
+				session.mu.Unlock()
+			}
+			return
+		}
+	}
+}
+
+func (h *CollabHub) activeSessions() []*collabSession {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	sessions := make([]*collabSession, 0, len(h.sessions))
+	for _, session := range h.sessions {
+		sessions = append(sessions, session)
+	}
+	return sessions
+}
+
+// Join connects user to the editing session of an annotation, starting the session if needed.
+// The client's first message is "init" with the current text and revision.
+func (h *CollabHub) Join(ctx context.Context, annotationID string, user *models.User) (*CollabClient, error) {
+	if err := h.service.CheckOwnership(ctx, annotationID, user); err != nil {
+		return nil, err
+	}
+
+	var session *collabSession
+	var annotation *models.Annotation
+	h.mu.Lock()
+	for {
+		if h.stopped {
+			h.mu.Unlock()
+			return nil, errShuttingDown
+		}
+		if session = h.sessions[annotationID]; session != nil || annotation != nil {
+			break
+		}
+
+		// The annotation is loaded without the hub lock, which would stall the joins and leaves of
+		// every session meanwhile. The session is looked up again afterwards, another join may have
+		// started it.
+		h.mu.Unlock()
+		var err error
+		if annotation, err = h.service.GetAnnotationByID(ctx, annotationID); err != nil {
+			return nil, err
+		}
+		if annotation.Status != "completed" {
+			return nil, fmt.Errorf("invalid annotation: only completed annotations can be edited")
+		}
+		h.mu.Lock()
+	}
+	defer h.mu.Unlock()
+
+	if session == nil {
+		session = &collabSession{
+			annotationID: annotationID,
+			text:         utf16.Encode([]rune(annotation.Annotation)),
+			clients:      make(map[string]*CollabClient),
+		}
+		h.sessions[annotationID] = session
+		log.Printf("Started collaborative editing session for annotation %s", annotationID)
+	}
+
+	client := &CollabClient{
+		participant: CollabParticipant{ClientID: uuid.New().String(), UserID: user.ID, Name: user.Name},
+		session:     session,
+		hub:         h,
+		send:        make(chan CollabMessage, collabSendBuffer),
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	participants := make([]CollabParticipant, 0, len(session.clients)+1)
+	for _, other := range session.clients {
+		participants = append(participants, other.participant)
+		other.deliverLocked(CollabMessage{Type: "join", Revision: session.revision(), Participant: &client.participant})
+	}
+	participants = append(participants, client.participant)
+	session.clients[client.participant.ClientID] = client
+
+	text := string(utf16.Decode(session.text))
+	client.deliverLocked(CollabMessage{
+		Type:         "init",
+		Revision:     session.revision(),
+		Text:         &text,
+		ClientID:     client.participant.ClientID,
+		Participants: participants,
+	})
+	return client, nil
+}
+
+// Messages returns the messages for the client
+func (c *CollabClient) Messages() <-chan CollabMessage {
+	return c.send
+}
+
+// Submit applies an operation the client made on revision. The client gets "ack" once it is
+// applied, or "error" if it was rejected.
+func (c *CollabClient) Submit(revision int, op TextOperation) error {
+	session := c.session
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	if c.closed {
+		return fmt.Errorf("client disconnected")
+	}
+
+	err := session.apply(c, revision, op)
+	if err != nil {
+		c.deliverLocked(CollabMessage{Type: "error", Revision: session.revision(), Error: err.Error()})
+	}
+	return err
+}
+
+// ReportError sends the client an "error" message, e.g. for a message that couldn't be parsed
+func (c *CollabClient) ReportError(err error) {
+	c.session.mu.Lock()
+	defer c.session.mu.Unlock()
+	c.deliverLocked(CollabMessage{Type: "error", Revision: c.session.revision(), Error: err.Error()})
+}
+
+// Leave disconnects the client. The session is saved and closed when its last editor leaves.
+func (c *CollabClient) Leave() {
+	session := c.session
+	session.mu.Lock()
+	if _, ok := session.clients[c.participant.ClientID]; ok {
+		delete(session.clients, c.participant.ClientID)
+		c.closeLocked()
+		for _, other := range session.clients {
+			other.deliverLocked(CollabMessage{Type: "leave", Revision: session.revision(), Participant: &c.participant})
+		}
+	}
+	last := len(session.clients) == 0
+	session.mu.Unlock()
+
+	// The session stays registered until its text is saved, so an editor joining meanwhile gets the
+	// edited text rather than the annotation's older one
+	if last {
+		c.hub.save(context.Background(), session)
+		if c.hub.closeIfIdle(session) {
+			log.Printf("Closed collaborative editing session for annotation %s", session.annotationID)
+		}
+	}
+}
+
+// closeIfIdle removes a session without editors whose text is saved. It reports false when an
+// editor joined or the save failed meanwhile; a failed save is retried by Run.
+func (h *CollabHub) closeIfIdle(session *collabSession) bool {
+	// Lock order is hub, then session
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	if len(session.clients) > 0 || session.revision() != session.saved || h.sessions[session.annotationID] != session {
+		return false
+	}
+	delete(h.sessions, session.annotationID)
+	return true
+}
+
+// apply transforms op against the operations applied since revision, applies it and sends it to
+// the other clients. session.mu must be held.
+func (s *collabSession) apply(from *CollabClient, revision int, op TextOperation) error {
+	if revision < s.base || revision > s.revision() {
+		return fmt.Errorf("invalid revision %d: the session is at revision %d, reconnect to resync", revision, s.revision())
+	}
+
+	for _, applied := range s.history[revision-s.base:] {
+		var err error
+		if op, err = transform(op, applied); err != nil {
+			return err
+		}
+	}
+
+	text, err := op.apply(s.text)
+	if err != nil {
+		return err
+	}
+	if len(text) > collabMaxLength {
+		return fmt.Errorf("invalid operation: the annotation would exceed %d characters", collabMaxLength)
+	}
+
+	s.text = text
+	s.history = append(s.history, op)
+	if len(s.history) > collabHistoryLimit {
+		trim := len(s.history) - collabHistoryLimit
+		s.history = append([]TextOperation(nil), s.history[trim:]...)
+		s.base += trim
+	}
+
+	for id, client := range s.clients {
+		if id == from.participant.ClientID {
+			client.deliverLocked(CollabMessage{Type: "ack", Revision: s.revision()})
+		} else {
+			client.deliverLocked(CollabMessage{Type: "op", Revision: s.revision(), Operation: op, ClientID: from.participant.ClientID})
+		}
+	}
+	return nil
+}
+
+// revision counts the operations applied since the session started. session.mu must be held.
+func (s *collabSession) revision() int {
+	return s.base + len(s.history)
+}
+
+// deliverLocked queues a message for the client. A client that doesn't keep up is disconnected,
+// it can rejoin and resync from a fresh "init". session.mu must be held.
+func (c *CollabClient) deliverLocked(msg CollabMessage) {
+	if c.closed {
+		return
+	}
+	select {
+	case c.send <- msg:
+	default:
+		log.Printf("Disconnecting slow collaborative editor %s of annotation %s", c.participant.ClientID, c.session.annotationID)
+		delete(c.session.clients, c.participant.ClientID)
+		c.closeLocked()
+	}
+}
+
+// closeLocked closes the client's message channel. session.mu must be held.
+func (c *CollabClient) closeLocked() {
+	if !c.closed {
+		c.closed = true
+		close(c.send)
+	}
+}
+
+// save writes the merged text of a session to the annotation if it changed since the last save
+func (h *CollabHub) save(ctx context.Context, session *collabSession) {
+	session.saving.Lock()
+	defer session.saving.Unlock()
+
+	session.mu.Lock()
+	revision := session.revision()
+	if revision == session.saved {
+		session.mu.Unlock()
+		return
+	}
+	text := string(utf16.Decode(session.text))
+	session.mu.Unlock()
+
+	_, err := h.service.collection.UpdateOne(ctx, bson.M{"_id": session.annotationID}, bson.M{"$set": bson.M{
+		"annotation": text,
+		"updated_at": time.Now(),
+	}})
+	if err != nil {
+		log.Printf("Warning: failed to save collaborative edits of annotation %s: %v", session.annotationID, err)
+		return
+	}
+	h.service.syncSearch(ctx, session.annotationID)
+	h.service.purgeCDN(session.annotationID)
+
+	session.mu.Lock()
+	session.saved = revision
+	session.mu.Unlock()
+}
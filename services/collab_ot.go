@@ -0,0 +1,215 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"unicode/utf16"
+)
+
+// TextOperation is an edit of the whole document in the JSON format of ot.js: positive numbers
+// retain characters, negative numbers delete them and strings are inserted, e.g. [5, "abc", -3, 10].
+// Lengths count UTF-16 code units like JavaScript strings, so browser clients can use ot.js as is.
+type TextOperation []opComponent
+
+// opComponent is one step of an operation: count > 0 retains, count < 0 deletes, or insert is set
+type opComponent struct {
+	count  int
+	insert string
+}
+
+func (c opComponent) isRetain() bool { return c.insert == "" && c.count > 0 }
+func (c opComponent) isDelete() bool { return c.insert == "" && c.count < 0 }
+func (c opComponent) isInsert() bool { return c.insert != "" }
+
+// utf16Len returns the length of s in UTF-16 code units
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		n += utf16.RuneLen(r)
+	}
+	return n
+}
+
+func (op *TextOperation) retain(n int) {
+	if n <= 0 {
+		return
+	}
+	if last := len(*op) - 1; last >= 0 && (*op)[last].isRetain() {
+		(*op)[last].count += n
+		return
+	}
+	*op = append(*op, opComponent{count: n})
+}
+
+// insertText keeps inserts before deletes at the same position, as ot.js does, so equal edits always
+// have the same form
+func (op *TextOperation) insertText(s string) {
+	if s == "" {
+		return
+	}
+	ops := *op
+	last := len(ops) - 1
+	switch {
+	case last >= 0 && ops[last].isInsert():
+		ops[last].insert += s
+	case last >= 0 && ops[last].isDelete():
+		if last > 0 && ops[last-1].isInsert() {
+			ops[last-1].insert += s
+		} else {
+			ops = append(ops, ops[last])
+			ops[last] = opComponent{insert: s}
+		}
+	default:
+		ops = append(ops, opComponent{insert: s})
+	}
+	*op = ops
+}
+
+func (op *TextOperation) delete(n int) {
+	if n <= 0 {
+		return
+	}
+	if last := len(*op) - 1; last >= 0 && (*op)[last].isDelete() {
+		(*op)[last].count -= n
+		return
+	}
+	*op = append(*op, opComponent{count: -n})
+}
+
+// baseLen is the length of the documents the operation applies to
+func (op TextOperation) baseLen() int {
+	n := 0
+	for _, c := range op {
+		if !c.isInsert() {
+			n += max(c.count, -c.count)
+		}
+	}
+	return n
+}
+
+// apply returns doc with the operation applied
+func (op TextOperation) apply(doc []uint16) ([]uint16, error) {
+	if op.baseLen() != len(doc) {
+		return nil, fmt.Errorf("invalid operation: it applies to %d characters, the document has %d", op.baseLen(), len(doc))
+	}
+
+	result := make([]uint16, 0, len(doc))
+	pos := 0
+	for _, c := range op {
+		switch {
+		case c.isRetain():
+			result = append(result, doc[pos:pos+c.count]...)
+			pos += c.count
+		case c.isDelete():
+			pos -= c.count
+		default:
+			result = append(result, utf16.Encode([]rune(c.insert))...)
+		}
+	}
+	return result, nil
+}
+
+// transform returns a changed so it applies after b, where both were made on the same document.
+// At the same position a's inserts come first. This is the server half of ot.js's transform.
+func transform(a, b TextOperation) (TextOperation, error) {
+	if a.baseLen() != b.baseLen() {
+		return nil, fmt.Errorf("invalid operation: concurrent operations apply to different document lengths")
+	}
+
+	var result TextOperation
+	i, j := 0, 0
+	var opA, opB *opComponent
+	next := func(ops TextOperation, k *int) *opComponent {
+		if *k >= len(ops) {
+			return nil
+		}
+		c := ops[*k]
+		*k++
+		return &c
+	}
+	opA, opB = next(a, &i), next(b, &j)
+
+	for opA != nil || opB != nil {
+		if opA != nil && opA.isInsert() {
+			result.insertText(opA.insert)
+			opA = next(a, &i)
+			continue
+		}
+		if opB != nil && opB.isInsert() {
+			result.retain(utf16Len(opB.insert))
+			opB = next(b, &j)
+			continue
+		}
+		if opA == nil || opB == nil {
+			return nil, fmt.Errorf("invalid operation: concurrent operations apply to different document lengths")
+		}
+
+		lenA, lenB := max(opA.count, -opA.count), max(opB.count, -opB.count)
+		n := min(lenA, lenB)
+		switch {
+		case opA.isRetain() && opB.isRetain():
+			result.retain(n)
+		case opA.isDelete() && opB.isRetain():
+			result.delete(n)
+		}
+		// Text deleted by b is gone, so a neither retains nor deletes it
+
+		if lenA == n {
+			opA = next(a, &i)
+		} else {
+			opA.count -= sign(opA.count) * n
+		}
+		if lenB == n {
+			opB = next(b, &j)
+		} else {
+			opB.count -= sign(opB.count) * n
+		}
+	}
+	return result, nil
+}
+
+func sign(n int) int {
+	if n < 0 {
+		return -1
+	}
+	return 1
+}
+
+func (op TextOperation) MarshalJSON() ([]byte, error) {
+	components := make([]any, len(op))
+	for i, c := range op {
+		if c.isInsert() {
+			components[i] = c.insert
+		} else {
+			components[i] = c.count
+		}
+	}
+	return json.Marshal(components)
+}
+
+func (op *TextOperation) UnmarshalJSON(data []byte) error {
+	var components []json.RawMessage
+	if err := json.Unmarshal(data, &components); err != nil {
+		return fmt.Errorf("invalid operation: %w", err)
+	}
+
+	var parsed TextOperation
+	for _, raw := range components {
+		var text string
+		if err := json.Unmarshal(raw, &text); err == nil {
+			parsed.insertText(text)
+			continue
+		}
+		var n int
+		if err := json.Unmarshal(raw, &n); err != nil || n == 0 {
+			return fmt.Errorf("invalid operation: components must be non-zero integers or strings")
+		}
+		if n > 0 {
+			parsed.retain(n)
+		} else {
+			parsed.delete(-n)
+		}
+	}
+	*op = parsed
+	return nil
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"auto-annotation-api/models"
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// consentRecord is one upload attestation in the consent report
+type consentRecord struct {
+	AnnotationID string               `json:"annotation_id"`
+	Title        string               `json:"title"`
+	SourceFile   string               `json:"source_file"`
+	UserID       string               `json:"user_id"`
+	Consent      models.UploadConsent `json:"consent"`
+	CreatedAt    time.Time            `json:"created_at"`
+}
+
+// ExportUploadConsents streams the copyright attestations recorded with uploads created in
+// [from, to) to w as csv or json, oldest first. Zero times leave the range open. Annotations
+// uploaded before consent was recorded are left out.
+func (s *AnnotationService) ExportUploadConsents(ctx context.Context, from, to time.Time, format string, w io.Writer) error {
+	if format != "csv" && format != "json" {
+		return fmt.Errorf("invalid export format %q", format)
+	}
+
+	query := bson.M{"consent": bson.M{"$exists": true}}
+	created := bson.M{}
+	if !from.IsZero() {
+		created["$gte"] = from
+	}
+	if !to.IsZero() {
+		created["$lt"] = to
+	}
+	if len(created) > 0 {
+		query["created_at"] = created
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: 1}}).
+		SetProjection(bson.M{"title": 1, "source_file": 1, "user_id": 1, "consent": 1, "created_at": 1})
+
+	cursor, err := s.collection.Find(ctx, query, opts)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	buffered := bufio.NewWriter(w)
+	csvWriter := csv.NewWriter(buffered)
+	if format == "csv" {
+		err = csvWriter.Write([]string{"annotation_id", "title", "source_file", "user_id", "accepted", "value", "terms_version", "ip", "user_agent", "recorded_at", "created_at"})
+	} else {
+		_, err = io.WriteString(buffered, "[")
+	}
+	if err != nil {
+		return err
+	}
+
+	count := 0
+	for cursor.Next(ctx) {
+		var annotation models.Annotation
+		if err := cursor.Decode(&annotation); err != nil {
+			return err
+		}
+		record := consentRecord{
+			AnnotationID: annotation.ID,
+			Title:        annotation.Title,
+			SourceFile:   annotation.SourceFile,
+			UserID:       annotation.UserID,
+			Consent:      *annotation.Consent,
+			CreatedAt:    annotation.CreatedAt,
+		}
+
+		if format == "csv" {
+			err = csvWriter.Write([]string{
+				record.AnnotationID,
+				spreadsheetSafe(record.Title),
+				spreadsheetSafe(record.SourceFile),
+				record.UserID,
+				strconv.FormatBool(record.Consent.Accepted),
+				spreadsheetSafe(record.Consent.Value),
+				spreadsheetSafe(record.Consent.TermsVersion),
+				record.Consent.IP,
+				spreadsheetSafe(record.Consent.UserAgent),
+				record.Consent.RecordedAt.UTC().Format(time.RFC3339),
+				record.CreatedAt.UTC().Format(time.RFC3339),
+			})
+		} else {
+			var data []byte
+			data, err = json.Marshal(record)
+			if err == nil {
+				separator := "\n"
+				if count > 0 {
+					separator = ",\n"
+				}
+				_, err = io.WriteString(buffered, separator+string(data))
+			}
+		}
+		if err != nil {
+			return err
+		}
+		count++
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+
+	if format == "csv" {
+		csvWriter.Flush()
+		err = csvWriter.Error()
+	} else {
+		_, err = io.WriteString(buffered, "\n]\n")
+	}
+	if err != nil {
+		return err
+	}
+	return buffered.Flush()
+}
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// repeatedSpacePattern matches the runs of spaces left behind by removed terms
+var repeatedSpacePattern = regexp.MustCompile(`[ \t]{2,}`)
+
+// ContentFilter masks or removes configured terms from text
+type ContentFilter struct {
+	pattern *regexp.Regexp
+	remove  bool
+}
+
+// NewContentFilter compiles a filter for the given terms. Matching is case-insensitive and
+// on whole words; a nil filter (no terms) leaves text unchanged.
+func NewContentFilter(terms []string, mode string) *ContentFilter {
+	quoted := make([]string, 0, len(terms))
+	for _, term := range terms {
+		if term = strings.TrimSpace(term); term != "" {
+			quoted = append(quoted, regexp.QuoteMeta(term))
+		}
+	}
+	if len(quoted) == 0 {
+		return nil
+	}
+
+	// Longest first so multi-word terms win over their parts
+	sort.Slice(quoted, func(i, j int) bool { return len(quoted[i]) > len(quoted[j]) })
+
+	return &ContentFilter{
+		pattern: regexp.MustCompile(`(?i)\b(` + strings.Join(quoted, "|") + `)\b`),
+		remove:  mode == "remove",
+	}
+}
+
+// Apply returns the text with every configured term masked or removed
+func (f *ContentFilter) Apply(text string) string {
+	if f == nil || text == "" {
+		return text
+	}
+
+	if f.remove {
+		text = f.pattern.ReplaceAllString(text, "")
+		return repeatedSpacePattern.ReplaceAllString(text, " ")
+	}
+
+	return f.pattern.ReplaceAllStringFunc(text, func(match string) string {
+		first, size := utf8.DecodeRuneInString(match)
+		return string(first) + strings.Repeat("*", utf8.RuneCountInString(match[size:]))
+	})
+}
+
+// NormalizeFilterTerms trims, lower-cases and de-duplicates filter terms
+func NormalizeFilterTerms(raw []string) []string {
+	terms := []string{}
+	seen := make(map[string]bool)
+	for _, term := range raw {
+		term = strings.ToLower(strings.Join(strings.Fields(term), " "))
+		if term != "" && !seen[term] {
+			seen[term] = true
+			terms = append(terms, term)
+		}
+	}
+	sort.Strings(terms)
+	return terms
+}
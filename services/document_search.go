@@ -0,0 +1,88 @@
+package services
+
+import (
+	"auto-annotation-api/models"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// pageMarkerPattern matches the page separators inserted by the PDF parser
+var pageMarkerPattern = regexp.MustCompile(`--- Page (\d+) ---`)
+
+// passageContextChars is how many characters of context are shown on each side of a match
+const passageContextChars = 120
+
+// searchDocumentText finds case-insensitive occurrences of query in the extracted text and
+// returns them with their page number and character offset
+func searchDocumentText(text, query string, limit int) []models.DocumentSearchMatch {
+	query = strings.TrimSpace(query)
+	if query == "" || text == "" {
+		return []models.DocumentSearchMatch{}
+	}
+
+	pageAt := pageLocator(text)
+	pattern := regexp.MustCompile(`(?i)` + regexp.QuoteMeta(query))
+	matches := []models.DocumentSearchMatch{}
+	for _, loc := range pattern.FindAllStringIndex(text, limit) {
+		matches = append(matches, models.DocumentSearchMatch{
+			Page:    pageAt(loc[0]),
+			Offset:  utf8.RuneCountInString(text[:loc[0]]),
+			Length:  utf8.RuneCountInString(text[loc[0]:loc[1]]),
+			Passage: extractPassage(text, loc[0], loc[1]),
+		})
+	}
+
+	return matches
+}
+
+// pageLocator returns a function giving the page number of a byte offset in the extracted text
+func pageLocator(text string) func(pos int) int {
+	// Page 1 has no marker, every following page starts with one
+	markers := pageMarkerPattern.FindAllStringSubmatchIndex(text, -1)
+	return func(pos int) int {
+		page := 1
+		for _, marker := range markers {
+			if marker[0] > pos {
+				break
+			}
+			if n, err := strconv.Atoi(text[marker[2]:marker[3]]); err == nil {
+				page = n
+			}
+		}
+		return page
+	}
+}
+
+// extractPassage returns the text surrounding a match, trimmed to whole words
+func extractPassage(text string, start, end int) string {
+	from := start
+	for i := 0; i < passageContextChars && from > 0; i++ {
+		_, size := utf8.DecodeLastRuneInString(text[:from])
+		from -= size
+	}
+	to := end
+	for i := 0; i < passageContextChars && to < len(text); i++ {
+		_, size := utf8.DecodeRuneInString(text[to:])
+		to += size
+	}
+
+	passage := text[from:to]
+	if from > 0 {
+		if idx := strings.IndexAny(passage, " \n"); idx >= 0 && idx < start-from {
+			passage = passage[idx+1:]
+		}
+		passage = "..." + passage
+	}
+	if to < len(text) {
+		if idx := strings.LastIndexAny(passage, " \n"); idx >= 0 && idx > len(passage)-(to-end) {
+			passage = passage[:idx]
+		}
+		passage += "..."
+	}
+
+	// Page separators are noise inside a passage
+	passage = pageMarkerPattern.ReplaceAllString(passage, " ")
+	return strings.Join(strings.Fields(passage), " ")
+}
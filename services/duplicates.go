@@ -0,0 +1,61 @@
+package services
+
+import (
+	"auto-annotation-api/models"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DuplicateUploadError rejects a document the user already uploaded. Existing is the annotation
+// created from it, nil while the first upload is still being processed.
+type DuplicateUploadError struct {
+	Existing *models.Annotation
+}
+
+func (e *DuplicateUploadError) Error() string {
+	if e.Existing == nil {
+		return "duplicate upload: the document is already being processed"
+	}
+	return fmt.Sprintf("duplicate upload: the document was already uploaded as annotation %s", e.Existing.ID)
+}
+
+// contentHash returns the hex SHA-256 of an uploaded file
+func contentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// claimUpload checks that the user has no annotation of the document with the given hash yet and
+// marks it as being processed, so a second copy uploaded meanwhile is rejected too. The returned
+// function releases the claim once the upload is stored or has failed.
+func (s *AnnotationService) claimUpload(ctx context.Context, userID, hash string) (func(), error) {
+	key := userID + ":" + hash
+	if _, processing := s.uploadsInFlight.LoadOrStore(key, struct{}{}); processing {
+		return nil, &DuplicateUploadError{}
+	}
+	release := func() { s.uploadsInFlight.Delete(key) }
+
+	// Failed uploads may be retried, merged and simulated annotations don't count
+	var existing models.Annotation
+	err := s.collection.FindOne(ctx, bson.M{
+		"user_id":      userID,
+		"content_hash": hash,
+		"status":       "completed",
+		"simulated":    bson.M{"$ne": true},
+	}, options.FindOne().SetProjection(bson.M{"text_content": 0, "embedding": 0})).Decode(&existing)
+	if err == nil {
+		release()
+		return nil, &DuplicateUploadError{Existing: &existing}
+	}
+	if err != mongo.ErrNoDocuments {
+		release()
+		return nil, fmt.Errorf("failed to check for duplicate uploads: %w", err)
+	}
+	return release, nil
+}
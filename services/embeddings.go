@@ -0,0 +1,192 @@
+package services
+
+import (
+	"auto-annotation-api/config"
+	"auto-annotation-api/models"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	maxEmbeddingChars   = 8000 // Limits how much text is sent to the embedding model
+	openAIEmbeddingsURL = "https://api.openai.com/v1/embeddings"
+)
+
+// Embedder turns text into embedding vectors, so notes about related concepts can be found even
+// when they share no words
+type Embedder interface {
+	// Model identifies the provider and model, e.g. "ollama/nomic-embed-text". Vectors of different
+	// models can't be compared.
+	Model() string
+	// Embed returns the embedding of text
+	Embed(text string) ([]float64, error)
+}
+
+// NewEmbedder creates the embedding provider selected by EMBEDDING_PROVIDER
+func NewEmbedder(cfg *config.Config) (Embedder, error) {
+	switch strings.ToLower(cfg.EmbedProvider) {
+	case "", "ollama":
+		return &ollamaEmbedder{
+			client: NewOllamaClientWithConfig(cfg.OllamaBaseURL, cfg.OllamaModel).WithFixtures(cfg.OllamaFixtures, cfg.OllamaFixtureDir),
+			model:  cfg.EmbeddingModel,
+		}, nil
+	case "openai":
+		if cfg.OpenAIKey == "" {
+			return nil, fmt.Errorf("embedding provider openai not configured: OPENAI_API_KEY missing")
+		}
+		return &openAIEmbedder{
+			client: &http.Client{Timeout: 60 * time.Second},
+			apiKey: cfg.OpenAIKey,
+			model:  cfg.OpenAIEmbedModel,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown embedding provider: %s", cfg.EmbedProvider)
+	}
+}
+
+// ollamaEmbedder generates embeddings with a local Ollama model
+type ollamaEmbedder struct {
+	client *OllamaClient
+	model  string
+}
+
+func (e *ollamaEmbedder) Model() string {
+	return "ollama/" + e.model
+}
+
+func (e *ollamaEmbedder) Embed(text string) ([]float64, error) {
+	return e.client.GenerateEmbedding(text, e.model)
+}
+
+// openAIEmbedder generates embeddings with the OpenAI embeddings API
+type openAIEmbedder struct {
+	client *http.Client
+	apiKey string
+	model  string
+}
+
+func (e *openAIEmbedder) Model() string {
+	return "openai/" + e.model
+}
+
+func (e *openAIEmbedder) Embed(text string) ([]float64, error) {
+	body, err := json.Marshal(map[string]string{"model": e.model, "input": text})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, openAIEmbeddingsURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+e.apiKey)
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request to OpenAI: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("OpenAI API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var result struct {
+		Data []struct {
+			Embedding []float64 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if len(result.Data) == 0 || len(result.Data[0].Embedding) == 0 {
+		return nil, fmt.Errorf("received empty embedding from OpenAI")
+	}
+	return result.Data[0].Embedding, nil
+}
+
+// embeddingText is the text an annotation's embedding is generated from: its title and notes,
+// limited to maxEmbeddingChars
+func embeddingText(title, notes string) string {
+	text := title + "\n\n" + notes
+	if len(text) > maxEmbeddingChars {
+		text = text[:maxEmbeddingChars]
+		for !utf8.ValidString(text) {
+			text = text[:len(text)-1]
+		}
+	}
+	return text
+}
+
+// embedNotes generates the embedding of generated notes for semantic search. Embedding is best
+// effort: failures leave the annotation out of semantic search until BackfillEmbeddings retries.
+func (s *AnnotationService) embedNotes(notes, title string) ([]float64, string) {
+	if s.embedder == nil {
+		return nil, ""
+	}
+	embedding, err := s.embedder.Embed(embeddingText(title, notes))
+	if err != nil {
+		log.Printf("Warning: failed to embed annotation %q: %v", title, err)
+		return nil, ""
+	}
+	return embedding, s.embedder.Model()
+}
+
+// BackfillEmbeddings embeds the completed annotations without an embedding of the configured model,
+// such as those created before semantic search or before the embedding provider changed
+func (s *AnnotationService) BackfillEmbeddings(ctx context.Context) {
+	if s.embedder == nil {
+		return
+	}
+	model := s.embedder.Model()
+
+	cursor, err := s.collection.Find(ctx, bson.M{
+		"status":          "completed",
+		"embedding_model": bson.M{"$ne": model},
+	}, options.Find().SetProjection(bson.M{"title": 1, "annotation": 1}))
+	if err != nil {
+		log.Printf("Warning: failed to load annotations to embed: %v", err)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	embedded := 0
+	for cursor.Next(ctx) {
+		var annotation models.Annotation
+		if err := cursor.Decode(&annotation); err != nil {
+			log.Printf("Warning: failed to decode annotation to embed: %v", err)
+			continue
+		}
+		// The provider is most likely down, the next start retries
+		embedding, err := s.embedder.Embed(embeddingText(annotation.Title, annotation.Annotation))
+		if err != nil {
+			log.Printf("Warning: embedding stopped after %d annotations: %v", embedded, err)
+			return
+		}
+		_, err = s.collection.UpdateOne(ctx, bson.M{"_id": annotation.ID}, bson.M{"$set": bson.M{
+			"embedding":       embedding,
+			"embedding_model": model,
+		}})
+		if err != nil {
+			log.Printf("Warning: failed to store embedding for annotation %s: %v", annotation.ID, err)
+			continue
+		}
+		embedded++
+	}
+	if embedded > 0 {
+		log.Printf("Embedded %d annotations with %s", embedded, model)
+	}
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"auto-annotation-api/models"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/ledongthuc/pdf"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	estimateSampleSize = 200 // Recent uploads the averages are taken from
+
+	// Used until uploads with processing stats exist
+	defaultCharsPerPage  = 2500
+	defaultCharsPerByte  = 0.02
+	defaultMsPerKiloChar = 1500
+	defaultNotesChars    = 3000
+)
+
+// uploadAverages are the averages of recent uploads that estimates scale by
+type uploadAverages struct {
+	CharsPerPage  float64
+	CharsPerByte  float64
+	MsPerKiloChar float64
+	NotesChars    float64
+	Samples       int
+}
+
+// EstimateProcessing estimates the processing time, token usage and TTS cost of a document from its
+// size or page count, scaled by the averages of recent uploads
+func (s *AnnotationService) EstimateProcessing(ctx context.Context, req *models.EstimateRequest) (*models.ProcessingEstimate, error) {
+	if req.FileSize < 0 || req.PageCount < 0 {
+		return nil, fmt.Errorf("file_size and page_count must not be negative")
+	}
+	if req.FileSize == 0 && req.PageCount == 0 {
+		return nil, fmt.Errorf("file_size or page_count is required")
+	}
+
+	averages, err := s.uploadAverages(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Page counts say more about the amount of text than file sizes, which images inflate
+	chars := averages.CharsPerByte * float64(req.FileSize)
+	if req.PageCount > 0 {
+		chars = averages.CharsPerPage * float64(req.PageCount)
+	}
+
+	estimate := &models.ProcessingEstimate{
+		FileSize:          req.FileSize,
+		PageCount:         req.PageCount,
+		TextChars:         int(chars),
+		InputTokens:       int(chars) / charsPerToken,
+		Chunks:            1,
+		ProcessingSeconds: int(math.Ceil(chars / 1000 * averages.MsPerKiloChar / 1000)),
+		TTSCharacters:     int(averages.NotesChars),
+		TTSCostUSD:        math.Round(averages.NotesChars*s.ttsCost/1e6*100) / 100,
+		BasedOn:           averages.Samples,
+	}
+	if s.chunkTokens > 0 && estimate.InputTokens > s.chunkTokens {
+		estimate.Chunks = (estimate.InputTokens + s.chunkTokens - 1) / s.chunkTokens
+	}
+	return estimate, nil
+}
+
+// EstimateUpload estimates the processing of a PDF from its size and page count, without
+// extracting its text
+func (s *AnnotationService) EstimateUpload(ctx context.Context, fileReader io.Reader) (*models.ProcessingEstimate, error) {
+	fileData, err := io.ReadAll(io.LimitReader(fileReader, s.maxUpload+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read uploaded file: %w", err)
+	}
+	if int64(len(fileData)) > s.maxUpload {
+		return nil, fmt.Errorf("file exceeds the maximum upload size of %d MB", s.maxUpload>>20)
+	}
+	if err := ValidatePDF(bytes.NewReader(fileData), int64(len(fileData))); err != nil {
+		return nil, err
+	}
+
+	r, err := pdf.NewReader(bytes.NewReader(fileData), int64(len(fileData)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse PDF: %w", err)
+	}
+	return s.EstimateProcessing(ctx, &models.EstimateRequest{FileSize: int64(len(fileData)), PageCount: r.NumPage()})
+}
+
+// uploadAverages averages the processing stats of recent uploads, falling back to defaults for
+// ratios without history
+func (s *AnnotationService) uploadAverages(ctx context.Context) (*uploadAverages, error) {
+	// Image documents are left out, their few characters per byte would skew the PDF estimates
+	cursor, err := s.collection.Aggregate(ctx, []bson.M{
+		{"$match": bson.M{"status": "completed", "source_type": "pdf", "processing": bson.M{"$exists": true}}},
+		{"$sort": bson.M{"created_at": -1}},
+		{"$limit": estimateSampleSize},
+		{"$group": bson.M{
+			"_id":         nil,
+			"samples":     bson.M{"$sum": 1},
+			"file_bytes":  bson.M{"$sum": "$processing.file_bytes"},
+			"pages":       bson.M{"$sum": "$processing.pages"},
+			"text_chars":  bson.M{"$sum": "$processing.text_chars"},
+			"duration_ms": bson.M{"$sum": "$processing.duration_ms"},
+			"notes_chars": bson.M{"$avg": bson.M{"$strLenCP": "$annotation"}},
+		}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load upload history: %w", err)
+	}
+	var totals []struct {
+		Samples    int     `bson:"samples"`
+		FileBytes  float64 `bson:"file_bytes"`
+		Pages      float64 `bson:"pages"`
+		TextChars  float64 `bson:"text_chars"`
+		DurationMs float64 `bson:"duration_ms"`
+		NotesChars float64 `bson:"notes_chars"`
+	}
+	if err := cursor.All(ctx, &totals); err != nil {
+		return nil, fmt.Errorf("failed to load upload history: %w", err)
+	}
+
+	averages := &uploadAverages{
+		CharsPerPage:  defaultCharsPerPage,
+		CharsPerByte:  defaultCharsPerByte,
+		MsPerKiloChar: defaultMsPerKiloChar,
+		NotesChars:    defaultNotesChars,
+	}
+	if len(totals) == 0 || totals[0].TextChars == 0 {
+		return averages, nil
+	}
+	t := totals[0]
+	averages.Samples = t.Samples
+	if t.Pages > 0 {
+		averages.CharsPerPage = t.TextChars / t.Pages
+	}
+	if t.FileBytes > 0 {
+		averages.CharsPerByte = t.TextChars / t.FileBytes
+	}
+	if t.DurationMs > 0 {
+		averages.MsPerKiloChar = t.DurationMs / (t.TextChars / 1000)
+	}
+	if t.NotesChars > 0 {
+		averages.NotesChars = t.NotesChars
+	}
+	return averages, nil
+}
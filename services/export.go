@@ -0,0 +1,262 @@
+package services
+
+import (
+	"auto-annotation-api/models"
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ExportFormat describes a file format annotations can be exported to
+type ExportFormat struct {
+	ContentType string
+	Extension   string
+}
+
+// ExportFormats are the formats supported by ExportAnnotations, by name
+var ExportFormats = map[string]ExportFormat{
+	"csv":  {ContentType: "text/csv; charset=utf-8", Extension: "csv"},
+	"json": {ContentType: "application/json; charset=utf-8", Extension: "json"},
+	"md":   {ContentType: "text/markdown; charset=utf-8", Extension: "md"},
+}
+
+// exportRecord is the part of an annotation included in exports
+type exportRecord struct {
+	ID         string          `json:"id"`
+	Title      string          `json:"title"`
+	Genre      string          `json:"genre"`
+	Length     string          `json:"length"`
+	License    models.License  `json:"license"`
+	Tags       []string        `json:"tags"`
+	Metadata   models.Metadata `json:"metadata,omitempty"`
+	Author     string          `json:"author,omitempty"`
+	Published  string          `json:"publication_date,omitempty"`
+	SourceURL  string          `json:"source_url,omitempty"`
+	Annotation string          `json:"annotation"`
+	ImageURL   string          `json:"image_url,omitempty"`
+	AudioURL   string          `json:"audio_url,omitempty"`
+	SourceFile string          `json:"source_file"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+// annotationExporter writes export records in one format
+type annotationExporter interface {
+	begin() error
+	write(record exportRecord) error
+	end() error
+}
+
+// ExportAnnotations streams the annotations matching filter to w in the given format, newest
+// first. Annotations still processing or failed have no annotation text and are left out. Output
+// is written as the cursor is read, so an error after the first record leaves a truncated file.
+func (s *AnnotationService) ExportAnnotations(ctx context.Context, filter models.AnnotationFilter, format string, w io.Writer) error {
+	buffered := bufio.NewWriter(w)
+	var exporter annotationExporter
+	switch format {
+	case "csv":
+		exporter = &csvExporter{w: csv.NewWriter(buffered)}
+	case "json":
+		exporter = &jsonExporter{w: buffered}
+	case "md":
+		exporter = &markdownExporter{w: buffered}
+	default:
+		return fmt.Errorf("invalid export format %q", format)
+	}
+
+	query := annotationQuery(filter)
+	query["status"] = "completed"
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}}).
+		SetProjection(bson.M{"text_content": 0, "embedding": 0})
+
+	cursor, err := s.collection.Find(ctx, query, opts)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	if err := exporter.begin(); err != nil {
+		return err
+	}
+	for cursor.Next(ctx) {
+		var annotation models.Annotation
+		if err := cursor.Decode(&annotation); err != nil {
+			return err
+		}
+		if err := s.FilterForDisplay(ctx, &annotation); err != nil {
+			return err
+		}
+		if err := exporter.write(newExportRecord(&annotation)); err != nil {
+			return err
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+	if err := exporter.end(); err != nil {
+		return err
+	}
+	return buffered.Flush()
+}
+
+func newExportRecord(annotation *models.Annotation) exportRecord {
+	response := annotation.ToResponse()
+	return exportRecord{
+		ID:         response.ID,
+		Title:      response.Title,
+		Genre:      response.Genre,
+		Length:     response.Length,
+		License:    response.License,
+		Tags:       response.Tags,
+		Metadata:   response.Metadata,
+		Author:     response.Author,
+		Published:  response.PublishedOn,
+		SourceURL:  response.SourceURL,
+		Annotation: response.Annotation,
+		ImageURL:   response.Image,
+		AudioURL:   response.TTSURL,
+		SourceFile: response.SourceFile,
+		CreatedAt:  response.CreatedAt,
+	}
+}
+
+// csvExporter writes one row per annotation; metadata is left out since its columns vary
+type csvExporter struct {
+	w *csv.Writer
+}
+
+func (e *csvExporter) begin() error {
+	return e.w.Write([]string{"id", "title", "genre", "length", "license", "license_url", "tags", "author", "publication_date", "source_url", "annotation", "image_url", "audio_url", "source_file", "created_at"})
+}
+
+func (e *csvExporter) write(r exportRecord) error {
+	return e.w.Write([]string{
+		r.ID,
+		spreadsheetSafe(r.Title),
+		spreadsheetSafe(r.Genre),
+		r.Length,
+		r.License.ID,
+		r.License.URL,
+		spreadsheetSafe(strings.Join(r.Tags, ", ")),
+		spreadsheetSafe(r.Author),
+		r.Published,
+		r.SourceURL,
+		spreadsheetSafe(r.Annotation),
+		r.ImageURL,
+		r.AudioURL,
+		spreadsheetSafe(r.SourceFile),
+		r.CreatedAt.UTC().Format(time.RFC3339),
+	})
+}
+
+func (e *csvExporter) end() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// spreadsheetSafe keeps spreadsheets from evaluating a cell as a formula
+func spreadsheetSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// jsonExporter writes a JSON array, one record at a time
+type jsonExporter struct {
+	w     io.Writer
+	count int
+}
+
+func (e *jsonExporter) begin() error {
+	_, err := io.WriteString(e.w, "[")
+	return err
+}
+
+func (e *jsonExporter) write(r exportRecord) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	separator := "\n"
+	if e.count > 0 {
+		separator = ",\n"
+	}
+	e.count++
+	if _, err := io.WriteString(e.w, separator); err != nil {
+		return err
+	}
+	_, err = e.w.Write(data)
+	return err
+}
+
+func (e *jsonExporter) end() error {
+	_, err := io.WriteString(e.w, "\n]\n")
+	return err
+}
+
+// markdownExporter writes a document with a section per annotation, for pasting into course docs
+type markdownExporter struct {
+	w io.Writer
+}
+
+func (e *markdownExporter) begin() error {
+	_, err := fmt.Fprintf(e.w, "# Annotations\n\nExported %s\n", time.Now().UTC().Format("2006-01-02"))
+	return err
+}
+
+func (e *markdownExporter) write(r exportRecord) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "\n## %s\n\n", strings.TrimSpace(r.Title))
+
+	details := []string{}
+	if r.Author != "" {
+		details = append(details, "**Author:** "+r.Author)
+	}
+	if r.Published != "" {
+		details = append(details, "**Published:** "+r.Published)
+	}
+	if r.SourceURL != "" {
+		details = append(details, fmt.Sprintf("**Source:** <%s>", r.SourceURL))
+	}
+	if r.Genre != "" {
+		details = append(details, "**Genre:** "+r.Genre)
+	}
+	if len(r.Tags) > 0 {
+		details = append(details, "**Tags:** "+strings.Join(r.Tags, ", "))
+	}
+	details = append(details, "**Created:** "+r.CreatedAt.UTC().Format("2006-01-02"))
+	if r.License.URL != "" {
+		details = append(details, fmt.Sprintf("**License:** [%s](%s)", r.License.Name, r.License.URL))
+	} else {
+		details = append(details, "**License:** "+r.License.Name)
+	}
+	b.WriteString(strings.Join(details, " · ") + "\n\n")
+
+	b.WriteString(strings.TrimSpace(r.Annotation) + "\n")
+
+	if r.ImageURL != "" || r.AudioURL != "" {
+		b.WriteString("\n")
+		if r.ImageURL != "" {
+			fmt.Fprintf(&b, "- [Image](%s)\n", r.ImageURL)
+		}
+		if r.AudioURL != "" {
+			fmt.Fprintf(&b, "- [Audio](%s)\n", r.AudioURL)
+		}
+	}
+
+	_, err := io.WriteString(e.w, b.String())
+	return err
+}
+
+func (e *markdownExporter) end() error {
+	return nil
+}
@@ -0,0 +1,191 @@
+package services
+
+import (
+	"auto-annotation-api/config"
+	"auto-annotation-api/models"
+	"context"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const featureFlagsSettingsID = "feature_flags"
+
+// FeatureFlagService decides which users can use experimental endpoints. Defaults come from the
+// FEATURE_FLAGS configuration; admins override them at runtime in the settings collection.
+type FeatureFlagService struct {
+	collection *mongo.Collection
+	defaults   map[string]models.FeatureFlag
+
+	mu     sync.Mutex
+	flags  map[string]models.FeatureFlag
+	loaded time.Time
+}
+
+// NewFeatureFlagService creates a new feature flag service
+func NewFeatureFlagService(db *mongo.Database, cfg *config.Config) *FeatureFlagService {
+	return &FeatureFlagService{
+		collection: db.Collection("settings"),
+		defaults:   parseFeatureFlags(cfg.FeatureFlags),
+	}
+}
+
+// parseFeatureFlags reads the configured defaults, e.g. "explain=100,clusters=25" for a rollout
+// percentage per flag. Flags that aren't configured are on for everyone.
+func parseFeatureFlags(spec string) map[string]models.FeatureFlag {
+	flags := make(map[string]models.FeatureFlag, len(models.Features))
+	for name := range models.Features {
+		flags[name] = models.FeatureFlag{Name: name, Enabled: true, Rollout: 100}
+	}
+
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, value, _ := strings.Cut(entry, "=")
+		name = strings.TrimSpace(name)
+		rollout, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(value), "%"))
+		if _, known := models.Features[name]; !known || err != nil || rollout < 0 || rollout > 100 {
+			log.Printf("Warning: ignoring invalid FEATURE_FLAGS entry %q, expected <flag>=<percentage>", entry)
+			continue
+		}
+		flags[name] = models.FeatureFlag{Name: name, Enabled: rollout > 0, Rollout: rollout}
+	}
+	return flags
+}
+
+// List returns every feature flag as it currently applies, sorted by name
+func (s *FeatureFlagService) List(ctx context.Context) ([]models.FeatureFlag, error) {
+	flags, err := s.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]models.FeatureFlag, 0, len(flags))
+	for _, flag := range flags {
+		list = append(list, flag)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list, nil
+}
+
+// Update changes a feature flag, taking effect on every instance within the settings cache window
+func (s *FeatureFlagService) Update(ctx context.Context, name string, req *models.UpdateFeatureFlagRequest, userID string) (*models.FeatureFlag, error) {
+	flags, err := s.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	flag, ok := flags[name]
+	if !ok {
+		return nil, fmt.Errorf("feature flag not found")
+	}
+
+	if req.Enabled != nil {
+		flag.Enabled = *req.Enabled
+	}
+	if req.Rollout != nil {
+		flag.Rollout = *req.Rollout
+	}
+	if req.Roles != nil {
+		flag.Roles = *req.Roles
+	}
+	if req.UserIDs != nil {
+		flag.UserIDs = *req.UserIDs
+	}
+
+	_, err = s.collection.UpdateOne(ctx, bson.M{"_id": featureFlagsSettingsID}, bson.M{"$set": bson.M{
+		"flags." + name: flag,
+		"updated_by":    userID,
+		"updated_at":    time.Now(),
+	}}, options.Update().SetUpsert(true))
+	if err != nil {
+		return nil, fmt.Errorf("failed to save feature flag: %w", err)
+	}
+
+	s.mu.Lock()
+	s.loaded = time.Time{} // Reload on next use
+	s.mu.Unlock()
+
+	return &flag, nil
+}
+
+// Enabled reports whether user may use the feature. Users without an account only get features
+// rolled out to everyone. When the settings can't be loaded the configured defaults apply.
+func (s *FeatureFlagService) Enabled(ctx context.Context, name string, user *models.User) bool {
+	flags, err := s.load(ctx)
+	if err != nil {
+		log.Printf("Warning: %v, using the configured feature flags", err)
+		flags = s.defaults
+	}
+
+	flag, ok := flags[name]
+	if !ok || !flag.Enabled {
+		return false
+	}
+	if flag.Rollout >= 100 {
+		return true
+	}
+	if user == nil {
+		return false
+	}
+	if slices.Contains(flag.Roles, user.Role) || slices.Contains(flag.UserIDs, user.ID) {
+		return true
+	}
+	return rolloutBucket(name, user.ID) < flag.Rollout
+}
+
+// rolloutBucket assigns a user a stable bucket from 0 to 99 per flag, so raising a flag's rollout
+// only adds users and different flags reach different users
+func rolloutBucket(name, userID string) int {
+	h := fnv.New32a()
+	h.Write([]byte(name + ":" + userID))
+	return int(h.Sum32() % 100)
+}
+
+// load returns the configured flags with the runtime overrides applied. They are cached briefly
+// since they are consulted on every gated request.
+func (s *FeatureFlagService) load(ctx context.Context) (map[string]models.FeatureFlag, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if time.Since(s.loaded) < settingsCacheTTL {
+		return s.flags, nil
+	}
+
+	var settings models.FeatureFlagSettings
+	err := s.collection.FindOne(ctx, bson.M{"_id": featureFlagsSettingsID}).Decode(&settings)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return nil, fmt.Errorf("failed to load feature flags: %w", err)
+	}
+
+	flags := make(map[string]models.FeatureFlag, len(s.defaults))
+	for name, flag := range s.defaults {
+		if override, ok := settings.Flags[name]; ok {
+			flag = override
+		}
+		flag.Name = name
+		flag.Description = models.Features[name]
+		if flag.Roles == nil {
+			flag.Roles = []string{}
+		}
+		if flag.UserIDs == nil {
+			flag.UserIDs = []string{}
+		}
+		flags[name] = flag
+	}
+
+	s.flags = flags
+	s.loaded = time.Now()
+	return flags, nil
+}
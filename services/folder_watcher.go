@@ -0,0 +1,160 @@
+package services
+
+import (
+	"auto-annotation-api/models"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// FolderWatcher polls a local directory for dropped PDFs and turns them into annotations
+type FolderWatcher struct {
+	dir               string
+	userID            string
+	interval          time.Duration
+	annotationService *AnnotationService
+	pendingSizes      map[string]int64 // File sizes seen on the previous poll, used to skip files still being written
+}
+
+// NewFolderWatcher creates a new folder watcher that creates annotations as the given user
+func NewFolderWatcher(dir, userID string, interval time.Duration, annotationService *AnnotationService) *FolderWatcher {
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+
+	return &FolderWatcher{
+		dir:               dir,
+		userID:            userID,
+		interval:          interval,
+		annotationService: annotationService,
+		pendingSizes:      make(map[string]int64),
+	}
+}
+
+// Start polls the directory until the context is cancelled
+func (w *FolderWatcher) Start(ctx context.Context) error {
+	for _, sub := range []string{"done", "failed"} {
+		if err := os.MkdirAll(filepath.Join(w.dir, sub), 0755); err != nil {
+			return fmt.Errorf("failed to create %s directory: %w", sub, err)
+		}
+	}
+
+	log.Printf("Watching %s for new PDFs (every %s)", w.dir, w.interval)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		w.poll(ctx)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll processes every PDF whose size hasn't changed since the previous poll
+func (w *FolderWatcher) poll(ctx context.Context) {
+	entries, err := os.ReadDir(w.dir)
+	if err != nil {
+		log.Printf("Folder watcher: failed to read %s: %v", w.dir, err)
+		return
+	}
+
+	seen := make(map[string]int64)
+	for _, entry := range entries {
+		if entry.IsDir() || strings.ToLower(filepath.Ext(entry.Name())) != ".pdf" {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+
+		name := entry.Name()
+		if previous, ok := w.pendingSizes[name]; !ok || previous != info.Size() {
+			// New or still growing (e.g. a scanner is writing it), check again next poll
+			seen[name] = info.Size()
+			continue
+		}
+
+		// Files left over at shutdown are picked up after the restart
+		if ctx.Err() != nil {
+			return
+		}
+		w.processFile(ctx, name, info.Size())
+	}
+	w.pendingSizes = seen
+}
+
+// processFile creates an annotation from a single file and moves it to done/ or failed/
+func (w *FolderWatcher) processFile(ctx context.Context, name string, size int64) {
+	path := filepath.Join(w.dir, name)
+	log.Printf("Folder watcher: processing %s", path)
+
+	// A file that is being processed is finished even when the watcher is stopped
+	err := w.createAnnotation(context.WithoutCancel(ctx), path, name, size)
+	if err != nil {
+		log.Printf("Folder watcher: failed to process %s: %v", name, err)
+		w.moveFile(path, "failed")
+
+		errorFile := filepath.Join(w.dir, "failed", name+".error.txt")
+		if writeErr := os.WriteFile(errorFile, []byte(err.Error()+"\n"), 0644); writeErr != nil {
+			log.Printf("Folder watcher: failed to write %s: %v", errorFile, writeErr)
+		}
+		return
+	}
+
+	w.moveFile(path, "done")
+}
+
+// createAnnotation runs the annotation pipeline for a file, using its name as the title
+func (w *FolderWatcher) createAnnotation(ctx context.Context, path, name string, size int64) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	annotation, err := w.annotationService.CreateAnnotationFromStream(
+		ctx,
+		w.userID,
+		&models.CreateAnnotationRequest{Title: titleFromFilename(name), TitleSource: models.TitleFileName, FileName: name},
+		file,
+		size,
+		"pdf",
+		nil,
+	)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Folder watcher: created annotation %s from %s", annotation.ID, name)
+	return nil
+}
+
+// moveFile moves a processed file into a subfolder, avoiding name collisions
+func (w *FolderWatcher) moveFile(path, subfolder string) {
+	target := filepath.Join(w.dir, subfolder, filepath.Base(path))
+	if _, err := os.Stat(target); err == nil {
+		target = filepath.Join(w.dir, subfolder, fmt.Sprintf("%d_%s", time.Now().Unix(), filepath.Base(path)))
+	}
+
+	if err := os.Rename(path, target); err != nil {
+		log.Printf("Folder watcher: failed to move %s to %s: %v", path, subfolder, err)
+	}
+}
+
+// titleFromFilename derives a readable title from a file name
+func titleFromFilename(name string) string {
+	title := strings.TrimSuffix(name, filepath.Ext(name))
+	title = strings.NewReplacer("_", " ", "-", " ").Replace(title)
+	return strings.Join(strings.Fields(title), " ")
+}
@@ -0,0 +1,172 @@
+package services
+
+import (
+	"auto-annotation-api/models"
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// maxGalleryImages limits the number of images per annotation
+const maxGalleryImages = 20
+
+// AddImage appends an image to an annotation's gallery. Images without alt text get generated alt text.
+func (s *AnnotationService) AddImage(ctx context.Context, annotationID, url, caption, altText string) (*models.Annotation, error) {
+	annotation, err := s.GetAnnotationByID(ctx, annotationID)
+	if err != nil {
+		return nil, err
+	}
+
+	images := annotation.GalleryImages()
+	if len(images) >= maxGalleryImages {
+		return nil, fmt.Errorf("gallery is full: at most %d images per annotation", maxGalleryImages)
+	}
+
+	image := models.NewGalleryImage(url, s.storageKeyFromURL(url), strings.TrimSpace(caption), strings.TrimSpace(altText))
+	if image.AltText == "" {
+		image.AltText = s.generateImageAltText(ctx, annotation, image.URL, image.Key)
+	}
+
+	return s.saveImages(ctx, annotationID, append(images, image))
+}
+
+// UpdateImage changes the caption or alt text of a gallery image
+func (s *AnnotationService) UpdateImage(ctx context.Context, annotationID, imageID string, req *models.UpdateGalleryImageRequest) (*models.Annotation, error) {
+	annotation, err := s.GetAnnotationByID(ctx, annotationID)
+	if err != nil {
+		return nil, err
+	}
+
+	images := annotation.GalleryImages()
+	i := galleryIndex(images, imageID)
+	if i < 0 {
+		return nil, fmt.Errorf("image not found")
+	}
+	if req.Caption != nil {
+		images[i].Caption = strings.TrimSpace(*req.Caption)
+	}
+	if req.AltText != nil {
+		images[i].AltText = strings.TrimSpace(*req.AltText)
+	}
+
+	return s.saveImages(ctx, annotationID, images)
+}
+
+// RemoveImage removes an image from the gallery and deletes its file when it was uploaded
+func (s *AnnotationService) RemoveImage(ctx context.Context, annotationID, imageID string) (*models.Annotation, error) {
+	annotation, err := s.GetAnnotationByID(ctx, annotationID)
+	if err != nil {
+		return nil, err
+	}
+
+	images := annotation.GalleryImages()
+	i := galleryIndex(images, imageID)
+	if i < 0 {
+		return nil, fmt.Errorf("image not found")
+	}
+	removed := images[i]
+
+	updated, err := s.saveImages(ctx, annotationID, append(images[:i:i], images[i+1:]...))
+	if err != nil {
+		return nil, err
+	}
+
+	if removed.Key != "" && s.storage != nil {
+		if err := s.storage.Delete(ctx, removed.Key); err != nil {
+			log.Printf("Warning: failed to delete image %s of annotation %s: %v", removed.Key, annotationID, err)
+		}
+	}
+
+	return updated, nil
+}
+
+// ReorderImages puts the gallery in the given order, which must list every image ID exactly once
+func (s *AnnotationService) ReorderImages(ctx context.Context, annotationID string, imageIDs []string) (*models.Annotation, error) {
+	annotation, err := s.GetAnnotationByID(ctx, annotationID)
+	if err != nil {
+		return nil, err
+	}
+
+	images := annotation.GalleryImages()
+	if len(imageIDs) != len(images) {
+		return nil, fmt.Errorf("invalid image order: expected %d image IDs, got %d", len(images), len(imageIDs))
+	}
+
+	ordered := make([]models.GalleryImage, 0, len(images))
+	seen := make(map[string]bool)
+	for _, id := range imageIDs {
+		i := galleryIndex(images, id)
+		if i < 0 || seen[id] {
+			return nil, fmt.Errorf("invalid image order: unknown or repeated image ID %s", id)
+		}
+		seen[id] = true
+		ordered = append(ordered, images[i])
+	}
+
+	return s.saveImages(ctx, annotationID, ordered)
+}
+
+// replaceCoverImage swaps the first gallery image for a new URL, or removes it when the URL is empty.
+// This keeps the legacy image field of UpdateAnnotationRequest working.
+func (s *AnnotationService) replaceCoverImage(images []models.GalleryImage, url string) []models.GalleryImage {
+	if url == "" {
+		if len(images) == 0 {
+			return images
+		}
+		return images[1:]
+	}
+
+	cover := models.NewGalleryImage(url, s.storageKeyFromURL(url), "", "")
+	if len(images) == 0 {
+		return []models.GalleryImage{cover}
+	}
+	return append([]models.GalleryImage{cover}, images[1:]...)
+}
+
+// saveImages stores the gallery and returns the updated annotation
+func (s *AnnotationService) saveImages(ctx context.Context, annotationID string, images []models.GalleryImage) (*models.Annotation, error) {
+	fields := galleryFields(images)
+	fields["updated_at"] = time.Now()
+
+	result, err := s.collection.UpdateOne(ctx, bson.M{"_id": annotationID}, bson.M{"$set": fields})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update images: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return nil, fmt.Errorf("annotation not found")
+	}
+	s.syncSearch(ctx, annotationID)
+	s.purgeCDN(annotationID)
+
+	return s.GetAnnotationByID(ctx, annotationID)
+}
+
+// galleryFields returns the update for a gallery, with its first image mirrored in the legacy image fields
+func galleryFields(images []models.GalleryImage) bson.M {
+	var annotation models.Annotation
+	annotation.SetImages(images)
+	if annotation.Images == nil {
+		annotation.Images = []models.GalleryImage{}
+	}
+
+	return bson.M{
+		"images":         annotation.Images,
+		"image":          annotation.Image,
+		"image_key":      annotation.ImageKey,
+		"image_alt_text": annotation.ImageAltText,
+	}
+}
+
+// galleryIndex returns the position of an image in the gallery, or -1
+func galleryIndex(images []models.GalleryImage, imageID string) int {
+	for i, image := range images {
+		if image.ID == imageID {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"auto-annotation-api/config"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// readinessCheckTimeout bounds each dependency check, so a hanging dependency fails the probe
+// instead of outliving it
+const readinessCheckTimeout = 3 * time.Second
+
+// ReadinessReport is the result of a readiness check. Checks maps each checked dependency to
+// "OK" or the error it failed with.
+type ReadinessReport struct {
+	Ready     bool              `json:"ready"`
+	Checks    map[string]string `json:"checks"`
+	CheckedAt time.Time         `json:"checked_at"`
+}
+
+// ReadinessChecker decides whether the API can serve traffic, for orchestrator readiness probes.
+// MongoDB is pinged on every check; Ollama and AWS are optional and their results are cached, as
+// probes run every few seconds on every instance.
+type ReadinessChecker struct {
+	db       *mongo.Database
+	ollama   *OllamaClient // nil when not checked
+	aws      *AWSService   // nil when not checked
+	cacheTTL time.Duration
+
+	mu        sync.Mutex
+	cached    map[string]string
+	cachedAt  time.Time
+	refreshed chan struct{} // Closed when a running refresh of the cached checks finishes
+}
+
+// NewReadinessChecker creates a readiness checker for the dependencies enabled in cfg
+func NewReadinessChecker(db *mongo.Database, cfg *config.Config, awsService *AWSService) *ReadinessChecker {
+	checker := &ReadinessChecker{
+		db:       db,
+		cacheTTL: time.Duration(cfg.ReadyCacheSeconds) * time.Second,
+	}
+	// Replayed fixtures don't need a running Ollama
+	if cfg.ReadyCheckOllama && cfg.OllamaFixtures != "replay" {
+		checker.ollama = NewOllamaClientWithConfig(cfg.OllamaBaseURL, cfg.OllamaModel)
+	}
+	if cfg.ReadyCheckAWS {
+		checker.aws = awsService
+	}
+	return checker
+}
+
+// Check reports whether MongoDB and the optional dependencies are reachable
+func (r *ReadinessChecker) Check(ctx context.Context) ReadinessReport {
+	report := ReadinessReport{
+		Ready:     true,
+		Checks:    make(map[string]string),
+		CheckedAt: time.Now(),
+	}
+
+	pingCtx, cancel := context.WithTimeout(ctx, readinessCheckTimeout)
+	defer cancel()
+	if err := r.db.Client().Ping(pingCtx, nil); err != nil {
+		report.Checks["mongodb"] = err.Error()
+	} else {
+		report.Checks["mongodb"] = "OK"
+	}
+
+	for name, result := range r.optionalChecks(ctx) {
+		report.Checks[name] = result
+	}
+	for _, result := range report.Checks {
+		if result != "OK" {
+			report.Ready = false
+		}
+	}
+	return report
+}
+
+// optionalChecks returns the cached results of the Ollama and AWS checks, refreshing them when
+// they are older than the cache lifetime. Concurrent probes share one refresh.
+func (r *ReadinessChecker) optionalChecks(ctx context.Context) map[string]string {
+	if r.ollama == nil && r.aws == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	if r.cached != nil && time.Since(r.cachedAt) < r.cacheTTL {
+		cached := r.cached
+		r.mu.Unlock()
+		return cached
+	}
+	if r.refreshed != nil {
+		refreshed := r.refreshed
+		r.mu.Unlock()
+		select {
+		case <-refreshed:
+		case <-ctx.Done():
+			return map[string]string{"dependencies": "check still running: " + ctx.Err().Error()}
+		}
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		return r.cached
+	}
+	refreshed := make(chan struct{})
+	r.refreshed = refreshed
+	r.mu.Unlock()
+
+	results := make(map[string]string)
+	if r.ollama != nil {
+		results["ollama"] = checkWithTimeout(r.ollama.TestConnection)
+	}
+	if r.aws != nil {
+		results["aws"] = checkWithTimeout(r.aws.TestConnection)
+	}
+
+	r.mu.Lock()
+	r.cached = results
+	r.cachedAt = time.Now()
+	r.refreshed = nil
+	r.mu.Unlock()
+	close(refreshed)
+	return results
+}
+
+// checkWithTimeout runs a connection test that takes no context, giving up after
+// readinessCheckTimeout. A test that hangs finishes in the background.
+func checkWithTimeout(test func() error) string {
+	result := make(chan error, 1)
+	go func() {
+		result <- test()
+	}()
+
+	select {
+	case err := <-result:
+		if err != nil {
+			return err.Error()
+		}
+		return "OK"
+	case <-time.After(readinessCheckTimeout):
+		return fmt.Sprintf("no response within %s", readinessCheckTimeout)
+	}
+}
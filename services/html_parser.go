@@ -0,0 +1,319 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// minArticleText is how much text an <article> or <main> element needs to be taken as the page's
+// content; shorter ones are teasers or cards, and the content is found by scoring paragraphs
+const minArticleText = 500
+
+// boilerplatePattern matches the class and id of page elements around the content
+var boilerplatePattern = regexp.MustCompile(`(?i)(^|[-_\s])(nav|navbar|menu|sidebar|footer|header|masthead|comments?|share|social|cookies?|consent|banner|ads?|advert\w*|promo|related|breadcrumbs?|subscribe|newsletter|popup|modal|skip)([-_\s]|$)`)
+
+// whitespacePattern matches runs of whitespace, which HTML renders as a single space
+var whitespacePattern = regexp.MustCompile(`\s+`)
+
+// boilerplateRoles are the ARIA roles of page elements around the content
+var boilerplateRoles = map[string]bool{
+	"navigation":    true,
+	"banner":        true,
+	"contentinfo":   true,
+	"complementary": true,
+	"dialog":        true,
+	"search":        true,
+}
+
+// HTMLParser extracts the readable text of web pages: the article, without navigation, ads,
+// scripts and other page furniture
+type HTMLParser struct{}
+
+// NewHTMLParser creates a new HTML parser
+func NewHTMLParser() *HTMLParser {
+	return &HTMLParser{}
+}
+
+// ExtractTextFromReader extracts the readable text of an HTML page. The page must be UTF-8, see
+// fetchDocument for pages in other encodings.
+func (p *HTMLParser) ExtractTextFromReader(reader io.Reader, size int64) (string, error) {
+	doc, err := html.Parse(reader)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse HTML: %w", err)
+	}
+
+	body := findElement(doc, atom.Body)
+	if body == nil {
+		body = doc
+	}
+	var text strings.Builder
+	writeReadableText(&text, contentElement(body))
+
+	extractedText := cleanExtractedText(text.String())
+	if extractedText == "" {
+		return "", fmt.Errorf("no text content found in web page")
+	}
+	return extractedText, nil
+}
+
+// ExtractText extracts the readable text of an HTML file
+func (p *HTMLParser) ExtractText(filePath string) (string, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open HTML file: %w", err)
+	}
+	return p.ExtractTextFromReader(bytes.NewReader(data), int64(len(data)))
+}
+
+// htmlTitle returns the title of a page: its og:title, else its <title>, else its first <h1>
+func htmlTitle(data []byte) string {
+	doc, err := html.Parse(bytes.NewReader(data))
+	if err != nil {
+		return ""
+	}
+
+	var ogTitle, title, heading string
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.DataAtom {
+			case atom.Meta:
+				if attr(n, "property") == "og:title" && ogTitle == "" {
+					ogTitle = attr(n, "content")
+				}
+			case atom.Title:
+				if title == "" {
+					title = nodeText(n)
+				}
+			case atom.H1:
+				if heading == "" {
+					heading = nodeText(n)
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	return strings.Join(strings.Fields(firstNonEmpty(ogTitle, title, heading)), " ")
+}
+
+// contentElement finds the element holding a page's content: its <article> or <main> when that
+// has enough text, else the element with the most paragraph text, in the manner of Readability
+func contentElement(body *html.Node) *html.Node {
+	var best *html.Node
+	bestLength := 0
+	var candidates func(n *html.Node)
+	candidates = func(n *html.Node) {
+		if n.Type == html.ElementNode && isBoilerplate(n) {
+			return
+		}
+		if n.Type == html.ElementNode && (n.DataAtom == atom.Article || n.DataAtom == atom.Main || attr(n, "role") == "main") {
+			if length := len(nodeText(n)); length > bestLength {
+				best, bestLength = n, length
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			candidates(c)
+		}
+	}
+	candidates(body)
+	if bestLength >= minArticleText {
+		return best
+	}
+
+	// Each paragraph adds its length to its parent and half of it to its grandparent, so the
+	// container of the article's paragraphs scores highest
+	scores := make(map[*html.Node]int)
+	var score func(n *html.Node)
+	score = func(n *html.Node) {
+		if n.Type == html.ElementNode && isBoilerplate(n) {
+			return
+		}
+		if n.Type == html.ElementNode && (n.DataAtom == atom.P || n.DataAtom == atom.Pre) && n.Parent != nil {
+			length := len(nodeText(n))
+			scores[n.Parent] += length
+			if n.Parent.Parent != nil {
+				scores[n.Parent.Parent] += length / 2
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			score(c)
+		}
+	}
+	score(body)
+
+	best, bestScore := body, 0
+	for n, s := range scores {
+		if s > bestScore {
+			best, bestScore = n, s
+		}
+	}
+	if bestScore < minArticleText {
+		return body // No clear article, the whole page is kept without its furniture
+	}
+	return best
+}
+
+// writeReadableText writes the text of n, one line per block element, leaving out boilerplate
+// and link lists
+func writeReadableText(b *strings.Builder, n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		// Line breaks in the source are layout, not text
+		b.WriteString(whitespacePattern.ReplaceAllString(n.Data, " "))
+		return
+	case html.ElementNode:
+		if isBoilerplate(n) || isLinkList(n) {
+			return
+		}
+		switch n.DataAtom {
+		case atom.Pre:
+			// Code and preformatted text keep their line breaks
+			b.WriteString("\n")
+			b.WriteString(nodeText(n))
+			b.WriteString("\n")
+			return
+		case atom.Br:
+			b.WriteString("\n")
+			return
+		case atom.Li:
+			b.WriteString("\n- ")
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				writeReadableText(b, c)
+			}
+			b.WriteString("\n")
+			return
+		case atom.Img:
+			if alt := strings.TrimSpace(attr(n, "alt")); alt != "" {
+				b.WriteString(alt + " ")
+			}
+			return
+		}
+	}
+
+	block := n.Type == html.ElementNode && isBlockElement(n.DataAtom)
+	if block {
+		b.WriteString("\n")
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		writeReadableText(b, c)
+	}
+	if block {
+		b.WriteString("\n")
+	}
+}
+
+// isBoilerplate reports whether an element is page furniture rather than content
+func isBoilerplate(n *html.Node) bool {
+	switch n.DataAtom {
+	case atom.Script, atom.Style, atom.Noscript, atom.Template, atom.Svg, atom.Canvas, atom.Iframe,
+		atom.Form, atom.Button, atom.Input, atom.Select, atom.Textarea, atom.Nav, atom.Header,
+		atom.Footer, atom.Aside, atom.Menu, atom.Dialog, atom.Head:
+		return true
+	}
+	if _, hidden := attrValue(n, "hidden"); hidden || attr(n, "aria-hidden") == "true" {
+		return true
+	}
+	if boilerplateRoles[attr(n, "role")] {
+		return true
+	}
+	// The content element itself often carries a class such as "post-header-wrapper"; only short
+	// elements are judged by class
+	return boilerplatePattern.MatchString(attr(n, "class")+" "+attr(n, "id")) && len(nodeText(n)) < minArticleText
+}
+
+// isLinkList reports whether a list or container is mostly links, such as a table of contents or a
+// list of related articles
+func isLinkList(n *html.Node) bool {
+	switch n.DataAtom {
+	case atom.Ul, atom.Ol, atom.Div, atom.Section, atom.Table:
+	default:
+		return false
+	}
+	text := len(nodeText(n))
+	if text == 0 {
+		return false
+	}
+	links := 0
+	var count func(n *html.Node)
+	count = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.DataAtom == atom.A {
+			links += len(nodeText(n))
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			count(c)
+		}
+	}
+	count(n)
+	return links*2 > text
+}
+
+// isBlockElement reports whether an element starts a new line of text
+func isBlockElement(a atom.Atom) bool {
+	switch a {
+	case atom.P, atom.Div, atom.Section, atom.Article, atom.Main, atom.H1, atom.H2, atom.H3, atom.H4,
+		atom.H5, atom.H6, atom.Ul, atom.Ol, atom.Li, atom.Blockquote, atom.Table, atom.Tr, atom.Dl,
+		atom.Dt, atom.Dd, atom.Figure, atom.Figcaption, atom.Hr:
+		return true
+	}
+	return false
+}
+
+// nodeText returns the text of a node and its descendants
+func nodeText(n *html.Node) string {
+	var b strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+			return
+		}
+		if n.Type == html.ElementNode && (n.DataAtom == atom.Script || n.DataAtom == atom.Style) {
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return strings.TrimSpace(b.String())
+}
+
+// findElement returns the first element of type a under n
+func findElement(n *html.Node, a atom.Atom) *html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == a {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findElement(c, a); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// attr returns the value of an element's attribute, or "" when it is not set
+func attr(n *html.Node, key string) string {
+	value, _ := attrValue(n, key)
+	return value
+}
+
+// attrValue returns the value of an element's attribute and whether it is set
+func attrValue(n *html.Node, key string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,80 @@
+package services
+
+import (
+	"auto-annotation-api/utils"
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/png"
+	"log"
+	"strings"
+)
+
+// imageDocumentTypes maps the image types accepted as source documents, such as photos of
+// whiteboards or slides, to their content types. Multimodal Ollama models read PNG and JPEG.
+var imageDocumentTypes = map[string]string{
+	"png":  "image/png",
+	"jpg":  "image/jpeg",
+	"jpeg": "image/jpeg",
+}
+
+// IsImageDocument reports whether a file type is an image accepted as a source document
+func IsImageDocument(fileType string) bool {
+	_, ok := imageDocumentTypes[strings.ToLower(fileType)]
+	return ok
+}
+
+// checkImageDocuments returns an error when images can't be read, with neither a vision model
+// nor OCR available
+func (s *AnnotationService) checkImageDocuments() error {
+	if s.visionModel == "" && s.ocr == nil {
+		return fmt.Errorf("image documents are not supported by this server, they need OLLAMA_VISION_MODEL or OCR")
+	}
+	return nil
+}
+
+// transcribeImageDocument reads the content of an image uploaded as a document. The vision model,
+// when configured, also reads handwriting and explains diagrams; Tesseract reads printed text only
+// and is used without a vision model or when it fails.
+func (s *AnnotationService) transcribeImageDocument(ctx context.Context, data []byte) (string, error) {
+	if s.visionModel != "" {
+		_, span := utils.StartSpan(ctx, "ollama.transcribe_image")
+		text, err := s.ollamaClient.TranscribeImage(ctx, s.visionModel, data)
+		text = cleanExtractedText(text)
+		span.SetAttribute("text.length", len(text))
+		span.RecordError(err)
+		span.End()
+		switch {
+		case err == nil && text != "":
+			return text, nil
+		case s.ocr == nil && err != nil:
+			return "", fmt.Errorf("failed to read image with %s: %w", s.visionModel, err)
+		case s.ocr == nil:
+			return "", fmt.Errorf("no text content found in image")
+		case err != nil:
+			log.Printf("Warning: failed to read image with %s, falling back to OCR: %v", s.visionModel, err)
+		}
+	}
+
+	_, span := utils.StartSpan(ctx, "annotation.ocr")
+	text, err := s.ocr.ExtractImageText(ctx, data)
+	span.SetAttribute("text.length", len(text))
+	span.RecordError(err)
+	span.End()
+	return text, err
+}
+
+// renderImageThumbnail scales an image document down to a PNG thumbnail
+func renderImageThumbnail(data []byte) ([]byte, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, scaleToWidth(img, thumbnailWidth)); err != nil {
+		return nil, fmt.Errorf("failed to encode thumbnail: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,222 @@
+package services
+
+import (
+	"auto-annotation-api/models"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	linkCheckWorkers = 8
+	linkCheckTimeout = 15 * time.Second
+)
+
+// urlPattern matches http(s) URLs in annotation text
+var urlPattern = regexp.MustCompile(`https?://[^\s<>"'()\[\]{}]+`)
+
+// LinkCheckService finds dead external links and image URLs in annotations
+type LinkCheckService struct {
+	annotations *mongo.Collection
+	client      *http.Client
+	mu          sync.Mutex // Prevents overlapping runs
+}
+
+// NewLinkCheckService creates a new link check service. Links come from users, so they are
+// requested through the transport of webClient, which only connects to public addresses.
+func NewLinkCheckService(db *mongo.Database) *LinkCheckService {
+	return &LinkCheckService{
+		annotations: db.Collection("annotations"),
+		client:      &http.Client{Timeout: linkCheckTimeout, Transport: webClient.Transport},
+	}
+}
+
+// StartBackgroundJob periodically checks all annotation links until the context is cancelled
+func (s *LinkCheckService) StartBackgroundJob(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.RunLinkCheck(ctx); err != nil {
+			log.Printf("Link check job failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunLinkCheck checks the links in the text and the image URLs of every completed annotation and
+// records the broken ones on the annotation. Each URL is requested once per run.
+func (s *LinkCheckService) RunLinkCheck(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cursor, err := s.annotations.Find(ctx, bson.M{"status": "completed"}, options.Find().SetProjection(bson.M{
+		"_id":        1,
+		"annotation": 1,
+		"image":      1,
+		"images":     1,
+	}))
+	if err != nil {
+		return fmt.Errorf("failed to load annotations: %w", err)
+	}
+	var annotations []models.Annotation
+	if err := cursor.All(ctx, &annotations); err != nil {
+		return fmt.Errorf("failed to load annotations: %w", err)
+	}
+
+	refs := make([][]models.BrokenLink, len(annotations))
+	urls := make(map[string]bool)
+	for i := range annotations {
+		refs[i] = annotationReferences(&annotations[i])
+		for _, ref := range refs[i] {
+			urls[ref.URL] = true
+		}
+	}
+
+	results := s.checkURLs(ctx, urls)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	broken, checkedAt := 0, time.Now()
+	for i, annotation := range annotations {
+		var brokenLinks []models.BrokenLink
+		for _, ref := range refs[i] {
+			if result, ok := results[ref.URL]; ok {
+				ref.Status, ref.Error = result.Status, result.Error
+				brokenLinks = append(brokenLinks, ref)
+			}
+		}
+		broken += len(brokenLinks)
+
+		update := bson.M{"$set": bson.M{"broken_links": brokenLinks, "links_checked_at": checkedAt}}
+		if len(brokenLinks) == 0 {
+			update = bson.M{"$set": bson.M{"links_checked_at": checkedAt}, "$unset": bson.M{"broken_links": ""}}
+		}
+		if _, err := s.annotations.UpdateOne(ctx, bson.M{"_id": annotation.ID}, update); err != nil {
+			log.Printf("Warning: failed to save link check of annotation %s: %v", annotation.ID, err)
+		}
+	}
+
+	log.Printf("Link check: %d URLs in %d annotations, %d broken references", len(urls), len(annotations), broken)
+	return nil
+}
+
+// BrokenLinkReport returns the annotations with broken references, most recently checked first
+func (s *LinkCheckService) BrokenLinkReport(ctx context.Context) ([]models.BrokenLinkReport, error) {
+	cursor, err := s.annotations.Find(ctx, bson.M{"broken_links.0": bson.M{"$exists": true}}, options.Find().
+		SetProjection(bson.M{"title": 1, "broken_links": 1, "links_checked_at": 1}).
+		SetSort(bson.D{{Key: "links_checked_at", Value: -1}}))
+	if err != nil {
+		return nil, err
+	}
+
+	reports := []models.BrokenLinkReport{}
+	if err := cursor.All(ctx, &reports); err != nil {
+		return nil, err
+	}
+	return reports, nil
+}
+
+// checkURLs requests URLs concurrently and returns the broken ones with their failure
+func (s *LinkCheckService) checkURLs(ctx context.Context, urls map[string]bool) map[string]models.BrokenLink {
+	jobs := make(chan string)
+	results := make(map[string]models.BrokenLink)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for i := 0; i < linkCheckWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for url := range jobs {
+				if status, err := s.checkURL(ctx, url); err != nil {
+					mu.Lock()
+					results[url] = models.BrokenLink{URL: url, Status: status, Error: err.Error()}
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	for url := range urls {
+		if ctx.Err() != nil {
+			break
+		}
+		jobs <- url
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+// checkURL requests a URL with HEAD, retrying with GET for servers that don't support HEAD. Access
+// restrictions and rate limits don't count as broken, since the resource exists.
+func (s *LinkCheckService) checkURL(ctx context.Context, url string) (int, error) {
+	status, err := s.request(ctx, http.MethodHead, url)
+	if err == nil && (status == http.StatusMethodNotAllowed || status == http.StatusNotImplemented || status == http.StatusForbidden) {
+		status, err = s.request(ctx, http.MethodGet, url)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	switch {
+	case status < 400, status == http.StatusUnauthorized, status == http.StatusForbidden, status == http.StatusTooManyRequests:
+		return status, nil
+	default:
+		return status, fmt.Errorf("HTTP %d", status)
+	}
+}
+
+// request sends a request and returns the response status, discarding the body
+func (s *LinkCheckService) request(ctx context.Context, method, url string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("User-Agent", "auto-annotation-api link checker")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+// annotationReferences returns the external links in an annotation's text and its image URLs
+func annotationReferences(annotation *models.Annotation) []models.BrokenLink {
+	var refs []models.BrokenLink
+	seen := make(map[string]bool)
+	add := func(url, source string) {
+		if url == "" || seen[url] || !strings.HasPrefix(url, "http") {
+			return
+		}
+		seen[url] = true
+		refs = append(refs, models.BrokenLink{URL: url, Source: source})
+	}
+
+	for _, url := range urlPattern.FindAllString(annotation.Annotation, -1) {
+		add(strings.TrimRight(url, ".,;:!?"), "text")
+	}
+	for _, image := range annotation.GalleryImages() {
+		add(image.URL, "image")
+	}
+	return refs
+}
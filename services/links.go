@@ -0,0 +1,158 @@
+package services
+
+import (
+	"auto-annotation-api/models"
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// linkSummaryLength is how much of a linked annotation's text is included with the link
+const linkSummaryLength = 300
+
+// CreateLink relates an annotation to another, e.g. "A prerequisite-of B"
+func (s *AnnotationService) CreateLink(ctx context.Context, sourceID, userID string, req *models.CreateLinkRequest) (*models.AnnotationLink, error) {
+	if sourceID == req.TargetID {
+		return nil, fmt.Errorf("cannot link an annotation to itself")
+	}
+	for _, id := range []string{sourceID, req.TargetID} {
+		if _, err := s.GetAnnotationByID(ctx, id); err != nil {
+			return nil, err
+		}
+	}
+
+	// Related-to is symmetric, so it also counts as a duplicate in the other direction
+	query := bson.M{"source_id": sourceID, "target_id": req.TargetID, "type": req.Type}
+	if req.Type == models.LinkRelatedTo {
+		query = bson.M{"type": req.Type, "$or": []bson.M{
+			{"source_id": sourceID, "target_id": req.TargetID},
+			{"source_id": req.TargetID, "target_id": sourceID},
+		}}
+	}
+	count, err := s.links.CountDocuments(ctx, query, options.Count().SetLimit(1))
+	if err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, fmt.Errorf("link already exists")
+	}
+
+	link := models.NewAnnotationLink(sourceID, req.TargetID, req.Type, userID)
+	if _, err := s.links.InsertOne(ctx, link); err != nil {
+		return nil, fmt.Errorf("failed to create link: %w", err)
+	}
+	s.purgeCDN(sourceID, req.TargetID)
+	return link, nil
+}
+
+// DeleteLink removes a link to or from an annotation
+func (s *AnnotationService) DeleteLink(ctx context.Context, annotationID, linkID string) error {
+	var link models.AnnotationLink
+	err := s.links.FindOneAndDelete(ctx, bson.M{
+		"_id": linkID,
+		"$or": []bson.M{{"source_id": annotationID}, {"target_id": annotationID}},
+	}).Decode(&link)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return fmt.Errorf("link not found")
+		}
+		return err
+	}
+	s.purgeCDN(link.SourceID, link.TargetID)
+	return nil
+}
+
+// RelatedLinks returns the links to and from an annotation with a summary of each linked annotation,
+// filtered for display
+func (s *AnnotationService) RelatedLinks(ctx context.Context, annotationID string) ([]models.RelatedLink, error) {
+	cursor, err := s.links.Find(ctx, bson.M{"$or": []bson.M{{"source_id": annotationID}, {"target_id": annotationID}}},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	var links []models.AnnotationLink
+	if err := cursor.All(ctx, &links); err != nil {
+		return nil, err
+	}
+	if len(links) == 0 {
+		return []models.RelatedLink{}, nil
+	}
+
+	otherIDs := make([]string, 0, len(links))
+	for _, link := range links {
+		otherIDs = append(otherIDs, otherEnd(link, annotationID))
+	}
+
+	cursor, err = s.collection.Find(ctx, bson.M{"_id": bson.M{"$in": otherIDs}, "taken_down": bson.M{"$ne": true}},
+		options.Find().SetProjection(bson.M{"title": 1, "annotation": 1, "image": 1}))
+	if err != nil {
+		return nil, err
+	}
+	var others []*models.Annotation
+	if err := cursor.All(ctx, &others); err != nil {
+		return nil, err
+	}
+	if err := s.FilterForDisplay(ctx, others...); err != nil {
+		return nil, err
+	}
+	byID := make(map[string]*models.Annotation, len(others))
+	for _, other := range others {
+		byID[other.ID] = other
+	}
+
+	related := make([]models.RelatedLink, 0, len(links))
+	for _, link := range links {
+		other, ok := byID[otherEnd(link, annotationID)]
+		if !ok {
+			continue // Deleted in the meantime, or taken down
+		}
+		direction := "outgoing"
+		if link.TargetID == annotationID {
+			direction = "incoming"
+		}
+		related = append(related, models.RelatedLink{
+			LinkID:    link.ID,
+			Type:      link.Type,
+			Direction: direction,
+			ID:        other.ID,
+			Title:     other.Title,
+			Summary:   summaryExcerpt(other.Annotation, linkSummaryLength),
+			Image:     other.Image,
+		})
+	}
+	return related, nil
+}
+
+// deleteLinksOf removes the links to and from a deleted annotation
+func (s *AnnotationService) deleteLinksOf(ctx context.Context, annotationID string) {
+	_, err := s.links.DeleteMany(ctx, bson.M{"$or": []bson.M{{"source_id": annotationID}, {"target_id": annotationID}}})
+	if err != nil {
+		log.Printf("Warning: failed to delete links of annotation %s: %v", annotationID, err)
+	}
+}
+
+// otherEnd returns the annotation at the other end of a link
+func otherEnd(link models.AnnotationLink, annotationID string) string {
+	if link.SourceID == annotationID {
+		return link.TargetID
+	}
+	return link.SourceID
+}
+
+// summaryExcerpt shortens text to at most limit bytes, cutting at a word boundary
+func summaryExcerpt(text string, limit int) string {
+	text = strings.TrimSpace(text)
+	if len(text) <= limit {
+		return text
+	}
+	cut := strings.ToValidUTF8(text[:limit], "")
+	if i := strings.LastIndexAny(cut, " \n"); i > limit/2 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, " ,;:.") + "..."
+}
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"auto-annotation-api/config"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	ollamaLatencyWeight = 0.2              // Weight of the newest request in the moving average
+	ollamaLatencyMaxAge = 10 * time.Minute // An average this old says nothing about the current load
+)
+
+// uploadLoad counts the documents held by the upload pipeline. Every AnnotationService of the
+// process feeds the same pipeline and Ollama, so the counts are shared.
+var uploadLoad struct {
+	processing atomic.Int64 // Documents being annotated
+	queued     atomic.Int64 // Bulk upload documents waiting for their turn
+}
+
+// ollamaLatency is a moving average of the duration of recent Ollama generate requests
+var ollamaLatency struct {
+	mu      sync.Mutex
+	average time.Duration
+	at      time.Time // Of the latest request
+}
+
+// recordOllamaLatency adds the duration of a finished generate request to the moving average
+func recordOllamaLatency(duration time.Duration) {
+	ollamaLatency.mu.Lock()
+	defer ollamaLatency.mu.Unlock()
+	if ollamaLatency.at.IsZero() || time.Since(ollamaLatency.at) > ollamaLatencyMaxAge {
+		ollamaLatency.average = duration
+	} else {
+		ollamaLatency.average += time.Duration(ollamaLatencyWeight * float64(duration-ollamaLatency.average))
+	}
+	ollamaLatency.at = time.Now()
+}
+
+// recentOllamaLatency returns the moving average of recent generate requests, 0 when Ollama
+// wasn't used lately
+func recentOllamaLatency() time.Duration {
+	ollamaLatency.mu.Lock()
+	defer ollamaLatency.mu.Unlock()
+	if ollamaLatency.at.IsZero() || time.Since(ollamaLatency.at) > ollamaLatencyMaxAge {
+		return 0
+	}
+	return ollamaLatency.average
+}
+
+// LoadState describes the load of the upload pipeline and whether new uploads are turned away
+type LoadState struct {
+	Shedding        bool   `json:"shedding"`
+	Reason          string `json:"reason,omitempty"`
+	QueueDepth      int64  `json:"queue_depth"` // Documents being annotated or waiting in bulk uploads
+	MaxQueueDepth   int    `json:"max_queue_depth,omitempty"`
+	OllamaLatencyMS int64  `json:"ollama_latency_ms"` // Moving average of recent generate requests
+	MaxOllamaMS     int    `json:"max_ollama_latency_ms,omitempty"`
+	RetryAfter      int    `json:"retry_after_seconds,omitempty"` // Set while shedding
+}
+
+// LoadShedder turns away new uploads while the pipeline is saturated, so clients retry later
+// instead of waiting for work that would time out. Each threshold is off when 0.
+type LoadShedder struct {
+	maxQueue   int
+	maxLatency time.Duration
+	retryAfter int // seconds
+}
+
+// NewLoadShedder creates a load shedder with the thresholds of cfg
+func NewLoadShedder(cfg *config.Config) *LoadShedder {
+	return &LoadShedder{
+		maxQueue:   cfg.ShedQueueDepth,
+		maxLatency: time.Duration(cfg.ShedOllamaMS) * time.Millisecond,
+		retryAfter: max(cfg.ShedRetryAfter, 1),
+	}
+}
+
+// Enabled reports whether any threshold is set
+func (l *LoadShedder) Enabled() bool {
+	return l != nil && (l.maxQueue > 0 || l.maxLatency > 0)
+}
+
+// State reports the current load and whether it exceeds a threshold
+func (l *LoadShedder) State() LoadState {
+	state := LoadState{
+		QueueDepth:      uploadLoad.processing.Load() + uploadLoad.queued.Load(),
+		OllamaLatencyMS: recentOllamaLatency().Milliseconds(),
+	}
+	if !l.Enabled() {
+		return state
+	}
+	state.MaxQueueDepth = l.maxQueue
+	state.MaxOllamaMS = int(l.maxLatency.Milliseconds())
+
+	switch {
+	case l.maxQueue > 0 && state.QueueDepth >= int64(l.maxQueue):
+		state.Reason = fmt.Sprintf("%d documents are waiting to be annotated, the limit is %d", state.QueueDepth, l.maxQueue)
+	case l.maxLatency > 0 && state.OllamaLatencyMS > l.maxLatency.Milliseconds():
+		state.Reason = fmt.Sprintf("the LLM takes %d ms per request, the limit is %d ms", state.OllamaLatencyMS, l.maxLatency.Milliseconds())
+	}
+	if state.Reason != "" {
+		state.Shedding = true
+		state.RetryAfter = l.retryAfter
+	}
+	return state
+}
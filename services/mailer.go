@@ -0,0 +1,58 @@
+package services
+
+import (
+	"auto-annotation-api/config"
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// Mailer sends notification emails over SMTP. Without a configured mail server messages are only
+// logged, so workflows that notify users also run in development.
+type Mailer struct {
+	addr string // host:port, empty when not configured
+	from string
+	auth smtp.Auth
+}
+
+// NewMailer creates a mailer for the SMTP server in cfg
+func NewMailer(cfg *config.Config) *Mailer {
+	mailer := &Mailer{from: cfg.MailFrom}
+	if cfg.SMTPHost != "" {
+		mailer.addr = net.JoinHostPort(cfg.SMTPHost, cfg.SMTPPort)
+		if cfg.SMTPUsername != "" {
+			mailer.auth = smtp.PlainAuth("", cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPHost)
+		}
+	}
+	return mailer
+}
+
+// Send sends a plain text email
+func (m *Mailer) Send(to, subject, body string) error {
+	if m.addr == "" {
+		log.Printf("Mail to %s (SMTP not configured): %s", to, subject)
+		return nil
+	}
+
+	// Header values come from user input in places, a line break would inject headers
+	if strings.ContainsAny(to+subject, "\r\n") {
+		return fmt.Errorf("invalid mail header")
+	}
+
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", m.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", to)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", subject)
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+
+	if err := smtp.SendMail(m.addr, m.auth, m.from, []string{to}, []byte(msg.String())); err != nil {
+		return fmt.Errorf("failed to send mail to %s: %w", to, err)
+	}
+	return nil
+}
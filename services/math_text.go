@@ -0,0 +1,412 @@
+package services
+
+import (
+	"auto-annotation-api/models"
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// maxFormulasPerDocument caps how many formulas are stored for one annotation
+const maxFormulasPerDocument = 200
+
+// latexMathPattern matches LaTeX math: $$...$$, \[...\], \(...\), math environments and inline $...$
+var latexMathPattern = regexp.MustCompile(`(?s)\$\$(.+?)\$\$|\\\[(.+?)\\\]|\\\((.+?)\\\)|\\begin\{(?:equation|align|gather|multline)\*?\}(.+?)\\end\{(?:equation|align|gather|multline)\*?\}|\$([^$\n]+?)\$`)
+
+// mathSymbols are characters that mark a line of extracted text as a formula
+const mathSymbols = "=<>≤≥≠≈∑∏∫√∂∞±×÷·→⇒⇔∈∉⊂⊆∪∩∀∃∇∆"
+
+// mathSpan is a formula found in text, with its byte range
+type mathSpan struct {
+	Start, End int
+	Source     string
+	Display    bool
+}
+
+// findMathSpans returns the LaTeX formulas in text, skipping dollar amounts like "$5 and $10"
+func findMathSpans(text string) []mathSpan {
+	var spans []mathSpan
+	for _, m := range latexMathPattern.FindAllStringSubmatchIndex(text, -1) {
+		span := mathSpan{Start: m[0], End: m[1], Display: true}
+		for group := 1; group <= 5; group++ {
+			if m[2*group] >= 0 {
+				span.Source = strings.TrimSpace(text[m[2*group]:m[2*group+1]])
+				span.Display = group != 3 && group != 5 // \(...\) and $...$ are inline
+				break
+			}
+		}
+
+		if !span.Display && !looksLikeInlineMath(text[m[0]+1:m[1]-1]) {
+			continue
+		}
+		if span.Source != "" {
+			spans = append(spans, span)
+		}
+	}
+	return spans
+}
+
+// looksLikeInlineMath tells LaTeX like "$x^2$" apart from text between two dollar amounts
+func looksLikeInlineMath(inner string) bool {
+	if inner == "" || unicode.IsSpace(rune(inner[0])) || unicode.IsSpace(rune(inner[len(inner)-1])) {
+		return false
+	}
+	if _, err := strconv.ParseFloat(strings.ReplaceAll(inner, ",", ""), 64); err == nil {
+		return false
+	}
+	return strings.ContainsAny(inner, `\^_=+-{}`) || len([]rune(inner)) <= 3
+}
+
+// isFormulaLine guesses whether a line of PDF text is a formula rendered with Unicode symbols
+func isFormulaLine(line string) bool {
+	if line == "" || len([]rune(line)) > 120 || !strings.ContainsAny(line, mathSymbols) {
+		return false
+	}
+
+	// Prose mentioning a comparison still has several ordinary words
+	words := 0
+	for _, word := range strings.Fields(line) {
+		letters := 0
+		for _, r := range word {
+			if unicode.IsLetter(r) && r < unicode.MaxLatin1 {
+				letters++
+			}
+		}
+		if letters >= 4 {
+			words++
+		}
+	}
+	return words <= 2
+}
+
+// protectMath swaps formulas for placeholders so text cleanup can't alter them
+func protectMath(text string) (string, []string) {
+	spans := findMathSpans(text)
+	if len(spans) == 0 {
+		return text, nil
+	}
+
+	var b strings.Builder
+	originals := make([]string, 0, len(spans))
+	last := 0
+	for i, span := range spans {
+		b.WriteString(text[last:span.Start])
+		b.WriteString(mathPlaceholder(i))
+		originals = append(originals, text[span.Start:span.End])
+		last = span.End
+	}
+	b.WriteString(text[last:])
+	return b.String(), originals
+}
+
+// restoreMath puts back the formulas removed by protectMath
+func restoreMath(text string, originals []string) string {
+	for i, original := range originals {
+		text = strings.Replace(text, mathPlaceholder(i), original, 1)
+	}
+	return text
+}
+
+// mathPlaceholder returns a token that survives whitespace cleanup
+func mathPlaceholder(i int) string {
+	return fmt.Sprintf("\x00MATH%d\x00", i)
+}
+
+// extractFormulas collects the LaTeX and Unicode formulas of extracted text with their pages
+// and MathML renderings
+func extractFormulas(text string) []models.Formula {
+	formulas := []models.Formula{}
+	add := func(page int, source string, display bool) {
+		if len(formulas) < maxFormulasPerDocument {
+			formulas = append(formulas, models.Formula{
+				Page:    page,
+				Source:  source,
+				Display: display,
+				MathML:  latexToMathML(source, display),
+			})
+		}
+	}
+
+	page := 1
+	for _, section := range splitPages(removeCodeBlocks(text)) {
+		page = section.Page
+		for _, span := range findMathSpans(section.Text) {
+			add(page, span.Source, span.Display)
+		}
+
+		for _, line := range strings.Split(latexMathPattern.ReplaceAllString(section.Text, ""), "\n") {
+			if line = strings.TrimSpace(line); isFormulaLine(line) {
+				add(page, line, true)
+			}
+		}
+	}
+	return formulas
+}
+
+// pageText is the text of one page of extracted text
+type pageText struct {
+	Page int
+	Text string
+}
+
+// splitPages splits extracted text on the parser's page markers
+func splitPages(text string) []pageText {
+	var pages []pageText
+	page, last := 1, 0
+	for _, m := range pageMarkerPattern.FindAllStringSubmatchIndex(text, -1) {
+		pages = append(pages, pageText{Page: page, Text: text[last:m[0]]})
+		if n, err := strconv.Atoi(text[m[2]:m[3]]); err == nil {
+			page = n
+		}
+		last = m[1]
+	}
+	return append(pages, pageText{Page: page, Text: text[last:]})
+}
+
+// speakableText replaces code and formulas with a short spoken cue, since Polly would read
+// them character by character
+func speakableText(text string) string {
+	text = speakableCode(text)
+	text = latexMathPattern.ReplaceAllStringFunc(text, func(match string) string {
+		if spans := findMathSpans(match); len(spans) == 0 {
+			return match // A dollar amount, not math
+		}
+		return "(formula)"
+	})
+
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if isFormulaLine(strings.TrimSpace(line)) {
+			lines[i] = "(formula)"
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// latexGreek maps LaTeX letter commands to Unicode
+var latexGreek = map[string]string{
+	"alpha": "α", "beta": "β", "gamma": "γ", "delta": "δ", "epsilon": "ε", "varepsilon": "ε", "zeta": "ζ",
+	"eta": "η", "theta": "θ", "iota": "ι", "kappa": "κ", "lambda": "λ", "mu": "μ", "nu": "ν", "xi": "ξ",
+	"pi": "π", "rho": "ρ", "sigma": "σ", "tau": "τ", "upsilon": "υ", "phi": "φ", "varphi": "φ", "chi": "χ",
+	"psi": "ψ", "omega": "ω", "Gamma": "Γ", "Delta": "Δ", "Theta": "Θ", "Lambda": "Λ", "Xi": "Ξ", "Pi": "Π",
+	"Sigma": "Σ", "Phi": "Φ", "Psi": "Ψ", "Omega": "Ω", "infty": "∞", "partial": "∂", "nabla": "∇",
+}
+
+// latexOperators maps LaTeX operator commands to Unicode
+var latexOperators = map[string]string{
+	"times": "×", "cdot": "·", "div": "÷", "pm": "±", "mp": "∓", "leq": "≤", "le": "≤", "geq": "≥", "ge": "≥",
+	"neq": "≠", "ne": "≠", "approx": "≈", "equiv": "≡", "sim": "∼", "propto": "∝", "sum": "∑", "prod": "∏",
+	"int": "∫", "oint": "∮", "to": "→", "rightarrow": "→", "leftarrow": "←", "Rightarrow": "⇒",
+	"Leftrightarrow": "⇔", "in": "∈", "notin": "∉", "subset": "⊂", "subseteq": "⊆", "cup": "∪", "cap": "∩",
+	"forall": "∀", "exists": "∃", "cdots": "⋯", "ldots": "…", "dots": "…", "lim": "lim", "log": "log",
+	"ln": "ln", "sin": "sin", "cos": "cos", "tan": "tan", "exp": "exp", "max": "max", "min": "min",
+}
+
+// unicodeSuperscripts maps superscript digits from PDF text to plain digits
+var unicodeSuperscripts = map[rune]string{'⁰': "0", '¹': "1", '²': "2", '³': "3", '⁴': "4", '⁵': "5", '⁶': "6", '⁷': "7", '⁸': "8", '⁹': "9"}
+
+// latexToMathML converts a common subset of LaTeX (and Unicode math) to presentation MathML.
+// Unknown commands are rendered as identifiers rather than failing.
+func latexToMathML(source string, display bool) string {
+	p := &mathParser{src: []rune(source)}
+	body := p.parseRow(0)
+
+	mode := "inline"
+	if display {
+		mode = "block"
+	}
+	return fmt.Sprintf(`<math xmlns="http://www.w3.org/1998/Math/MathML" display="%s"><mrow>%s</mrow></math>`, mode, body)
+}
+
+// mathParser is a small recursive-descent LaTeX to MathML converter
+type mathParser struct {
+	src []rune
+	pos int
+}
+
+// parseRow parses elements until the closing rune (or the end) and returns their MathML
+func (p *mathParser) parseRow(closing rune) string {
+	var out strings.Builder
+	for p.pos < len(p.src) {
+		r := p.src[p.pos]
+		if closing != 0 && r == closing {
+			p.pos++
+			break
+		}
+
+		element := p.parseAtom()
+		if element == "" {
+			continue
+		}
+		out.WriteString(p.parseScripts(element))
+	}
+	return out.String()
+}
+
+// parseScripts wraps base in msub/msup/msubsup when followed by _ or ^
+func (p *mathParser) parseScripts(base string) string {
+	var sub, sup string
+	for p.pos < len(p.src) {
+		switch r := p.src[p.pos]; {
+		case r == '_' && sub == "":
+			p.pos++
+			sub = p.parseArgument()
+		case r == '^' && sup == "":
+			p.pos++
+			sup = p.parseArgument()
+		case unicodeSuperscripts[r] != "" && sup == "":
+			var digits strings.Builder
+			for p.pos < len(p.src) && unicodeSuperscripts[p.src[p.pos]] != "" {
+				digits.WriteString(unicodeSuperscripts[p.src[p.pos]])
+				p.pos++
+			}
+			sup = "<mn>" + digits.String() + "</mn>"
+		default:
+			return wrapScripts(base, sub, sup)
+		}
+	}
+	return wrapScripts(base, sub, sup)
+}
+
+// wrapScripts builds the script element for a base
+func wrapScripts(base, sub, sup string) string {
+	switch {
+	case sub != "" && sup != "":
+		return "<msubsup>" + base + sub + sup + "</msubsup>"
+	case sub != "":
+		return "<msub>" + base + sub + "</msub>"
+	case sup != "":
+		return "<msup>" + base + sup + "</msup>"
+	default:
+		return base
+	}
+}
+
+// parseArgument parses a braced group or a single atom, as used by scripts and commands
+func (p *mathParser) parseArgument() string {
+	p.skipSpace()
+	if p.pos >= len(p.src) {
+		return "<mrow></mrow>"
+	}
+	if p.src[p.pos] == '{' {
+		p.pos++
+		return "<mrow>" + p.parseRow('}') + "</mrow>"
+	}
+	if element := p.parseAtom(); element != "" {
+		return element
+	}
+	return "<mrow></mrow>"
+}
+
+// parseAtom parses one token: a number, identifier, operator, group or command
+func (p *mathParser) parseAtom() string {
+	r := p.src[p.pos]
+	switch {
+	case unicode.IsSpace(r) || r == '&':
+		p.pos++
+		return ""
+	case r == '{':
+		p.pos++
+		return "<mrow>" + p.parseRow('}') + "</mrow>"
+	case r == '\\':
+		return p.parseCommand()
+	case unicode.IsDigit(r):
+		start := p.pos
+		for p.pos < len(p.src) && (unicode.IsDigit(p.src[p.pos]) || p.src[p.pos] == '.') {
+			p.pos++
+		}
+		return "<mn>" + string(p.src[start:p.pos]) + "</mn>"
+	case unicode.IsLetter(r):
+		p.pos++
+		return "<mi>" + html.EscapeString(string(r)) + "</mi>"
+	default:
+		p.pos++
+		return "<mo>" + html.EscapeString(string(r)) + "</mo>"
+	}
+}
+
+// parseCommand parses a backslash command
+func (p *mathParser) parseCommand() string {
+	p.pos++ // Backslash
+	if p.pos >= len(p.src) {
+		return ""
+	}
+
+	start := p.pos
+	for p.pos < len(p.src) && unicode.IsLetter(p.src[p.pos]) {
+		p.pos++
+	}
+	name := string(p.src[start:p.pos])
+	if name == "" {
+		// Escaped character (\{, \%) or line break (\\)
+		r := p.src[p.pos]
+		p.pos++
+		if r == '\\' {
+			return `<mspace linebreak="newline"/>`
+		}
+		if r == ',' || r == ';' || r == ' ' {
+			return `<mspace width="0.2em"/>`
+		}
+		return "<mo>" + html.EscapeString(string(r)) + "</mo>"
+	}
+
+	switch name {
+	case "frac", "dfrac", "tfrac":
+		return "<mfrac>" + p.parseArgument() + p.parseArgument() + "</mfrac>"
+	case "sqrt":
+		p.skipSpace()
+		if p.pos < len(p.src) && p.src[p.pos] == '[' {
+			p.pos++
+			index := p.parseRow(']')
+			return "<mroot>" + p.parseArgument() + "<mrow>" + index + "</mrow></mroot>"
+		}
+		return "<msqrt>" + p.parseArgument() + "</msqrt>"
+	case "text", "mathrm", "textrm", "operatorname":
+		return "<mtext>" + html.EscapeString(p.rawArgument()) + "</mtext>"
+	case "mathbf", "mathit", "mathbb", "mathcal":
+		return p.parseArgument()
+	case "left", "right", "big", "Big", "bigl", "bigr", "displaystyle", "quad", "qquad":
+		return ""
+	}
+
+	if symbol, ok := latexGreek[name]; ok {
+		return "<mi>" + symbol + "</mi>"
+	}
+	if symbol, ok := latexOperators[name]; ok {
+		return "<mo>" + symbol + "</mo>"
+	}
+	return "<mi>" + html.EscapeString(name) + "</mi>"
+}
+
+// rawArgument returns the unparsed text of a braced argument
+func (p *mathParser) rawArgument() string {
+	p.skipSpace()
+	if p.pos >= len(p.src) || p.src[p.pos] != '{' {
+		return ""
+	}
+
+	depth, start := 0, p.pos+1
+	for ; p.pos < len(p.src); p.pos++ {
+		switch p.src[p.pos] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				p.pos++
+				return string(p.src[start : p.pos-1])
+			}
+		}
+	}
+	return string(p.src[start:])
+}
+
+// skipSpace advances past whitespace
+func (p *mathParser) skipSpace() {
+	for p.pos < len(p.src) && unicode.IsSpace(p.src[p.pos]) {
+		p.pos++
+	}
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"auto-annotation-api/models"
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// MergeAnnotations combines duplicate annotations, e.g. two scans of the same book, into a new
+// annotation with LLM-merged text, the union of their tags and images, and their combined source text.
+// The originals are kept with status "merged" and point to the new annotation.
+func (s *AnnotationService) MergeAnnotations(ctx context.Context, userID string, req *models.MergeAnnotationsRequest) (*models.Annotation, error) {
+	var originals []*models.Annotation
+	seen := make(map[string]bool)
+	for _, id := range req.AnnotationIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		annotation, err := s.GetAnnotationByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if annotation.Status != "completed" {
+			return nil, fmt.Errorf("cannot merge annotation %s with status %q", id, annotation.Status)
+		}
+		originals = append(originals, annotation)
+	}
+	if len(originals) < 2 {
+		return nil, fmt.Errorf("cannot merge fewer than two distinct annotations")
+	}
+	first := originals[0]
+
+	title := strings.TrimSpace(req.Title)
+	if title == "" {
+		title = first.Title
+	}
+	length, err := normalizeSummaryLength(firstNonEmpty(req.Length, first.Length))
+	if err != nil {
+		return nil, err
+	}
+
+	notes := make([]string, len(originals))
+	texts := make([]string, len(originals))
+	var tags []string
+	var images []models.GalleryImage
+	imageURLs := make(map[string]bool)
+	for i, original := range originals {
+		notes[i] = original.Annotation
+		texts[i] = original.TextContent
+		tags = append(tags, original.Tags...)
+		for _, image := range original.GalleryImages() {
+			if !imageURLs[image.URL] {
+				imageURLs[image.URL] = true
+				image.Key = "" // The file stays owned by the original annotation
+				images = append(images, image)
+			}
+		}
+	}
+
+	log.Printf("Merging %d annotations into: %s", len(originals), title)
+	result, err := s.ollamaClient.ConsolidateAnnotations(ctx, notes, title, length, first.Genre)
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge annotations: %w", err)
+	}
+
+	merged := models.NewAnnotation(userID, title, first.SourceFile, first.SourceType)
+	merged.TextContent = strings.Join(texts, "\n\n")
+	merged.Formulas = extractFormulas(merged.TextContent)
+	merged.CodeBlocks = extractCodeBlocks(merged.TextContent)
+	merged.Annotation = result.Annotation
+	merged.Genre = firstNonEmpty(result.Genre, first.Genre)
+	merged.Model = s.ollamaClient.modelFor(ctx)
+	merged.Length = length
+	merged.Tags = NormalizeTags(tags)
+	merged.Metadata = first.Metadata
+	merged.SourceURL, merged.Author, merged.PublishedOn = first.SourceURL, first.Author, first.PublishedOn
+	merged.SetImages(images)
+	merged.Objectives, merged.Prereqs = s.extractLearningOutline(ctx, result, title)
+	merged.KeyTerms = s.extractKeyTerms(ctx, result.Annotation, title)
+	merged.SafetyLabels = s.labelSafety(ctx, result.Annotation, title)
+	merged.Embedding, merged.EmbedModel = s.embedNotes(result.Annotation, title)
+	merged.MergedFrom = make([]string, len(originals))
+	for i, original := range originals {
+		merged.MergedFrom[i] = original.ID
+		if merged.Book == nil {
+			merged.Book = original.Book
+		}
+		// Notes combining differently licensed annotations get the default, most restrictive license
+		if i == 0 {
+			merged.License = original.License
+		} else if original.License != merged.License {
+			merged.License = models.DefaultLicense
+		}
+		// Only public annotations combine into a public one
+		if i == 0 {
+			merged.Visibility = original.Visibility
+		} else if original.Visibility != merged.Visibility {
+			merged.Visibility = models.VisibilityPrivate
+		}
+	}
+	merged.Status = "completed"
+	merged.ReviewState = s.initialReviewState()
+	merged.UpdatedAt = time.Now()
+
+	if _, err := s.collection.InsertOne(ctx, merged); err != nil {
+		return nil, fmt.Errorf("failed to create merged annotation: %w", err)
+	}
+
+	_, err = s.collection.UpdateMany(ctx, bson.M{"_id": bson.M{"$in": merged.MergedFrom}}, bson.M{"$set": bson.M{
+		"status":      "merged",
+		"merged_into": merged.ID,
+		"updated_at":  time.Now(),
+	}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to mark merged annotations: %w", err)
+	}
+	s.syncSearch(ctx, append([]string{merged.ID}, merged.MergedFrom...)...)
+	s.purgeCDN(append([]string{merged.ID}, merged.MergedFrom...)...)
+
+	return merged, nil
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"auto-annotation-api/models"
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// metadataKeyPattern restricts field keys, which become part of MongoDB field paths
+var metadataKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// IsValidMetadataKey reports whether key can name a metadata field
+func IsValidMetadataKey(key string) bool {
+	return metadataKeyPattern.MatchString(key)
+}
+
+// checkMetadata validates custom field values against the current schema
+func (s *AnnotationService) checkMetadata(ctx context.Context, values models.Metadata) (models.Metadata, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	schema, err := s.settings.GetMetadataSchema(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return validateMetadata(schema, values)
+}
+
+// validateMetadataSchema checks field keys and enum options before a schema is saved
+func validateMetadataSchema(fields []models.MetadataField) error {
+	seen := make(map[string]bool)
+	for _, field := range fields {
+		if !IsValidMetadataKey(field.Key) {
+			return fmt.Errorf("invalid metadata field key %q: use lowercase letters, digits and underscores", field.Key)
+		}
+		if seen[field.Key] {
+			return fmt.Errorf("invalid metadata schema: duplicate field key %q", field.Key)
+		}
+		seen[field.Key] = true
+
+		if field.Type == models.MetadataEnum && len(field.Options) == 0 {
+			return fmt.Errorf("invalid metadata field %q: enum fields need options", field.Key)
+		}
+	}
+	return nil
+}
+
+// validateMetadata checks values against the schema and returns them normalized. Fields not in the
+// schema are rejected; empty values are dropped.
+func validateMetadata(schema *models.MetadataSchema, values models.Metadata) (models.Metadata, error) {
+	fields := make(map[string]models.MetadataField, len(schema.Fields))
+	for _, field := range schema.Fields {
+		fields[field.Key] = field
+	}
+
+	normalized := models.Metadata{}
+	for key, value := range values {
+		field, ok := fields[key]
+		if !ok {
+			return nil, fmt.Errorf("invalid metadata: unknown field %q", key)
+		}
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+
+		switch field.Type {
+		case models.MetadataNumber:
+			if _, err := strconv.ParseFloat(value, 64); err != nil {
+				return nil, fmt.Errorf("invalid metadata: %s must be a number", key)
+			}
+		case models.MetadataBoolean:
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid metadata: %s must be true or false", key)
+			}
+			value = strconv.FormatBool(b)
+		case models.MetadataDate:
+			if _, err := time.Parse("2006-01-02", value); err != nil {
+				return nil, fmt.Errorf("invalid metadata: %s must be a date (YYYY-MM-DD)", key)
+			}
+		case models.MetadataEnum:
+			if !slices.Contains(field.Options, value) {
+				return nil, fmt.Errorf("invalid metadata: %s must be one of %s", key, strings.Join(field.Options, ", "))
+			}
+		}
+		normalized[key] = value
+	}
+	return normalized, nil
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"auto-annotation-api/models"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// maxMetadataImportRows caps a single import, which runs synchronously
+const maxMetadataImportRows = 5000
+
+// ImportMetadataCSV sets custom metadata on many annotations from a CSV with an "id" column and one
+// column per metadata field. Empty cells leave a field unchanged. Rows are validated and applied
+// independently, so one bad row doesn't stop the rest; with dryRun nothing is written.
+func (s *AnnotationService) ImportMetadataCSV(ctx context.Context, r io.Reader, dryRun bool) (*models.MetadataImportReport, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1 // Short rows are reported per row
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("invalid CSV: file is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+
+	schema, err := s.settings.GetMetadataSchema(ctx)
+	if err != nil {
+		return nil, err
+	}
+	fields := make(map[string]bool, len(schema.Fields))
+	for _, field := range schema.Fields {
+		fields[field.Key] = true
+	}
+
+	idColumn := -1
+	for i, column := range header {
+		column = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(column, "\ufeff"))) // Excel adds a BOM
+		header[i] = column
+		if column == "id" {
+			idColumn = i
+		} else if !fields[column] {
+			return nil, fmt.Errorf("invalid metadata: unknown field %q in CSV header", column)
+		}
+	}
+	if idColumn < 0 {
+		return nil, fmt.Errorf("invalid CSV: an \"id\" column is required")
+	}
+
+	report := &models.MetadataImportReport{DryRun: dryRun, Rows: []models.MetadataImportRow{}}
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if report.Total >= maxMetadataImportRows {
+			return nil, fmt.Errorf("invalid CSV: more than %d rows", maxMetadataImportRows)
+		}
+		report.Total++
+
+		row := models.MetadataImportRow{Row: line}
+		if err == nil {
+			err = s.importMetadataRow(ctx, schema, header, idColumn, record, dryRun, &row)
+		}
+		if err != nil {
+			row.Status = "failed"
+			row.Error = err.Error()
+			report.Failed++
+		} else {
+			report.Updated++
+		}
+		report.Rows = append(report.Rows, row)
+	}
+
+	return report, nil
+}
+
+// importMetadataRow validates one CSV record and applies it unless dryRun is set
+func (s *AnnotationService) importMetadataRow(ctx context.Context, schema *models.MetadataSchema, header []string, idColumn int, record []string, dryRun bool, row *models.MetadataImportRow) error {
+	if len(record) != len(header) {
+		return fmt.Errorf("expected %d columns, got %d", len(header), len(record))
+	}
+
+	row.AnnotationID = strings.TrimSpace(record[idColumn])
+	if row.AnnotationID == "" {
+		return fmt.Errorf("missing annotation id")
+	}
+
+	values := models.Metadata{}
+	for i, column := range header {
+		if i != idColumn {
+			values[column] = record[i]
+		}
+	}
+	metadata, err := validateMetadata(schema, values)
+	if err != nil {
+		return err
+	}
+	row.Metadata = metadata
+
+	query := bson.M{"_id": row.AnnotationID}
+	if dryRun || len(metadata) == 0 {
+		count, err := s.collection.CountDocuments(ctx, query, options.Count().SetLimit(1))
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return fmt.Errorf("annotation not found")
+		}
+		row.Status = "valid"
+		if !dryRun {
+			row.Status = "updated"
+		}
+		return nil
+	}
+
+	fields := bson.M{"updated_at": time.Now()}
+	for key, value := range metadata {
+		fields["metadata."+key] = value
+	}
+	result, err := s.collection.UpdateOne(ctx, query, bson.M{"$set": fields})
+	if err != nil {
+		return fmt.Errorf("failed to update annotation: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("annotation not found")
+	}
+	s.syncSearch(ctx, row.AnnotationID)
+	s.purgeCDN(row.AnnotationID)
+	row.Status = "updated"
+	return nil
+}
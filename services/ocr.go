@@ -0,0 +1,204 @@
+package services
+
+import (
+	"auto-annotation-api/config"
+	"auto-annotation-api/utils"
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// minTextPerPage is the average number of characters per page below which a PDF is read as a scan.
+// Scans often carry a few words of text, such as a stamped header or a page number.
+const minTextPerPage = 100
+
+// PDFOCR reads the text of scanned PDFs, which have little or no text layer: their pages are
+// rasterized with pdftoppm (poppler-utils) and recognized with Tesseract. It also reads images
+// uploaded as documents.
+type PDFOCR struct {
+	pdftoppm  string
+	tesseract string
+	languages string
+	maxPages  int
+	timeout   time.Duration
+}
+
+// NewPDFOCR creates the OCR reader, or returns nil when OCR_ENABLED is off
+func NewPDFOCR(cfg *config.Config) (*PDFOCR, error) {
+	if !cfg.OCREnabled {
+		return nil, nil
+	}
+	pdftoppm, err := exec.LookPath("pdftoppm")
+	if err != nil {
+		return nil, fmt.Errorf("OCR not available, pdftoppm (poppler-utils) is not installed")
+	}
+	tesseract, err := exec.LookPath("tesseract")
+	if err != nil {
+		return nil, fmt.Errorf("OCR not available, tesseract is not installed")
+	}
+
+	maxPages := cfg.OCRMaxPages
+	if maxPages <= 0 {
+		maxPages = 200
+	}
+	timeout := cfg.OCRTimeout
+	if timeout <= 0 {
+		timeout = 600
+	}
+	return &PDFOCR{
+		pdftoppm:  pdftoppm,
+		tesseract: tesseract,
+		languages: firstNonEmpty(strings.TrimSpace(cfg.OCRLanguages), "eng"),
+		maxPages:  maxPages,
+		timeout:   time.Duration(timeout) * time.Second,
+	}, nil
+}
+
+// ExtractText recognizes the text of a PDF's pages, up to maxPages, and returns it with the same
+// page markers as the PDF parser, along with the number of pages read
+func (o *PDFOCR) ExtractText(ctx context.Context, data []byte) (string, int, error) {
+	ctx, cancel := context.WithTimeout(ctx, o.timeout)
+	defer cancel()
+
+	dir, err := os.MkdirTemp("", "ocr-*")
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	input := filepath.Join(dir, "source.pdf")
+	if err := os.WriteFile(input, data, 0600); err != nil {
+		return "", 0, fmt.Errorf("failed to write PDF: %w", err)
+	}
+
+	// Tesseract is most accurate on grayscale pages at 300 dpi
+	cmd := exec.CommandContext(ctx, o.pdftoppm, "-r", "300", "-gray", "-png",
+		"-f", "1", "-l", strconv.Itoa(o.maxPages), input, filepath.Join(dir, "page"))
+	if out, err := cmd.CombinedOutput(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return "", 0, fmt.Errorf("OCR took longer than %s", o.timeout)
+		}
+		return "", 0, fmt.Errorf("pdftoppm failed: %w: %s", err, bytes.TrimSpace(out))
+	}
+
+	// pdftoppm pads the page numbers of the file names to equal width, so they sort by name
+	pages, err := filepath.Glob(filepath.Join(dir, "page-*.png"))
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to list rendered pages: %w", err)
+	}
+	sort.Strings(pages)
+
+	var textBuilder strings.Builder
+	for i, page := range pages {
+		text, err := o.recognize(ctx, page)
+		if err != nil {
+			return "", 0, fmt.Errorf("page %d: %w", i+1, err)
+		}
+
+		if i > 0 {
+			textBuilder.WriteString(fmt.Sprintf("\n\n--- Page %d ---\n\n", i+1))
+		}
+		textBuilder.WriteString(text)
+	}
+
+	text := cleanExtractedText(textBuilder.String())
+	if textChars(text) == 0 {
+		return "", 0, fmt.Errorf("no text recognized in scanned PDF")
+	}
+	return text, len(pages), nil
+}
+
+// ExtractImageText recognizes the text of an image, a PNG or JPEG
+func (o *PDFOCR) ExtractImageText(ctx context.Context, data []byte) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, o.timeout)
+	defer cancel()
+
+	file, err := os.CreateTemp("", "ocr-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(file.Name())
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to write image: %w", err)
+	}
+
+	text, err := o.recognize(ctx, file.Name())
+	if err != nil {
+		return "", err
+	}
+	if text = cleanExtractedText(text); text == "" {
+		return "", fmt.Errorf("no text recognized in image")
+	}
+	return text, nil
+}
+
+// recognize runs Tesseract on an image file and returns the recognized text
+func (o *PDFOCR) recognize(ctx context.Context, image string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, o.tesseract, image, "stdout", "-l", o.languages)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return "", fmt.Errorf("OCR took longer than %s", o.timeout)
+		}
+		return "", fmt.Errorf("tesseract failed: %w: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return stdout.String(), nil
+}
+
+// textChars counts the characters of extracted text without its page markers
+func textChars(text string) int {
+	chars := 0
+	for _, page := range splitPages(text) {
+		chars += len(strings.TrimSpace(page.Text))
+	}
+	return chars
+}
+
+// needsOCR reports whether the result of parsing a PDF looks like a scan: no text at all, or too
+// little for its number of pages
+func needsOCR(text string, err error) bool {
+	if err != nil {
+		return strings.Contains(err.Error(), "no text content found")
+	}
+	return textChars(text) < minTextPerPage*len(splitPages(text))
+}
+
+// readScannedPDF reads a PDF with OCR when the parser found little or no text in it, returning the
+// text and error to continue with and the number of pages read with OCR. The parsed text is kept
+// when OCR is off, fails or recognizes less.
+func (s *AnnotationService) readScannedPDF(ctx context.Context, fileData []byte, text string, err error) (string, int, error) {
+	if s.ocr == nil || !needsOCR(text, err) {
+		return text, 0, err
+	}
+
+	log.Printf("PDF has little or no text layer, reading it with OCR")
+	_, span := utils.StartSpan(ctx, "annotation.ocr")
+	ocrText, pages, ocrErr := s.ocr.ExtractText(ctx, fileData)
+	span.SetAttribute("pages", pages)
+	span.SetAttribute("text.length", len(ocrText))
+	span.RecordError(ocrErr)
+	span.End()
+	if ocrErr != nil {
+		log.Printf("Warning: OCR failed: %v", ocrErr)
+		return text, 0, err
+	}
+	if err == nil && textChars(ocrText) <= textChars(text) {
+		return text, 0, nil
+	}
+	log.Printf("Read %d characters from %d scanned pages", len(ocrText), pages)
+	return ocrText, pages, nil
+}
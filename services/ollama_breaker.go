@@ -0,0 +1,111 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"time"
+)
+
+// OllamaUnavailableError is returned without calling Ollama while the circuit breaker is open,
+// so requests fail at once instead of waiting for the request timeout
+type OllamaUnavailableError struct {
+	RetryAfter time.Duration
+}
+
+func (e *OllamaUnavailableError) Error() string {
+	return fmt.Sprintf("Ollama is unavailable after repeated failures, retry in %d seconds", int(e.RetryAfter.Seconds())+1)
+}
+
+// ollamaTransientError is an Ollama failure that suggests Ollama is down: a network error, a timeout or a 5xx
+type ollamaTransientError struct {
+	err error
+}
+
+func (e *ollamaTransientError) Error() string { return e.err.Error() }
+func (e *ollamaTransientError) Unwrap() error { return e.err }
+
+// isTransientOllamaError reports whether a failed Ollama request points to Ollama being down
+func isTransientOllamaError(err error) bool {
+	var transient *ollamaTransientError
+	return errors.As(err, &transient)
+}
+
+// isOllamaTimeout reports whether a request ran into the client timeout. Those aren't retried,
+// another attempt would most likely wait just as long.
+func isOllamaTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// ollamaBreaker stops calling Ollama after consecutive transient failures. Every OllamaClient of
+// the process talks to the same Ollama, so the state is shared. After the cooldown one request
+// is let through to probe whether Ollama is back.
+var ollamaBreaker struct {
+	mu        sync.Mutex
+	threshold int // Consecutive failures that open the breaker, 0 disables it
+	cooldown  time.Duration
+	failures  int
+	openUntil time.Time
+}
+
+// configureOllamaBreaker sets the failure threshold and cooldown of the circuit breaker
+func configureOllamaBreaker(threshold int, cooldown time.Duration) {
+	ollamaBreaker.mu.Lock()
+	defer ollamaBreaker.mu.Unlock()
+	ollamaBreaker.threshold = threshold
+	ollamaBreaker.cooldown = max(cooldown, time.Second)
+}
+
+// checkOllamaAvailable returns an OllamaUnavailableError while the breaker is open, without
+// taking the probe request of a breaker whose cooldown is over
+func checkOllamaAvailable() error {
+	ollamaBreaker.mu.Lock()
+	defer ollamaBreaker.mu.Unlock()
+	if ollamaBreaker.threshold <= 0 || ollamaBreaker.failures < ollamaBreaker.threshold {
+		return nil
+	}
+	if wait := time.Until(ollamaBreaker.openUntil); wait > 0 {
+		return &OllamaUnavailableError{RetryAfter: wait}
+	}
+	return nil
+}
+
+// allowOllamaRequest returns an OllamaUnavailableError while the breaker is open
+func allowOllamaRequest() error {
+	ollamaBreaker.mu.Lock()
+	defer ollamaBreaker.mu.Unlock()
+	if ollamaBreaker.threshold <= 0 || ollamaBreaker.failures < ollamaBreaker.threshold {
+		return nil
+	}
+	if wait := time.Until(ollamaBreaker.openUntil); wait > 0 {
+		return &OllamaUnavailableError{RetryAfter: wait}
+	}
+	// Half-open: this request probes Ollama, the others keep failing fast until it is done
+	ollamaBreaker.openUntil = time.Now().Add(ollamaBreaker.cooldown)
+	return nil
+}
+
+// recordOllamaResult updates the breaker with the outcome of a request. Only transient failures
+// count, an error response still means Ollama is up.
+func recordOllamaResult(err error) {
+	ollamaBreaker.mu.Lock()
+	defer ollamaBreaker.mu.Unlock()
+	if !isTransientOllamaError(err) {
+		if ollamaBreaker.threshold > 0 && ollamaBreaker.failures >= ollamaBreaker.threshold {
+			log.Printf("Ollama is reachable again, closing the circuit breaker")
+		}
+		ollamaBreaker.failures = 0
+		return
+	}
+
+	ollamaBreaker.failures++
+	if ollamaBreaker.threshold > 0 && ollamaBreaker.failures >= ollamaBreaker.threshold {
+		ollamaBreaker.openUntil = time.Now().Add(ollamaBreaker.cooldown)
+		if ollamaBreaker.failures == ollamaBreaker.threshold {
+			log.Printf("Warning: %d consecutive Ollama failures, failing requests for %s: %v", ollamaBreaker.failures, ollamaBreaker.cooldown, err)
+		}
+	}
+}
@@ -1,12 +1,18 @@
 package services
 
 import (
+	"auto-annotation-api/models"
 	"bytes"
+	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log"
+	"math"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -16,19 +22,35 @@ type OllamaClient struct {
 	baseURL string
 	model   string
 	client  *http.Client
+	retries int           // Extra attempts of annotation requests after transient failures
+	backoff time.Duration // Delay before the first retry, doubled for each further one
 }
 
 // OllamaRequest represents the request to Ollama API
 type OllamaRequest struct {
-	Model  string `json:"model"`
-	Prompt string `json:"prompt"`
-	Stream bool   `json:"stream"`
+	Model  string   `json:"model"`
+	Prompt string   `json:"prompt"`
+	Images []string `json:"images,omitempty"` // Base64-encoded images for multimodal models
+	Format string   `json:"format,omitempty"` // "json" constrains the response to valid JSON
+	Stream bool     `json:"stream"`
 }
 
 // OllamaResponse represents the response from Ollama API
 type OllamaResponse struct {
 	Response string `json:"response"`
 	Done     bool   `json:"done"`
+	Error    string `json:"error,omitempty"` // Set when a streamed generation fails midway
+}
+
+// OllamaEmbeddingRequest represents the request to the Ollama embeddings API
+type OllamaEmbeddingRequest struct {
+	Model  string `json:"model"`
+	Prompt string `json:"prompt"`
+}
+
+// OllamaEmbeddingResponse represents the response from the Ollama embeddings API
+type OllamaEmbeddingResponse struct {
+	Embedding []float64 `json:"embedding"`
 }
 
 // NewOllamaClient creates a new Ollama client
@@ -71,6 +93,14 @@ func NewOllamaClientWithConfig(baseURL, model string) *OllamaClient {
 	}
 }
 
+// WithRetries retries annotation requests up to retries times after transient failures, waiting
+// backoff before the first retry and twice as long before each further one
+func (o *OllamaClient) WithRetries(retries int, backoff time.Duration) *OllamaClient {
+	o.retries = max(retries, 0)
+	o.backoff = backoff
+	return o
+}
+
 // AnnotationWithGenre holds annotation text and detected genre
 type AnnotationWithGenre struct {
 	Annotation string
@@ -78,65 +108,551 @@ type AnnotationWithGenre struct {
 }
 
 // GenerateAnnotation generates an annotation for the given text using Ollama
-func (o *OllamaClient) GenerateAnnotation(text, title string) (string, error) {
-	result, err := o.GenerateAnnotationWithGenre(text, title)
+func (o *OllamaClient) GenerateAnnotation(ctx context.Context, text, title string) (string, error) {
+	result, err := o.GenerateAnnotationWithGenre(ctx, text, title, "medium", "")
 	if err != nil {
 		return "", err
 	}
 	return result.Annotation, nil
 }
 
-// GenerateAnnotationWithGenre generates an annotation and detects genre for the given text
-func (o *OllamaClient) GenerateAnnotationWithGenre(text, title string) (*AnnotationWithGenre, error) {
-	prompt := o.createAnnotationPrompt(text, title)
+// GenerateAnnotationWithGenre generates an annotation of the given length ("short", "medium" or
+// "detailed"), focused on what matters for the genre. An empty genre lets the model detect it.
+func (o *OllamaClient) GenerateAnnotationWithGenre(ctx context.Context, text, title, length, genre string) (*AnnotationWithGenre, error) {
+	responseText, err := o.generateWithRetries(ctx, o.notesRequest(ctx, o.createAnnotationPrompt(text, title, length, genre)))
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse the response to extract genre and annotation
+	result := o.parseAnnotationResponse(responseText)
+	
+	return result, nil
+}
+
+// SummarizeChunk generates intermediate notes for one part of a long document
+func (o *OllamaClient) SummarizeChunk(ctx context.Context, chunk, title, genre string, part, totalParts int) (string, error) {
+	return o.generate(ctx, o.createChunkPrompt(chunk, title, genre, part, totalParts))
+}
+
+// ConsolidateAnnotations merges per-chunk notes into a single annotation, detecting the genre
+// when it is empty
+func (o *OllamaClient) ConsolidateAnnotations(ctx context.Context, partialNotes []string, title, length, genre string) (*AnnotationWithGenre, error) {
+	responseText, err := o.send(ctx, o.notesRequest(ctx, o.createConsolidationPrompt(partialNotes, title, length, genre)))
+	if err != nil {
+		return nil, err
+	}
+
+	return o.parseAnnotationResponse(responseText), nil
+}
+
+// ClassifyGenre makes a quick genre guess from the opening of a document, so generation can use
+// a genre-specific prompt. It returns one of the genres in genreFocus, or "Other".
+func (o *OllamaClient) ClassifyGenre(ctx context.Context, excerpt, title string) (string, error) {
+	prompt := fmt.Sprintf(`Classify the genre of this document.
+
+Title: %s
+
+Opening of the document:
+%s
+
+Reply with exactly one word from this list: Fiction, Non-Fiction, Academic, Educational, Other`, title, excerpt)
+
+	response, err := o.generate(ctx, prompt)
+	if err != nil {
+		return "", err
+	}
+	return normalizeGenre(response), nil
+}
+
+// LearningOutline lists what educational material teaches and what a learner should know beforehand
+type LearningOutline struct {
+	Objectives    []string
+	Prerequisites []string
+}
+
+// ExtractLearningOutline lists the learning objectives and prerequisite knowledge of educational
+// material from its notes
+func (o *OllamaClient) ExtractLearningOutline(ctx context.Context, notes, title string) (*LearningOutline, error) {
+	prompt := fmt.Sprintf(`You are a curriculum designer reviewing study notes for educational material.
+
+Title: %s
+
+Notes:
+%s
+
+List what a learner will be able to do after studying this material, and what they need to know beforehand.
+
+Reply in exactly this format, with 2 to 6 short bullet points per section and nothing else:
+OBJECTIVES:
+- [an objective starting with a verb, e.g. "Explain how..."]
+PREREQUISITES:
+- [a topic or skill, e.g. "Basic algebra"]
+
+Write "- None" under PREREQUISITES if no prior knowledge is needed.`, title, notes)
+
+	response, err := o.generate(ctx, prompt)
+	if err != nil {
+		return nil, err
+	}
+	return parseLearningOutline(response), nil
+}
+
+// parseLearningOutline reads the bullet lists of an ExtractLearningOutline response
+func parseLearningOutline(response string) *LearningOutline {
+	outline := &LearningOutline{Objectives: []string{}, Prerequisites: []string{}}
+
+	var current *[]string
+	for _, line := range strings.Split(response, "\n") {
+		line = strings.TrimSpace(line)
+		switch upper := strings.ToUpper(strings.Trim(line, "*# ")); {
+		case strings.HasPrefix(upper, "OBJECTIVES"):
+			current = &outline.Objectives
+		case strings.HasPrefix(upper, "PREREQUISITES"):
+			current = &outline.Prerequisites
+		case current != nil && (strings.HasPrefix(line, "-") || strings.HasPrefix(line, "*")):
+			item := strings.TrimSpace(strings.TrimLeft(line, "-* "))
+			if item != "" && !strings.EqualFold(strings.TrimRight(item, "."), "none") {
+				*current = append(*current, item)
+			}
+		}
+	}
+	return outline
+}
+
+// ExtractKeyTerms lists the key terms a document introduces, each with a one-line definition,
+// from its notes
+func (o *OllamaClient) ExtractKeyTerms(ctx context.Context, notes, title string) ([]models.KeyTerm, error) {
+	prompt := fmt.Sprintf(`You are writing the glossary for a set of study notes.
+
+Title: %s
+
+Notes:
+%s
+
+List the key terms a reader needs to understand this material: names of concepts, methods, people, places or events central to it.
+Give each a definition of one sentence, written for someone new to the subject.
+
+Reply in exactly this format, with 3 to 15 lines in order of appearance and nothing else:
+- [term]: [definition]
+
+Reply "- None" if the material introduces no particular terms.`, title, notes)
+
+	response, err := o.generate(ctx, prompt)
+	if err != nil {
+		return nil, err
+	}
+	return parseKeyTerms(response), nil
+}
+
+// parseKeyTerms reads the "- term: definition" lines of an ExtractKeyTerms response, skipping
+// lines without a definition and repeated terms
+func parseKeyTerms(response string) []models.KeyTerm {
+	var terms []models.KeyTerm
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(response, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "-") && !strings.HasPrefix(line, "*") {
+			continue
+		}
+		term, definition, ok := strings.Cut(strings.TrimLeft(line, "-* "), ":")
+		term = strings.Trim(strings.TrimSpace(term), "*_\"'`")
+		definition = strings.TrimSpace(strings.TrimLeft(definition, "*_ "))
+		if !ok || term == "" || definition == "" || seen[strings.ToLower(term)] {
+			continue
+		}
+		seen[strings.ToLower(term)] = true
+		terms = append(terms, models.KeyTerm{Term: term, Definition: definition})
+	}
+	return terms
+}
+
+// GenerateQuiz writes multiple-choice questions testing the understanding of a document. The
+// questions are only checked to be JSON, not to be well-formed.
+func (o *OllamaClient) GenerateQuiz(ctx context.Context, text, title string, count int) ([]models.QuizQuestion, error) {
+	prompt := fmt.Sprintf(`You are a teacher writing a multiple-choice quiz for students who studied the document below.
+
+Title: %s
+
+Document:
+%s
+
+Write %d questions testing understanding of the most important ideas, not trivia such as page numbers or exact wording.
+Each question has 4 answer choices, exactly one of them correct, with plausible wrong choices.
+
+Reply with a JSON object in exactly this form and nothing else:
+{"questions": [{"question": "...", "choices": ["...", "...", "...", "..."], "answer": [0-based index of the correct choice], "explanation": "[one sentence on why the answer is correct]"}]}`, title, text, count)
+
+	response, err := o.generateJSON(ctx, prompt)
+	if err != nil {
+		return nil, err
+	}
+
+	var quiz struct {
+		Questions []models.QuizQuestion `json:"questions"`
+	}
+	if err := json.Unmarshal([]byte(response), &quiz); err != nil {
+		return nil, fmt.Errorf("invalid quiz JSON: %w", err)
+	}
+	return quiz.Questions, nil
+}
+
+// GenerateTitle writes a title for a document from its opening
+func (o *OllamaClient) GenerateTitle(ctx context.Context, excerpt string) (string, error) {
+	prompt := fmt.Sprintf(`Write a title for the document below, as it would appear in a library catalog.
+
+Opening of the document:
+%s
+
+Use the document's own title if it states one. Otherwise write a short, descriptive title of at most 12 words.
+Reply with the title only, without quotes or any other text.`, excerpt)
+
+	response, err := o.generate(ctx, prompt)
+	if err != nil {
+		return "", err
+	}
+	return normalizeTitle(response), nil
+}
+
+// normalizeTitle cleans up a title written by the LLM: the first non-empty line, without a "Title:"
+// prefix, quotes or markdown
+func normalizeTitle(reply string) string {
+	for _, line := range strings.Split(reply, "\n") {
+		line = strings.Trim(strings.TrimSpace(line), "*#\"' ")
+		if len(line) >= 6 && strings.EqualFold(line[:6], "title:") {
+			line = strings.Trim(strings.TrimSpace(line[6:]), "*\"' ")
+		}
+		if line != "" {
+			return line
+		}
+	}
+	return ""
+}
+
+// ClassifySafety estimates from the notes how much a document deals with each sensitive topic,
+// as a confidence from 0 to 1 per topic
+func (o *OllamaClient) ClassifySafety(ctx context.Context, notes, title string) (map[string]float64, error) {
+	prompt := fmt.Sprintf(`You are a content reviewer deciding whether study material needs a content warning.
+
+Title: %s
+
+Notes:
+%s
+
+Rate how strongly the material deals with each of these topics, from 0.0 (not at all) to 1.0 (central theme):
+- violence: physical harm, war, abuse, weapons, crime
+- medical: illness, injury, surgery, medication, mental health
+- adult_themes: sexual content, drugs, alcohol, self-harm
+
+Reply in exactly this format and nothing else:
+violence: [score]
+medical: [score]
+adult_themes: [score]`, title, notes)
+
+	response, err := o.generate(ctx, prompt)
+	if err != nil {
+		return nil, err
+	}
+	return parseSafetyScores(response), nil
+}
+
+// parseSafetyScores reads the "topic: score" lines of a ClassifySafety response, clamping scores
+// to 0..1. Lines it can't read are skipped.
+func parseSafetyScores(response string) map[string]float64 {
+	scores := make(map[string]float64)
+	for _, line := range strings.Split(response, "\n") {
+		topic, value, ok := strings.Cut(strings.Trim(strings.TrimSpace(line), "*-# "), ":")
+		if !ok {
+			continue
+		}
+		topic = strings.ToLower(strings.Trim(strings.TrimSpace(topic), "*"))
+		score, err := strconv.ParseFloat(strings.TrimRight(strings.TrimLeft(strings.TrimSpace(value), "*"), "*."), 64)
+		if err != nil {
+			continue
+		}
+		scores[topic] = math.Max(0, math.Min(1, score))
+	}
+	return scores
+}
+
+// ExplainSelection explains a passage selected by a reader, using surrounding text as context
+func (o *OllamaClient) ExplainSelection(ctx context.Context, selection, surrounding, title string) (string, error) {
+	prompt := fmt.Sprintf(`You are a tutor helping a student who is reading a document and selected a passage they want explained.
+
+Document title: %s
+
+Surrounding text from the document:
+%s
+
+Selected passage:
+"%s"
+
+Explain the selected passage in plain language, in a few short paragraphs. Define any technical terms it uses and relate it to the surrounding text where helpful. Do not repeat the passage verbatim. Begin now:`, title, surrounding, selection)
+
+	return o.generate(ctx, prompt)
+}
+
+// AnswerQuestion answers a reader's question from numbered excerpts of a document and its notes.
+// The answer cites the excerpts it relies on as [1], [2], ...
+func (o *OllamaClient) AnswerQuestion(ctx context.Context, question, title, notes string, excerpts []string) (string, error) {
+	var numbered strings.Builder
+	for i, excerpt := range excerpts {
+		fmt.Fprintf(&numbered, "[%d]\n%s\n\n", i+1, excerpt)
+	}
+
+	prompt := fmt.Sprintf(`You are a tutor answering a student's question about a document they are studying.
+
+Document title: %s
+
+Study notes on the whole document:
+%s
+
+Excerpts from the document:
+%s
+Question: %s
+
+Answer the question using only the excerpts and the notes. Cite the excerpts you rely on by their number in square brackets, e.g. [2].
+If they don't contain the answer, say that the document doesn't cover it instead of guessing.
+Answer in a few short paragraphs at most. Begin now:`, title, notes, numbered.String(), question)
+
+	return o.generate(ctx, prompt)
+}
+
+// SummarizeSectionForAudio writes a short spoken overview of one section of a document
+func (o *OllamaClient) SummarizeSectionForAudio(ctx context.Context, sectionTitle, sectionText, title string) (string, error) {
+	prompt := fmt.Sprintf(`You are narrating a short audio preview of a document for students.
+
+Document title: %s
+Section: %s
+
+Section text:
+%s
+
+INSTRUCTIONS:
+- Write 2 to 4 sentences (at most 80 words) that will be read aloud as the overview of this section
+- Use plain spoken language, no lists, headings, markdown or symbols
+- Do not start with "This section"; speak directly about the subject matter
+
+Begin now:`, title, sectionTitle, sectionText)
+
+	return o.generate(ctx, prompt)
+}
 
-	request := OllamaRequest{
-		Model:  o.model,
+// SummarizeSection writes the notes for one section of a long document, such as a chapter
+func (o *OllamaClient) SummarizeSection(ctx context.Context, sectionTitle, sectionText, title string) (string, error) {
+	prompt := fmt.Sprintf(`You are writing study notes for one section of a long document.
+
+Document title: %s
+Section: %s
+
+Section text:
+%s
+
+INSTRUCTIONS:
+- Summarize this section in one paragraph of 3 to 6 sentences: its main points, arguments or events
+- Only cover this section, don't introduce the document as a whole
+- Write plain text, no headings, lists or markdown
+
+Begin now:`, title, sectionTitle, sectionText)
+
+	response, err := o.generate(ctx, prompt)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(response), nil
+}
+
+// ExplainCodeExamples picks the most instructive code snippets of a document and explains them
+func (o *OllamaClient) ExplainCodeExamples(ctx context.Context, snippets, title string) (string, error) {
+	prompt := fmt.Sprintf(`You are writing study notes for programming material titled "%s".
+
+Code snippets from the material:
+%s
+
+INSTRUCTIONS:
+- Pick the 1 to 3 snippets that best illustrate the key concepts
+- For each one, write one or two sentences explaining what it demonstrates, followed by the snippet in a fenced code block (`+"```"+`)
+- Keep the code exactly as given, do not rewrite or extend it
+- Do not add an introduction or conclusion
+
+Begin now:`, title, snippets)
+
+	return o.generate(ctx, prompt)
+}
+
+// DescribeImage writes alt text for an image using a multimodal model
+func (o *OllamaClient) DescribeImage(ctx context.Context, model string, image []byte, title string) (string, error) {
+	prompt := fmt.Sprintf(`Write alt text for this image, which illustrates educational notes titled "%s".
+
+Describe what the image shows in one sentence of at most 125 characters, for a reader who cannot see it. Do not start with "Image of" or "Picture of". Reply with the alt text only.`, title)
+
+	return o.generateWithModel(ctx, model, prompt, []string{base64.StdEncoding.EncodeToString(image)})
+}
+
+// DescribeFigure explains a figure from a document using a multimodal model. It returns
+// "DECORATIVE" for images that carry no information.
+func (o *OllamaClient) DescribeFigure(ctx context.Context, model string, image []byte, title string, page int) (string, error) {
+	prompt := fmt.Sprintf(`This image is a figure from page %d of a document titled "%s".
+
+If it is a chart, diagram, table or other informational figure, explain in 2 to 3 sentences what it shows and the key insight it conveys (trends, comparisons, relationships or structure). Write directly about the subject, not about "the figure".
+
+If it is decorative (a logo, a photo without informational content, a border), reply with the single word DECORATIVE.`, page, title)
+
+	return o.generateWithModel(ctx, model, prompt, []string{base64.StdEncoding.EncodeToString(image)})
+}
+
+// TranscribeImage reads the content of an image uploaded as a document, such as a photo of a
+// whiteboard or a slide, using a multimodal model
+func (o *OllamaClient) TranscribeImage(ctx context.Context, model string, image []byte) (string, error) {
+	prompt := `This image is the source document for educational notes, for example a photo of a whiteboard, a slide or a book page.
+
+INSTRUCTIONS:
+- Transcribe all text in the image, including handwriting, in reading order
+- Keep headings, lists and line breaks; write formulas in LaTeX
+- After the text, explain in a few sentences any diagram, chart or table whose content the text doesn't give
+- Do not add an introduction or comments about the image or its quality
+- If the image contains no readable content, reply with nothing
+
+Begin now:`
+
+	return o.generateWithModel(ctx, model, prompt, []string{base64.StdEncoding.EncodeToString(image)})
+}
+
+// GenerateAltText writes alt text for an annotation image from its title and summary, for when
+// no multimodal model is available
+func (o *OllamaClient) GenerateAltText(ctx context.Context, title, summary string) (string, error) {
+	prompt := fmt.Sprintf(`An image illustrates educational notes. You cannot see the image, only the notes.
+
+Title: %s
+
+Notes:
+%s
+
+Write alt text for the image in one sentence of at most 125 characters, describing what it most likely depicts given the subject. Do not start with "Image of" or "Picture of". Reply with the alt text only.`, title, summary)
+
+	return o.generate(ctx, prompt)
+}
+
+// generate sends a prompt to the context's model and returns the trimmed response text
+func (o *OllamaClient) generate(ctx context.Context, prompt string) (string, error) {
+	return o.generateWithModel(ctx, o.modelFor(ctx), prompt, nil)
+}
+
+// generateWithRetries is send, retried with exponential backoff after transient failures. It
+// gives up early when the circuit breaker opens. Transient failures happen before any text is
+// streamed, so a retry never repeats streamed text.
+func (o *OllamaClient) generateWithRetries(ctx context.Context, request OllamaRequest) (string, error) {
+	for attempt := 0; ; attempt++ {
+		response, err := o.send(ctx, request)
+		if err == nil || attempt >= o.retries || !isTransientOllamaError(err) || isOllamaTimeout(err) {
+			return response, err
+		}
+		delay := o.backoff << attempt
+		log.Printf("Warning: Ollama request failed, retrying in %s (%d/%d): %v", delay, attempt+1, o.retries, err)
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// notesRequest builds the request for annotation notes, streamed when ctx is from WithNotesStream
+func (o *OllamaClient) notesRequest(ctx context.Context, prompt string) OllamaRequest {
+	return OllamaRequest{Model: o.modelFor(ctx), Prompt: prompt, Stream: notesStreamFrom(ctx) != nil}
+}
+
+// generateJSON sends a prompt whose response must be a JSON object
+func (o *OllamaClient) generateJSON(ctx context.Context, prompt string) (string, error) {
+	return o.send(ctx, OllamaRequest{Model: o.modelFor(ctx), Prompt: prompt, Format: "json"})
+}
+
+// generateWithModel sends a prompt, with optional images, to a specific Ollama model
+func (o *OllamaClient) generateWithModel(ctx context.Context, model, prompt string, images []string) (string, error) {
+	return o.send(ctx, OllamaRequest{
+		Model:  model,
 		Prompt: prompt,
+		Images: images,
 		Stream: false,
-	}
+	})
+}
 
+// send makes a generate request and returns the trimmed response text. Streamed responses are
+// passed to the context's notes stream as they arrive. The request is abandoned when ctx is canceled. While the circuit breaker is open it fails with an
+// OllamaUnavailableError without calling Ollama.
+func (o *OllamaClient) send(ctx context.Context, request OllamaRequest) (string, error) {
 	jsonData, err := json.Marshal(request)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+	if err := allowOllamaRequest(); err != nil {
+		return "", err
 	}
 
 	// Make request to Ollama
-	resp, err := o.client.Post(o.baseURL+"/api/generate", "application/json", bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.baseURL+"/api/generate", bytes.NewBuffer(jsonData))
 	if err != nil {
-		return nil, fmt.Errorf("failed to make request to Ollama: %w", err)
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	started := time.Now()
+	resp, err := o.client.Do(req)
+	if err != nil && ctx.Err() != nil {
+		return "", fmt.Errorf("Ollama request canceled: %w", ctx.Err()) // Not Ollama's fault, the breaker ignores it
+	}
+	if err != nil {
+		err = &ollamaTransientError{fmt.Errorf("failed to make request to Ollama: %w", err)}
+		recordOllamaResult(err)
+		return "", err
 	}
 	defer resp.Body.Close()
+	if !request.Stream {
+		recordOllamaLatency(time.Since(started)) // Without streaming, Ollama answers once generation is done
+	}
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("Ollama API error (status %d): %s", resp.StatusCode, string(body))
-	}
-
-	// Read response
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+		err := fmt.Errorf("Ollama API error (status %d): %s", resp.StatusCode, string(body))
+		if resp.StatusCode >= http.StatusInternalServerError {
+			err = &ollamaTransientError{err}
+		}
+		recordOllamaResult(err)
+		return "", err
 	}
+	recordOllamaResult(nil)
+
+	var responseText string
+	if request.Stream {
+		stream := &notesStream{fn: notesStreamFrom(ctx)}
+		responseText, err = readOllamaStream(resp.Body, stream.write)
+		if err != nil {
+			return "", err
+		}
+		recordOllamaLatency(time.Since(started))
+	} else {
+		// Read response
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return "", fmt.Errorf("failed to read response: %w", err)
+		}
 
-	var ollamaResp OllamaResponse
-	if err := json.Unmarshal(body, &ollamaResp); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+		var ollamaResp OllamaResponse
+		if err := json.Unmarshal(body, &ollamaResp); err != nil {
+			return "", fmt.Errorf("failed to unmarshal response: %w", err)
+		}
+		responseText = ollamaResp.Response
 	}
 
-	responseText := strings.TrimSpace(ollamaResp.Response)
+	responseText = strings.TrimSpace(responseText)
 	if responseText == "" {
-		return nil, fmt.Errorf("received empty response from Ollama")
+		return "", fmt.Errorf("received empty response from Ollama")
 	}
 
-	// Parse the response to extract genre and annotation
-	result := o.parseAnnotationResponse(responseText)
-	
-	return result, nil
+	return responseText, nil
 }
 
 // createAnnotationPrompt creates a comprehensive prompt for annotation generation
-func (o *OllamaClient) createAnnotationPrompt(text, title string) string {
+func (o *OllamaClient) createAnnotationPrompt(text, title, length, genre string) string {
 	prompt := fmt.Sprintf(`You are creating educational study notes. Write directly about the concepts and ideas, not about the document itself.
 
 Title: %s
@@ -145,15 +661,19 @@ Source Material:
 %s
 
 INSTRUCTIONS:
-1. Start with: GENRE: [pick one: Fiction, Non-Fiction, Academic, Educational, or Other]
+1. Start with: %s
+
+2. Then write your educational notes/annotation.%s
 
-2. Then write your educational notes/annotation.
+3. LENGTH: %s
 
 CRITICAL RULES - YOU MUST FOLLOW THESE:
 - NEVER start sentences with: "This paper", "This document", "This case study", "This content", "The author", "The research"
 - NEVER use phrases like: "discusses", "presents", "explores", "examines" when referring to the document
 - Write DIRECTLY about the subject matter itself
 - Act as if YOU are teaching the topic, not describing someone else's work
+- Write any mathematical expressions in LaTeX, inline as $...$ and on their own line as $$...$$
+- Put any code in fenced code blocks (`+"```"+`), never inside a sentence
 
 WRONG (DO NOT DO THIS):
 "This case study presents the Software as a Service lifecycle..."
@@ -165,11 +685,108 @@ CORRECT (DO THIS):
 "Cloud computing relies on distributed infrastructure..."
 "Modern software sourcing involves strategic vendor selection..."
 
-Start your response with "GENRE:" followed by your direct educational content. Begin now:`, title, text)
+Start your response with "GENRE:" followed by your direct educational content. Begin now:`, title, text, genreLine(genre), genreFocus(genre), lengthInstruction(length))
 
 	return prompt
 }
 
+// createChunkPrompt creates a prompt for summarizing one part of a long document
+func (o *OllamaClient) createChunkPrompt(chunk, title, genre string, part, totalParts int) string {
+	return fmt.Sprintf(`You are creating educational study notes for a long document that has been split into parts.
+
+Title: %s
+Part: %d of %d
+
+Source Material:
+%s
+
+INSTRUCTIONS:
+- Write concise notes covering the key concepts, facts and arguments in THIS part only
+- Write DIRECTLY about the subject matter, not about the document itself
+- Do not add an introduction or conclusion, these notes will be combined with notes for the other parts
+- Write any mathematical expressions in LaTeX, inline as $...$ and on their own line as $$...$$
+- Put any code in fenced code blocks (`+"```"+`), never inside a sentence%s
+
+Begin now:`, title, part, totalParts, chunk, genreFocus(genre))
+}
+
+// createConsolidationPrompt creates a prompt that merges per-part notes into one annotation
+func (o *OllamaClient) createConsolidationPrompt(partialNotes []string, title, length, genre string) string {
+	var notes strings.Builder
+	for i, note := range partialNotes {
+		notes.WriteString(fmt.Sprintf("--- Notes for part %d ---\n%s\n\n", i+1, note))
+	}
+
+	return fmt.Sprintf(`You are creating educational study notes. Below are notes written for consecutive parts of one document. Combine them into a single coherent annotation.
+
+Title: %s
+
+Notes:
+%s
+INSTRUCTIONS:
+1. Start with: %s
+
+2. Then write one unified annotation covering the whole document. Remove repetition between parts.%s
+
+3. LENGTH: %s
+
+CRITICAL RULES - YOU MUST FOLLOW THESE:
+- NEVER start sentences with: "This paper", "This document", "This case study", "This content", "The author", "The research"
+- NEVER mention the parts or notes you were given
+- Write DIRECTLY about the subject matter itself
+- Write any mathematical expressions in LaTeX, inline as $...$ and on their own line as $$...$$
+- Put any code in fenced code blocks (`+"```"+`), never inside a sentence
+
+Start your response with "GENRE:" followed by your direct educational content. Begin now:`, title, notes.String(), genreLine(genre), genreFocus(genre), lengthInstruction(length))
+}
+
+// genreLine tells the model how to start its response: with the classified genre, or with its own pick
+func genreLine(genre string) string {
+	if genre == "" {
+		return "GENRE: [pick one: Fiction, Non-Fiction, Academic, Educational, or Other]"
+	}
+	return "GENRE: " + genre
+}
+
+// genreFocus describes what the notes should cover for a genre, or nothing for unknown genres
+func genreFocus(genre string) string {
+	switch genre {
+	case "Fiction":
+		return "\n- FOCUS: the plot and how it develops, the central themes, and the main characters with their motivations and relationships"
+	case "Academic":
+		return "\n- FOCUS: the research question, the methodology, the key findings with supporting evidence, and their limitations and implications"
+	case "Educational":
+		return "\n- FOCUS: the learning objectives, the core concepts explained with examples, and the key takeaways a student should remember"
+	case "Non-Fiction":
+		return "\n- FOCUS: the main argument, the most important facts and evidence behind it, and the conclusions"
+	default:
+		return ""
+	}
+}
+
+// normalizeGenre maps a model reply like "academic." to one of the known genres, or "Other"
+func normalizeGenre(reply string) string {
+	reply = strings.ToLower(reply)
+	for _, genre := range []string{"Non-Fiction", "Fiction", "Academic", "Educational"} {
+		if strings.Contains(reply, strings.ToLower(genre)) || genre == "Non-Fiction" && strings.Contains(reply, "nonfiction") {
+			return genre
+		}
+	}
+	return "Other"
+}
+
+// lengthInstruction describes the target length of an annotation for the prompts
+func lengthInstruction(length string) string {
+	switch length {
+	case "short":
+		return "Keep it short, about 100 words in one or two paragraphs. Cover only the central ideas."
+	case "detailed":
+		return "Write a detailed annotation of about 600 words covering all major concepts, with examples where useful."
+	default:
+		return "Aim for about 300 words covering the main concepts."
+	}
+}
+
 // parseAnnotationResponse parses the Ollama response to extract genre and annotation
 func (o *OllamaClient) parseAnnotationResponse(response string) *AnnotationWithGenre {
 	result := &AnnotationWithGenre{
@@ -201,6 +818,39 @@ func (o *OllamaClient) parseAnnotationResponse(response string) *AnnotationWithG
 	return result
 }
 
+// GenerateEmbedding returns the embedding vector for the given text using the given model
+func (o *OllamaClient) GenerateEmbedding(text, model string) ([]float64, error) {
+	jsonData, err := json.Marshal(OllamaEmbeddingRequest{
+		Model:  model,
+		Prompt: text,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	resp, err := o.client.Post(o.baseURL+"/api/embeddings", "application/json", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request to Ollama: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("Ollama API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var embeddingResp OllamaEmbeddingResponse
+	if err := json.NewDecoder(resp.Body).Decode(&embeddingResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	if len(embeddingResp.Embedding) == 0 {
+		return nil, fmt.Errorf("received empty embedding from Ollama")
+	}
+
+	return embeddingResp.Embedding, nil
+}
+
 // TestConnection tests if Ollama is accessible
 func (o *OllamaClient) TestConnection() error {
 	resp, err := o.client.Get(o.baseURL + "/api/tags")
@@ -0,0 +1,173 @@
+package services
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Ollama fixture modes, selected with OLLAMA_FIXTURE_MODE
+const (
+	OllamaFixturesOff    = ""
+	OllamaFixturesRecord = "record" // Call Ollama and store every successful response
+	OllamaFixturesReplay = "replay" // Answer from stored responses only, Ollama is never called
+)
+
+// ollamaFixture is a recorded Ollama exchange. The request is kept so fixtures can be reviewed.
+type ollamaFixture struct {
+	Method   string          `json:"method"`
+	Path     string          `json:"path"`
+	Request  json.RawMessage `json:"request,omitempty"`
+	Status   int             `json:"status"`
+	Response json.RawMessage `json:"response"`
+}
+
+// ollamaFixtureTransport records Ollama responses to disk, keyed by a hash of the request, or
+// replays them. Requests are marshaled from structs, so the same prompt and model always hash the
+// same and the pipeline runs reproducibly without a GPU.
+type ollamaFixtureTransport struct {
+	mode string
+	dir  string
+	next http.RoundTripper
+}
+
+// WithFixtures makes the client record responses to dir or replay them from it. An empty mode
+// leaves the client talking to Ollama directly.
+func (o *OllamaClient) WithFixtures(mode, dir string) *OllamaClient {
+	mode = strings.ToLower(strings.TrimSpace(mode))
+	switch mode {
+	case OllamaFixturesOff:
+		return o
+	case OllamaFixturesRecord, OllamaFixturesReplay:
+	default:
+		log.Printf("Warning: unknown OLLAMA_FIXTURE_MODE %q, calling Ollama directly", mode)
+		return o
+	}
+
+	if dir == "" {
+		dir = "testdata/ollama"
+	}
+	if mode == OllamaFixturesRecord {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			log.Printf("Warning: failed to create Ollama fixture directory, calling Ollama directly: %v", err)
+			return o
+		}
+	}
+
+	next := o.client.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	o.client.Transport = &ollamaFixtureTransport{mode: mode, dir: dir, next: next}
+	return o
+}
+
+func (t *ollamaFixtureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s %s\n", req.Method, req.URL.Path)
+	hash.Write(body)
+	path := filepath.Join(t.dir, hex.EncodeToString(hash.Sum(nil))+".json")
+
+	if t.mode == OllamaFixturesReplay {
+		return t.replay(req, path)
+	}
+	return t.record(req, body, path)
+}
+
+// replay answers from the fixture at path. A missing fixture is an error rather than a live call,
+// so a replayed run can't silently depend on Ollama.
+func (t *ollamaFixtureTransport) replay(req *http.Request, path string) (*http.Response, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("no recorded Ollama response for %s %s (%s), record it with OLLAMA_FIXTURE_MODE=record", req.Method, req.URL.Path, filepath.Base(path))
+		}
+		return nil, fmt.Errorf("failed to read Ollama fixture: %w", err)
+	}
+
+	var fixture ollamaFixture
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		return nil, fmt.Errorf("invalid Ollama fixture %s: %w", filepath.Base(path), err)
+	}
+	return &http.Response{
+		StatusCode:    fixture.Status,
+		Status:        fmt.Sprintf("%d %s", fixture.Status, http.StatusText(fixture.Status)),
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": {"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(fixture.Response)),
+		ContentLength: int64(len(fixture.Response)),
+		Request:       req,
+	}, nil
+}
+
+// record calls Ollama and stores successful JSON responses at path
+func (t *ollamaFixtureTransport) record(req *http.Request, body []byte, path string) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+
+	if resp.StatusCode != http.StatusOK || !json.Valid(data) {
+		return resp, nil
+	}
+
+	fixture := ollamaFixture{Method: req.Method, Path: req.URL.Path, Status: resp.StatusCode, Response: data}
+	if json.Valid(body) {
+		fixture.Request = body
+	}
+	if err := writeOllamaFixture(path, &fixture); err != nil {
+		log.Printf("Warning: failed to record Ollama response: %v", err)
+	}
+	return resp, nil
+}
+
+// writeOllamaFixture writes through a temporary file, so a replay never reads half a fixture
+func writeOllamaFixture(path string, fixture *ollamaFixture) error {
+	data, err := json.MarshalIndent(fixture, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".fixture-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
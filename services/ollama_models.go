@@ -0,0 +1,239 @@
+package services
+
+import (
+	"auto-annotation-api/models"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+type ollamaModelKey struct{}
+
+// withOllamaModel returns a context under which text prompts go to model instead of the client's
+// default model. The vision model is chosen separately.
+func withOllamaModel(ctx context.Context, model string) context.Context {
+	return context.WithValue(ctx, ollamaModelKey{}, model)
+}
+
+// modelFor returns the model set by withOllamaModel, or the client's default model
+func (o *OllamaClient) modelFor(ctx context.Context) string {
+	if model, _ := ctx.Value(ollamaModelKey{}).(string); model != "" {
+		return model
+	}
+	return o.model
+}
+
+// parseModelList parses OLLAMA_ALLOWED_MODELS, a comma-separated list of model names
+func parseModelList(spec string) []string {
+	var list []string
+	for _, model := range strings.Split(spec, ",") {
+		if model = strings.TrimSpace(model); model != "" && !slices.Contains(list, model) {
+			list = append(list, model)
+		}
+	}
+	return list
+}
+
+// selectModel validates the model an upload or regeneration asks for and returns the model to use.
+// Empty selects the default model. Other models must be on OLLAMA_ALLOWED_MODELS and installed in
+// Ollama, so a typo fails the request instead of every prompt.
+func (s *AnnotationService) selectModel(model string) (string, error) {
+	model = strings.TrimSpace(model)
+	if model == "" || model == s.ollamaClient.model {
+		return s.ollamaClient.model, nil
+	}
+	if !slices.Contains(s.textModels, model) {
+		return "", fmt.Errorf("invalid model %q, must be one of %s", model, strings.Join(s.SelectableModels(), ", "))
+	}
+
+	installed, err := s.ollamaClient.GetAvailableModels()
+	if err != nil {
+		return "", err
+	}
+	for _, name := range installed {
+		if sameModel(name, model) {
+			return model, nil
+		}
+	}
+	return "", fmt.Errorf("invalid model %q: not installed in Ollama", model)
+}
+
+// SelectableModels returns the models uploads and regenerations may ask for, the default first
+func (s *AnnotationService) SelectableModels() []string {
+	selectable := []string{s.ollamaClient.model}
+	for _, model := range s.textModels {
+		if model != s.ollamaClient.model {
+			selectable = append(selectable, model)
+		}
+	}
+	return selectable
+}
+
+// ListModels returns the models installed in Ollama with their size and details
+func (o *OllamaClient) ListModels(ctx context.Context) ([]models.LLMModel, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, o.baseURL+"/api/tags", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get models: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("Ollama API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var result struct {
+		Models []struct {
+			Name       string    `json:"name"`
+			Size       int64     `json:"size"`
+			Digest     string    `json:"digest"`
+			ModifiedAt time.Time `json:"modified_at"`
+			Details    struct {
+				Family            string `json:"family"`
+				ParameterSize     string `json:"parameter_size"`
+				QuantizationLevel string `json:"quantization_level"`
+			} `json:"details"`
+		} `json:"models"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	installed := make([]models.LLMModel, 0, len(result.Models))
+	for _, m := range result.Models {
+		installed = append(installed, models.LLMModel{
+			Name:          m.Name,
+			Size:          m.Size,
+			Digest:        m.Digest,
+			ModifiedAt:    m.ModifiedAt,
+			Family:        m.Details.Family,
+			ParameterSize: m.Details.ParameterSize,
+			Quantization:  m.Details.QuantizationLevel,
+		})
+	}
+	return installed, nil
+}
+
+// PullModel downloads a model into Ollama, passing each progress update to progress. Downloads can
+// take far longer than the client timeout, so they only end with ctx.
+func (o *OllamaClient) PullModel(ctx context.Context, name string, progress func(models.PullProgress)) error {
+	body, err := json.Marshal(map[string]interface{}{"model": name, "stream": true})
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.baseURL+"/api/pull", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{Transport: o.client.Transport}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to make request to Ollama: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("Ollama API error (status %d): %s", resp.StatusCode, string(message))
+	}
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var update struct {
+			models.PullProgress
+			Error string `json:"error"`
+		}
+		if err := decoder.Decode(&update); err != nil {
+			return fmt.Errorf("failed to read pull progress: %w", err)
+		}
+		if update.Error != "" {
+			return fmt.Errorf("failed to pull model %s: %s", name, update.Error)
+		}
+		progress(update.PullProgress)
+		if update.Status == "success" {
+			return nil
+		}
+	}
+}
+
+// DeleteModel removes a model from Ollama
+func (o *OllamaClient) DeleteModel(ctx context.Context, name string) error {
+	body, err := json.Marshal(map[string]string{"model": name})
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, o.baseURL+"/api/delete", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to make request to Ollama: %w", err)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusNotFound:
+		return fmt.Errorf("model %s not found", name)
+	default:
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("Ollama API error (status %d): %s", resp.StatusCode, string(message))
+	}
+}
+
+// sameModel reports whether an installed model's name refers to model, which may omit the tag
+func sameModel(installed, model string) bool {
+	return installed == model || installed == model+":latest"
+}
+
+// ListLLMModels returns the models installed in Ollama, marking the default and selectable ones
+func (s *AnnotationService) ListLLMModels(ctx context.Context) ([]models.LLMModel, error) {
+	installed, err := s.ollamaClient.ListModels(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range installed {
+		installed[i].Default = sameModel(installed[i].Name, s.ollamaClient.model)
+		for _, model := range s.SelectableModels() {
+			if sameModel(installed[i].Name, model) {
+				installed[i].Selectable = true
+			}
+		}
+	}
+	return installed, nil
+}
+
+// PullLLMModel downloads a model into Ollama. It only becomes selectable once it is also on
+// OLLAMA_ALLOWED_MODELS.
+func (s *AnnotationService) PullLLMModel(ctx context.Context, name string, progress func(models.PullProgress)) error {
+	name = strings.TrimSpace(name)
+	if name == "" || strings.ContainsAny(name, " \t\n") {
+		return fmt.Errorf("invalid model name %q", name)
+	}
+	return s.ollamaClient.PullModel(ctx, name, progress)
+}
+
+// DeleteLLMModel removes a model from Ollama. The models the server is configured with can't be
+// removed, the pipeline would fail without them.
+func (s *AnnotationService) DeleteLLMModel(ctx context.Context, name string) error {
+	name = strings.TrimSpace(name)
+	for _, model := range []string{s.ollamaClient.model, s.visionModel} {
+		if model != "" && (sameModel(name, model) || sameModel(model, name)) {
+			return fmt.Errorf("cannot delete model %s, the server is configured to use it", name)
+		}
+	}
+	return s.ollamaClient.DeleteModel(ctx, name)
+}
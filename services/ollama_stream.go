@@ -0,0 +1,83 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// genrePrefix opens the genre line that parseAnnotationResponse removes from the notes
+const genrePrefix = "GENRE:"
+
+type notesStreamKey struct{}
+
+// WithNotesStream returns a context under which annotation notes are generated in Ollama's streaming
+// mode, with fn receiving each piece of text as it is written. Only the final notes are streamed:
+// chunk summaries of long documents and the other prompts of the pipeline are not.
+func WithNotesStream(ctx context.Context, fn func(text string)) context.Context {
+	return context.WithValue(ctx, notesStreamKey{}, fn)
+}
+
+// notesStreamFrom returns the function set by WithNotesStream, or nil
+func notesStreamFrom(ctx context.Context) func(text string) {
+	fn, _ := ctx.Value(notesStreamKey{}).(func(text string))
+	return fn
+}
+
+// notesStream passes streamed notes on, holding back their opening until it is known whether it
+// is the genre line, which isn't part of the notes
+type notesStream struct {
+	fn      func(text string)
+	pending strings.Builder
+	started bool
+}
+
+func (n *notesStream) write(text string) {
+	if n.started {
+		n.fn(text)
+		return
+	}
+
+	n.pending.WriteString(text)
+	head := strings.TrimLeft(n.pending.String(), " \r\n")
+	if len(head) < len(genrePrefix) && strings.HasPrefix(genrePrefix, head) {
+		return // Too short to tell yet
+	}
+	if strings.HasPrefix(head, genrePrefix) {
+		_, rest, complete := strings.Cut(head, "\n")
+		if !complete {
+			return
+		}
+		head = strings.TrimLeft(rest, " \r\n")
+	}
+	n.started = true
+	if head != "" {
+		n.fn(head)
+	}
+}
+
+// readOllamaStream reads a streamed generate response, one JSON object per line, passing each
+// piece of text to onText as it arrives. It returns the whole text once Ollama reports it is done.
+func readOllamaStream(body io.Reader, onText func(text string)) (string, error) {
+	var text strings.Builder
+	decoder := json.NewDecoder(body)
+	for {
+		var chunk OllamaResponse
+		if err := decoder.Decode(&chunk); err != nil {
+			return "", fmt.Errorf("failed to read streamed response: %w", err)
+		}
+		if chunk.Error != "" {
+			return "", fmt.Errorf("Ollama failed while streaming: %s", chunk.Error)
+		}
+
+		text.WriteString(chunk.Response)
+		if onText != nil && chunk.Response != "" {
+			onText(chunk.Response)
+		}
+		if chunk.Done {
+			return text.String(), nil
+		}
+	}
+}
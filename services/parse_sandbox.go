@@ -0,0 +1,149 @@
+package services
+
+import (
+	"auto-annotation-api/config"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseWorkerCommand is the command line argument that starts the API binary as a parse worker
+const ParseWorkerCommand = "parse-worker"
+
+// parseWorkerFailed is the exit code of a worker that reported an error on stderr; other exit codes
+// mean the worker crashed or was killed
+const parseWorkerFailed = 3
+
+// ParseSandbox extracts text from PDFs in a separate process, a copy of the API binary started
+// with ParseWorkerCommand. The worker is limited in memory and CPU time and killed after a timeout,
+// so a malformed or hostile PDF can't exhaust the API's memory or keep a parser spinning.
+type ParseSandbox struct {
+	executable string
+	timeout    time.Duration
+	memoryMB   int
+}
+
+// NewParseSandbox creates the parse sandbox, or returns nil when PARSE_SANDBOX is off
+func NewParseSandbox(cfg *config.Config) (*ParseSandbox, error) {
+	if !cfg.ParseSandbox {
+		return nil, nil
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("parse sandbox not available: %w", err)
+	}
+
+	timeout := cfg.ParseTimeout
+	if timeout <= 0 {
+		timeout = 120
+	}
+	memoryMB := cfg.ParseMemoryMB
+	if memoryMB <= 0 {
+		memoryMB = 1024
+	}
+	return &ParseSandbox{
+		executable: executable,
+		timeout:    time.Duration(timeout) * time.Second,
+		memoryMB:   memoryMB,
+	}, nil
+}
+
+// ExtractTextFromReader extracts the text of a PDF in a worker process
+func (p *ParseSandbox) ExtractTextFromReader(reader io.Reader, size int64) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+
+	cpuSeconds := int(p.timeout / time.Second)
+	cmd := exec.CommandContext(ctx, p.executable, ParseWorkerCommand, strconv.Itoa(p.memoryMB), strconv.Itoa(cpuSeconds))
+	cmd.Stdin = reader
+	cmd.Env = []string{} // The worker needs no configuration, and shouldn't see credentials
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return "", fmt.Errorf("failed to parse PDF: parsing took longer than %s", p.timeout)
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		if exitErr.ExitCode() == parseWorkerFailed {
+			return "", errors.New(strings.TrimSpace(stderr.String()))
+		}
+		return "", fmt.Errorf("failed to parse PDF: parser exceeded its limits of %d MB memory and %d seconds CPU time (%s)",
+			p.memoryMB, cpuSeconds, exitErr)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to parse PDF: failed to run parse worker: %w", err)
+	}
+	return stdout.String(), nil
+}
+
+// ExtractText extracts the text of a PDF file in a worker process
+func (p *ParseSandbox) ExtractText(filePath string) (string, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open PDF: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return "", fmt.Errorf("failed to open PDF: %w", err)
+	}
+	return p.ExtractTextFromReader(f, info.Size())
+}
+
+// RunParseWorker is the parse worker's main function: it reads a PDF from stdin and writes its text
+// to stdout. Errors are written to stderr with exit code parseWorkerFailed. The arguments after
+// ParseWorkerCommand are the memory limit in MB and the CPU time limit in seconds.
+func RunParseWorker(args []string) {
+	if len(args) != 2 {
+		fmt.Fprintf(os.Stderr, "usage: %s <memory MB> <CPU seconds>\n", ParseWorkerCommand)
+		os.Exit(2)
+	}
+	memoryMB, err1 := strconv.Atoi(args[0])
+	cpuSeconds, err2 := strconv.Atoi(args[1])
+	if err1 != nil || err2 != nil || memoryMB <= 0 || cpuSeconds <= 0 {
+		fmt.Fprintf(os.Stderr, "invalid limits: %s\n", strings.Join(args, " "))
+		os.Exit(2)
+	}
+
+	// The soft limit makes the garbage collector work harder before the hard limit kills the worker
+	debug.SetMemoryLimit(int64(memoryMB) << 20 * 9 / 10)
+	if err := limitResources(memoryMB, cpuSeconds); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to limit resources: %v\n", err)
+		os.Exit(2)
+	}
+
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to read PDF data: %v\n", err)
+		os.Exit(parseWorkerFailed)
+	}
+	text, err := extractPDFText(data)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(parseWorkerFailed)
+	}
+	os.Stdout.WriteString(text)
+}
+
+// extractPDFText parses a PDF in the worker. The PDF library panics on some malformed documents;
+// that is reported as a parse error rather than a crash.
+func extractPDFText(data []byte) (text string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("failed to parse PDF: %v", r)
+		}
+	}()
+	return NewPDFParser().ExtractTextFromReader(bytes.NewReader(data), int64(len(data)))
+}
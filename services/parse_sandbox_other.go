@@ -0,0 +1,9 @@
+//go:build !linux && !darwin
+
+package services
+
+// limitResources can't cap resources on this platform; the worker is still killed after the timeout
+// and its memory is freed when it exits
+func limitResources(memoryMB, cpuSeconds int) error {
+	return nil
+}
@@ -0,0 +1,16 @@
+//go:build linux || darwin
+
+package services
+
+import "syscall"
+
+// limitResources caps the memory and CPU time of the current process. Exceeding the memory limit
+// makes allocations fail; exceeding the CPU time gets the process killed.
+func limitResources(memoryMB, cpuSeconds int) error {
+	memory := uint64(memoryMB) << 20
+	if err := syscall.Setrlimit(syscall.RLIMIT_DATA, &syscall.Rlimit{Cur: memory, Max: memory}); err != nil {
+		return err
+	}
+	cpu := uint64(cpuSeconds)
+	return syscall.Setrlimit(syscall.RLIMIT_CPU, &syscall.Rlimit{Cur: cpu, Max: cpu})
+}
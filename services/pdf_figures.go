@@ -0,0 +1,224 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"log"
+
+	"github.com/ledongthuc/pdf"
+)
+
+const (
+	maxFiguresPerDocument = 6
+	minFigureSide         = 150     // Smaller images are usually icons, logos or bullets
+	maxFigurePixels       = 4 << 20 // Larger raw images are skipped rather than decoded in memory
+)
+
+// pdfFigure is an image embedded in a PDF page, encoded as JPEG or PNG
+type pdfFigure struct {
+	Page  int
+	Image []byte
+}
+
+// extractPDFFigures returns up to limit figures from a PDF in page order. JPEG images are taken
+// as-is from the file; Flate-compressed RGB, CMYK and grayscale images are re-encoded as PNG. Other
+// image formats are skipped.
+func extractPDFFigures(data []byte, limit int) (figures []pdfFigure, err error) {
+	// The PDF library panics on some malformed documents
+	defer func() {
+		if r := recover(); r != nil {
+			figures, err = nil, fmt.Errorf("failed to read PDF images: %v", r)
+		}
+	}()
+
+	r, err := pdf.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse PDF: %w", err)
+	}
+
+	jpegs := findJPEGStreams(data)
+	used := make(map[int]bool)
+	seen := make(map[string]bool) // The same image object is often referenced from many pages
+
+	for pageIndex := 1; pageIndex <= r.NumPage() && len(figures) < limit; pageIndex++ {
+		page := r.Page(pageIndex)
+		if page.V.IsNull() {
+			continue
+		}
+
+		xobjects := page.Resources().Key("XObject")
+		for _, name := range xobjects.Keys() {
+			if len(figures) >= limit {
+				break
+			}
+
+			xobject := xobjects.Key(name)
+			if xobject.Key("Subtype").Name() != "Image" {
+				continue
+			}
+
+			width, height := xobject.Key("Width").Int64(), xobject.Key("Height").Int64()
+			if width < minFigureSide || height < minFigureSide {
+				continue
+			}
+
+			id := fmt.Sprintf("%dx%d/%d", width, height, xobject.Key("Length").Int64())
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+
+			var img []byte
+			switch imageFilter(xobject) {
+			case "DCTDecode":
+				img = takeJPEGStream(data, jpegs, used, xobject.Key("Length").Int64())
+			case "FlateDecode", "":
+				img, err = decodeRawImage(xobject, width, height)
+				if err != nil {
+					log.Printf("Skipping figure on page %d: %v", pageIndex, err)
+					continue
+				}
+			}
+
+			if img != nil {
+				figures = append(figures, pdfFigure{Page: pageIndex, Image: img})
+			}
+		}
+	}
+
+	return figures, nil
+}
+
+// imageFilter returns the single compression filter of an image stream, or "unsupported"
+// for filter chains
+func imageFilter(xobject pdf.Value) string {
+	filter := xobject.Key("Filter")
+	switch filter.Kind() {
+	case pdf.Null:
+		return ""
+	case pdf.Name:
+		return filter.Name()
+	case pdf.Array:
+		if filter.Len() == 1 {
+			return filter.Index(0).Name()
+		}
+	}
+	return "unsupported"
+}
+
+// findJPEGStreams returns the offsets of PDF streams whose data starts with a JPEG header.
+// The PDF library cannot read DCT-encoded streams, but their raw bytes are plain JPEG files.
+func findJPEGStreams(data []byte) []int {
+	var offsets []int
+	keyword := []byte("stream")
+	for i := 0; ; {
+		idx := bytes.Index(data[i:], keyword)
+		if idx < 0 {
+			return offsets
+		}
+		start := i + idx + len(keyword)
+		if start < len(data) && data[start] == '\r' {
+			start++
+		}
+		if start < len(data) && data[start] == '\n' {
+			start++
+		}
+		if bytes.HasPrefix(data[start:], []byte{0xFF, 0xD8, 0xFF}) {
+			offsets = append(offsets, start)
+		}
+		i = start
+	}
+}
+
+// takeJPEGStream returns the first unused JPEG stream of exactly length bytes
+func takeJPEGStream(data []byte, offsets []int, used map[int]bool, length int64) []byte {
+	for _, offset := range offsets {
+		end := offset + int(length)
+		if used[offset] || length <= 0 || end > len(data) {
+			continue
+		}
+		if !bytes.HasSuffix(bytes.TrimRight(data[offset:end], "\r\n"), []byte{0xFF, 0xD9}) {
+			continue
+		}
+		used[offset] = true
+		return data[offset:end]
+	}
+	return nil
+}
+
+// decodeRawImage decodes an 8-bit RGB, CMYK or grayscale image stream and encodes it as PNG
+func decodeRawImage(xobject pdf.Value, width, height int64) (img []byte, err error) {
+	if xobject.Key("BitsPerComponent").Int64() != 8 {
+		return nil, fmt.Errorf("unsupported bits per component")
+	}
+	if width*height > maxFigurePixels {
+		return nil, fmt.Errorf("image too large")
+	}
+
+	components := colorComponents(xobject.Key("ColorSpace"))
+	if components != 1 && components != 3 && components != 4 {
+		return nil, fmt.Errorf("unsupported color space")
+	}
+
+	// The PDF library panics on malformed or unsupported streams
+	defer func() {
+		if r := recover(); r != nil {
+			img, err = nil, fmt.Errorf("failed to decode image stream: %v", r)
+		}
+	}()
+
+	pixels := make([]byte, width*height*components)
+	if _, err := io.ReadFull(xobject.Reader(), pixels); err != nil {
+		return nil, fmt.Errorf("failed to read image stream: %w", err)
+	}
+
+	w, h := int(width), int(height)
+	var decoded image.Image
+	switch components {
+	case 1:
+		gray := image.NewGray(image.Rect(0, 0, w, h))
+		copy(gray.Pix, pixels)
+		decoded = gray
+	case 4:
+		cmyk := image.NewCMYK(image.Rect(0, 0, w, h))
+		copy(cmyk.Pix, pixels)
+		decoded = cmyk
+	default:
+		rgba := image.NewRGBA(image.Rect(0, 0, w, h))
+		for i := 0; i < w*h; i++ {
+			rgba.Set(i%w, i/w, color.RGBA{pixels[i*3], pixels[i*3+1], pixels[i*3+2], 0xFF})
+		}
+		decoded = rgba
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, decoded); err != nil {
+		return nil, fmt.Errorf("failed to encode figure: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// colorComponents returns the number of color components of a DeviceRGB, DeviceCMYK,
+// DeviceGray or ICCBased color space, or 0 for anything else
+func colorComponents(colorSpace pdf.Value) int64 {
+	switch colorSpace.Kind() {
+	case pdf.Name:
+		switch colorSpace.Name() {
+		case "DeviceRGB":
+			return 3
+		case "DeviceGray":
+			return 1
+		case "DeviceCMYK":
+			return 4
+		}
+	case pdf.Array:
+		if colorSpace.Index(0).Name() == "ICCBased" {
+			return colorSpace.Index(1).Key("N").Int64()
+		}
+	}
+	return 0
+}
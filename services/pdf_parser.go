@@ -108,6 +108,10 @@ func cleanExtractedText(text string) string {
 	// Replace multiple whitespaces with single space
 	text = strings.ReplaceAll(text, "\r\n", "\n")
 	text = strings.ReplaceAll(text, "\r", "\n")
+
+	// Keep code blocks and LaTeX formulas out of the cleanup so their spacing and line breaks survive
+	text, code := protectCode(text)
+	text, formulas := protectMath(text)
 	
 	// Remove excessive line breaks
 	lines := strings.Split(text, "\n")
@@ -128,7 +132,7 @@ func cleanExtractedText(text string) string {
 		result = strings.ReplaceAll(result, "  ", " ")
 	}
 	
-	return strings.TrimSpace(result)
+	return strings.TrimSpace(restoreCode(restoreMath(result, formulas), code))
 }
 
 // FileParser interface for unified file parsing
@@ -142,6 +146,8 @@ func GetParser(fileType string) FileParser {
 	switch strings.ToLower(fileType) {
 	case "pdf", ".pdf":
 		return NewPDFParser()
+	case "html", ".html":
+		return NewHTMLParser()
 	default:
 		return nil
 	}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/jpeg" // Figures taken as-is from the PDF are JPEGs
+	"image/png"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+)
+
+const (
+	thumbnailWidth         = 400
+	thumbnailRenderTimeout = 30 * time.Second
+)
+
+// renderPDFThumbnail renders the first page of a PDF to a PNG thumbnail. Pages are rasterized with
+// pdftoppm (poppler-utils) when it is installed; otherwise the largest figure of the first page is
+// used, and documents without one get no thumbnail.
+func renderPDFThumbnail(data []byte) ([]byte, error) {
+	if path, err := exec.LookPath("pdftoppm"); err == nil {
+		return renderWithPdftoppm(path, data)
+	}
+	return firstPageFigureThumbnail(data)
+}
+
+// renderWithPdftoppm rasterizes the first page with pdftoppm, scaled to thumbnailWidth
+func renderWithPdftoppm(path string, data []byte) ([]byte, error) {
+	dir, err := os.MkdirTemp("", "thumbnail-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	input := filepath.Join(dir, "source.pdf")
+	if err := os.WriteFile(input, data, 0600); err != nil {
+		return nil, fmt.Errorf("failed to write PDF: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), thumbnailRenderTimeout)
+	defer cancel()
+
+	output := filepath.Join(dir, "thumbnail")
+	cmd := exec.CommandContext(ctx, path, "-png", "-f", "1", "-l", "1", "-singlefile",
+		"-scale-to-x", fmt.Sprint(thumbnailWidth), "-scale-to-y", "-1", input, output)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("pdftoppm failed: %w: %s", err, bytes.TrimSpace(out))
+	}
+
+	return os.ReadFile(output + ".png")
+}
+
+// firstPageFigureThumbnail scales the largest figure on the first page down to thumbnailWidth
+func firstPageFigureThumbnail(data []byte) ([]byte, error) {
+	figures, err := extractPDFFigures(data, maxFiguresPerDocument)
+	if err != nil {
+		return nil, err
+	}
+
+	var best image.Image
+	for _, figure := range figures {
+		if figure.Page != 1 {
+			break
+		}
+		img, _, err := image.Decode(bytes.NewReader(figure.Image))
+		if err != nil {
+			continue
+		}
+		if best == nil || area(img.Bounds()) > area(best.Bounds()) {
+			best = img
+		}
+	}
+	if best == nil {
+		return nil, fmt.Errorf("first page has no figure to use as a thumbnail")
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, scaleToWidth(best, thumbnailWidth)); err != nil {
+		return nil, fmt.Errorf("failed to encode thumbnail: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// area returns the number of pixels in a rectangle
+func area(r image.Rectangle) int {
+	return r.Dx() * r.Dy()
+}
+
+// scaleToWidth downscales an image to the given width, keeping its aspect ratio, by averaging the
+// source pixels under each target pixel. Narrower images are returned unchanged.
+func scaleToWidth(src image.Image, width int) image.Image {
+	bounds := src.Bounds()
+	if bounds.Dx() <= width {
+		return src
+	}
+
+	height := max(1, bounds.Dy()*width/bounds.Dx())
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0 := bounds.Min.Y + y*bounds.Dy()/height
+		y1 := max(y0+1, bounds.Min.Y+(y+1)*bounds.Dy()/height)
+		for x := 0; x < width; x++ {
+			x0 := bounds.Min.X + x*bounds.Dx()/width
+			x1 := max(x0+1, bounds.Min.X+(x+1)*bounds.Dx()/width)
+
+			var r, g, b, a, n uint32
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := src.At(sx, sy).RGBA()
+					r, g, b, a, n = r+pr, g+pg, b+pb, a+pa, n+1
+				}
+			}
+			dst.Set(x, y, color.RGBA64{uint16(r / n), uint16(g / n), uint16(b / n), uint16(a / n)})
+		}
+	}
+	return dst
+}
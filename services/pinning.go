@@ -0,0 +1,114 @@
+package services
+
+import (
+	"auto-annotation-api/models"
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// PinAnnotation pins an annotation to the top of the annotation list, after the annotations
+// already pinned. Pinning a pinned annotation keeps its position.
+func (s *AnnotationService) PinAnnotation(ctx context.Context, annotationID string) (*models.Annotation, error) {
+	annotation, err := s.GetAnnotationByID(ctx, annotationID)
+	if err != nil {
+		return nil, err
+	}
+	if annotation.Status == "merged" {
+		return nil, fmt.Errorf("annotation not found")
+	}
+	if annotation.Pinned {
+		return annotation, nil
+	}
+
+	var last models.Annotation
+	err = s.collection.FindOne(ctx, bson.M{"pinned": true},
+		options.FindOne().SetSort(bson.D{{Key: "position", Value: -1}}).SetProjection(bson.M{"position": 1})).Decode(&last)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return nil, fmt.Errorf("failed to find the last pinned annotation: %w", err)
+	}
+
+	annotation.Pinned = true
+	annotation.Position = last.Position + 1
+	annotation.UpdatedAt = time.Now()
+	_, err = s.collection.UpdateOne(ctx, bson.M{"_id": annotationID}, bson.M{"$set": bson.M{
+		"pinned":     true,
+		"position":   annotation.Position,
+		"updated_at": annotation.UpdatedAt,
+	}})
+	if err != nil {
+		return nil, err
+	}
+
+	s.purgeCDN(annotationID)
+	return annotation, nil
+}
+
+// UnpinAnnotation moves an annotation back among the unpinned annotations
+func (s *AnnotationService) UnpinAnnotation(ctx context.Context, annotationID string) error {
+	result, err := s.collection.UpdateOne(ctx, bson.M{"_id": annotationID}, bson.M{
+		"$set":   bson.M{"updated_at": time.Now()},
+		"$unset": bson.M{"pinned": "", "position": ""},
+	})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("annotation not found")
+	}
+
+	s.purgeCDN(annotationID)
+	return nil
+}
+
+// SetPinnedAnnotations replaces the pinned annotations with annotationIDs, listed in that order.
+// Annotations pinned before but missing from annotationIDs are unpinned.
+func (s *AnnotationService) SetPinnedAnnotations(ctx context.Context, annotationIDs []string) error {
+	seen := make(map[string]bool, len(annotationIDs))
+	for _, id := range annotationIDs {
+		if seen[id] {
+			return fmt.Errorf("invalid pinned annotations: %s is listed twice", id)
+		}
+		seen[id] = true
+	}
+
+	if len(annotationIDs) > 0 {
+		query := annotationQuery(models.AnnotationFilter{})
+		query["_id"] = bson.M{"$in": annotationIDs}
+		found, err := s.collection.CountDocuments(ctx, query)
+		if err != nil {
+			return err
+		}
+		if found != int64(len(annotationIDs)) {
+			return fmt.Errorf("annotation not found")
+		}
+	}
+
+	now := time.Now()
+	_, err := s.collection.UpdateMany(ctx, bson.M{"pinned": true, "_id": bson.M{"$nin": annotationIDs}}, bson.M{
+		"$set":   bson.M{"updated_at": now},
+		"$unset": bson.M{"pinned": "", "position": ""},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to unpin annotations: %w", err)
+	}
+
+	if len(annotationIDs) > 0 {
+		writes := make([]mongo.WriteModel, len(annotationIDs))
+		for i, id := range annotationIDs {
+			writes[i] = mongo.NewUpdateOneModel().
+				SetFilter(bson.M{"_id": id}).
+				SetUpdate(bson.M{"$set": bson.M{"pinned": true, "position": i + 1, "updated_at": now}})
+		}
+		if _, err := s.collection.BulkWrite(ctx, writes); err != nil {
+			return fmt.Errorf("failed to pin annotations: %w", err)
+		}
+	}
+
+	s.purgeCDN(annotationIDs...)
+	return nil
+}
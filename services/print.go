@@ -0,0 +1,216 @@
+package services
+
+import (
+	"auto-annotation-api/models"
+	"auto-annotation-api/utils"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"html/template"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// boldPattern matches **bold** text, which the LLM uses for key terms
+var boldPattern = regexp.MustCompile(`\*\*([^*\n]+)\*\*`)
+
+// printTemplate is a self-contained page: styles are inline, the image is embedded and the QR code
+// is inline SVG, so it prints the same saved to disk or offline
+var printTemplate = template.Must(template.New("print").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+@page { margin: 18mm 16mm; }
+* { box-sizing: border-box; }
+body { margin: 0 auto; max-width: 48rem; padding: 2rem 1.5rem; color: #000; background: #fff;
+  font: 11.5pt/1.5 Georgia, "Times New Roman", serif; }
+header { border-bottom: 1.5pt solid #000; margin-bottom: 1.25rem; padding-bottom: .5rem; }
+h1 { font-size: 22pt; line-height: 1.2; margin: 0 0 .35rem; }
+h2 { font-size: 15pt; margin: 1.4rem 0 .4rem; }
+h3 { font-size: 13pt; margin: 1.2rem 0 .3rem; }
+h4 { font-size: 11.5pt; margin: 1rem 0 .25rem; }
+h1, h2, h3, h4 { font-family: "Helvetica Neue", Arial, sans-serif; break-after: avoid; page-break-after: avoid; }
+.meta { font: 9.5pt/1.4 "Helvetica Neue", Arial, sans-serif; color: #333; margin: 0; }
+.meta span + span::before { content: " · "; }
+figure { margin: 0 0 1.25rem; text-align: center; break-inside: avoid; page-break-inside: avoid; }
+figure img { max-width: 100%; max-height: 8cm; }
+p, li { orphans: 3; widows: 3; }
+pre { font: 9pt/1.4 "Courier New", monospace; border: .75pt solid #999; padding: .5rem .75rem;
+  white-space: pre-wrap; break-inside: avoid; page-break-inside: avoid; }
+.audio { display: flex; align-items: center; gap: 1rem; margin-top: 2rem; padding-top: 1rem;
+  border-top: .75pt solid #999; break-inside: avoid; page-break-inside: avoid; }
+.audio svg { width: 3cm; height: 3cm; flex: none; }
+.audio p { margin: 0; font: 10pt/1.4 "Helvetica Neue", Arial, sans-serif; }
+.audio .url { font-size: 8pt; color: #333; word-break: break-all; }
+footer { margin-top: 1.5rem; font: 8pt "Helvetica Neue", Arial, sans-serif; color: #555; }
+@media print { body { max-width: none; padding: 0; } }
+</style>
+</head>
+<body>
+<article>
+<header>
+<h1>{{.Title}}</h1>
+<p class="meta">{{range .Details}}<span>{{.}}</span>{{end}}</p>
+</header>
+{{if .Image}}<figure><img src="{{.Image}}" alt="{{.ImageAltText}}"></figure>
+{{end}}<section class="notes">
+{{.Notes}}</section>
+{{if .QRCode}}<aside class="audio">
+{{.QRCode}}
+<p><strong>Listen to these notes</strong><br>Scan the code with a phone camera.<br><span class="url">{{.AudioURL}}</span></p>
+</aside>
+{{end}}<footer>{{if .SourceURL}}Source: {{.SourceURL}} · {{end}}{{.License}} · Printed {{.Printed}}</footer>
+</article>
+</body>
+</html>
+`))
+
+// printPage is the data of printTemplate
+type printPage struct {
+	Title        string
+	Details      []string
+	Image        template.URL
+	ImageAltText string
+	Notes        template.HTML
+	QRCode       template.HTML
+	AudioURL     string
+	SourceURL    string // Printed in full, paper copies can't link
+	License      string // Name and URL, so paper copies carry their reuse terms too
+	Printed      string
+}
+
+// WritePrintableHTML writes a print-optimized page of an annotation: title, cover image, genre,
+// the notes and a QR code linking to the audio, for handing out paper copies
+func (s *AnnotationService) WritePrintableHTML(ctx context.Context, annotation *models.Annotation, w io.Writer) error {
+	page := printPage{
+		Title:        annotation.Title,
+		ImageAltText: annotation.ImageAltText,
+		Notes:        template.HTML(renderNotesHTML(annotation.Annotation)),
+		SourceURL:    annotation.SourceURL,
+		Printed:      time.Now().UTC().Format("January 2, 2006"),
+	}
+
+	license := annotation.LicenseOf()
+	page.License = license.Name
+	if license.URL != "" {
+		page.License += " (" + license.URL + ")"
+	}
+
+	if annotation.Genre != "" {
+		page.Details = append(page.Details, annotation.Genre)
+	}
+	if annotation.Author != "" {
+		page.Details = append(page.Details, "By "+annotation.Author)
+	} else if annotation.Book != nil && len(annotation.Book.Authors) > 0 {
+		page.Details = append(page.Details, "By "+strings.Join(annotation.Book.Authors, ", "))
+	}
+	if annotation.PublishedOn != "" {
+		page.Details = append(page.Details, "Published "+annotation.PublishedOn)
+	}
+	if len(annotation.Tags) > 0 {
+		page.Details = append(page.Details, strings.Join(annotation.Tags, ", "))
+	}
+
+	// The image is embedded, a page that links it would print blank once the URL expires
+	if annotation.Image != "" {
+		data, err := s.loadImage(ctx, annotation.Image, annotation.ImageKey)
+		if err != nil {
+			log.Printf("Warning: printing annotation %s without its image: %v", annotation.ID, err)
+		} else if contentType := http.DetectContentType(data); strings.HasPrefix(contentType, "image/") {
+			page.Image = template.URL("data:" + contentType + ";base64," + base64.StdEncoding.EncodeToString(data))
+		}
+	}
+
+	if annotation.TTSURL != "" {
+		qr, err := utils.EncodeQR(annotation.TTSURL)
+		if err != nil {
+			log.Printf("Warning: printing annotation %s without an audio QR code: %v", annotation.ID, err)
+		} else {
+			page.QRCode = template.HTML(qr.SVG(4, 4))
+			page.AudioURL = annotation.TTSURL
+		}
+	}
+
+	return printTemplate.Execute(w, page)
+}
+
+// renderNotesHTML converts the notes, plain text with a little Markdown (headings, bullet lists,
+// **bold** and fenced code), into HTML. Everything else is escaped.
+func renderNotesHTML(text string) string {
+	var out strings.Builder
+	var paragraph, list, code []string
+	inCode := false
+
+	inline := func(s string) string {
+		return boldPattern.ReplaceAllString(html.EscapeString(s), "<strong>$1</strong>")
+	}
+	flush := func() {
+		if len(paragraph) > 0 {
+			out.WriteString("<p>" + inline(strings.Join(paragraph, " ")) + "</p>\n")
+			paragraph = nil
+		}
+		if len(list) > 0 {
+			out.WriteString("<ul>\n")
+			for _, item := range list {
+				out.WriteString("<li>" + inline(item) + "</li>\n")
+			}
+			out.WriteString("</ul>\n")
+			list = nil
+		}
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			if inCode {
+				out.WriteString("<pre><code>" + html.EscapeString(strings.Join(code, "\n")) + "</code></pre>\n")
+				code = nil
+			} else {
+				flush()
+			}
+			inCode = !inCode
+			continue
+		}
+		if inCode {
+			code = append(code, line)
+			continue
+		}
+
+		switch {
+		case trimmed == "":
+			flush()
+		case strings.HasPrefix(trimmed, "#") && strings.HasPrefix(strings.TrimLeft(trimmed, "#"), " "):
+			flush()
+			// The title is the page's h1, so note headings start at h2
+			heading := strings.TrimLeft(trimmed, "#")
+			level := min(len(trimmed)-len(heading)+1, 4)
+			out.WriteString(fmt.Sprintf("<h%d>%s</h%d>\n", level, inline(strings.TrimSpace(heading)), level))
+		case strings.HasPrefix(trimmed, "- ") || strings.HasPrefix(trimmed, "* ") || strings.HasPrefix(trimmed, "• "):
+			if len(paragraph) > 0 {
+				out.WriteString("<p>" + inline(strings.Join(paragraph, " ")) + "</p>\n")
+				paragraph = nil
+			}
+			_, item, _ := strings.Cut(trimmed, " ")
+			list = append(list, strings.TrimSpace(item))
+		default:
+			if len(list) > 0 {
+				flush()
+			}
+			paragraph = append(paragraph, trimmed)
+		}
+	}
+	if inCode {
+		out.WriteString("<pre><code>" + html.EscapeString(strings.Join(code, "\n")) + "</code></pre>\n")
+	}
+	flush()
+
+	return out.String()
+}
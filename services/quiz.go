@@ -0,0 +1,149 @@
+package services
+
+import (
+	"auto-annotation-api/models"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	defaultQuizQuestions = 5
+	quizSourceChars      = 12000 // Of the source text the LLM writes questions from
+	quizExcerpts         = 4     // Longer documents are sampled at this many evenly spaced places
+	quizAttempts         = 2     // The LLM is asked again when its reply has no usable question
+	maxQuizChoices       = 6
+)
+
+// GenerateQuiz asks the LLM for multiple-choice questions on the source text of an annotation and
+// stores them as a quiz. Malformed questions are dropped; a reply without any usable question is
+// retried once.
+func (s *AnnotationService) GenerateQuiz(ctx context.Context, annotation *models.Annotation, user *models.User, count int) (*models.Quiz, error) {
+	if count <= 0 {
+		count = defaultQuizQuestions
+	}
+	if annotation.Status != "completed" {
+		return nil, fmt.Errorf("invalid quiz: annotation is %s", annotation.Status)
+	}
+	if strings.TrimSpace(annotation.TextContent) == "" {
+		return nil, errors.New("invalid quiz: annotation has no source text")
+	}
+
+	log.Printf("Generating a %d question quiz for annotation ID: %s", count, annotation.ID)
+	source := quizSource(annotation.TextContent)
+
+	var questions []models.QuizQuestion
+	var err error
+	for attempt := 1; attempt <= quizAttempts && len(questions) == 0; attempt++ {
+		var generated []models.QuizQuestion
+		generated, err = s.ollamaClient.GenerateQuiz(ctx, source, annotation.Title, count)
+		if err != nil {
+			log.Printf("Warning: quiz generation attempt %d failed: %v", attempt, err)
+			continue
+		}
+		questions = validQuizQuestions(generated)
+	}
+	if len(questions) == 0 {
+		if err == nil {
+			err = errors.New("no valid questions in the reply")
+		}
+		return nil, fmt.Errorf("failed to generate quiz: %w", err)
+	}
+	if len(questions) > count {
+		questions = questions[:count]
+	}
+
+	quiz := models.NewQuiz(annotation, user.ID, questions)
+	if _, err := s.quizzes.InsertOne(ctx, quiz); err != nil {
+		return nil, fmt.Errorf("failed to save quiz: %w", err)
+	}
+	return quiz, nil
+}
+
+// quizSource returns the source text the questions are written from. Long documents are sampled
+// evenly, so the questions cover more than the opening chapters.
+func quizSource(text string) string {
+	if len(text) <= quizSourceChars {
+		return text
+	}
+
+	excerptChars := quizSourceChars / quizExcerpts
+	stride := (len(text) - excerptChars) / (quizExcerpts - 1)
+	excerpts := make([]string, quizExcerpts)
+	for i := range excerpts {
+		start := i * stride
+		excerpts[i] = strings.ToValidUTF8(text[start:start+excerptChars], "")
+	}
+	return strings.Join(excerpts, "\n\n[...]\n\n")
+}
+
+// validQuizQuestions keeps the well-formed questions: with a question, 2 to maxQuizChoices distinct
+// non-empty choices and an answer that is one of them
+func validQuizQuestions(questions []models.QuizQuestion) []models.QuizQuestion {
+	valid := []models.QuizQuestion{}
+	for _, q := range questions {
+		q.Question = strings.TrimSpace(q.Question)
+		q.Explanation = strings.TrimSpace(q.Explanation)
+		if q.Question == "" || len(q.Choices) < 2 || len(q.Choices) > maxQuizChoices || q.Answer < 0 || q.Answer >= len(q.Choices) {
+			continue
+		}
+
+		seen := make(map[string]bool, len(q.Choices))
+		for i, choice := range q.Choices {
+			q.Choices[i] = strings.TrimSpace(choice)
+			seen[strings.ToLower(q.Choices[i])] = true
+		}
+		if seen[""] || len(seen) < len(q.Choices) {
+			continue
+		}
+		valid = append(valid, q)
+	}
+	return valid
+}
+
+// ListQuizzes returns the quizzes of an annotation, newest first
+func (s *AnnotationService) ListQuizzes(ctx context.Context, annotationID string, limit, offset int64) ([]*models.Quiz, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	if limit > 0 {
+		opts.SetLimit(limit)
+	}
+	if offset > 0 {
+		opts.SetSkip(offset)
+	}
+
+	cursor, err := s.quizzes.Find(ctx, bson.M{"annotation_id": annotationID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	quizzes := []*models.Quiz{}
+	if err := cursor.All(ctx, &quizzes); err != nil {
+		return nil, err
+	}
+	return quizzes, nil
+}
+
+// CountQuizzes returns how many quizzes an annotation has
+func (s *AnnotationService) CountQuizzes(ctx context.Context, annotationID string) (int64, error) {
+	return s.quizzes.CountDocuments(ctx, bson.M{"annotation_id": annotationID})
+}
+
+// GetQuiz retrieves a quiz by ID
+func (s *AnnotationService) GetQuiz(ctx context.Context, quizID string) (*models.Quiz, error) {
+	var quiz models.Quiz
+	err := s.quizzes.FindOne(ctx, bson.M{"_id": quizID}).Decode(&quiz)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("quiz not found")
+		}
+		return nil, err
+	}
+	return &quiz, nil
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"auto-annotation-api/models"
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// uploadQuotaHistory is how long daily counters are kept after their day, so the admin reports can
+// show quota breaches
+const uploadQuotaHistory = 35 * 24 * time.Hour
+
+// uploadQuotaDay is the usage counter of one user on one UTC day
+type uploadQuotaDay struct {
+	ID        string    `bson:"_id"` // "<user ID>:<YYYY-MM-DD>"
+	UserID    string    `bson:"user_id"`
+	Day       time.Time `bson:"day"` // Midnight UTC
+	Count     int       `bson:"count"`
+	Rejected  int       `bson:"rejected,omitempty"` // Documents refused for exceeding the quota
+	ExpiresAt time.Time `bson:"expires_at"`
+}
+
+// UploadQuota returns a user's upload allowance for today, or nil when quotas are disabled or
+// don't apply to the user
+func (s *AnnotationService) UploadQuota(ctx context.Context, user *models.User) (*models.UploadQuota, error) {
+	if s.uploadQuota <= 0 || user.IsAdmin() {
+		return nil, nil
+	}
+
+	id, resetAt := uploadQuotaKey(user.ID)
+	var day uploadQuotaDay
+	err := s.quotas.FindOne(ctx, bson.M{"_id": id}).Decode(&day)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+	return s.newUploadQuota(day.Count, resetAt), nil
+}
+
+// ReserveUploads counts n documents against the user's daily quota before they are processed. The
+// reservation is rejected as a whole if it doesn't fit, and is kept when processing fails later;
+// check requests first, so invalid ones aren't counted.
+func (s *AnnotationService) ReserveUploads(ctx context.Context, user *models.User, n int) (*models.UploadQuota, error) {
+	if s.uploadQuota <= 0 || user.IsAdmin() || n <= 0 {
+		return s.UploadQuota(ctx, user)
+	}
+
+	id, resetAt := uploadQuotaKey(user.ID)
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	var day uploadQuotaDay
+	err := s.quotas.FindOneAndUpdate(ctx, bson.M{"_id": id}, bson.M{
+		"$inc":         bson.M{"count": n},
+		"$setOnInsert": bson.M{"user_id": user.ID, "day": resetAt.AddDate(0, 0, -1), "expires_at": resetAt.Add(uploadQuotaHistory)},
+	}, opts).Decode(&day)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update upload quota: %w", err)
+	}
+
+	if day.Count > s.uploadQuota {
+		// Concurrent reservations may both overshoot and be undone, which only errs on the safe side
+		if _, err := s.quotas.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$inc": bson.M{"count": -n, "rejected": n}}); err != nil {
+			return nil, fmt.Errorf("failed to update upload quota: %w", err)
+		}
+		quota := s.newUploadQuota(day.Count-n, resetAt)
+		return quota, fmt.Errorf("upload quota exceeded: %d of %d documents left today", quota.Remaining, quota.Limit)
+	}
+	return s.newUploadQuota(day.Count, resetAt), nil
+}
+
+// ReleaseUploads gives back n documents reserved with ReserveUploads, for uploads rejected as
+// invalid before any work was done. Counters that were reset in the meantime are left alone.
+func (s *AnnotationService) ReleaseUploads(ctx context.Context, user *models.User, n int) {
+	if s.uploadQuota <= 0 || user.IsAdmin() || n <= 0 {
+		return
+	}
+
+	id, _ := uploadQuotaKey(user.ID)
+	_, err := s.quotas.UpdateOne(ctx, bson.M{"_id": id, "count": bson.M{"$gte": n}}, bson.M{"$inc": bson.M{"count": -n}})
+	if err != nil {
+		log.Printf("Warning: failed to release %d uploads of user %s: %v", n, user.ID, err)
+	}
+}
+
+func (s *AnnotationService) newUploadQuota(used int, resetAt time.Time) *models.UploadQuota {
+	return &models.UploadQuota{
+		Limit:     s.uploadQuota,
+		Used:      used,
+		Remaining: max(s.uploadQuota-used, 0),
+		ResetAt:   resetAt,
+	}
+}
+
+// uploadQuotaKey returns the counter ID of a user for today and when today ends (UTC)
+func uploadQuotaKey(userID string) (string, time.Time) {
+	now := time.Now().UTC()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	return userID + ":" + day.Format("2006-01-02"), day.AddDate(0, 0, 1)
+}
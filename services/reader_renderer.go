@@ -0,0 +1,159 @@
+package services
+
+import (
+	"auto-annotation-api/models"
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// numberedHeadingPattern matches headings like "1. Introduction", "2.3 Results" or "Chapter 4"
+var numberedHeadingPattern = regexp.MustCompile(`^((\d+(\.\d+)*\.?)|(chapter|section|part|appendix)\s+\w+)\s+\S`)
+
+// renderReaderPages converts extracted text into cleaned HTML, one entry per source page
+func renderReaderPages(text string) []models.ReaderPage {
+	var pages []models.ReaderPage
+
+	pageNumber := 1
+	var pageLines []string
+	flush := func() {
+		if body := renderPageHTML(pageLines); body != "" {
+			pages = append(pages, models.ReaderPage{Number: pageNumber, HTML: body})
+		}
+		pageLines = nil
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		// Lines keep their indentation, which matters for code blocks
+		line = strings.TrimRight(line, " \t")
+		if match := pageMarkerPattern.FindStringSubmatch(line); match != nil && match[0] == strings.TrimSpace(line) {
+			flush()
+			if n, err := strconv.Atoi(match[1]); err == nil {
+				pageNumber = n
+			}
+			continue
+		}
+		pageLines = append(pageLines, line)
+	}
+	flush()
+
+	return pages
+}
+
+// renderPageHTML groups the lines of a page into headings, paragraphs and code blocks
+func renderPageHTML(lines []string) string {
+	var out strings.Builder
+	var paragraph []string
+
+	codeStarts := make(map[int]int)
+	for _, span := range findCodeSpans(lines) {
+		codeStarts[span.Start] = span.End
+	}
+
+	flushParagraph := func() {
+		if len(paragraph) > 0 {
+			out.WriteString("<p>" + html.EscapeString(joinWrappedLines(paragraph)) + "</p>\n")
+			paragraph = nil
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		if end, ok := codeStarts[i]; ok {
+			flushParagraph()
+			out.WriteString("<pre><code>" + html.EscapeString(codeBlockText(lines[i:end])) + "</code></pre>\n")
+			i = end - 1
+			continue
+		}
+
+		line := strings.TrimSpace(lines[i])
+		if line == "" {
+			flushParagraph()
+			continue
+		}
+
+		if isHeading(line) {
+			flushParagraph()
+			level := 2
+			if numberedHeadingPattern.MatchString(strings.ToLower(line)) && strings.Count(strings.Fields(line)[0], ".") > 1 {
+				level = 3 // Sub-sections like "2.3.1 Details"
+			}
+			out.WriteString(fmt.Sprintf("<h%d>%s</h%d>\n", level, html.EscapeString(line), level))
+			continue
+		}
+
+		paragraph = append(paragraph, line)
+		if endsSentence(line) {
+			flushParagraph()
+		}
+	}
+	flushParagraph()
+
+	return out.String()
+}
+
+// isHeading guesses whether a line is a heading: short, not ending like a sentence, and either
+// numbered, in all caps, or in title case
+func isHeading(line string) bool {
+	if len(line) > 80 || len(strings.Fields(line)) > 12 || endsSentence(line) || strings.HasSuffix(line, ",") {
+		return false
+	}
+
+	if numberedHeadingPattern.MatchString(strings.ToLower(line)) {
+		return true
+	}
+
+	letters, upper := 0, 0
+	for _, r := range line {
+		if unicode.IsLetter(r) {
+			letters++
+			if unicode.IsUpper(r) {
+				upper++
+			}
+		}
+	}
+	if letters >= 3 && upper == letters {
+		return true
+	}
+
+	// Title case: every longer word starts with a capital letter
+	words := strings.Fields(line)
+	if len(words) < 2 {
+		return false
+	}
+	for _, word := range words {
+		r := []rune(word)
+		if len(r) > 3 && !unicode.IsUpper(r[0]) {
+			return false
+		}
+	}
+	return true
+}
+
+// endsSentence reports whether a line ends with sentence punctuation
+func endsSentence(line string) bool {
+	return strings.HasSuffix(line, ".") || strings.HasSuffix(line, "?") || strings.HasSuffix(line, "!") ||
+		strings.HasSuffix(line, ":") || strings.HasSuffix(line, ";")
+}
+
+// joinWrappedLines joins lines that were wrapped by the PDF layout, repairing hyphenated words
+func joinWrappedLines(lines []string) string {
+	var b strings.Builder
+	for i, line := range lines {
+		if i > 0 {
+			prev := lines[i-1]
+			if strings.HasSuffix(prev, "-") && len(prev) > 1 && unicode.IsLetter(rune(prev[len(prev)-2])) {
+				// Drop the hyphen of a word broken across lines
+				s := b.String()
+				b.Reset()
+				b.WriteString(s[:len(s)-1])
+			} else {
+				b.WriteString(" ")
+			}
+		}
+		b.WriteString(line)
+	}
+	return b.String()
+}
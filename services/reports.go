@@ -0,0 +1,258 @@
+package services
+
+import (
+	"auto-annotation-api/config"
+	"auto-annotation-api/models"
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	reportCheckInterval = time.Hour // How often the job checks whether a report is due
+	reportTopN          = 10        // Entries in the top and failure lists
+)
+
+// ReportService summarizes uploads for the admins, on demand and as a daily or weekly email
+type ReportService struct {
+	annotations *mongo.Collection
+	quotas      *mongo.Collection
+	runs        *mongo.Collection // One document per sent report, so each period is mailed once
+	users       *UserService
+	mailer      *Mailer
+	frequency   string // models.ReportsDaily, models.ReportsWeekly or models.ReportsOff
+}
+
+// NewReportService creates a new report service
+func NewReportService(db *mongo.Database, cfg *config.Config, users *UserService, mailer *Mailer) *ReportService {
+	return &ReportService{
+		annotations: db.Collection("annotations"),
+		quotas:      db.Collection("upload_quotas"),
+		runs:        db.Collection("report_runs"),
+		users:       users,
+		mailer:      mailer,
+		frequency:   cfg.ContentReports,
+	}
+}
+
+// Generate builds the report of the uploads between from (inclusive) and to (exclusive).
+// Simulated load-test uploads are left out.
+func (s *ReportService) Generate(ctx context.Context, from, to time.Time) (*models.ContentReport, error) {
+	report := &models.ContentReport{From: from, To: to}
+	created := bson.M{
+		"created_at": bson.M{"$gte": from, "$lt": to},
+		"simulated":  bson.M{"$ne": true},
+	}
+
+	var statuses []struct {
+		Status string `bson:"_id"`
+		Count  int64  `bson:"count"`
+	}
+	if err := s.aggregate(ctx, s.annotations, &statuses,
+		bson.M{"$match": created},
+		bson.M{"$group": bson.M{"_id": "$status", "count": bson.M{"$sum": 1}}},
+	); err != nil {
+		return nil, fmt.Errorf("failed to count new annotations: %w", err)
+	}
+	for _, status := range statuses {
+		report.NewAnnotations += status.Count
+		switch status.Status {
+		case "completed", "merged":
+			report.Completed += status.Count
+		case "failed":
+			report.Failed += status.Count
+		case "processing":
+			report.Processing += status.Count
+		}
+	}
+
+	report.Failures = []models.FailureReason{}
+	if err := s.aggregate(ctx, s.annotations, &report.Failures,
+		bson.M{"$match": bson.M{"created_at": created["created_at"], "simulated": created["simulated"], "status": "failed"}},
+		bson.M{"$group": bson.M{"_id": bson.M{"$ifNull": bson.A{"$error_message", "unknown error"}}, "count": bson.M{"$sum": 1}}},
+		bson.M{"$sort": bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}},
+		bson.M{"$limit": reportTopN},
+	); err != nil {
+		return nil, fmt.Errorf("failed to group upload failures: %w", err)
+	}
+
+	report.TopTags = []models.TagCount{}
+	if err := s.aggregate(ctx, s.annotations, &report.TopTags,
+		bson.M{"$match": created},
+		bson.M{"$unwind": "$tags"},
+		bson.M{"$group": bson.M{"_id": "$tags", "count": bson.M{"$sum": 1}}},
+		bson.M{"$sort": bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}},
+		bson.M{"$limit": reportTopN},
+	); err != nil {
+		return nil, fmt.Errorf("failed to count tags: %w", err)
+	}
+
+	report.TopUploaders = []models.UploaderCount{}
+	if err := s.aggregate(ctx, s.annotations, &report.TopUploaders,
+		bson.M{"$match": created},
+		bson.M{"$group": bson.M{"_id": "$user_id", "annotations": bson.M{"$sum": 1}}},
+		bson.M{"$sort": bson.D{{Key: "annotations", Value: -1}, {Key: "_id", Value: 1}}},
+		bson.M{"$limit": reportTopN},
+		bson.M{"$lookup": bson.M{"from": "users", "localField": "_id", "foreignField": "_id", "as": "user"}},
+		bson.M{"$set": bson.M{
+			"email": bson.M{"$first": "$user.email"},
+			"name":  bson.M{"$first": "$user.name"},
+		}},
+	); err != nil {
+		return nil, fmt.Errorf("failed to rank uploaders: %w", err)
+	}
+
+	// Quota counters are per UTC day, days overlapping the period count
+	report.QuotaBreaches = []models.QuotaBreach{}
+	if err := s.aggregate(ctx, s.quotas, &report.QuotaBreaches,
+		bson.M{"$match": bson.M{"day": bson.M{"$gt": from.AddDate(0, 0, -1), "$lt": to}, "rejected": bson.M{"$gt": 0}}},
+		bson.M{"$sort": bson.D{{Key: "day", Value: 1}, {Key: "rejected", Value: -1}}},
+		bson.M{"$lookup": bson.M{"from": "users", "localField": "user_id", "foreignField": "_id", "as": "user"}},
+		bson.M{"$set": bson.M{"email": bson.M{"$first": "$user.email"}}},
+	); err != nil {
+		return nil, fmt.Errorf("failed to load quota breaches: %w", err)
+	}
+
+	return report, nil
+}
+
+// aggregate runs a pipeline and decodes all results into results
+func (s *ReportService) aggregate(ctx context.Context, collection *mongo.Collection, results interface{}, pipeline ...bson.M) error {
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return err
+	}
+	return cursor.All(ctx, results)
+}
+
+// StartBackgroundJob emails the report of each finished period to the admins until ctx is canceled
+func (s *ReportService) StartBackgroundJob(ctx context.Context) {
+	ticker := time.NewTicker(reportCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.SendDue(ctx); err != nil {
+			log.Printf("Content report job failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SendDue emails the report of the last finished day or week (Monday to Sunday, UTC) to the admins,
+// unless it was sent already, by this or another instance
+func (s *ReportService) SendDue(ctx context.Context) error {
+	from, to, ok := s.lastPeriod(time.Now())
+	if !ok {
+		return nil
+	}
+
+	runID := s.frequency + ":" + from.Format("2006-01-02")
+	_, err := s.runs.InsertOne(ctx, bson.M{"_id": runID, "sent_at": time.Now()})
+	if mongo.IsDuplicateKeyError(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record report run: %w", err)
+	}
+
+	report, err := s.Generate(ctx, from, to)
+	if err == nil {
+		var admins []*models.User
+		admins, err = s.users.ListUsers(ctx, "admin", 0, 0)
+		if err == nil {
+			subject := fmt.Sprintf("Content report %s", periodLabel(from, to))
+			body := formatReport(report)
+			for _, admin := range admins {
+				if err := s.mailer.Send(admin.Email, subject, body); err != nil {
+					log.Printf("Warning: %v", err)
+				}
+			}
+		}
+	}
+	if err != nil {
+		// Let the next run retry the period
+		if _, deleteErr := s.runs.DeleteOne(ctx, bson.M{"_id": runID}); deleteErr != nil {
+			log.Printf("Warning: failed to reset report run %s: %v", runID, deleteErr)
+		}
+		return err
+	}
+
+	log.Printf("Content report %s sent", runID)
+	return nil
+}
+
+// lastPeriod returns the last finished reporting period before now, false when reports are off
+func (s *ReportService) lastPeriod(now time.Time) (time.Time, time.Time, bool) {
+	now = now.UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	switch s.frequency {
+	case models.ReportsDaily:
+		return today.AddDate(0, 0, -1), today, true
+	case models.ReportsWeekly:
+		monday := today.AddDate(0, 0, -(int(today.Weekday())+6)%7)
+		return monday.AddDate(0, 0, -7), monday, true
+	default:
+		return time.Time{}, time.Time{}, false
+	}
+}
+
+// periodLabel formats a period as its first and last day
+func periodLabel(from, to time.Time) string {
+	last := to.AddDate(0, 0, -1)
+	if !last.After(from) {
+		return from.Format("2006-01-02")
+	}
+	return from.Format("2006-01-02") + " to " + last.Format("2006-01-02")
+}
+
+// formatReport renders a report as the plain text of an email
+func formatReport(report *models.ContentReport) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Uploads %s\n\n", periodLabel(report.From, report.To))
+	fmt.Fprintf(&b, "New annotations: %d (%d completed, %d failed, %d still processing)\n",
+		report.NewAnnotations, report.Completed, report.Failed, report.Processing)
+
+	if len(report.Failures) > 0 {
+		b.WriteString("\nFailures:\n")
+		for _, failure := range report.Failures {
+			fmt.Fprintf(&b, "  %4d  %s\n", failure.Count, failure.Reason)
+		}
+	}
+
+	if len(report.TopUploaders) > 0 {
+		b.WriteString("\nTop uploaders:\n")
+		for _, uploader := range report.TopUploaders {
+			who := uploader.Email
+			if who == "" {
+				who = "deleted user " + uploader.UserID
+			}
+			fmt.Fprintf(&b, "  %4d  %s\n", uploader.Annotations, who)
+		}
+	}
+
+	if len(report.TopTags) > 0 {
+		b.WriteString("\nTop tags:\n")
+		for _, tag := range report.TopTags {
+			fmt.Fprintf(&b, "  %4d  %s\n", tag.Count, tag.Tag)
+		}
+	}
+
+	if len(report.QuotaBreaches) > 0 {
+		b.WriteString("\nUpload quota breaches:\n")
+		for _, breach := range report.QuotaBreaches {
+			fmt.Fprintf(&b, "  %s  %s, %d documents refused\n", breach.Day.Format("2006-01-02"), breach.Email, breach.Rejected)
+		}
+	}
+
+	return b.String()
+}
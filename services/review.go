@@ -0,0 +1,169 @@
+package services
+
+import (
+	"auto-annotation-api/models"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ReviewService runs the review of generated annotations: the creator submits a draft, and a
+// reviewer approves it, showing it to students, or rejects it with a comment so it can be edited and
+// resubmitted.
+type ReviewService struct {
+	annotations *AnnotationService
+	users       *UserService
+	mailer      *Mailer
+}
+
+// NewReviewService creates a new review service
+func NewReviewService(annotations *AnnotationService, users *UserService, mailer *Mailer) *ReviewService {
+	return &ReviewService{
+		annotations: annotations,
+		users:       users,
+		mailer:      mailer,
+	}
+}
+
+// initialReviewState is the review state of newly generated annotations
+func (s *AnnotationService) initialReviewState() string {
+	if s.mustReview {
+		return models.ReviewDraft
+	}
+	return ""
+}
+
+// Submit asks the reviewers to review a finished annotation. Drafts, rejected annotations and
+// annotations never reviewed can be submitted.
+func (s *ReviewService) Submit(ctx context.Context, annotationID string, user *models.User) (*models.Annotation, error) {
+	if err := s.annotations.CheckOwnership(ctx, annotationID, user); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	annotation, err := s.transition(ctx, annotationID, []interface{}{nil, "", models.ReviewDraft, models.ReviewRejected}, bson.M{
+		"review_state": models.ReviewPending,
+		"review":       models.ReviewRecord{SubmittedBy: user.ID, SubmittedAt: &now},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	reviewers, err := s.users.ListUsers(ctx, "reviewer", 0, 0)
+	if err != nil {
+		log.Printf("Warning: failed to notify reviewers of annotation %s: %v", annotationID, err)
+	}
+	for _, reviewer := range reviewers {
+		s.notify(reviewer.Email, "Annotation waiting for review", fmt.Sprintf(
+			"%s submitted the annotation %q for review.\n", user.Name, annotation.Title))
+	}
+	return annotation, nil
+}
+
+// Approve shows a submitted annotation to students and lets its creator know
+func (s *ReviewService) Approve(ctx context.Context, annotationID string, reviewer *models.User, comment string) (*models.Annotation, error) {
+	annotation, err := s.decide(ctx, annotationID, reviewer, models.ReviewApproved, comment)
+	if err != nil {
+		return nil, err
+	}
+
+	s.notifyCreator(ctx, annotation, "Your annotation was approved", fmt.Sprintf(
+		"Your annotation %q was approved and is now shown to students.\n\n%s", annotation.Title, reviewNote(comment)))
+	return annotation, nil
+}
+
+// Reject sends a submitted annotation back to its creator with the reviewer's comment
+func (s *ReviewService) Reject(ctx context.Context, annotationID string, reviewer *models.User, comment string) (*models.Annotation, error) {
+	if comment == "" {
+		return nil, errors.New("invalid review: a comment is required to reject an annotation")
+	}
+
+	annotation, err := s.decide(ctx, annotationID, reviewer, models.ReviewRejected, comment)
+	if err != nil {
+		return nil, err
+	}
+
+	s.notifyCreator(ctx, annotation, "Your annotation needs changes", fmt.Sprintf(
+		"Your annotation %q was not approved. Edit it and submit it again.\n\n%s", annotation.Title, reviewNote(comment)))
+	return annotation, nil
+}
+
+// decide records a reviewer's decision on a submitted annotation. Reviewers can't review their own
+// annotations.
+func (s *ReviewService) decide(ctx context.Context, annotationID string, reviewer *models.User, state, comment string) (*models.Annotation, error) {
+	annotation, err := s.annotations.GetAnnotationByID(ctx, annotationID)
+	if err != nil {
+		return nil, err
+	}
+	if annotation.UserID == reviewer.ID {
+		return nil, errors.New("cannot review your own annotation")
+	}
+
+	now := time.Now()
+	return s.transition(ctx, annotationID, []interface{}{models.ReviewPending}, bson.M{
+		"review_state":       state,
+		"review.reviewed_by": reviewer.ID,
+		"review.reviewed_at": now,
+		"review.comment":     comment,
+	})
+}
+
+// transition moves a finished annotation from one of the review states in from to another, setting
+// fields, and returns the updated annotation. A nil state matches annotations without one.
+func (s *ReviewService) transition(ctx context.Context, annotationID string, from []interface{}, set bson.M) (*models.Annotation, error) {
+	set["updated_at"] = time.Now()
+
+	var annotation models.Annotation
+	err := s.annotations.collection.FindOneAndUpdate(ctx, bson.M{
+		"_id":          annotationID,
+		"status":       "completed",
+		"taken_down":   bson.M{"$ne": true},
+		"review_state": bson.M{"$in": from},
+	}, bson.M{"$set": set}, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&annotation)
+	if err == mongo.ErrNoDocuments {
+		current, err := s.annotations.GetAnnotationByID(ctx, annotationID)
+		if err != nil {
+			return nil, err
+		}
+		if current.Status != "completed" {
+			return nil, fmt.Errorf("invalid transition: annotation is %s", current.Status)
+		}
+		state := current.ReviewState
+		if state == "" {
+			state = "not reviewed"
+		}
+		return nil, fmt.Errorf("invalid transition: annotation is %s", state)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.annotations.syncSearch(ctx, annotationID)
+	s.annotations.purgeCDN(annotationID)
+	return &annotation, nil
+}
+
+// notifyCreator emails the owner of an annotation
+func (s *ReviewService) notifyCreator(ctx context.Context, annotation *models.Annotation, subject, body string) {
+	creator, err := s.users.GetUser(ctx, annotation.UserID)
+	if err != nil {
+		log.Printf("Warning: failed to notify the creator of annotation %s: %v", annotation.ID, err)
+		return
+	}
+	s.notify(creator.Email, subject, body)
+}
+
+// notify sends an email in the background, so requests don't wait for the mail server
+func (s *ReviewService) notify(to, subject, body string) {
+	RunInBackground(func(context.Context) {
+		if err := s.mailer.Send(to, subject, body); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+	})
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"auto-annotation-api/models"
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// safetyPublicRole stands for visitors without an account in SAFETY_RESTRICTIONS
+const safetyPublicRole = "public"
+
+// safetyPolicy holds the safety labels hidden from each role
+type safetyPolicy map[string][]string
+
+// parseSafetyPolicy reads the configured restrictions, e.g.
+// "public=adult_themes,violence;basic=adult_themes". Unknown labels are ignored.
+func parseSafetyPolicy(spec string) safetyPolicy {
+	policy := safetyPolicy{}
+	for _, entry := range strings.Split(spec, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		role, labels, _ := strings.Cut(entry, "=")
+		role = strings.TrimSpace(role)
+		for _, label := range strings.Split(labels, ",") {
+			label = strings.TrimSpace(label)
+			if !slices.Contains(models.SafetyLabelNames, label) {
+				log.Printf("Warning: ignoring unknown safety label %q in SAFETY_RESTRICTIONS", label)
+				continue
+			}
+			if !slices.Contains(policy[role], label) {
+				policy[role] = append(policy[role], label)
+			}
+		}
+	}
+	return policy
+}
+
+// labelSafety labels the sensitive topics of generated notes. Labeling is best effort: failures
+// leave the annotation unlabeled.
+func (s *AnnotationService) labelSafety(ctx context.Context, notes, title string) []models.SafetyLabel {
+	log.Printf("Labeling sensitive topics for: %s", title)
+	scores, err := s.ollamaClient.ClassifySafety(ctx, notes, title)
+	if err != nil {
+		log.Printf("Warning: failed to label sensitive topics: %v", err)
+		return nil
+	}
+
+	var labels []models.SafetyLabel
+	for _, name := range models.SafetyLabelNames {
+		if score, ok := scores[name]; ok && score >= s.safetyMin {
+			labels = append(labels, models.SafetyLabel{Label: name, Confidence: score})
+		}
+	}
+	sort.SliceStable(labels, func(i, j int) bool { return labels[i].Confidence > labels[j].Confidence })
+	return labels
+}
+
+// HiddenSafetyLabels returns the safety labels of annotations user may not see: those restricted
+// for the user's role and for the user personally. A nil user is a visitor without an account.
+// Admins see everything.
+func (s *AnnotationService) HiddenSafetyLabels(user *models.User) []string {
+	if user == nil {
+		return s.safety[safetyPublicRole]
+	}
+	if user.Role == "admin" {
+		return nil
+	}
+
+	hidden := slices.Clone(s.safety[user.Role])
+	for _, label := range user.Restricted {
+		if !slices.Contains(hidden, label) {
+			hidden = append(hidden, label)
+		}
+	}
+	return hidden
+}
+
+// CheckSafetyRestrictions returns an error when annotation has a safety label hidden from user
+func (s *AnnotationService) CheckSafetyRestrictions(annotation *models.Annotation, user *models.User) error {
+	hidden := s.HiddenSafetyLabels(user)
+	for _, label := range annotation.SafetyLabels {
+		if slices.Contains(hidden, label.Label) {
+			return fmt.Errorf("content restricted: annotation is labeled %s", label.Label)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package services
+
+import (
+	"auto-annotation-api/models"
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SearchAnnotations searches completed annotations, using the external search backend when one is
+// configured. If the backend fails, MongoDB is searched instead without highlights or facets.
+func (s *AnnotationService) SearchAnnotations(ctx context.Context, query models.SearchQuery) (*models.SearchResults, error) {
+	var results *models.SearchResults
+	var err error
+	if s.search != nil {
+		results, err = s.search.Search(query)
+		if err != nil {
+			log.Printf("Warning: %s search failed, falling back to MongoDB: %v", s.search.Name(), err)
+		}
+	}
+	if results == nil {
+		results, err = s.searchMongo(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	filter, err := s.settings.ContentFilter(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if filter != nil {
+		for i := range results.Hits {
+			hit := &results.Hits[i]
+			hit.Title = filter.Apply(hit.Title)
+			for field, text := range hit.Highlights {
+				hit.Highlights[field] = filter.Apply(text)
+			}
+		}
+	}
+	return results, nil
+}
+
+// searchMongo matches the query as a substring of the title, annotation or tags
+func (s *AnnotationService) searchMongo(ctx context.Context, query models.SearchQuery) (*models.SearchResults, error) {
+	filter := bson.M{"status": "completed", "taken_down": bson.M{"$ne": true}}
+	if q := strings.TrimSpace(query.Query); q != "" {
+		filter["$or"] = []bson.M{
+			{"title": containsPattern(q)},
+			{"annotation": containsPattern(q)},
+			{"tags": strings.ToLower(q)},
+		}
+	}
+	if query.Tag != "" {
+		filter["tags"] = strings.ToLower(strings.TrimSpace(query.Tag))
+	}
+	if query.Genre != "" {
+		filter["genre"] = query.Genre
+	}
+	if len(query.Hidden) > 0 {
+		filter["safety_labels.label"] = bson.M{"$nin": query.Hidden}
+	}
+	if query.ReviewedOnly {
+		filter["review_state"] = bson.M{"$nin": models.UnreviewedStates}
+	}
+
+	total, err := s.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search annotations: %w", err)
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}}).
+		SetLimit(int64(query.Limit)).
+		SetSkip(int64(query.Offset)).
+		SetProjection(bson.M{"title": 1, "genre": 1, "tags": 1, "image": 1})
+	cursor, err := s.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search annotations: %w", err)
+	}
+	var annotations []models.Annotation
+	if err := cursor.All(ctx, &annotations); err != nil {
+		return nil, fmt.Errorf("failed to search annotations: %w", err)
+	}
+
+	results := &models.SearchResults{
+		Query:   query.Query,
+		Hits:    make([]models.SearchHit, 0, len(annotations)),
+		Total:   int(total),
+		Backend: "mongodb",
+	}
+	for _, annotation := range annotations {
+		tags := annotation.Tags
+		if tags == nil {
+			tags = []string{}
+		}
+		results.Hits = append(results.Hits, models.SearchHit{
+			ID:    annotation.ID,
+			Title: annotation.Title,
+			Genre: annotation.Genre,
+			Tags:  tags,
+			Image: annotation.Image,
+		})
+	}
+	return results, nil
+}
+
+// SetupSearchIndex prepares the external search backend, if one is configured
+func (s *AnnotationService) SetupSearchIndex() error {
+	if s.search == nil {
+		return nil
+	}
+	return s.search.Setup()
+}
+
+// syncSearch updates the search index after annotations were written: completed annotations are
+// (re)indexed, others, including deleted and merged ones, are removed. Failures are only logged so
+// that writes don't fail when the search backend is down.
+func (s *AnnotationService) syncSearch(ctx context.Context, annotationIDs ...string) {
+	if s.search == nil {
+		return
+	}
+	s.markReindexDirty(annotationIDs)
+
+	var docs []SearchDocument
+	var removed []string
+	for _, id := range annotationIDs {
+		annotation, err := s.GetAnnotationByID(ctx, id)
+		switch {
+		case err == nil && annotation.Status == "completed":
+			docs = append(docs, newSearchDocument(annotation))
+		case err == nil || err.Error() == "annotation not found":
+			removed = append(removed, id)
+		default:
+			log.Printf("Warning: failed to load annotation %s for search indexing: %v", id, err)
+		}
+	}
+
+	if err := s.search.Index(docs...); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+	if err := s.search.Delete(removed...); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"auto-annotation-api/config"
+	"auto-annotation-api/models"
+	"fmt"
+	"strings"
+)
+
+// SearchIndex is an external full-text search backend for annotations, which adds typo tolerance,
+// highlighting and facets to search. It is optional; without it search falls back to MongoDB.
+type SearchIndex interface {
+	// Name identifies the backend, e.g. "meilisearch"
+	Name() string
+	// Setup creates the index and configures its searchable and filterable fields
+	Setup() error
+	// Index adds or replaces documents
+	Index(docs ...SearchDocument) error
+	// Rebuild replaces the index with the documents passed to add by fill, without downtime: searches
+	// use the old index until the rebuild is complete
+	Rebuild(fill func(add func(docs ...SearchDocument) error) error) error
+	// Delete removes documents by annotation ID
+	Delete(ids ...string) error
+	// Search runs a query
+	Search(query models.SearchQuery) (*models.SearchResults, error)
+	// TestConnection checks that the backend is reachable
+	TestConnection() error
+}
+
+// SearchDocument is the searchable representation of an annotation
+type SearchDocument struct {
+	ID           string            `json:"id"`
+	Title        string            `json:"title"`
+	Annotation   string            `json:"annotation"`
+	Genre        string            `json:"genre"`
+	Tags         []string          `json:"tags"`
+	Image        string            `json:"image,omitempty"`
+	BookTitle    string            `json:"book_title,omitempty"`
+	BookAuthors  []string          `json:"book_authors,omitempty"`
+	Metadata     map[string]string `json:"metadata,omitempty"`
+	SafetyLabels []string          `json:"safety_labels"`
+	Reviewed     bool              `json:"reviewed"`   // May be shown to students
+	CreatedAt    int64             `json:"created_at"` // Unix seconds, for sorting
+}
+
+// NewSearchIndex creates the search backend selected by SEARCH_BACKEND, or nil when none is selected
+func NewSearchIndex(cfg *config.Config) (SearchIndex, error) {
+	switch strings.ToLower(cfg.SearchBackend) {
+	case "":
+		return nil, nil
+	case "meilisearch":
+		if cfg.MeilisearchURL == "" {
+			return nil, fmt.Errorf("search backend meilisearch not configured: MEILISEARCH_URL missing")
+		}
+		return NewMeilisearchIndex(cfg.MeilisearchURL, cfg.MeilisearchKey, cfg.SearchIndexName), nil
+	default:
+		return nil, fmt.Errorf("unknown search backend: %s", cfg.SearchBackend)
+	}
+}
+
+// newSearchDocument converts an annotation for indexing. runReindex loads only the fields read
+// here, fields added here must be added to its projection too.
+func newSearchDocument(annotation *models.Annotation) SearchDocument {
+	doc := SearchDocument{
+		ID:         annotation.ID,
+		Title:      annotation.Title,
+		Annotation: annotation.Annotation,
+		Genre:      annotation.Genre,
+		Tags:       annotation.Tags,
+		Image:      annotation.Image,
+		Metadata:   annotation.Metadata,
+		Reviewed:   annotation.IsReviewed(),
+		CreatedAt:  annotation.CreatedAt.Unix(),
+	}
+	if doc.Tags == nil {
+		doc.Tags = []string{}
+	}
+	doc.SafetyLabels = make([]string, len(annotation.SafetyLabels))
+	for i, label := range annotation.SafetyLabels {
+		doc.SafetyLabels[i] = label.Label
+	}
+	if annotation.Book != nil {
+		doc.BookTitle = annotation.Book.Title
+		doc.BookAuthors = annotation.Book.Authors
+	}
+	return doc
+}
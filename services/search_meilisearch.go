@@ -0,0 +1,251 @@
+package services
+
+import (
+	"auto-annotation-api/models"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// meilisearchCropLength is the length, in words, of the annotation excerpt around a match
+const meilisearchCropLength = 30
+
+// MeilisearchIndex keeps annotations in a Meilisearch index
+type MeilisearchIndex struct {
+	client  *http.Client
+	baseURL string
+	apiKey  string
+	index   string
+}
+
+// NewMeilisearchIndex creates a Meilisearch backend for the index named index
+func NewMeilisearchIndex(baseURL, apiKey, index string) *MeilisearchIndex {
+	if index == "" {
+		index = "annotations"
+	}
+	return &MeilisearchIndex{
+		client:  &http.Client{Timeout: 30 * time.Second},
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		apiKey:  apiKey,
+		index:   index,
+	}
+}
+
+func (m *MeilisearchIndex) Name() string {
+	return "meilisearch"
+}
+
+// Setup creates the index if needed and configures it. Meilisearch applies settings asynchronously.
+func (m *MeilisearchIndex) Setup() error {
+	return m.configure(m.index)
+}
+
+// Rebuild fills a staging index and then swaps it with the live one in a single task, so searches
+// keep using the complete old index until the new one is ready. Meilisearch runs tasks in order, so
+// the swap only happens after every document was added.
+func (m *MeilisearchIndex) Rebuild(fill func(add func(docs ...SearchDocument) error) error) error {
+	staging := m.index + "_rebuild"
+
+	// Leftovers of an aborted rebuild would end up in the new index
+	if err := m.deleteIndex(staging); err != nil {
+		return err
+	}
+	for _, index := range []string{m.index, staging} {
+		if err := m.configure(index); err != nil {
+			return err
+		}
+	}
+
+	err := fill(func(docs ...SearchDocument) error {
+		return m.addDocuments(staging, docs)
+	})
+	if err != nil {
+		m.deleteIndex(staging)
+		return err
+	}
+
+	swap := []map[string][]string{{"indexes": {m.index, staging}}}
+	if _, err := m.do(http.MethodPost, "/swap-indexes", swap); err != nil {
+		m.deleteIndex(staging)
+		return fmt.Errorf("failed to swap search index: %w", err)
+	}
+
+	// After the swap the staging index holds the old documents
+	return m.deleteIndex(staging)
+}
+
+// configure creates an index if needed and sets its searchable, filterable and sortable fields
+func (m *MeilisearchIndex) configure(index string) error {
+	settings := map[string]interface{}{
+		"searchableAttributes": []string{"title", "book_title", "book_authors", "tags", "annotation"},
+		"filterableAttributes": []string{"tags", "genre", "metadata", "safety_labels", "reviewed"},
+		"sortableAttributes":   []string{"created_at"},
+	}
+	if _, err := m.do(http.MethodPatch, "/indexes/"+url.PathEscape(index)+"/settings", settings); err != nil {
+		return fmt.Errorf("failed to configure search index: %w", err)
+	}
+	return nil
+}
+
+func (m *MeilisearchIndex) Index(docs ...SearchDocument) error {
+	return m.addDocuments(m.index, docs)
+}
+
+// addDocuments adds or replaces documents in an index
+func (m *MeilisearchIndex) addDocuments(index string, docs []SearchDocument) error {
+	if len(docs) == 0 {
+		return nil
+	}
+	if _, err := m.do(http.MethodPost, "/indexes/"+url.PathEscape(index)+"/documents?primaryKey=id", docs); err != nil {
+		return fmt.Errorf("failed to index documents: %w", err)
+	}
+	return nil
+}
+
+// deleteIndex removes an index; deleting one that doesn't exist is not an error
+func (m *MeilisearchIndex) deleteIndex(index string) error {
+	if _, err := m.do(http.MethodDelete, "/indexes/"+url.PathEscape(index), nil); err != nil {
+		return fmt.Errorf("failed to delete search index %s: %w", index, err)
+	}
+	return nil
+}
+
+func (m *MeilisearchIndex) Delete(ids ...string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	if _, err := m.do(http.MethodPost, "/indexes/"+url.PathEscape(m.index)+"/documents/delete-batch", ids); err != nil {
+		return fmt.Errorf("failed to delete documents: %w", err)
+	}
+	return nil
+}
+
+func (m *MeilisearchIndex) Search(query models.SearchQuery) (*models.SearchResults, error) {
+	var filters []string
+	if query.Tag != "" {
+		filters = append(filters, "tags = "+strconv.Quote(strings.ToLower(strings.TrimSpace(query.Tag))))
+	}
+	if query.Genre != "" {
+		filters = append(filters, "genre = "+strconv.Quote(query.Genre))
+	}
+	if len(query.Hidden) > 0 {
+		hidden := make([]string, len(query.Hidden))
+		for i, label := range query.Hidden {
+			hidden[i] = strconv.Quote(label)
+		}
+		filters = append(filters, "safety_labels NOT IN ["+strings.Join(hidden, ", ")+"]")
+	}
+	if query.ReviewedOnly {
+		filters = append(filters, "reviewed != false") // Also matches documents indexed before reviews
+	}
+
+	request := map[string]interface{}{
+		"q":                     query.Query,
+		"limit":                 query.Limit,
+		"offset":                query.Offset,
+		"facets":                []string{"tags", "genre"},
+		"attributesToHighlight": []string{"title", "annotation"},
+		"attributesToCrop":      []string{"annotation"},
+		"cropLength":            meilisearchCropLength,
+		"highlightPreTag":       "<mark>",
+		"highlightPostTag":      "</mark>",
+	}
+	if len(filters) > 0 {
+		request["filter"] = strings.Join(filters, " AND ")
+	}
+
+	body, err := m.do(http.MethodPost, "/indexes/"+url.PathEscape(m.index)+"/search", request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search: %w", err)
+	}
+
+	var response struct {
+		Hits []struct {
+			SearchDocument
+			Formatted struct {
+				Title      string `json:"title"`
+				Annotation string `json:"annotation"`
+			} `json:"_formatted"`
+		} `json:"hits"`
+		EstimatedTotalHits int                       `json:"estimatedTotalHits"`
+		FacetDistribution  map[string]map[string]int `json:"facetDistribution"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to decode search results: %w", err)
+	}
+
+	results := &models.SearchResults{
+		Query:   query.Query,
+		Hits:    make([]models.SearchHit, 0, len(response.Hits)),
+		Total:   response.EstimatedTotalHits,
+		Facets:  response.FacetDistribution,
+		Backend: m.Name(),
+	}
+	for _, hit := range response.Hits {
+		highlights := map[string]string{}
+		if strings.Contains(hit.Formatted.Title, "<mark>") {
+			highlights["title"] = hit.Formatted.Title
+		}
+		if strings.Contains(hit.Formatted.Annotation, "<mark>") {
+			highlights["annotation"] = hit.Formatted.Annotation
+		}
+		results.Hits = append(results.Hits, models.SearchHit{
+			ID:         hit.ID,
+			Title:      hit.Title,
+			Genre:      hit.Genre,
+			Tags:       hit.Tags,
+			Image:      hit.Image,
+			Highlights: highlights,
+		})
+	}
+	return results, nil
+}
+
+func (m *MeilisearchIndex) TestConnection() error {
+	_, err := m.do(http.MethodGet, "/health", nil)
+	return err
+}
+
+// do sends an authenticated JSON request and returns the response body
+func (m *MeilisearchIndex) do(method, path string, payload interface{}) ([]byte, error) {
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal request: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, m.baseURL+path, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if m.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+m.apiKey)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach Meilisearch: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("Meilisearch returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,160 @@
+package services
+
+import (
+	"auto-annotation-api/models"
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// reindexBatchSize is how many documents are sent to the search backend per request
+const reindexBatchSize = 500
+
+// searchReindex tracks the running search index rebuild, if any
+type searchReindex struct {
+	mu     sync.Mutex
+	status *models.ReindexStatus
+	dirty  map[string]bool // Annotations written during the rebuild, re-synced once it is done
+}
+
+// StartReindex rebuilds the external search index from MongoDB in the background, e.g. after the
+// index settings changed or the index was lost
+func (s *AnnotationService) StartReindex(ctx context.Context) (*models.ReindexStatus, error) {
+	if s.search == nil {
+		return nil, fmt.Errorf("search backend not configured")
+	}
+
+	s.reindex.mu.Lock()
+	defer s.reindex.mu.Unlock()
+	if s.reindex.status != nil && s.reindex.status.State == "running" {
+		return nil, fmt.Errorf("reindex already running")
+	}
+
+	total, err := s.collection.CountDocuments(ctx, bson.M{"status": "completed"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to count annotations: %w", err)
+	}
+
+	previous := s.reindex.status
+	now := time.Now()
+	s.reindex.status = &models.ReindexStatus{State: "running", Total: int(total), StartedAt: &now}
+	s.reindex.dirty = make(map[string]bool)
+	status := *s.reindex.status
+
+	if !RunInBackground(s.runReindex) {
+		s.reindex.status, s.reindex.dirty = previous, nil
+		return nil, errShuttingDown
+	}
+
+	return &status, nil
+}
+
+// ReindexStatus returns the progress of the current or last rebuild
+func (s *AnnotationService) ReindexStatus() *models.ReindexStatus {
+	s.reindex.mu.Lock()
+	defer s.reindex.mu.Unlock()
+
+	if s.reindex.status == nil {
+		return &models.ReindexStatus{State: "idle"}
+	}
+	status := *s.reindex.status
+	return &status
+}
+
+// runReindex streams every completed annotation into a rebuilt index. A shutdown cancels ctx, which
+// fails the rebuild; the previous index stays in use.
+func (s *AnnotationService) runReindex(ctx context.Context) {
+	log.Printf("Rebuilding %s search index", s.search.Name())
+
+	err := s.search.Rebuild(func(add func(docs ...SearchDocument) error) error {
+		// The projection must cover every field newSearchDocument reads, a missing one is indexed
+		// as its zero value
+		cursor, err := s.collection.Find(ctx, bson.M{"status": "completed", "taken_down": bson.M{"$ne": true}}, options.Find().SetProjection(bson.M{
+			"title":         1,
+			"annotation":    1,
+			"genre":         1,
+			"tags":          1,
+			"image":         1,
+			"book":          1,
+			"metadata":      1,
+			"review_state":  1,
+			"safety_labels": 1,
+			"created_at":    1,
+		}))
+		if err != nil {
+			return fmt.Errorf("failed to load annotations: %w", err)
+		}
+		defer cursor.Close(ctx)
+
+		batch := make([]SearchDocument, 0, reindexBatchSize)
+		flush := func() error {
+			if err := add(batch...); err != nil {
+				return err
+			}
+			s.reindex.mu.Lock()
+			s.reindex.status.Indexed += len(batch)
+			s.reindex.mu.Unlock()
+			batch = batch[:0]
+			return nil
+		}
+
+		for cursor.Next(ctx) {
+			var annotation models.Annotation
+			if err := cursor.Decode(&annotation); err != nil {
+				return fmt.Errorf("failed to decode annotation: %w", err)
+			}
+			batch = append(batch, newSearchDocument(&annotation))
+			if len(batch) == reindexBatchSize {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+		}
+		if err := cursor.Err(); err != nil {
+			return fmt.Errorf("failed to load annotations: %w", err)
+		}
+		return flush()
+	})
+
+	s.reindex.mu.Lock()
+	now := time.Now()
+	s.reindex.status.FinishedAt = &now
+	s.reindex.status.State = "completed"
+	if err != nil {
+		s.reindex.status.State = "failed"
+		s.reindex.status.Error = err.Error()
+	}
+	dirty := make([]string, 0, len(s.reindex.dirty))
+	for id := range s.reindex.dirty {
+		dirty = append(dirty, id)
+	}
+	s.reindex.dirty = nil
+	s.reindex.mu.Unlock()
+
+	if err != nil {
+		log.Printf("Search index rebuild failed: %v", err)
+		return
+	}
+	log.Printf("Search index rebuilt with %d annotations", s.ReindexStatus().Indexed)
+
+	// Writes during the rebuild may have missed the new index, depending on timing
+	s.syncSearch(ctx, dirty...)
+}
+
+// markReindexDirty records annotations written while a rebuild is running
+func (s *AnnotationService) markReindexDirty(annotationIDs []string) {
+	s.reindex.mu.Lock()
+	defer s.reindex.mu.Unlock()
+
+	if s.reindex.dirty == nil {
+		return
+	}
+	for _, id := range annotationIDs {
+		s.reindex.dirty[id] = true
+	}
+}
@@ -0,0 +1,185 @@
+package services
+
+import (
+	"auto-annotation-api/models"
+	"auto-annotation-api/utils"
+	"context"
+	"fmt"
+	"log"
+	"strings"
+)
+
+const (
+	maxDocumentSections  = 40   // Adjacent sections are combined beyond this
+	minSectionText       = 1000 // Characters; a heading ending a shorter section is read as body text
+	fallbackSectionPages = 20   // Page range per section of documents without headings
+)
+
+// documentSection is a section of a document's text with the pages it spans
+type documentSection struct {
+	Title string
+	Text  string
+	Start int
+	End   int
+}
+
+// detectSections splits document text into sections at chapter and section headings, falling back to
+// fixed page ranges when the document has no usable outline. It returns nil when the document is a
+// single section, whose summary would only repeat the annotation.
+func detectSections(text string) []documentSection {
+	pages := splitPages(text)
+
+	// Running headers repeat on every page, they are not part of the outline
+	headingCounts := make(map[string]int)
+	for _, page := range pages {
+		for _, line := range strings.Split(page.Text, "\n") {
+			if line = strings.TrimSpace(line); isSectionHeading(line) {
+				headingCounts[line]++
+			}
+		}
+	}
+
+	var sections []documentSection
+	current := documentSection{Start: pages[0].Page}
+	var body strings.Builder
+	for _, page := range pages {
+		for _, line := range strings.Split(page.Text, "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" {
+				continue
+			}
+			if isSectionHeading(line) && headingCounts[line] <= 2 {
+				switch {
+				case strings.TrimSpace(body.String()) == "":
+					// Consecutive headings, such as a chapter and its first section, open one section
+					current.Title = strings.TrimSpace(current.Title + " " + line)
+					current.Start = page.Page
+					continue
+				case body.Len() >= minSectionText:
+					current.Text = strings.TrimSpace(body.String())
+					sections = append(sections, current)
+					body.Reset()
+					current = documentSection{Title: line, Start: page.Page}
+					continue
+				}
+			}
+			body.WriteString(line)
+			body.WriteString("\n")
+			current.End = page.Page
+		}
+	}
+	if current.Text = strings.TrimSpace(body.String()); current.Text != "" {
+		sections = append(sections, current)
+	}
+
+	if len(sections) < 2 {
+		sections = sectionsByPages(pages, fallbackSectionPages)
+	}
+	if len(sections) < 2 {
+		return nil
+	}
+	if sections[0].Title == "" {
+		sections[0].Title = "Introduction"
+	}
+	return mergeDocumentSections(sections, maxDocumentSections)
+}
+
+// isSectionHeading reports whether line opens a chapter or numbered section. Title-case lines, which
+// isHeading also accepts, are too frequent in long documents to mark sections.
+func isSectionHeading(line string) bool {
+	return isHeading(line) && numberedHeadingPattern.MatchString(strings.ToLower(line))
+}
+
+// sectionsByPages divides extracted pages into sections of size pages, titled by their page range
+func sectionsByPages(pages []pageText, size int) []documentSection {
+	var sections []documentSection
+	for start := 0; start < len(pages); start += size {
+		group := pages[start:min(start+size, len(pages))]
+		texts := make([]string, 0, len(group))
+		for _, page := range group {
+			if text := strings.TrimSpace(page.Text); text != "" {
+				texts = append(texts, text)
+			}
+		}
+		if len(texts) == 0 {
+			continue
+		}
+		first, last := group[0].Page, group[len(group)-1].Page
+		sections = append(sections, documentSection{
+			Title: fmt.Sprintf("Pages %d-%d", first, last),
+			Text:  strings.Join(texts, "\n"),
+			Start: first,
+			End:   last,
+		})
+	}
+	return sections
+}
+
+// mergeDocumentSections combines adjacent sections until at most limit remain
+func mergeDocumentSections(sections []documentSection, limit int) []documentSection {
+	if len(sections) <= limit {
+		return sections
+	}
+
+	merged := make([]documentSection, 0, limit)
+	for i := 0; i < limit; i++ {
+		from := i * len(sections) / limit
+		to := (i + 1) * len(sections) / limit
+
+		group := sections[from]
+		for _, section := range sections[from+1 : to] {
+			group.Text += "\n" + section.Text
+			group.End = section.End
+		}
+		merged = append(merged, group)
+	}
+	return merged
+}
+
+// summarizeSections summarizes each detected section of a long document. Sections that fail are
+// left out, and the annotation keeps its overall notes either way.
+func (s *AnnotationService) summarizeSections(ctx context.Context, text, title, genre string) []models.Section {
+	detected := detectSections(text)
+	if len(detected) == 0 {
+		log.Printf("No sections detected for: %s", title)
+		return nil
+	}
+
+	sections := make([]models.Section, 0, len(detected))
+	for i, section := range detected {
+		log.Printf("Summarizing section %d/%d (pages %d-%d) for: %s", i+1, len(detected), section.Start, section.End, title)
+		_, span := utils.StartSpan(ctx, "ollama.summarize_section")
+		span.SetAttribute("section", i+1)
+		summary, err := s.summarizeSection(ctx, section, title, genre)
+		span.RecordError(err)
+		span.End()
+		if err != nil {
+			log.Printf("Warning: failed to summarize section %q: %v", section.Title, err)
+			continue
+		}
+		sections = append(sections, models.Section{
+			Title:   section.Title,
+			Summary: summary,
+			Pages:   models.PageRange{Start: section.Start, End: section.End},
+		})
+	}
+	return sections
+}
+
+// summarizeSection summarizes one section, condensing sections longer than a chunk into chunk notes first
+func (s *AnnotationService) summarizeSection(ctx context.Context, section documentSection, title, genre string) (string, error) {
+	text := section.Text
+	if s.chunkTokens > 0 && estimateTokens(text) > s.chunkTokens {
+		chunks := splitTextIntoChunks(text, s.chunkTokens)
+		notes := make([]string, len(chunks))
+		for i, chunk := range chunks {
+			note, err := s.ollamaClient.SummarizeChunk(ctx, chunk, title, genre, i+1, len(chunks))
+			if err != nil {
+				return "", fmt.Errorf("failed to summarize chunk %d/%d: %w", i+1, len(chunks), err)
+			}
+			notes[i] = note
+		}
+		text = strings.Join(notes, "\n\n")
+	}
+	return s.ollamaClient.SummarizeSection(ctx, section.Title, text, title)
+}
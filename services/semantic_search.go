@@ -0,0 +1,79 @@
+package services
+
+import (
+	"auto-annotation-api/models"
+	"context"
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// SemanticSearch finds the completed annotations whose notes are closest in meaning to the query,
+// ranked by embedding similarity. Only annotations embedded with the configured model are found.
+func (s *AnnotationService) SemanticSearch(ctx context.Context, query models.SearchQuery) (*models.SearchResults, error) {
+	if s.embedder == nil || s.vectors == nil {
+		return nil, fmt.Errorf("semantic search not configured")
+	}
+	q := strings.TrimSpace(query.Query)
+	if q == "" {
+		return nil, fmt.Errorf("invalid query: q is required")
+	}
+
+	vector, err := s.embedder.Embed(q)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+
+	filter := bson.M{
+		"status":          "completed",
+		"taken_down":      bson.M{"$ne": true},
+		"embedding_model": s.embedder.Model(),
+	}
+	if query.Tag != "" {
+		filter["tags"] = strings.ToLower(strings.TrimSpace(query.Tag))
+	}
+	if query.Genre != "" {
+		filter["genre"] = query.Genre
+	}
+	if len(query.Hidden) > 0 {
+		filter["safety_labels.label"] = bson.M{"$nin": query.Hidden}
+	}
+	if query.ReviewedOnly {
+		filter["review_state"] = bson.M{"$nin": models.UnreviewedStates}
+	}
+
+	// Every embedded annotation matches to some degree
+	total, err := s.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search annotations: %w", err)
+	}
+
+	// Vector stores return the nearest matches only, so a page means fetching everything before it
+	hits, err := s.vectors.Nearest(ctx, vector, filter, query.Offset+query.Limit)
+	if err != nil {
+		return nil, err
+	}
+	if query.Offset < len(hits) {
+		hits = hits[query.Offset:]
+	} else {
+		hits = []models.SearchHit{}
+	}
+
+	contentFilter, err := s.settings.ContentFilter(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if contentFilter != nil {
+		for i := range hits {
+			hits[i].Title = contentFilter.Apply(hits[i].Title)
+		}
+	}
+
+	return &models.SearchResults{
+		Query:   q,
+		Hits:    hits,
+		Total:   int(total),
+		Backend: s.vectors.Name(),
+	}, nil
+}
@@ -0,0 +1,189 @@
+package services
+
+import (
+	"auto-annotation-api/models"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	contentFilterSettingsID = "content_filter"
+	ttsVoiceSettingsID      = "tts_voice"
+	metadataSchemaID        = "metadata_schema"
+	settingsCacheTTL        = 30 * time.Second // Other instances pick up changes within this window
+)
+
+// SettingsService manages application-wide settings stored in MongoDB
+type SettingsService struct {
+	collection *mongo.Collection
+
+	mu           sync.Mutex
+	filter       *ContentFilter
+	filterLoaded time.Time
+}
+
+// NewSettingsService creates a new settings service
+func NewSettingsService(db *mongo.Database) *SettingsService {
+	return &SettingsService{
+		collection: db.Collection("settings"),
+	}
+}
+
+// GetContentFilterSettings returns the content filter settings, disabled by default
+func (s *SettingsService) GetContentFilterSettings(ctx context.Context) (*models.ContentFilterSettings, error) {
+	var settings models.ContentFilterSettings
+	err := s.collection.FindOne(ctx, bson.M{"_id": contentFilterSettingsID}).Decode(&settings)
+	if err == mongo.ErrNoDocuments {
+		return &models.ContentFilterSettings{ID: contentFilterSettingsID, Mode: "mask", Terms: []string{}}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load content filter settings: %w", err)
+	}
+
+	if settings.Terms == nil {
+		settings.Terms = []string{}
+	}
+	return &settings, nil
+}
+
+// UpdateContentFilterSettings changes the content filter settings
+func (s *SettingsService) UpdateContentFilterSettings(ctx context.Context, req *models.UpdateContentFilterRequest, userID string) (*models.ContentFilterSettings, error) {
+	settings, err := s.GetContentFilterSettings(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Enabled != nil {
+		settings.Enabled = *req.Enabled
+	}
+	if req.Mode != nil {
+		settings.Mode = *req.Mode
+	}
+	if req.Terms != nil {
+		settings.Terms = NormalizeFilterTerms(*req.Terms)
+	}
+	settings.UpdatedBy = userID
+	settings.UpdatedAt = time.Now()
+
+	_, err = s.collection.ReplaceOne(ctx, bson.M{"_id": contentFilterSettingsID}, settings, options.Replace().SetUpsert(true))
+	if err != nil {
+		return nil, fmt.Errorf("failed to save content filter settings: %w", err)
+	}
+
+	s.mu.Lock()
+	s.filterLoaded = time.Time{} // Rebuild on next use
+	s.mu.Unlock()
+
+	return settings, nil
+}
+
+// ContentFilter returns the active content filter, or nil when filtering is disabled.
+// The filter is cached briefly since it is consulted on every annotation read.
+func (s *SettingsService) ContentFilter(ctx context.Context) (*ContentFilter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if time.Since(s.filterLoaded) < settingsCacheTTL {
+		return s.filter, nil
+	}
+
+	settings, err := s.GetContentFilterSettings(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.filter = nil
+	if settings.Enabled {
+		s.filter = NewContentFilter(settings.Terms, settings.Mode)
+	}
+	s.filterLoaded = time.Now()
+
+	return s.filter, nil
+}
+
+// GetTTSVoiceSettings returns the TTS voice override, empty when the configured defaults apply
+func (s *SettingsService) GetTTSVoiceSettings(ctx context.Context) (*models.TTSVoiceSettings, error) {
+	var settings models.TTSVoiceSettings
+	err := s.collection.FindOne(ctx, bson.M{"_id": ttsVoiceSettingsID}).Decode(&settings)
+	if err == mongo.ErrNoDocuments {
+		return &models.TTSVoiceSettings{ID: ttsVoiceSettingsID}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TTS voice settings: %w", err)
+	}
+
+	return &settings, nil
+}
+
+// UpdateTTSVoiceSettings changes the TTS voice override
+func (s *SettingsService) UpdateTTSVoiceSettings(ctx context.Context, req *models.UpdateTTSVoiceRequest, userID string) (*models.TTSVoiceSettings, error) {
+	settings, err := s.GetTTSVoiceSettings(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.VoiceID != nil {
+		settings.VoiceID = strings.TrimSpace(*req.VoiceID)
+	}
+	if req.Engine != nil {
+		settings.Engine = *req.Engine
+	}
+	settings.UpdatedBy = userID
+	settings.UpdatedAt = time.Now()
+
+	_, err = s.collection.ReplaceOne(ctx, bson.M{"_id": ttsVoiceSettingsID}, settings, options.Replace().SetUpsert(true))
+	if err != nil {
+		return nil, fmt.Errorf("failed to save TTS voice settings: %w", err)
+	}
+
+	return settings, nil
+}
+
+// GetMetadataSchema returns the custom metadata fields, none by default
+func (s *SettingsService) GetMetadataSchema(ctx context.Context) (*models.MetadataSchema, error) {
+	var schema models.MetadataSchema
+	err := s.collection.FindOne(ctx, bson.M{"_id": metadataSchemaID}).Decode(&schema)
+	if err == mongo.ErrNoDocuments {
+		return &models.MetadataSchema{ID: metadataSchemaID, Fields: []models.MetadataField{}}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load metadata schema: %w", err)
+	}
+
+	if schema.Fields == nil {
+		schema.Fields = []models.MetadataField{}
+	}
+	return &schema, nil
+}
+
+// UpdateMetadataSchema replaces the custom metadata fields. Values already stored on annotations are
+// kept; they are validated against the new schema when next written.
+func (s *SettingsService) UpdateMetadataSchema(ctx context.Context, req *models.UpdateMetadataSchemaRequest, userID string) (*models.MetadataSchema, error) {
+	if err := validateMetadataSchema(req.Fields); err != nil {
+		return nil, err
+	}
+
+	schema := &models.MetadataSchema{
+		ID:        metadataSchemaID,
+		Fields:    req.Fields,
+		UpdatedBy: userID,
+		UpdatedAt: time.Now(),
+	}
+	if schema.Fields == nil {
+		schema.Fields = []models.MetadataField{}
+	}
+
+	_, err := s.collection.ReplaceOne(ctx, bson.M{"_id": metadataSchemaID}, schema, options.Replace().SetUpsert(true))
+	if err != nil {
+		return nil, fmt.Errorf("failed to save metadata schema: %w", err)
+	}
+
+	return schema, nil
+}
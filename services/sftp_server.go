@@ -0,0 +1,298 @@
+package services
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+// SFTPAccount maps an SFTP login to its upload directory and the user that owns the resulting annotations
+type SFTPAccount struct {
+	Login     string
+	Password  string
+	UserEmail string
+	Dir       string
+}
+
+// SFTPServer is an embedded, upload-only SFTP server. Each account is confined to its own
+// directory, which is processed by a FolderWatcher like any other watch folder.
+type SFTPServer struct {
+	listenAddr string
+	sshConfig  *ssh.ServerConfig
+	accounts   map[string]SFTPAccount
+}
+
+// ParseSFTPAccounts parses "login:password:email" entries separated by commas.
+// Each account gets a directory named after its login under rootDir.
+func ParseSFTPAccounts(spec, rootDir string) ([]SFTPAccount, error) {
+	var accounts []SFTPAccount
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.SplitN(entry, ":", 3)
+		if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+			return nil, fmt.Errorf("invalid SFTP account %q, expected login:password:email", entry)
+		}
+		if strings.ContainsAny(parts[0], `/\.`) {
+			return nil, fmt.Errorf("invalid SFTP login %q", parts[0])
+		}
+
+		accounts = append(accounts, SFTPAccount{
+			Login:     parts[0],
+			Password:  parts[1],
+			UserEmail: parts[2],
+			Dir:       filepath.Join(rootDir, parts[0]),
+		})
+	}
+
+	if len(accounts) == 0 {
+		return nil, errors.New("no SFTP accounts configured")
+	}
+	return accounts, nil
+}
+
+// NewSFTPServer creates a new SFTP server, generating a host key at hostKeyFile if none exists
+func NewSFTPServer(listenAddr, hostKeyFile string, accounts []SFTPAccount) (*SFTPServer, error) {
+	hostKey, err := loadOrCreateHostKey(hostKeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &SFTPServer{
+		listenAddr: listenAddr,
+		accounts:   make(map[string]SFTPAccount),
+	}
+	for _, account := range accounts {
+		if err := os.MkdirAll(account.Dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create SFTP directory for %s: %w", account.Login, err)
+		}
+		s.accounts[account.Login] = account
+	}
+
+	s.sshConfig = &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			account, ok := s.accounts[conn.User()]
+			if ok && subtle.ConstantTimeCompare([]byte(account.Password), password) == 1 {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("invalid credentials for %s", conn.User())
+		},
+	}
+	s.sshConfig.AddHostKey(hostKey)
+
+	return s, nil
+}
+
+// Start accepts SFTP connections until the context is cancelled
+func (s *SFTPServer) Start(ctx context.Context) error {
+	listener, err := net.Listen("tcp", s.listenAddr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.listenAddr, err)
+	}
+
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+
+	log.Printf("SFTP ingestion server listening on %s", s.listenAddr)
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to accept SFTP connection: %w", err)
+		}
+		go s.handleConn(conn)
+	}
+}
+
+// handleConn performs the SSH handshake and serves the sftp subsystem on session channels
+func (s *SFTPServer) handleConn(conn net.Conn) {
+	sshConn, channels, requests, err := ssh.NewServerConn(conn, s.sshConfig)
+	if err != nil {
+		log.Printf("SFTP handshake failed from %s: %v", conn.RemoteAddr(), err)
+		conn.Close()
+		return
+	}
+	defer sshConn.Close()
+	go ssh.DiscardRequests(requests)
+
+	account := s.accounts[sshConn.User()]
+	log.Printf("SFTP login %s from %s", account.Login, conn.RemoteAddr())
+
+	for newChannel := range channels {
+		if newChannel.ChannelType() != "session" {
+			newChannel.Reject(ssh.UnknownChannelType, "unknown channel type")
+			continue
+		}
+
+		channel, channelRequests, err := newChannel.Accept()
+		if err != nil {
+			log.Printf("SFTP channel accept failed: %v", err)
+			continue
+		}
+
+		// Only the sftp subsystem is supported, no shells or exec
+		go func(in <-chan *ssh.Request) {
+			for req := range in {
+				ok := req.Type == "subsystem" && len(req.Payload) > 4 && string(req.Payload[4:]) == "sftp"
+				req.Reply(ok, nil)
+			}
+		}(channelRequests)
+
+		handler := &sftpDirHandler{dir: account.Dir}
+		server := sftp.NewRequestServer(channel, sftp.Handlers{
+			FileGet:  handler,
+			FilePut:  handler,
+			FileCmd:  handler,
+			FileList: handler,
+		})
+		if err := server.Serve(); err != nil && err != io.EOF {
+			log.Printf("SFTP session for %s ended with error: %v", account.Login, err)
+		}
+		server.Close()
+	}
+}
+
+// sftpDirHandler confines an SFTP session to a single flat directory and only accepts PDF uploads
+type sftpDirHandler struct {
+	dir string
+}
+
+// resolve maps an SFTP path onto the account directory
+func (h *sftpDirHandler) resolve(p string) string {
+	return filepath.Join(h.dir, filepath.FromSlash(path.Clean("/"+p)))
+}
+
+// isUploadPath reports whether a path is a PDF directly inside the account directory
+func isUploadPath(p string) bool {
+	clean := path.Clean("/" + p)
+	return path.Dir(clean) == "/" && strings.ToLower(path.Ext(clean)) == ".pdf"
+}
+
+// Fileread serves downloads (e.g. clients verifying an upload)
+func (h *sftpDirHandler) Fileread(r *sftp.Request) (io.ReaderAt, error) {
+	return os.Open(h.resolve(r.Filepath))
+}
+
+// Filewrite accepts PDF uploads into the account directory
+func (h *sftpDirHandler) Filewrite(r *sftp.Request) (io.WriterAt, error) {
+	if !isUploadPath(r.Filepath) {
+		return nil, sftp.ErrSSHFxPermissionDenied
+	}
+	return os.OpenFile(h.resolve(r.Filepath), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+}
+
+// Filecmd handles remove and rename of pending uploads; everything else is rejected
+func (h *sftpDirHandler) Filecmd(r *sftp.Request) error {
+	switch r.Method {
+	case "Setstat":
+		// Clients commonly set times/permissions after upload, ignore it
+		return nil
+	case "Remove":
+		if !isUploadPath(r.Filepath) {
+			return sftp.ErrSSHFxPermissionDenied
+		}
+		return os.Remove(h.resolve(r.Filepath))
+	case "Rename":
+		if !isUploadPath(r.Filepath) || !isUploadPath(r.Target) {
+			return sftp.ErrSSHFxPermissionDenied
+		}
+		return os.Rename(h.resolve(r.Filepath), h.resolve(r.Target))
+	default:
+		return sftp.ErrSSHFxOpUnsupported
+	}
+}
+
+// Filelist handles directory listings and stat calls
+func (h *sftpDirHandler) Filelist(r *sftp.Request) (sftp.ListerAt, error) {
+	switch r.Method {
+	case "List":
+		entries, err := os.ReadDir(h.resolve(r.Filepath))
+		if err != nil {
+			return nil, err
+		}
+
+		infos := make([]os.FileInfo, 0, len(entries))
+		for _, entry := range entries {
+			if info, err := entry.Info(); err == nil {
+				infos = append(infos, info)
+			}
+		}
+		return fileInfoLister(infos), nil
+	case "Stat", "Lstat":
+		info, err := os.Stat(h.resolve(r.Filepath))
+		if err != nil {
+			return nil, err
+		}
+		return fileInfoLister{info}, nil
+	default:
+		return nil, sftp.ErrSSHFxOpUnsupported
+	}
+}
+
+// fileInfoLister implements sftp.ListerAt over a slice of file infos
+type fileInfoLister []os.FileInfo
+
+// ListAt copies entries starting at offset into ls
+func (f fileInfoLister) ListAt(ls []os.FileInfo, offset int64) (int, error) {
+	if offset >= int64(len(f)) {
+		return 0, io.EOF
+	}
+
+	n := copy(ls, f[offset:])
+	if n < len(ls) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// loadOrCreateHostKey reads the SSH host key, generating and saving an ed25519 key if missing
+func loadOrCreateHostKey(hostKeyFile string) (ssh.Signer, error) {
+	keyData, err := os.ReadFile(hostKeyFile)
+	if errors.Is(err, os.ErrNotExist) {
+		_, privateKey, genErr := ed25519.GenerateKey(rand.Reader)
+		if genErr != nil {
+			return nil, fmt.Errorf("failed to generate SFTP host key: %w", genErr)
+		}
+
+		block, marshalErr := ssh.MarshalPrivateKey(privateKey, "auto-annotation-api sftp")
+		if marshalErr != nil {
+			return nil, fmt.Errorf("failed to encode SFTP host key: %w", marshalErr)
+		}
+
+		keyData = pem.EncodeToMemory(block)
+		if writeErr := os.WriteFile(hostKeyFile, keyData, 0600); writeErr != nil {
+			return nil, fmt.Errorf("failed to save SFTP host key: %w", writeErr)
+		}
+		log.Printf("Generated new SFTP host key at %s", hostKeyFile)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read SFTP host key: %w", err)
+	}
+
+	signer, err := ssh.ParsePrivateKey(keyData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse SFTP host key: %w", err)
+	}
+	return signer, nil
+}
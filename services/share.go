@@ -0,0 +1,82 @@
+package services
+
+import (
+	"auto-annotation-api/models"
+	"auto-annotation-api/utils"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ShareLink returns the link that opens an annotation in the web app
+func (s *AnnotationService) ShareLink(annotationID string) string {
+	return strings.TrimRight(s.shareBaseURL, "/") + "/annotations/" + url.PathEscape(annotationID)
+}
+
+// CreateShareLink creates a link to an annotation that opens without an account, expiring after
+// expiresInDays unless it is 0
+func (s *AnnotationService) CreateShareLink(ctx context.Context, annotationID string, user *models.User, expiresInDays int) (*models.ShareLinkResponse, error) {
+	annotation, err := s.GetAnnotationByID(ctx, annotationID)
+	if err == nil && annotation.MergedInto != "" {
+		annotation, err = s.GetAnnotationByID(ctx, annotation.MergedInto)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var expiresAt time.Time
+	link := &models.ShareLinkResponse{}
+	if expiresInDays > 0 {
+		expiresAt = time.Now().AddDate(0, 0, expiresInDays).UTC()
+		link.ExpiresAt = &expiresAt
+	}
+
+	link.Token, err = utils.GenerateShareToken(annotation.ID, user.ID, expiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign share token: %w", err)
+	}
+	link.URL = strings.TrimRight(s.shareBaseURL, "/") + "/shared/" + url.PathEscape(link.Token)
+	return link, nil
+}
+
+// GetSharedAnnotation returns the annotation a share token grants access to. Links to an annotation
+// merged into another open that one; deleted and taken down annotations are not found.
+func (s *AnnotationService) GetSharedAnnotation(ctx context.Context, token string) (*models.Annotation, error) {
+	claims, err := utils.ValidateShareToken(token)
+	if errors.Is(err, utils.ErrShareTokenExpired) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid share link")
+	}
+
+	annotation, err := s.GetAnnotationByID(ctx, claims.AnnotationID)
+	if err == nil && annotation.MergedInto != "" {
+		annotation, err = s.GetAnnotationByID(ctx, annotation.MergedInto)
+	}
+	return annotation, err
+}
+
+// ShareQRCode renders the share link of an annotation as a QR code with a quiet zone of border
+// modules, as a PNG of size by size pixels or an SVG of at most that size. It returns the image
+// and its content type.
+func (s *AnnotationService) ShareQRCode(annotationID, format string, size, border int) ([]byte, string, error) {
+	qr, err := utils.EncodeQR(s.ShareLink(annotationID))
+	if err != nil {
+		return nil, "", err
+	}
+
+	if format == "svg" {
+		// The SVG scales freely, so only its declared size follows the request
+		scale := max(size/(qr.Size+2*border), 1)
+		return []byte(qr.SVG(scale, border)), "image/svg+xml", nil
+	}
+	data, err := qr.PNG(size, border)
+	if err != nil {
+		return nil, "", err
+	}
+	return data, "image/png", nil
+}
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"auto-annotation-api/config"
+	"auto-annotation-api/models"
+	"context"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// simulatedTextLimit caps the fabricated source text, which grows with the upload size
+const simulatedTextLimit = 200_000
+
+// simulatedSentence is repeated to fabricate text of a realistic size
+const simulatedSentence = "This text was fabricated by a simulated upload for load testing. "
+
+// simulation fakes the slow steps of the pipeline for uploads with simulate=true, so load tests
+// exercise uploads, the database and streaming without Ollama, storage or a TTS provider
+type simulation struct {
+	enabled bool
+	extract time.Duration
+	llm     time.Duration
+	tts     time.Duration
+}
+
+func newSimulation(cfg *config.Config) simulation {
+	return simulation{
+		enabled: cfg.AllowSimulated,
+		extract: time.Duration(cfg.SimulateExtractMs) * time.Millisecond,
+		llm:     time.Duration(cfg.SimulateLLMMs) * time.Millisecond,
+		tts:     time.Duration(cfg.SimulateTTSMs) * time.Millisecond,
+	}
+}
+
+// wait takes about d, varied by up to 25% so concurrent simulated jobs don't move in lockstep
+func (sim simulation) wait(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	d += time.Duration((rand.Float64() - 0.5) * 0.5 * float64(d))
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// completeSimulatedAnnotation fills annotation with fabricated text after the simulated extraction
+// and generation delays, and stores it like a real upload
+func (s *AnnotationService) completeSimulatedAnnotation(ctx context.Context, annotation *models.Annotation, fileSize int) (*models.Annotation, error) {
+	log.Printf("Simulating annotation pipeline for: %s", annotation.Title)
+	annotation.Simulated = true
+
+	if err := s.simulation.wait(ctx, s.simulation.extract); err != nil {
+		return nil, err
+	}
+	annotation.TextContent = simulatedText(fileSize / 10)
+
+	if err := s.simulation.wait(ctx, s.simulation.llm); err != nil {
+		return nil, err
+	}
+	summaryLength := map[string]int{"short": 600, "medium": 1500, "detailed": 4000}[annotation.Length]
+	annotation.Annotation = simulatedText(summaryLength)
+	annotation.Genre = "Simulated"
+	annotation.Status = "completed"
+	annotation.UpdatedAt = time.Now()
+
+	if _, err := s.collection.InsertOne(ctx, annotation); err != nil {
+		return nil, fmt.Errorf("failed to create annotation record: %w", err)
+	}
+	s.syncSearch(ctx, annotation.ID)
+	s.purgeCDN(annotation.ID)
+
+	return annotation, nil
+}
+
+// simulateTTS stands in for speech synthesis of a simulated annotation; no audio is stored
+func (s *AnnotationService) simulateTTS(ctx context.Context, annotation *models.Annotation) (*models.Annotation, error) {
+	log.Printf("Simulating TTS for annotation ID: %s", annotation.ID)
+	if err := s.simulation.wait(ctx, s.simulation.tts); err != nil {
+		return nil, err
+	}
+	return annotation, nil
+}
+
+// DeleteSimulatedAnnotations removes every annotation created by a simulated upload, for cleaning
+// up after a load test. Simulated annotations have no stored files.
+func (s *AnnotationService) DeleteSimulatedAnnotations(ctx context.Context) (int64, error) {
+	ids, err := s.collection.Distinct(ctx, "_id", bson.M{"simulated": true})
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := s.collection.DeleteMany(ctx, bson.M{"simulated": true})
+	if err != nil {
+		return 0, err
+	}
+
+	annotationIDs := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if annotationID, ok := id.(string); ok {
+			annotationIDs = append(annotationIDs, annotationID)
+			s.deleteLinksOf(ctx, annotationID)
+		}
+	}
+	s.syncSearch(ctx, annotationIDs...)
+	s.purgeCDN(annotationIDs...)
+
+	log.Printf("Deleted %d simulated annotations", result.DeletedCount)
+	return result.DeletedCount, nil
+}
+
+// simulatedText returns about n characters of filler text
+func simulatedText(n int) string {
+	n = min(max(n, len(simulatedSentence)), simulatedTextLimit)
+	return strings.TrimSpace(strings.Repeat(simulatedSentence, n/len(simulatedSentence)))
+}
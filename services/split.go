@@ -0,0 +1,122 @@
+package services
+
+import (
+	"auto-annotation-api/models"
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SplitAnnotation creates a child annotation per page range of an annotation whose upload contained
+// several distinct works. Each part gets its own generated annotation and a part-of link to the parent,
+// which is left unchanged.
+func (s *AnnotationService) SplitAnnotation(ctx context.Context, annotationID, userID string, req *models.SplitAnnotationRequest) ([]*models.Annotation, error) {
+	parent, err := s.GetAnnotationByID(ctx, annotationID)
+	if err != nil {
+		return nil, err
+	}
+	if parent.Status != "completed" {
+		return nil, fmt.Errorf("cannot split annotation with status %q", parent.Status)
+	}
+	if parent.TextContent == "" {
+		return nil, fmt.Errorf("cannot split annotation without source text")
+	}
+
+	length, err := normalizeSummaryLength(firstNonEmpty(req.Length, parent.Length))
+	if err != nil {
+		return nil, err
+	}
+
+	pages := splitPages(parent.TextContent)
+	if err := validateSplitParts(req.Parts, pages[len(pages)-1].Page); err != nil {
+		return nil, err
+	}
+
+	parts := make([]*models.Annotation, 0, len(req.Parts))
+	for _, part := range req.Parts {
+		title := strings.TrimSpace(part.Title)
+		text := pageRangeText(pages, part.StartPage, part.EndPage)
+		if text == "" {
+			return nil, fmt.Errorf("cannot split: pages %d-%d have no text", part.StartPage, part.EndPage)
+		}
+
+		log.Printf("Generating part %q (pages %d-%d) of annotation %s", title, part.StartPage, part.EndPage, annotationID)
+		result, err := s.generateAnnotation(ctx, text, title, length)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate annotation for %q: %w", title, err)
+		}
+
+		child := models.NewAnnotation(userID, title, parent.SourceFile, parent.SourceType)
+		child.TextContent = text
+		child.Formulas = extractFormulas(text)
+		child.CodeBlocks = extractCodeBlocks(text)
+		child.Annotation = result.Annotation
+		child.Genre = result.Genre
+		child.Model = s.ollamaClient.modelFor(ctx)
+		child.Length = length
+		child.Tags = parent.Tags
+		child.Book = parent.Book
+		child.Metadata = parent.Metadata
+		child.SourceURL, child.Author, child.PublishedOn = parent.SourceURL, parent.Author, parent.PublishedOn
+		child.License = parent.License
+		child.Visibility = parent.Visibility
+		child.Consent = parent.Consent // The parts come from the same upload
+		child.Objectives, child.Prereqs = s.extractLearningOutline(ctx, result, title)
+		child.KeyTerms = s.extractKeyTerms(ctx, result.Annotation, title)
+		child.SafetyLabels = s.labelSafety(ctx, result.Annotation, title)
+		child.Embedding, child.EmbedModel = s.embedNotes(result.Annotation, title)
+		child.Status = "completed"
+		child.ReviewState = s.initialReviewState()
+		child.UpdatedAt = time.Now()
+
+		if _, err := s.collection.InsertOne(ctx, child); err != nil {
+			return nil, fmt.Errorf("failed to create part %q: %w", title, err)
+		}
+		link := models.NewAnnotationLink(child.ID, parent.ID, models.LinkPartOf, userID)
+		if _, err := s.links.InsertOne(ctx, link); err != nil {
+			return nil, fmt.Errorf("failed to link part %q: %w", title, err)
+		}
+		parts = append(parts, child)
+		s.syncSearch(ctx, child.ID)
+	}
+	// The parent's related links now list the parts
+	s.purgeCDN(parent.ID)
+
+	return parts, nil
+}
+
+// validateSplitParts checks that the page ranges are within the document and don't overlap
+func validateSplitParts(parts []models.SplitPart, pageCount int) error {
+	ranges := make([]models.SplitPart, len(parts))
+	copy(ranges, parts)
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].StartPage < ranges[j].StartPage })
+
+	for i, part := range ranges {
+		if part.StartPage > part.EndPage || part.EndPage > pageCount {
+			return fmt.Errorf("invalid page range %d-%d: the document has %d pages", part.StartPage, part.EndPage, pageCount)
+		}
+		if i > 0 && part.StartPage <= ranges[i-1].EndPage {
+			return fmt.Errorf("invalid page range %d-%d: overlaps %d-%d", part.StartPage, part.EndPage, ranges[i-1].StartPage, ranges[i-1].EndPage)
+		}
+	}
+	return nil
+}
+
+// pageRangeText rebuilds the text of pages start to end, keeping the original page numbers so search
+// results and the reader view still point to the right pages
+func pageRangeText(pages []pageText, start, end int) string {
+	var text strings.Builder
+	for _, page := range pages {
+		if page.Page < start || page.Page > end {
+			continue
+		}
+		if text.Len() > 0 || page.Page > 1 {
+			text.WriteString(fmt.Sprintf("\n\n--- Page %d ---\n\n", page.Page))
+		}
+		text.WriteString(strings.TrimSpace(page.Text))
+	}
+	return strings.TrimSpace(text.String())
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"auto-annotation-api/config"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// StorageBackend stores generated files (audio, captions, images, source documents) and serves
+// them by URL. S3 is the default; MinIO and the local disk suit self-hosted deployments.
+type StorageBackend interface {
+	// Name identifies the backend, e.g. "s3"
+	Name() string
+	// Put stores data under key and returns its public URL
+	Put(ctx context.Context, key string, data []byte, contentType string) (string, error)
+	// PutStream stores size bytes read from body under key without buffering them, and returns its public URL
+	PutStream(ctx context.Context, key string, body io.Reader, size int64, contentType string) (string, error)
+	// Get returns the data stored under key
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Delete removes the data stored under key
+	Delete(ctx context.Context, key string) error
+	// SignedURL returns a URL that grants temporary read access to key
+	SignedURL(ctx context.Context, key string, expiry time.Duration) (string, error)
+	// KeyFromURL returns the key of a URL returned by Put, or "" if the URL points elsewhere
+	KeyFromURL(url string) string
+	// TestConnection checks that the backend is reachable and writable
+	TestConnection() error
+}
+
+// NewStorageBackend creates the storage backend selected by STORAGE_BACKEND. Without a setting, S3
+// is used when AWS is configured and the local disk otherwise.
+func NewStorageBackend(cfg *config.Config, awsService *AWSService) (StorageBackend, error) {
+	backend := strings.ToLower(cfg.StorageBackend)
+	if backend == "" {
+		backend = "local"
+		if awsService != nil {
+			backend = "s3"
+		}
+	}
+
+	switch backend {
+	case "s3":
+		if awsService == nil {
+			return nil, fmt.Errorf("storage backend s3 not configured: AWS credentials missing")
+		}
+		storage := newS3Storage("s3", awsService.s3Client, awsService.bucketName, fmt.Sprintf("https://%s.s3.amazonaws.com", awsService.bucketName))
+		if cfg.S3ReplicaBucket != "" && cfg.S3ReplicaRegion != "" {
+			return NewReplicatedStorage(storage, cfg.AWSRegion, cfg.S3ReplicaBucket, cfg.S3ReplicaRegion, strings.Split(cfg.S3ReplicaCountry, ",")), nil
+		}
+		return storage, nil
+	case "minio":
+		if cfg.MinIOEndpoint == "" || cfg.MinIOBucket == "" {
+			return nil, fmt.Errorf("storage backend minio not configured: MINIO_ENDPOINT or MINIO_BUCKET missing")
+		}
+		return NewMinIOStorage(cfg.MinIOEndpoint, cfg.MinIOAccessKey, cfg.MinIOSecretKey, cfg.MinIOBucket)
+	case "local":
+		publicURL := cfg.StoragePublicURL
+		if publicURL == "" {
+			publicURL = "http://localhost:" + cfg.Port + LocalStorageRoute
+		}
+		return NewLocalStorage(cfg.StorageLocalDir, publicURL), nil
+	default:
+		return nil, fmt.Errorf("unknown storage backend: %s", cfg.StorageBackend)
+	}
+}
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// LocalStorageRoute is the path the API serves local storage files under
+const LocalStorageRoute = "/storage"
+
+// LocalStorage stores files in a directory on disk. The API serves the directory under
+// LocalStorageRoute, so files are public like objects in the S3 bucket.
+type LocalStorage struct {
+	dir     string
+	baseURL string // Public URL of LocalStorageRoute, without a trailing slash
+}
+
+// NewLocalStorage creates local disk storage in dir, served at publicURL
+func NewLocalStorage(dir, publicURL string) *LocalStorage {
+	return &LocalStorage{
+		dir:     dir,
+		baseURL: strings.TrimSuffix(publicURL, "/"),
+	}
+}
+
+// Dir returns the directory files are stored in
+func (l *LocalStorage) Dir() string {
+	return l.dir
+}
+
+func (l *LocalStorage) Name() string {
+	return "local"
+}
+
+func (l *LocalStorage) Put(ctx context.Context, key string, data []byte, contentType string) (string, error) {
+	return l.PutStream(ctx, key, bytes.NewReader(data), int64(len(data)), contentType)
+}
+
+func (l *LocalStorage) PutStream(ctx context.Context, key string, body io.Reader, size int64, contentType string) (string, error) {
+	path, err := l.path(key)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("failed to create storage directory: %w", err)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to write file: %w", err)
+	}
+	written, err := io.Copy(file, body)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && written != size {
+		err = fmt.Errorf("expected %d bytes, got %d", size, written)
+	}
+	if err != nil {
+		os.Remove(path)
+		return "", fmt.Errorf("failed to write file: %w", err)
+	}
+
+	return l.baseURL + "/" + key, nil
+}
+
+func (l *LocalStorage) Get(ctx context.Context, key string) ([]byte, error) {
+	path, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	return data, nil
+}
+
+func (l *LocalStorage) Delete(ctx context.Context, key string) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete file: %w", err)
+	}
+	return nil
+}
+
+// SignedURL returns the public URL: local files are served without access control
+func (l *LocalStorage) SignedURL(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	if _, err := l.path(key); err != nil {
+		return "", err
+	}
+	return l.baseURL + "/" + key, nil
+}
+
+func (l *LocalStorage) KeyFromURL(url string) string {
+	prefix := l.baseURL + "/"
+	if !strings.HasPrefix(url, prefix) {
+		return ""
+	}
+	return strings.TrimPrefix(url, prefix)
+}
+
+func (l *LocalStorage) TestConnection() error {
+	if err := os.MkdirAll(l.dir, 0755); err != nil {
+		return fmt.Errorf("storage directory not accessible: %w", err)
+	}
+
+	f, err := os.CreateTemp(l.dir, ".write-test-*")
+	if err != nil {
+		return fmt.Errorf("storage directory not writable: %w", err)
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// path maps a key to a file inside the storage directory, rejecting keys that would escape it
+func (l *LocalStorage) path(key string) (string, error) {
+	cleaned := filepath.Clean("/" + key)
+	if cleaned == "/" || cleaned != "/"+key {
+		return "", fmt.Errorf("invalid storage key: %s", key)
+	}
+	return filepath.Join(l.dir, filepath.FromSlash(cleaned)), nil
+}
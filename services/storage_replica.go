@@ -0,0 +1,162 @@
+package services
+
+import (
+	"auto-annotation-api/models"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// ReplicatedStorage keeps a copy of every object in a second S3 bucket, usually in another region,
+// and hands clients near that region URLs of the copy. Reads, signed URLs and stored URLs always
+// refer to the primary bucket.
+type ReplicatedStorage struct {
+	*S3Storage
+	replica       *S3Storage
+	primaryRegion string
+	replicaRegion string
+	countries     map[string]bool // Countries served from the replica
+}
+
+// NewReplicatedStorage copies the objects of primary to bucket in region. Clients in countries
+// (ISO codes) are served from the copy.
+func NewReplicatedStorage(primary *S3Storage, primaryRegion, bucket, region string, countries []string) *ReplicatedStorage {
+	client := s3.New(primary.client.Options(), func(o *s3.Options) {
+		o.Region = region
+	})
+
+	served := make(map[string]bool)
+	for _, country := range countries {
+		if country = strings.ToUpper(strings.TrimSpace(country)); country != "" {
+			served[country] = true
+		}
+	}
+
+	return &ReplicatedStorage{
+		S3Storage:     primary,
+		replica:       newS3Storage(primary.name+"-replica", client, bucket, fmt.Sprintf("https://%s.s3.%s.amazonaws.com", bucket, region)),
+		primaryRegion: strings.ToLower(primaryRegion),
+		replicaRegion: strings.ToLower(region),
+		countries:     served,
+	}
+}
+
+// Put stores data in the primary bucket and then copies it to the replica
+func (r *ReplicatedStorage) Put(ctx context.Context, key string, data []byte, contentType string) (string, error) {
+	url, err := r.S3Storage.Put(ctx, key, data, contentType)
+	if err != nil {
+		return "", err
+	}
+	r.replicate(ctx, key)
+	return url, nil
+}
+
+// PutStream stores body in the primary bucket and then copies it to the replica
+func (r *ReplicatedStorage) PutStream(ctx context.Context, key string, body io.Reader, size int64, contentType string) (string, error) {
+	url, err := r.S3Storage.PutStream(ctx, key, body, size, contentType)
+	if err != nil {
+		return "", err
+	}
+	r.replicate(ctx, key)
+	return url, nil
+}
+
+// Delete removes the object from both buckets. Only the primary decides the result, a copy left
+// behind in the replica is never handed out again.
+func (r *ReplicatedStorage) Delete(ctx context.Context, key string) error {
+	if err := r.replica.Delete(ctx, key); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+	return r.S3Storage.Delete(ctx, key)
+}
+
+// KeyFromURL also recognizes replica URLs, which clients may send back
+func (r *ReplicatedStorage) KeyFromURL(url string) string {
+	if key := r.S3Storage.KeyFromURL(url); key != "" {
+		return key
+	}
+	return r.replica.KeyFromURL(url)
+}
+
+// replicate copies an object server-side, without sending it through the API again. The copy is
+// made before the upload returns, so replica URLs handed out afterwards are valid. Failures are
+// only logged, an upload never fails because of the replica.
+func (r *ReplicatedStorage) replicate(ctx context.Context, key string) {
+	_, err := r.replica.client.CopyObject(ctx, &s3.CopyObjectInput{
+		Bucket:     aws.String(r.replica.bucketName),
+		Key:        aws.String(key),
+		CopySource: aws.String(r.bucketName + "/" + url.PathEscape(key)),
+	})
+	if err != nil {
+		log.Printf("Warning: failed to replicate %s to %s: %v", key, r.replicaRegion, err)
+	}
+}
+
+// UseReplica reports whether a client is closer to the replica. hint is a region the client asked
+// for (e.g. from measuring latency itself) and wins over country, the client's ISO country code as
+// reported by the CDN or load balancer.
+func (r *ReplicatedStorage) UseReplica(hint, country string) bool {
+	switch strings.ToLower(strings.TrimSpace(hint)) {
+	case r.replicaRegion:
+		return true
+	case r.primaryRegion:
+		return false
+	}
+	return r.countries[strings.ToUpper(strings.TrimSpace(country))]
+}
+
+// RegionalURL returns the replica URL of a URL of the primary bucket; other URLs are unchanged
+func (r *ReplicatedStorage) RegionalURL(url string) string {
+	if key := r.S3Storage.KeyFromURL(url); key != "" {
+		return r.replica.baseURL + "/" + key
+	}
+	return url
+}
+
+// LocalizeURLs points the stored files of annotations at the replica when the client is closer to
+// it. hint and country are as for ReplicatedStorage.UseReplica.
+func (s *AnnotationService) LocalizeURLs(hint, country string, responses ...*models.AnnotationResponse) {
+	replicated, ok := s.storage.(*ReplicatedStorage)
+	if !ok || !replicated.UseReplica(hint, country) {
+		return
+	}
+
+	for _, response := range responses {
+		response.Image = replicated.RegionalURL(response.Image)
+		images := make([]models.GalleryImage, len(response.Images))
+		for i, image := range response.Images {
+			image.URL = replicated.RegionalURL(image.URL)
+			images[i] = image
+		}
+		response.Images = images
+		response.TTSURL = replicated.RegionalURL(response.TTSURL)
+		response.TTSOpusURL = replicated.RegionalURL(response.TTSOpusURL)
+		response.TTSMarksURL = replicated.RegionalURL(response.TTSMarksURL)
+		if response.Captions != nil {
+			captions := *response.Captions
+			captions.VTTURL = replicated.RegionalURL(captions.VTTURL)
+			captions.SRTURL = replicated.RegionalURL(captions.SRTURL)
+			response.Captions = &captions
+		}
+		if response.AudioTour != nil {
+			tour := *response.AudioTour
+			tour.URL = replicated.RegionalURL(tour.URL)
+			response.AudioTour = &tour
+		}
+	}
+}
+
+// LocalizeURL is LocalizeURLs for a single file URL
+func (s *AnnotationService) LocalizeURL(hint, country, url string) string {
+	replicated, ok := s.storage.(*ReplicatedStorage)
+	if !ok || !replicated.UseReplica(hint, country) {
+		return url
+	}
+	return replicated.RegionalURL(url)
+}
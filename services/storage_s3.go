@@ -0,0 +1,137 @@
+package services
+
+import (
+	"auto-annotation-api/utils"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// S3Storage stores files in an S3 bucket, or in any S3-compatible service such as MinIO
+type S3Storage struct {
+	name       string
+	client     *s3.Client
+	presigner  *s3.PresignClient
+	bucketName string
+	baseURL    string // Public URL of the bucket, without a trailing slash
+}
+
+// newS3Storage creates S3 storage for a bucket whose objects are public under baseURL
+func newS3Storage(name string, client *s3.Client, bucketName, baseURL string) *S3Storage {
+	return &S3Storage{
+		name:       name,
+		client:     client,
+		presigner:  s3.NewPresignClient(client),
+		bucketName: bucketName,
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+	}
+}
+
+// NewMinIOStorage creates storage for a bucket on a MinIO server, e.g. endpoint "http://minio:9000".
+// Objects are addressed path-style, so their URLs are {endpoint}/{bucket}/{key}.
+func NewMinIOStorage(endpoint, accessKey, secretKey, bucketName string) (*S3Storage, error) {
+	endpoint = strings.TrimSuffix(endpoint, "/")
+	if !strings.HasPrefix(endpoint, "http://") && !strings.HasPrefix(endpoint, "https://") {
+		return nil, fmt.Errorf("invalid MinIO endpoint %q: must start with http:// or https://", endpoint)
+	}
+
+	client := s3.New(s3.Options{
+		Region:       "us-east-1", // MinIO's default region
+		BaseEndpoint: aws.String(endpoint),
+		UsePathStyle: true,
+		Credentials:  credentials.NewStaticCredentialsProvider(accessKey, secretKey, ""),
+		APIOptions:   utils.AWSTracingOptions(),
+	})
+	return newS3Storage("minio", client, bucketName, endpoint+"/"+bucketName), nil
+}
+
+func (s *S3Storage) Name() string {
+	return s.name
+}
+
+// Put uploads data and returns its public URL (public access is controlled by the bucket policy, not ACLs)
+func (s *S3Storage) Put(ctx context.Context, key string, data []byte, contentType string) (string, error) {
+	return s.PutStream(ctx, key, bytes.NewReader(data), int64(len(data)), contentType)
+}
+
+// PutStream uploads body in a single request. Uploaded files are seekable, so the SDK signs them
+// without reading them into memory; uploads are capped by MAX_UPLOAD_MB, far below the 5 GB
+// single-request limit.
+func (s *S3Storage) PutStream(ctx context.Context, key string, body io.Reader, size int64, contentType string) (string, error) {
+	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:        aws.String(s.bucketName),
+		Key:           aws.String(key),
+		Body:          body,
+		ContentLength: aws.Int64(size),
+		ContentType:   aws.String(contentType),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to upload to %s: %w", s.name, err)
+	}
+
+	return s.baseURL + "/" + key, nil
+}
+
+func (s *S3Storage) Get(ctx context.Context, key string) ([]byte, error) {
+	result, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucketName),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to download from %s: %w", s.name, err)
+	}
+	defer result.Body.Close()
+
+	data, err := io.ReadAll(result.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s object: %w", s.name, err)
+	}
+	return data, nil
+}
+
+func (s *S3Storage) Delete(ctx context.Context, key string) error {
+	_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucketName),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete from %s: %w", s.name, err)
+	}
+	return nil
+}
+
+func (s *S3Storage) SignedURL(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	request, err := s.presigner.PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucketName),
+		Key:    aws.String(key),
+	}, s3.WithPresignExpires(expiry))
+	if err != nil {
+		return "", fmt.Errorf("failed to sign %s URL: %w", s.name, err)
+	}
+	return request.URL, nil
+}
+
+func (s *S3Storage) KeyFromURL(url string) string {
+	prefix := s.baseURL + "/"
+	if !strings.HasPrefix(url, prefix) {
+		return ""
+	}
+	return strings.TrimPrefix(url, prefix)
+}
+
+func (s *S3Storage) TestConnection() error {
+	_, err := s.client.HeadBucket(context.TODO(), &s3.HeadBucketInput{
+		Bucket: aws.String(s.bucketName),
+	})
+	if err != nil {
+		return fmt.Errorf("%s bucket not accessible: %w", s.name, err)
+	}
+	return nil
+}
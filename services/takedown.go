@@ -0,0 +1,383 @@
+package services
+
+import (
+	"auto-annotation-api/config"
+	"auto-annotation-api/models"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// takedownRepublishInterval is how often countered claims are checked for due republication
+const takedownRepublishInterval = 15 * time.Minute
+
+// TakedownService handles copyright takedown notices: an admin upholds a claim to unpublish the
+// annotation, the uploader may answer with a counter-notice, and unless the claimant goes to court
+// the annotation is republished once the waiting period has passed.
+type TakedownService struct {
+	takedowns      *mongo.Collection
+	annotations    *AnnotationService
+	users          *UserService
+	mailer         *Mailer
+	republishAfter time.Duration
+	mu             sync.Mutex // Prevents overlapping republication runs
+}
+
+// NewTakedownService creates a new takedown service
+func NewTakedownService(db *mongo.Database, cfg *config.Config, annotations *AnnotationService, users *UserService, mailer *Mailer) *TakedownService {
+	return &TakedownService{
+		takedowns:      db.Collection("takedowns"),
+		annotations:    annotations,
+		users:          users,
+		mailer:         mailer,
+		republishAfter: time.Duration(cfg.TakedownRepublish) * 24 * time.Hour,
+	}
+}
+
+// FileClaim records a takedown notice against a published annotation and lets the admins know.
+// Claims against a merged annotation apply to the annotation it was merged into.
+func (s *TakedownService) FileClaim(ctx context.Context, req *models.CreateTakedownRequest) (*models.TakedownClaim, error) {
+	annotation, err := s.annotations.GetAnnotationByID(ctx, req.AnnotationID)
+	if err == nil && annotation.MergedInto != "" {
+		annotation, err = s.annotations.GetAnnotationByID(ctx, annotation.MergedInto)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	claim := models.NewTakedownClaim(annotation, req)
+	if _, err := s.takedowns.InsertOne(ctx, claim); err != nil {
+		return nil, fmt.Errorf("failed to save takedown claim: %w", err)
+	}
+	log.Printf("Takedown claim %s filed against annotation %s", claim.ID, claim.AnnotationID)
+
+	admins, err := s.users.ListUsers(ctx, "admin", 0, 0)
+	if err != nil {
+		log.Printf("Warning: failed to load admins to notify of takedown claim %s: %v", claim.ID, err)
+	}
+	for _, admin := range admins {
+		s.notify(admin.Email, "New copyright takedown claim", fmt.Sprintf(
+			"%s (%s) filed a takedown claim against %q.\n\nClaimed work: %s\n\nReview it in the admin panel, claim %s.\n",
+			claim.ClaimantName, claim.ClaimantEmail, claim.Title, claim.Work, claim.ID))
+	}
+
+	return claim, nil
+}
+
+// ListClaims returns the claims matching filter, newest first
+func (s *TakedownService) ListClaims(ctx context.Context, filter models.TakedownFilter, limit, offset int64) ([]*models.TakedownClaim, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	if limit > 0 {
+		opts.SetLimit(limit)
+	}
+	if offset > 0 {
+		opts.SetSkip(offset)
+	}
+
+	cursor, err := s.takedowns.Find(ctx, takedownQuery(filter), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	claims := []*models.TakedownClaim{}
+	if err := cursor.All(ctx, &claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// CountClaims returns how many claims ListClaims lists for filter in total
+func (s *TakedownService) CountClaims(ctx context.Context, filter models.TakedownFilter) (int64, error) {
+	return s.takedowns.CountDocuments(ctx, takedownQuery(filter))
+}
+
+// takedownQuery builds the query for a claim list filter
+func takedownQuery(filter models.TakedownFilter) bson.M {
+	query := bson.M{}
+	if filter.Status != "" {
+		query["status"] = filter.Status
+	}
+	if filter.UploaderID != "" {
+		query["uploader_id"] = filter.UploaderID
+	}
+	return query
+}
+
+// GetClaim retrieves a claim by ID
+func (s *TakedownService) GetClaim(ctx context.Context, claimID string) (*models.TakedownClaim, error) {
+	var claim models.TakedownClaim
+	err := s.takedowns.FindOne(ctx, bson.M{"_id": claimID}).Decode(&claim)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("takedown claim not found")
+		}
+		return nil, err
+	}
+	return &claim, nil
+}
+
+// Uphold unpublishes the annotation of a pending claim and notifies the uploader, who may answer
+// with a counter-notice
+func (s *TakedownService) Uphold(ctx context.Context, claimID string, admin *models.User, note string) (*models.TakedownClaim, error) {
+	claim, err := s.transition(ctx, claimID, models.TakedownPending, bson.M{
+		"status":      models.TakedownUpheld,
+		"review_note": note,
+		"reviewed_by": admin.ID,
+		"reviewed_at": time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.annotations.SetTakenDown(ctx, claim.AnnotationID, true); err != nil {
+		return nil, fmt.Errorf("claim upheld, but failed to unpublish the annotation: %w", err)
+	}
+	log.Printf("Annotation %s unpublished by takedown claim %s", claim.AnnotationID, claim.ID)
+
+	s.notifyUploader(ctx, claim, "Your annotation was unpublished after a copyright claim", fmt.Sprintf(
+		"Your annotation %q was unpublished after a copyright takedown claim by %s.\n\n"+
+			"Claimed work: %s\n\n%s"+
+			"If you believe it was removed by mistake, you can send a counter-notice for claim %s. "+
+			"The annotation is then republished after %d days unless the claimant takes legal action.\n",
+		claim.Title, claim.ClaimantName, claim.Work, reviewNote(note), claim.ID, s.republishDays()))
+
+	return claim, nil
+}
+
+// Reject closes a pending claim, leaving the annotation published, and notifies the claimant
+func (s *TakedownService) Reject(ctx context.Context, claimID string, admin *models.User, note string) (*models.TakedownClaim, error) {
+	claim, err := s.transition(ctx, claimID, models.TakedownPending, bson.M{
+		"status":      models.TakedownRejected,
+		"review_note": note,
+		"reviewed_by": admin.ID,
+		"reviewed_at": time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.notify(claim.ClaimantEmail, "Your copyright takedown claim was rejected", fmt.Sprintf(
+		"Your takedown claim against %q was reviewed and rejected; the annotation stays published.\n\n%s",
+		claim.Title, reviewNote(note)))
+
+	return claim, nil
+}
+
+// SubmitCounterNotice records the uploader's counter-notice to an upheld claim and schedules the
+// republication of the annotation. The claimant receives the counter-notice.
+func (s *TakedownService) SubmitCounterNotice(ctx context.Context, claimID string, user *models.User, req *models.CounterNoticeRequest) (*models.TakedownClaim, error) {
+	claim, err := s.GetClaim(ctx, claimID)
+	if err != nil {
+		return nil, err
+	}
+	if claim.UploaderID != user.ID {
+		return nil, errors.New("takedown claim not found")
+	}
+
+	now := time.Now()
+	republishAt := now.Add(s.republishAfter)
+	claim, err = s.transition(ctx, claimID, models.TakedownUpheld, bson.M{
+		"status": models.TakedownCountered,
+		"counter_notice": models.CounterNotice{
+			Name:        user.Name,
+			Email:       user.Email,
+			Statement:   req.Statement,
+			Signature:   req.Signature,
+			SubmittedAt: now,
+		},
+		"republish_at": republishAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.notify(claim.ClaimantEmail, "Counter-notice to your copyright takedown claim", fmt.Sprintf(
+		"The uploader of %q sent a counter-notice to your takedown claim.\n\n"+
+			"Name: %s\nEmail: %s\nStatement: %s\nSignature: %s\n\n"+
+			"The annotation will be republished on %s unless you notify us before then that you have "+
+			"filed an action seeking a court order against the uploader.\n",
+		claim.Title, user.Name, user.Email, req.Statement, req.Signature, republishAt.UTC().Format(time.RFC1123)))
+
+	return claim, nil
+}
+
+// KeepDown cancels the scheduled republication of a countered claim, when the claimant reported a
+// court action, and notifies the uploader
+func (s *TakedownService) KeepDown(ctx context.Context, claimID string, admin *models.User, note string) (*models.TakedownClaim, error) {
+	claim, err := s.transition(ctx, claimID, models.TakedownCountered, bson.M{
+		"status":      models.TakedownClosed,
+		"review_note": note,
+		"reviewed_by": admin.ID,
+		"reviewed_at": time.Now(),
+	}, "republish_at")
+	if err != nil {
+		return nil, err
+	}
+
+	s.notifyUploader(ctx, claim, "Your annotation stays unpublished", fmt.Sprintf(
+		"The claimant of the copyright claim against %q reported a court action, so the annotation "+
+			"will not be republished.\n\n%s", claim.Title, reviewNote(note)))
+
+	return claim, nil
+}
+
+// StartBackgroundJob periodically republishes annotations whose counter-notice waiting period has
+// passed, until the context is cancelled
+func (s *TakedownService) StartBackgroundJob(ctx context.Context) {
+	ticker := time.NewTicker(takedownRepublishInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.RepublishDue(ctx); err != nil {
+			log.Printf("Takedown republication job failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RepublishDue reinstates the countered claims whose waiting period has passed. The annotation is
+// republished once no other claim keeps it unpublished.
+func (s *TakedownService) RepublishDue(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cursor, err := s.takedowns.Find(ctx, bson.M{
+		"status":       models.TakedownCountered,
+		"republish_at": bson.M{"$lte": time.Now()},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to load due takedown claims: %w", err)
+	}
+	var due []*models.TakedownClaim
+	if err := cursor.All(ctx, &due); err != nil {
+		return fmt.Errorf("failed to load due takedown claims: %w", err)
+	}
+
+	for _, dueClaim := range due {
+		claim, err := s.transition(ctx, dueClaim.ID, models.TakedownCountered, bson.M{"status": models.TakedownReinstated}, "republish_at")
+		if err != nil {
+			log.Printf("Warning: failed to reinstate takedown claim %s: %v", dueClaim.ID, err)
+			continue
+		}
+
+		active, err := s.takedowns.CountDocuments(ctx, bson.M{
+			"annotation_id": claim.AnnotationID,
+			"status":        bson.M{"$in": []string{models.TakedownUpheld, models.TakedownCountered, models.TakedownClosed}},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to check other claims against annotation %s: %w", claim.AnnotationID, err)
+		}
+		if active > 0 {
+			log.Printf("Takedown claim %s reinstated, annotation %s stays unpublished by %d other claims", claim.ID, claim.AnnotationID, active)
+			continue
+		}
+
+		if err := s.annotations.SetTakenDown(ctx, claim.AnnotationID, false); err != nil {
+			log.Printf("Warning: failed to republish annotation %s: %v", claim.AnnotationID, err)
+			continue
+		}
+		log.Printf("Annotation %s republished after counter-notice to takedown claim %s", claim.AnnotationID, claim.ID)
+
+		s.notifyUploader(ctx, claim, "Your annotation was republished", fmt.Sprintf(
+			"Your annotation %q was republished after your counter-notice.\n\n%s\n",
+			claim.Title, s.annotations.ShareLink(claim.AnnotationID)))
+		s.notify(claim.ClaimantEmail, "Annotation republished after counter-notice", fmt.Sprintf(
+			"No court action was reported within the waiting period, so %q was republished.\n", claim.Title))
+	}
+	return nil
+}
+
+// transition moves a claim from one status to another, setting fields and unsetting others, and
+// returns the updated claim. It fails when the claim is in a different status.
+func (s *TakedownService) transition(ctx context.Context, claimID, from string, set bson.M, unset ...string) (*models.TakedownClaim, error) {
+	set["updated_at"] = time.Now()
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		fields := bson.M{}
+		for _, field := range unset {
+			fields[field] = ""
+		}
+		update["$unset"] = fields
+	}
+
+	var claim models.TakedownClaim
+	err := s.takedowns.FindOneAndUpdate(ctx, bson.M{"_id": claimID, "status": from}, update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&claim)
+	if err == mongo.ErrNoDocuments {
+		current, err := s.GetClaim(ctx, claimID)
+		if err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("invalid transition: takedown claim is %s, not %s", current.Status, from)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &claim, nil
+}
+
+// notifyUploader emails the owner of the annotation a claim is against
+func (s *TakedownService) notifyUploader(ctx context.Context, claim *models.TakedownClaim, subject, body string) {
+	uploader, err := s.users.GetUser(ctx, claim.UploaderID)
+	if err != nil {
+		log.Printf("Warning: failed to notify uploader of takedown claim %s: %v", claim.ID, err)
+		return
+	}
+	s.notify(uploader.Email, subject, body)
+}
+
+// notify sends an email in the background, so requests don't wait for the mail server
+func (s *TakedownService) notify(to, subject, body string) {
+	RunInBackground(func(context.Context) {
+		if err := s.mailer.Send(to, subject, body); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+	})
+}
+
+func (s *TakedownService) republishDays() int {
+	return int(s.republishAfter / (24 * time.Hour))
+}
+
+// reviewNote formats an admin's note for a notification, if there is one
+func reviewNote(note string) string {
+	if strings.TrimSpace(note) == "" {
+		return ""
+	}
+	return "Note from the reviewer: " + strings.TrimSpace(note) + "\n\n"
+}
+
+// SetTakenDown unpublishes an annotation for a copyright claim, or republishes it. Unpublished
+// annotations are not found by reads, lists, search or exports.
+func (s *AnnotationService) SetTakenDown(ctx context.Context, annotationID string, takenDown bool) error {
+	update := bson.M{"$set": bson.M{"taken_down": true, "updated_at": time.Now()}}
+	if !takenDown {
+		update = bson.M{"$set": bson.M{"updated_at": time.Now()}, "$unset": bson.M{"taken_down": ""}}
+	}
+
+	result, err := s.collection.UpdateOne(ctx, bson.M{"_id": annotationID}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("annotation not found")
+	}
+
+	s.syncSearch(ctx, annotationID)
+	s.purgeCDN(annotationID)
+	return nil
+}
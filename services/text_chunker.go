@@ -0,0 +1,59 @@
+package services
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// charsPerToken is a rough approximation of how many characters make up one LLM token
+const charsPerToken = 4
+
+// estimateTokens returns an approximate token count for the given text
+func estimateTokens(text string) int {
+	return (utf8.RuneCountInString(text) + charsPerToken - 1) / charsPerToken
+}
+
+// splitTextIntoChunks splits text into chunks of at most maxTokens (estimated),
+// preferring line boundaries and falling back to word boundaries for long lines
+func splitTextIntoChunks(text string, maxTokens int) []string {
+	if maxTokens <= 0 || estimateTokens(text) <= maxTokens {
+		return []string{text}
+	}
+
+	maxChars := maxTokens * charsPerToken
+	var chunks []string
+	var current strings.Builder
+
+	flush := func() {
+		if chunk := strings.TrimSpace(current.String()); chunk != "" {
+			chunks = append(chunks, chunk)
+		}
+		current.Reset()
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		// Lines that don't fit in a chunk on their own are split by words
+		if utf8.RuneCountInString(line) > maxChars {
+			for _, word := range strings.Fields(line) {
+				if current.Len() > 0 && utf8.RuneCountInString(current.String())+utf8.RuneCountInString(word)+1 > maxChars {
+					flush()
+				}
+				if current.Len() > 0 {
+					current.WriteString(" ")
+				}
+				current.WriteString(word)
+			}
+			current.WriteString("\n")
+			continue
+		}
+
+		if current.Len() > 0 && utf8.RuneCountInString(current.String())+utf8.RuneCountInString(line)+1 > maxChars {
+			flush()
+		}
+		current.WriteString(line)
+		current.WriteString("\n")
+	}
+	flush()
+
+	return chunks
+}
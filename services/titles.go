@@ -0,0 +1,170 @@
+package services
+
+import (
+	"auto-annotation-api/models"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ledongthuc/pdf"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	titleExcerptChars = 3000 // Opening of the document the LLM writes a title from
+	maxTitleLength    = 200
+	untitledDocument  = "Untitled document"
+)
+
+// placeholderPDFTitles are document information titles set by authoring tools rather than authors
+var placeholderPDFTitles = regexp.MustCompile(`(?i)^(untitled|document\d*|presentation\d*|slide \d+|title|new document|microsoft word - .*|.*\.(docx?|pptx?|odt|pdf|tex|dvi))$`)
+
+// titleNumberPattern matches the number suffix uniqueTitle appends, e.g. " (2)"
+var titleNumberPattern = regexp.MustCompile(`\((\d+)\)$`)
+
+// SuggestTitle determines a title for a document without creating an annotation, so the uploader can
+// confirm or change it before uploading
+func (s *AnnotationService) SuggestTitle(ctx context.Context, fileReader io.Reader, fileType, fileName string) (*models.TitleSuggestion, error) {
+	fileData, err := io.ReadAll(io.LimitReader(fileReader, s.maxUpload+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read uploaded file: %w", err)
+	}
+	if int64(len(fileData)) > s.maxUpload {
+		return nil, fmt.Errorf("file exceeds the maximum upload size of %d MB", s.maxUpload>>20)
+	}
+	if fileType == "pdf" {
+		if err := ValidatePDF(bytes.NewReader(fileData), int64(len(fileData))); err != nil {
+			return nil, err
+		}
+	}
+
+	text, err := s.extractTextFromStream(bytes.NewReader(fileData), int64(len(fileData)), fileType)
+	if err != nil {
+		log.Printf("Warning: failed to extract text for a title suggestion: %v", err)
+	}
+
+	suggestion := s.determineTitle(ctx, fileData, fileType, text, fileName)
+	return &suggestion, nil
+}
+
+// determineTitle titles a document uploaded without one: from the PDF's document information,
+// else written by the LLM from the opening of the text, else from the file name. The title is
+// numbered if another annotation already has it.
+func (s *AnnotationService) determineTitle(ctx context.Context, fileData []byte, fileType, text, fileName string) models.TitleSuggestion {
+	suggestion := models.TitleSuggestion{Title: untitledDocument, Source: models.TitleFileName}
+	if title := pdfMetadataTitle(fileData, fileType); title != "" {
+		suggestion = models.TitleSuggestion{Title: title, Source: models.TitlePDFMetadata}
+	} else if title := s.generateTitle(ctx, text); title != "" {
+		suggestion = models.TitleSuggestion{Title: title, Source: models.TitleGenerated}
+	} else if title := titleFromFilename(fileName); title != "" {
+		suggestion.Title = title
+	}
+
+	title, err := s.uniqueTitle(ctx, suggestion.Title)
+	if err != nil {
+		log.Printf("Warning: failed to check for annotations titled %q: %v", suggestion.Title, err)
+	} else {
+		suggestion.Title = title
+	}
+	return suggestion
+}
+
+// generateTitle asks the LLM for a title; failures only return no title
+func (s *AnnotationService) generateTitle(ctx context.Context, text string) string {
+	excerpt := strings.TrimSpace(text)
+	if excerpt == "" {
+		return ""
+	}
+	if len(excerpt) > titleExcerptChars {
+		excerpt = excerpt[:titleExcerptChars]
+		for !utf8.ValidString(excerpt) {
+			excerpt = excerpt[:len(excerpt)-1]
+		}
+	}
+
+	title, err := s.ollamaClient.GenerateTitle(ctx, excerpt)
+	if err != nil {
+		log.Printf("Warning: failed to generate a title: %v", err)
+		return ""
+	}
+	return truncateTitle(title)
+}
+
+// pdfMetadataTitle returns the title in a PDF's document information, unless it is missing or a
+// placeholder left by the authoring tool
+func pdfMetadataTitle(data []byte, fileType string) (title string) {
+	if fileType != "pdf" || len(data) == 0 {
+		return ""
+	}
+	// The PDF library panics on some malformed documents
+	defer func() {
+		if r := recover(); r != nil {
+			title = ""
+		}
+	}()
+
+	r, err := pdf.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return ""
+	}
+	title = strings.Join(strings.Fields(r.Trailer().Key("Info").Key("Title").Text()), " ")
+	if utf8.RuneCountInString(title) < 3 || placeholderPDFTitles.MatchString(title) {
+		return ""
+	}
+	return truncateTitle(title)
+}
+
+// truncateTitle shortens overly long titles at a word boundary
+func truncateTitle(title string) string {
+	if len(title) <= maxTitleLength {
+		return title
+	}
+	cut := strings.LastIndex(title[:maxTitleLength], " ")
+	if cut <= 0 {
+		cut = maxTitleLength
+	}
+	for !utf8.ValidString(title[:cut]) {
+		cut--
+	}
+	return strings.TrimRight(title[:cut], " ,;:-") + "…"
+}
+
+// uniqueTitle numbers a title, e.g. "Cell biology (2)", when other annotations already have it,
+// ignoring case
+func (s *AnnotationService) uniqueTitle(ctx context.Context, title string) (string, error) {
+	pattern := "^" + regexp.QuoteMeta(title) + `( \(\d+\))?$`
+	cursor, err := s.collection.Find(ctx, bson.M{"title": bson.M{"$regex": pattern, "$options": "i"}},
+		options.Find().SetProjection(bson.M{"title": 1}))
+	if err != nil {
+		return "", err
+	}
+	var existing []models.Annotation
+	if err := cursor.All(ctx, &existing); err != nil {
+		return "", err
+	}
+	if len(existing) == 0 {
+		return title, nil
+	}
+
+	next := 2
+	for _, annotation := range existing {
+		if len(annotation.Title) <= len(title) {
+			continue
+		}
+		match := titleNumberPattern.FindStringSubmatch(annotation.Title[len(title):])
+		if match == nil {
+			continue
+		}
+		if n, err := strconv.Atoi(match[1]); err == nil && n >= next {
+			next = n + 1
+		}
+	}
+	return fmt.Sprintf("%s (%d)", title, next), nil
+}
@@ -0,0 +1,155 @@
+package services
+
+import (
+	"auto-annotation-api/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// azureTTSMaxChars keeps each request well under Azure's 10 minutes of audio per request
+const azureTTSMaxChars = 3000
+
+// AzureTTSProvider synthesizes speech with the Azure AI Speech text-to-speech REST API
+type AzureTTSProvider struct {
+	client  *http.Client
+	key     string
+	region  string
+	voiceID string
+}
+
+// NewAzureTTSProvider creates an Azure AI Speech provider for the given region, e.g. "westeurope"
+func NewAzureTTSProvider(key, region, voiceID string) *AzureTTSProvider {
+	if voiceID == "" {
+		voiceID = "en-US-JennyNeural"
+	}
+	return &AzureTTSProvider{
+		client:  &http.Client{Timeout: 60 * time.Second},
+		key:     key,
+		region:  region,
+		voiceID: voiceID,
+	}
+}
+
+func (a *AzureTTSProvider) Name() string {
+	return "azure"
+}
+
+func (a *AzureTTSProvider) Synthesize(ctx context.Context, text, voiceID, engine, format string) ([]byte, error) {
+	if voiceID == "" {
+		voiceID = a.voiceID
+	}
+
+	outputFormat := "audio-24khz-96kbitrate-mono-mp3"
+	if format == AudioFormatOgg {
+		outputFormat = "ogg-24khz-16bit-mono-opus"
+	}
+
+	return synthesizeInChunks(text, azureTTSMaxChars, format == AudioFormatMP3, func(chunk string) ([]byte, error) {
+		return a.synthesizeChunk(ctx, chunk, voiceID, outputFormat)
+	})
+}
+
+// synthesizeChunk sends one SSML request for text within the request limit
+func (a *AzureTTSProvider) synthesizeChunk(ctx context.Context, text, voiceID, outputFormat string) ([]byte, error) {
+	ssml := fmt.Sprintf(`<speak version="1.0" xml:lang="%s"><voice name="%s">%s</voice></speak>`,
+		voiceLanguage(voiceID), html.EscapeString(voiceID), html.EscapeString(text))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.endpoint("/cognitiveservices/v1"), strings.NewReader(ssml))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/ssml+xml")
+	req.Header.Set("X-Microsoft-OutputFormat", outputFormat)
+	req.Header.Set("User-Agent", "auto-annotation-api")
+
+	audio, err := a.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to synthesize speech: %w", err)
+	}
+	return audio, nil
+}
+
+// SpeechMarks is not supported: word boundaries are only available through the Speech SDK
+func (a *AzureTTSProvider) SpeechMarks(ctx context.Context, text, voiceID, engine string) ([]SpeechMark, error) {
+	return nil, errSpeechMarksUnsupported
+}
+
+func (a *AzureTTSProvider) ListVoices(ctx context.Context, languageCode string) ([]models.TTSVoice, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.endpoint("/cognitiveservices/voices/list"), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	body, err := a.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list voices: %w", err)
+	}
+
+	var result []struct {
+		ShortName           string   `json:"ShortName"`
+		DisplayName         string   `json:"DisplayName"`
+		Gender              string   `json:"Gender"`
+		Locale              string   `json:"Locale"`
+		LocaleName          string   `json:"LocaleName"`
+		SecondaryLocaleList []string `json:"SecondaryLocaleList"`
+		VoiceType           string   `json:"VoiceType"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to decode voices: %w", err)
+	}
+
+	voices := []models.TTSVoice{}
+	for _, v := range result {
+		if languageCode != "" && !strings.EqualFold(v.Locale, languageCode) {
+			continue
+		}
+		voices = append(voices, models.TTSVoice{
+			ID:                      v.ShortName,
+			Name:                    v.DisplayName,
+			Gender:                  v.Gender,
+			LanguageCode:            v.Locale,
+			LanguageName:            v.LocaleName,
+			AdditionalLanguageCodes: v.SecondaryLocaleList,
+			SupportedEngines:        []string{strings.ToLower(v.VoiceType)},
+		})
+	}
+	return voices, nil
+}
+
+func (a *AzureTTSProvider) TestConnection() error {
+	_, err := a.ListVoices(context.Background(), "en-US")
+	return err
+}
+
+// endpoint returns the regional text-to-speech URL for a path
+func (a *AzureTTSProvider) endpoint(path string) string {
+	return fmt.Sprintf("https://%s.tts.speech.microsoft.com%s", a.region, path)
+}
+
+// do sends an authenticated request and returns the response body
+func (a *AzureTTSProvider) do(req *http.Request) ([]byte, error) {
+	req.Header.Set("Ocp-Apim-Subscription-Key", a.key)
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach Azure Speech: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("Azure Speech returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	return body, nil
+}
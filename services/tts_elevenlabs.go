@@ -0,0 +1,142 @@
+package services
+
+import (
+	"auto-annotation-api/models"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// elevenLabsMaxChars keeps requests under the smallest per-request limit of ElevenLabs models
+const elevenLabsMaxChars = 2500
+
+// ElevenLabsTTSProvider synthesizes speech with the ElevenLabs text-to-speech API
+type ElevenLabsTTSProvider struct {
+	client  *http.Client
+	apiKey  string
+	voiceID string
+	modelID string
+}
+
+// NewElevenLabsTTSProvider creates an ElevenLabs provider
+func NewElevenLabsTTSProvider(apiKey, voiceID, modelID string) *ElevenLabsTTSProvider {
+	if voiceID == "" {
+		voiceID = "21m00Tcm4TlvDq8ikWAM" // "Rachel", a premade voice available to every account
+	}
+	if modelID == "" {
+		modelID = "eleven_multilingual_v2"
+	}
+	return &ElevenLabsTTSProvider{
+		client:  &http.Client{Timeout: 120 * time.Second},
+		apiKey:  apiKey,
+		voiceID: voiceID,
+		modelID: modelID,
+	}
+}
+
+func (e *ElevenLabsTTSProvider) Name() string {
+	return "elevenlabs"
+}
+
+func (e *ElevenLabsTTSProvider) Synthesize(ctx context.Context, text, voiceID, engine, format string) ([]byte, error) {
+	if format != AudioFormatMP3 {
+		return nil, fmt.Errorf("audio format %s not supported by ElevenLabs", format)
+	}
+	if voiceID == "" {
+		voiceID = e.voiceID
+	}
+
+	return synthesizeInChunks(text, elevenLabsMaxChars, true, func(chunk string) ([]byte, error) {
+		body, err := json.Marshal(map[string]string{"text": chunk, "model_id": e.modelID})
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal request: %w", err)
+		}
+
+		path := "/v1/text-to-speech/" + url.PathEscape(voiceID) + "?output_format=mp3_44100_128"
+		audio, err := e.do(ctx, http.MethodPost, path, body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to synthesize speech: %w", err)
+		}
+		return audio, nil
+	})
+}
+
+// SpeechMarks is not supported: ElevenLabs reports character alignment, not Polly-style marks
+func (e *ElevenLabsTTSProvider) SpeechMarks(ctx context.Context, text, voiceID, engine string) ([]SpeechMark, error) {
+	return nil, errSpeechMarksUnsupported
+}
+
+// ListVoices returns the voices of the account. Voices are multilingual, so a language only
+// filters out voices labeled with a different one.
+func (e *ElevenLabsTTSProvider) ListVoices(ctx context.Context, languageCode string) ([]models.TTSVoice, error) {
+	body, err := e.do(ctx, http.MethodGet, "/v1/voices", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list voices: %w", err)
+	}
+
+	var result struct {
+		Voices []struct {
+			VoiceID string            `json:"voice_id"`
+			Name    string            `json:"name"`
+			Labels  map[string]string `json:"labels"`
+		} `json:"voices"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to decode voices: %w", err)
+	}
+
+	voices := []models.TTSVoice{}
+	for _, v := range result.Voices {
+		language := v.Labels["language"]
+		if languageCode != "" && language != "" && !strings.HasPrefix(strings.ToLower(languageCode), strings.ToLower(language)) {
+			continue
+		}
+		voices = append(voices, models.TTSVoice{
+			ID:               v.VoiceID,
+			Name:             v.Name,
+			Gender:           titleCase(v.Labels["gender"]),
+			LanguageCode:     language,
+			LanguageName:     v.Labels["accent"],
+			SupportedEngines: []string{e.modelID},
+		})
+	}
+	return voices, nil
+}
+
+func (e *ElevenLabsTTSProvider) TestConnection() error {
+	_, err := e.do(context.Background(), http.MethodGet, "/v1/voices", nil)
+	return err
+}
+
+// do sends an authenticated request and returns the response body
+func (e *ElevenLabsTTSProvider) do(ctx context.Context, method, path string, body []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, "https://api.elevenlabs.io"+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("xi-api-key", e.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach ElevenLabs: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("ElevenLabs returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	return data, nil
+}
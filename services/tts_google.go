@@ -0,0 +1,158 @@
+package services
+
+import (
+	"auto-annotation-api/models"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// googleTTSMaxChars keeps requests under Google's 5000-byte input limit for any UTF-8 text
+const googleTTSMaxChars = 1200
+
+// GoogleTTSProvider synthesizes speech with the Google Cloud Text-to-Speech REST API
+type GoogleTTSProvider struct {
+	client  *http.Client
+	apiKey  string
+	voiceID string
+}
+
+// NewGoogleTTSProvider creates a Google Cloud Text-to-Speech provider
+func NewGoogleTTSProvider(apiKey, voiceID string) *GoogleTTSProvider {
+	if voiceID == "" {
+		voiceID = "en-US-Neural2-F"
+	}
+	return &GoogleTTSProvider{
+		client:  &http.Client{Timeout: 60 * time.Second},
+		apiKey:  apiKey,
+		voiceID: voiceID,
+	}
+}
+
+func (g *GoogleTTSProvider) Name() string {
+	return "google"
+}
+
+func (g *GoogleTTSProvider) Synthesize(ctx context.Context, text, voiceID, engine, format string) ([]byte, error) {
+	if voiceID == "" {
+		voiceID = g.voiceID
+	}
+
+	encoding := "MP3"
+	if format == AudioFormatOgg {
+		encoding = "OGG_OPUS"
+	}
+
+	return synthesizeInChunks(text, googleTTSMaxChars, format == AudioFormatMP3, func(chunk string) ([]byte, error) {
+		return g.synthesizeChunk(ctx, chunk, voiceID, encoding)
+	})
+}
+
+// synthesizeChunk calls text:synthesize for text within the request limit
+func (g *GoogleTTSProvider) synthesizeChunk(ctx context.Context, text, voiceID, encoding string) ([]byte, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"input": map[string]string{"text": text},
+		"voice": map[string]string{
+			"languageCode": voiceLanguage(voiceID),
+			"name":         voiceID,
+		},
+		"audioConfig": map[string]string{"audioEncoding": encoding},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	var result struct {
+		AudioContent string `json:"audioContent"`
+	}
+	if err := g.do(ctx, http.MethodPost, "/v1/text:synthesize", nil, body, &result); err != nil {
+		return nil, fmt.Errorf("failed to synthesize speech: %w", err)
+	}
+
+	audio, err := base64.StdEncoding.DecodeString(result.AudioContent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode audio: %w", err)
+	}
+	return audio, nil
+}
+
+// SpeechMarks is not supported: word timings need SSML marks on the v1beta1 API
+func (g *GoogleTTSProvider) SpeechMarks(ctx context.Context, text, voiceID, engine string) ([]SpeechMark, error) {
+	return nil, errSpeechMarksUnsupported
+}
+
+func (g *GoogleTTSProvider) ListVoices(ctx context.Context, languageCode string) ([]models.TTSVoice, error) {
+	query := url.Values{}
+	if languageCode != "" {
+		query.Set("languageCode", languageCode)
+	}
+
+	var result struct {
+		Voices []struct {
+			Name          string   `json:"name"`
+			LanguageCodes []string `json:"languageCodes"`
+			SSMLGender    string   `json:"ssmlGender"`
+		} `json:"voices"`
+	}
+	if err := g.do(ctx, http.MethodGet, "/v1/voices", query, nil, &result); err != nil {
+		return nil, fmt.Errorf("failed to list voices: %w", err)
+	}
+
+	voices := make([]models.TTSVoice, 0, len(result.Voices))
+	for _, v := range result.Voices {
+		voice := models.TTSVoice{
+			ID:               v.Name,
+			Name:             v.Name,
+			Gender:           titleCase(v.SSMLGender),
+			SupportedEngines: []string{},
+		}
+		if len(v.LanguageCodes) > 0 {
+			voice.LanguageCode = v.LanguageCodes[0]
+			voice.AdditionalLanguageCodes = v.LanguageCodes[1:]
+		}
+		voices = append(voices, voice)
+	}
+	return voices, nil
+}
+
+func (g *GoogleTTSProvider) TestConnection() error {
+	_, err := g.ListVoices(context.Background(), "en-US")
+	return err
+}
+
+// do sends a request to the Text-to-Speech API and decodes the JSON response into result
+func (g *GoogleTTSProvider) do(ctx context.Context, method, path string, query url.Values, body []byte, result interface{}) error {
+	if query == nil {
+		query = url.Values{}
+	}
+	query.Set("key", g.apiKey)
+
+	req, err := http.NewRequestWithContext(ctx, method, "https://texttospeech.googleapis.com"+path+"?"+query.Encode(), bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach Google Text-to-Speech: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("Google Text-to-Speech returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package services
+
+import (
+	"auto-annotation-api/config"
+	"auto-annotation-api/models"
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	pollyTypes "github.com/aws/aws-sdk-go-v2/service/polly/types"
+)
+
+// Audio formats a TTSProvider can produce
+const (
+	AudioFormatMP3 = "mp3"
+	AudioFormatOgg = "ogg" // OGG/Opus
+)
+
+// TTSProvider synthesizes speech. AWS Polly is the default; Google, Azure and ElevenLabs can be
+// selected with TTS_PROVIDER for deployments where AWS isn't available.
+type TTSProvider interface {
+	// Name identifies the provider, e.g. "polly"
+	Name() string
+	// Synthesize returns audio in the given format for text of any length. Empty voice and engine
+	// values fall back to the provider's configured defaults; engine only applies to Polly.
+	Synthesize(ctx context.Context, text, voiceID, engine, format string) ([]byte, error)
+	// SpeechMarks returns sentence and word timings for the audio Synthesize produces
+	SpeechMarks(ctx context.Context, text, voiceID, engine string) ([]SpeechMark, error)
+	// ListVoices returns the available voices, optionally limited to a language such as "en-US"
+	ListVoices(ctx context.Context, languageCode string) ([]models.TTSVoice, error)
+	// TestConnection checks that the provider is reachable with the configured credentials
+	TestConnection() error
+}
+
+// NewTTSProvider creates the TTS provider selected by TTS_PROVIDER
+func NewTTSProvider(cfg *config.Config, awsService *AWSService) (TTSProvider, error) {
+	switch strings.ToLower(cfg.TTSProvider) {
+	case "", "polly":
+		if awsService == nil {
+			return nil, fmt.Errorf("TTS provider polly not configured: AWS credentials missing")
+		}
+		return &pollyTTSProvider{aws: awsService}, nil
+	case "google":
+		if cfg.GoogleTTSAPIKey == "" {
+			return nil, fmt.Errorf("TTS provider google not configured: GOOGLE_TTS_API_KEY missing")
+		}
+		return NewGoogleTTSProvider(cfg.GoogleTTSAPIKey, cfg.GoogleTTSVoice), nil
+	case "azure":
+		if cfg.AzureSpeechKey == "" || cfg.AzureSpeechRegion == "" {
+			return nil, fmt.Errorf("TTS provider azure not configured: AZURE_SPEECH_KEY or AZURE_SPEECH_REGION missing")
+		}
+		return NewAzureTTSProvider(cfg.AzureSpeechKey, cfg.AzureSpeechRegion, cfg.AzureTTSVoice), nil
+	case "elevenlabs":
+		if cfg.ElevenLabsAPIKey == "" {
+			return nil, fmt.Errorf("TTS provider elevenlabs not configured: ELEVENLABS_API_KEY missing")
+		}
+		return NewElevenLabsTTSProvider(cfg.ElevenLabsAPIKey, cfg.ElevenLabsVoiceID, cfg.ElevenLabsModelID), nil
+	default:
+		return nil, fmt.Errorf("unknown TTS provider: %s", cfg.TTSProvider)
+	}
+}
+
+// pollyTTSProvider adapts the Polly half of AWSService to TTSProvider
+type pollyTTSProvider struct {
+	aws *AWSService
+}
+
+func (p *pollyTTSProvider) Name() string {
+	return "polly"
+}
+
+func (p *pollyTTSProvider) Synthesize(ctx context.Context, text, voiceID, engine, format string) ([]byte, error) {
+	outputFormat := pollyTypes.OutputFormatMp3
+	if format == AudioFormatOgg {
+		outputFormat = pollyTypes.OutputFormatOggOpus
+	}
+	return p.aws.synthesize(ctx, text, voiceID, engine, outputFormat)
+}
+
+func (p *pollyTTSProvider) SpeechMarks(ctx context.Context, text, voiceID, engine string) ([]SpeechMark, error) {
+	return p.aws.GenerateSpeechMarks(ctx, text, voiceID, engine)
+}
+
+func (p *pollyTTSProvider) ListVoices(ctx context.Context, languageCode string) ([]models.TTSVoice, error) {
+	return p.aws.ListVoices(ctx, languageCode)
+}
+
+func (p *pollyTTSProvider) TestConnection() error {
+	_, err := p.aws.ListVoices(context.Background(), "en-US")
+	return err
+}
+
+// errSpeechMarksUnsupported is returned by providers without word timings
+var errSpeechMarksUnsupported = fmt.Errorf("speech marks not supported by this TTS provider")
+
+// synthesizeInChunks synthesizes text over a provider's request limit in chunks and concatenates
+// the audio: MP3 frames play back-to-back (ID3 headers after the first chunk are dropped), and
+// OGG/Opus chunks form a chained Ogg stream.
+func synthesizeInChunks(text string, limit int, mp3 bool, synthesize func(chunk string) ([]byte, error)) ([]byte, error) {
+	var audioData []byte
+	for i, chunk := range splitSpeechText(text, limit) {
+		audio, err := synthesize(chunk.Text)
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 && mp3 {
+			audio = stripID3(audio)
+		}
+		audioData = append(audioData, audio...)
+	}
+	return audioData, nil
+}
+
+// speechChunk is a part of the text sent to a TTS provider, with its byte offset in the full text
+type speechChunk struct {
+	Offset int
+	Text   string
+}
+
+// splitSpeechText splits text into chunks of at most limit characters, preferring sentence
+// boundaries, then line breaks, then spaces. Chunks are substrings of text so speech mark
+// offsets can be mapped back.
+func splitSpeechText(text string, limit int) []speechChunk {
+	var chunks []speechChunk
+	offset := 0
+	for utf8.RuneCountInString(text[offset:]) > limit {
+		// Byte index just past the first limit runes
+		end := offset
+		for n := 0; n < limit; n++ {
+			_, size := utf8.DecodeRuneInString(text[end:])
+			end += size
+		}
+
+		window := text[offset:end]
+		cut := lastBoundary(window, ". ", "! ", "? ")
+		if cut <= 0 {
+			cut = lastBoundary(window, "\n", " ")
+		}
+		if cut <= 0 {
+			cut = len(window) // No break opportunity, cut mid-word
+		}
+
+		chunks = append(chunks, speechChunk{Offset: offset, Text: text[offset : offset+cut]})
+		offset += cut
+	}
+	return append(chunks, speechChunk{Offset: offset, Text: text[offset:]})
+}
+
+// lastBoundary returns the byte index just past the last of the given separators in s, or -1
+func lastBoundary(s string, separators ...string) int {
+	cut := -1
+	for _, sep := range separators {
+		if idx := strings.LastIndex(s, sep); idx >= 0 && idx+len(sep) > cut {
+			cut = idx + len(sep)
+		}
+	}
+	return cut
+}
+
+// voiceLanguage returns the locale a Google or Azure voice name starts with, e.g. "en-US" for
+// "en-US-Neural2-F" or "en-US-JennyNeural"
+func voiceLanguage(voiceID string) string {
+	parts := strings.SplitN(voiceID, "-", 3)
+	if len(parts) < 2 {
+		return "en-US"
+	}
+	return parts[0] + "-" + parts[1]
+}
+
+// titleCase turns an API enum like "FEMALE" into "Female", matching Polly's gender values
+func titleCase(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + strings.ToLower(s[1:])
+}
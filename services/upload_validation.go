@@ -0,0 +1,55 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// pdfHeaderWindow is how far into a file the %PDF- marker may appear; readers tolerate leading junk
+const pdfHeaderWindow = 1024
+
+var pdfMagic = []byte("%PDF-")
+
+// imageMagics are the signatures of the image types accepted as source documents
+var imageMagics = [][]byte{
+	[]byte("\x89PNG\r\n\x1a\n"),
+	{0xFF, 0xD8, 0xFF}, // JPEG
+}
+
+// ValidatePDF checks that an uploaded file is not empty and actually is a PDF, rather than trusting
+// its extension
+func ValidatePDF(file io.ReaderAt, size int64) error {
+	if size <= 0 {
+		return fmt.Errorf("file is empty")
+	}
+
+	header := make([]byte, min(size, pdfHeaderWindow))
+	n, err := file.ReadAt(header, 0)
+	if err != nil && err != io.EOF {
+		return fmt.Errorf("failed to read uploaded file: %w", err)
+	}
+	if !bytes.Contains(header[:n], pdfMagic) {
+		return fmt.Errorf("file is not a valid PDF")
+	}
+	return nil
+}
+
+// ValidateImage checks that an uploaded source image is not empty and actually is a PNG or JPEG
+func ValidateImage(file io.ReaderAt, size int64) error {
+	if size <= 0 {
+		return fmt.Errorf("file is empty")
+	}
+
+	header := make([]byte, min(size, 8))
+	n, err := file.ReadAt(header, 0)
+	if err != nil && err != io.EOF {
+		return fmt.Errorf("failed to read uploaded file: %w", err)
+	}
+	for _, magic := range imageMagics {
+		if bytes.HasPrefix(header[:n], magic) {
+			return nil
+		}
+	}
+	return fmt.Errorf("file is not a valid PNG or JPEG image")
+}
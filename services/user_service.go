@@ -0,0 +1,157 @@
+package services
+
+import (
+	"auto-annotation-api/models"
+	"context"
+	"errors"
+	"slices"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// UserService handles user management for admins
+type UserService struct {
+	collection *mongo.Collection
+}
+
+// NewUserService creates a new user service
+func NewUserService(db *mongo.Database) *UserService {
+	return &UserService{
+		collection: db.Collection("users"),
+	}
+}
+
+// ListUsers returns users, newest first, optionally limited to a role
+func (s *UserService) ListUsers(ctx context.Context, role string, limit, offset int64) ([]*models.User, error) {
+	opts := options.Find()
+	if limit > 0 {
+		opts.SetLimit(limit)
+	}
+	if offset > 0 {
+		opts.SetSkip(offset)
+	}
+	opts.SetSort(bson.D{{Key: "created_at", Value: -1}})
+
+	cursor, err := s.collection.Find(ctx, userQuery(role), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var users []*models.User
+	if err = cursor.All(ctx, &users); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// CountUsers returns how many users ListUsers lists for role in total. Without a role filter the
+// collection's estimated count is used, which is much cheaper and exact outside of crashes.
+func (s *UserService) CountUsers(ctx context.Context, role string) (int64, error) {
+	if role == "" {
+		return s.collection.EstimatedDocumentCount(ctx)
+	}
+	return s.collection.CountDocuments(ctx, userQuery(role))
+}
+
+// userQuery builds the query for the user list
+func userQuery(role string) bson.M {
+	query := bson.M{}
+	if role != "" {
+		query["role"] = role
+	}
+	return query
+}
+
+// GetUser retrieves a user by ID
+func (s *UserService) GetUser(ctx context.Context, userID string) (*models.User, error) {
+	var user models.User
+	err := s.collection.FindOne(ctx, bson.M{"_id": userID}).Decode(&user)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("user not found")
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+// UpdateRole changes a user's role. Admins cannot change their own role, so there is always an admin left.
+func (s *UserService) UpdateRole(ctx context.Context, adminID, userID, role string) (*models.User, error) {
+	if adminID == userID {
+		return nil, errors.New("cannot change your own role")
+	}
+	return s.update(ctx, userID, bson.M{"role": role})
+}
+
+// SetDisabled disables or re-enables a user. Disabled users cannot log in and their tokens stop working.
+func (s *UserService) SetDisabled(ctx context.Context, adminID, userID string, disabled bool) (*models.User, error) {
+	if adminID == userID {
+		return nil, errors.New("cannot disable your own account")
+	}
+	return s.update(ctx, userID, bson.M{"disabled": disabled})
+}
+
+// SetContentRestrictions replaces the safety labels hidden from a user, e.g. a minor, on top of the
+// restrictions of the user's role
+func (s *UserService) SetContentRestrictions(ctx context.Context, userID string, labels []string) (*models.User, error) {
+	restricted := []string{}
+	for _, label := range labels {
+		if !slices.Contains(restricted, label) {
+			restricted = append(restricted, label)
+		}
+	}
+	sort.Strings(restricted)
+	return s.update(ctx, userID, bson.M{"content_restrictions": restricted})
+}
+
+// DeleteUser removes a user account. Their annotations are kept.
+func (s *UserService) DeleteUser(ctx context.Context, adminID, userID string) error {
+	if adminID == userID {
+		return errors.New("cannot delete your own account")
+	}
+
+	result, err := s.collection.DeleteOne(ctx, bson.M{"_id": userID})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return errors.New("user not found")
+	}
+	return nil
+}
+
+// EnsureAdmin gives the admin role to the user with the given email, so a fresh deployment has an admin
+func (s *UserService) EnsureAdmin(ctx context.Context, email string) error {
+	result, err := s.collection.UpdateOne(ctx, bson.M{"email": email}, bson.M{
+		"$set": bson.M{"role": "admin", "disabled": false, "updated_at": time.Now()},
+	})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("user not found")
+	}
+	return nil
+}
+
+// update sets fields on a user and returns the updated user
+func (s *UserService) update(ctx context.Context, userID string, fields bson.M) (*models.User, error) {
+	fields["updated_at"] = time.Now()
+
+	var user models.User
+	err := s.collection.FindOneAndUpdate(ctx, bson.M{"_id": userID}, bson.M{"$set": fields},
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&user)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("user not found")
+		}
+		return nil, err
+	}
+	return &user, nil
+}
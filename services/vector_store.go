@@ -0,0 +1,177 @@
+package services
+
+import (
+	"auto-annotation-api/config"
+	"auto-annotation-api/models"
+	"container/heap"
+	"context"
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// vectorSearchCandidates is how many nearest neighbours Atlas considers per requested result.
+	// Filters are applied to the candidates, so restrictive filters need more of them.
+	vectorSearchCandidates = 20
+	maxVectorCandidates    = 10000 // Atlas limit of numCandidates
+)
+
+// VectorStore finds the annotations whose embeddings are nearest to a query vector. Embeddings are
+// stored on the annotations themselves; stores only differ in how they are searched.
+type VectorStore interface {
+	// Name identifies the backend, e.g. "atlas"
+	Name() string
+	// Nearest returns up to limit annotations matching filter, closest to vector first
+	Nearest(ctx context.Context, vector []float64, filter bson.M, limit int) ([]models.SearchHit, error)
+}
+
+// NewVectorStore creates the vector search backend selected by VECTOR_BACKEND
+func NewVectorStore(cfg *config.Config, annotations *mongo.Collection) (VectorStore, error) {
+	switch strings.ToLower(cfg.VectorBackend) {
+	case "", "mongodb":
+		return &mongoVectorStore{annotations: annotations}, nil
+	case "atlas":
+		return &atlasVectorStore{annotations: annotations, index: cfg.VectorIndex}, nil
+	default:
+		return nil, fmt.Errorf("unknown vector backend: %s", cfg.VectorBackend)
+	}
+}
+
+// hitProjection are the annotation fields a search hit is built from
+var hitProjection = bson.M{"title": 1, "genre": 1, "tags": 1, "image": 1}
+
+// newSearchHit converts an annotation into a search hit
+func newSearchHit(annotation *models.Annotation, score float64) models.SearchHit {
+	tags := annotation.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	return models.SearchHit{
+		ID:    annotation.ID,
+		Title: annotation.Title,
+		Genre: annotation.Genre,
+		Tags:  tags,
+		Image: annotation.Image,
+		Score: score,
+	}
+}
+
+// mongoVectorStore compares the query with every matching embedding in the API. It needs no index
+// and suits libraries of up to some ten thousand annotations.
+type mongoVectorStore struct {
+	annotations *mongo.Collection
+}
+
+func (m *mongoVectorStore) Name() string {
+	return "mongodb"
+}
+
+func (m *mongoVectorStore) Nearest(ctx context.Context, vector []float64, filter bson.M, limit int) ([]models.SearchHit, error) {
+	projection := bson.M{"embedding": 1}
+	for field := range hitProjection {
+		projection[field] = 1
+	}
+	cursor, err := m.annotations.Find(ctx, filter, options.Find().SetProjection(projection))
+	if err != nil {
+		return nil, fmt.Errorf("failed to search annotations: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	// Keep the closest annotations in a min-heap, so the scan holds at most limit of them
+	nearest := &hitHeap{}
+	for cursor.Next(ctx) {
+		var annotation models.Annotation
+		if err := cursor.Decode(&annotation); err != nil {
+			return nil, fmt.Errorf("failed to search annotations: %w", err)
+		}
+		hit := newSearchHit(&annotation, cosineSimilarity(vector, annotation.Embedding))
+		if nearest.Len() < limit {
+			heap.Push(nearest, hit)
+		} else if limit > 0 && hit.Score > (*nearest)[0].Score {
+			(*nearest)[0] = hit
+			heap.Fix(nearest, 0)
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("failed to search annotations: %w", err)
+	}
+
+	hits := make([]models.SearchHit, nearest.Len())
+	for i := len(hits) - 1; i >= 0; i-- {
+		hits[i] = heap.Pop(nearest).(models.SearchHit)
+	}
+	return hits, nil
+}
+
+// hitHeap is a min-heap of search hits by score
+type hitHeap []models.SearchHit
+
+func (h hitHeap) Len() int            { return len(h) }
+func (h hitHeap) Less(i, j int) bool  { return h[i].Score < h[j].Score }
+func (h hitHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *hitHeap) Push(x interface{}) { *h = append(*h, x.(models.SearchHit)) }
+func (h *hitHeap) Pop() interface{} {
+	old := *h
+	hit := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return hit
+}
+
+// atlasVectorStore searches with Atlas Vector Search. The index must exist on the annotations
+// collection, e.g.
+//
+//	{"fields": [{"type": "vector", "path": "embedding", "numDimensions": 768, "similarity": "cosine"}]}
+//
+// with numDimensions matching the embedding model.
+type atlasVectorStore struct {
+	annotations *mongo.Collection
+	index       string
+}
+
+func (a *atlasVectorStore) Name() string {
+	return "atlas"
+}
+
+func (a *atlasVectorStore) Nearest(ctx context.Context, vector []float64, filter bson.M, limit int) ([]models.SearchHit, error) {
+	// Only fields declared in the index can filter $vectorSearch itself, so the filter is applied to
+	// the candidates instead
+	candidates := min(limit*vectorSearchCandidates, maxVectorCandidates)
+	project := bson.M{"score": bson.M{"$meta": "vectorSearchScore"}}
+	for field := range hitProjection {
+		project[field] = 1
+	}
+	pipeline := mongo.Pipeline{
+		{{Key: "$vectorSearch", Value: bson.M{
+			"index":         a.index,
+			"path":          "embedding",
+			"queryVector":   vector,
+			"numCandidates": candidates,
+			"limit":         candidates,
+		}}},
+		{{Key: "$match", Value: filter}},
+		{{Key: "$limit", Value: limit}},
+		{{Key: "$project", Value: project}},
+	}
+
+	cursor, err := a.annotations.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search annotations: %w", err)
+	}
+	var results []struct {
+		models.Annotation `bson:",inline"`
+		Score             float64 `bson:"score"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, fmt.Errorf("failed to search annotations: %w", err)
+	}
+
+	hits := make([]models.SearchHit, len(results))
+	for i := range results {
+		hits[i] = newSearchHit(&results[i].Annotation, results[i].Score)
+	}
+	return hits, nil
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"auto-annotation-api/models"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"golang.org/x/net/html/charset"
+)
+
+// CreateAnnotationFromURL fetches a web page or a remote PDF and annotates it like an uploaded file.
+// Pages are reduced to their readable text; their title is used when req has none. The URL is
+// recorded as the source URL unless req sets one.
+func (s *AnnotationService) CreateAnnotationFromURL(ctx context.Context, userID string, req *models.CreateAnnotationRequest, rawURL string) (*models.Annotation, error) {
+	pageURL, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || (pageURL.Scheme != "http" && pageURL.Scheme != "https") || pageURL.Host == "" {
+		return nil, fmt.Errorf("invalid url: must be an absolute http(s) URL")
+	}
+
+	data, fileType, finalURL, err := s.fetchDocument(ctx, pageURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.TrimSpace(req.SourceURL) == "" {
+		req.SourceURL = finalURL.String()
+	}
+	if name := path.Base(finalURL.Path); name != "/" && name != "." {
+		req.FileName = name
+	} else {
+		req.FileName = finalURL.Hostname()
+	}
+	if fileType == "html" && strings.TrimSpace(req.Title) == "" {
+		if title := htmlTitle(data); title != "" {
+			req.Title, req.TitleSource = title, models.TitleWebPage
+		}
+	}
+
+	return s.CreateAnnotationFromStream(ctx, userID, req, bytes.NewReader(data), int64(len(data)), fileType, nil)
+}
+
+// fetchDocument downloads a URL, limited to the upload size, and returns its content with its file
+// type, "pdf" or "html", and the URL after redirects. Pages are converted to UTF-8.
+func (s *AnnotationService) fetchDocument(ctx context.Context, pageURL *url.URL) ([]byte, string, *url.URL, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL.String(), nil)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("invalid url: %w", err)
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; auto-annotation-api)")
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/pdf;q=0.9,*/*;q=0.5")
+
+	resp, err := webClient.Do(req)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("failed to fetch url: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", nil, fmt.Errorf("failed to fetch url: status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, s.maxUpload+1))
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("failed to fetch url: %w", err)
+	}
+	if int64(len(data)) > s.maxUpload {
+		return nil, "", nil, fmt.Errorf("file exceeds the maximum upload size of %d MB", s.maxUpload>>20)
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == "application/pdf" || bytes.Contains(data[:min(len(data), pdfHeaderWindow)], pdfMagic):
+		return data, "pdf", resp.Request.URL, nil
+	case mediaType == "text/html" || mediaType == "application/xhtml+xml":
+		reader, err := charset.NewReader(bytes.NewReader(data), contentType)
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("failed to decode web page: %w", err)
+		}
+		if data, err = io.ReadAll(reader); err != nil {
+			return nil, "", nil, fmt.Errorf("failed to decode web page: %w", err)
+		}
+		return data, "html", resp.Request.URL, nil
+	default:
+		return nil, "", nil, fmt.Errorf("unsupported content type %q, only web pages and PDFs can be annotated", mediaType)
+	}
+}
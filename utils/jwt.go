@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
 var (
@@ -15,6 +16,14 @@ var (
 	jwtSecretOnce sync.Once
 )
 
+// SetJWTSecret sets the secret tokens are signed with, normally from config.Load. It must be called
+// before the first token is issued or checked, later the secret no longer changes.
+func SetJWTSecret(secret string) {
+	jwtSecretOnce.Do(func() {
+		jwtSecret = []byte(secret)
+	})
+}
+
 // getJWTSecret returns the JWT secret, loading it lazily
 func getJWTSecret() []byte {
 	jwtSecretOnce.Do(func() {
@@ -34,6 +43,7 @@ func GenerateToken(user *models.User) (string, error) {
 		UserID: user.ID,
 		Email:  user.Email,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),                                // Token ID used for revocation
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)), // Token expires in 24 hours
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
@@ -64,13 +74,60 @@ func ValidateToken(tokenString string) (*models.JWTClaims, error) {
 	}
 
 	claims, ok := token.Claims.(*models.JWTClaims)
-	if !ok {
+	if !ok || claims.UserID == "" {
 		return nil, errors.New("invalid token claims")
 	}
 
 	return claims, nil
 }
 
+// GenerateShareToken generates a token granting read access to an annotation without an account,
+// valid until expiresAt unless it is zero
+func GenerateShareToken(annotationID, sharedBy string, expiresAt time.Time) (string, error) {
+	claims := models.ShareClaims{
+		AnnotationID: annotationID,
+		SharedBy:     sharedBy,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:       uuid.New().String(),
+			Audience: jwt.ClaimStrings{models.ShareTokenAudience},
+			IssuedAt: jwt.NewNumericDate(time.Now()),
+			Issuer:   "auto-annotation-api",
+		},
+	}
+	if !expiresAt.IsZero() {
+		claims.ExpiresAt = jwt.NewNumericDate(expiresAt)
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(getJWTSecret())
+}
+
+// ErrShareTokenExpired is returned by ValidateShareToken for a share link past its expiry
+var ErrShareTokenExpired = errors.New("share link has expired")
+
+// ValidateShareToken validates a share token and returns its claims
+func ValidateShareToken(tokenString string) (*models.ShareClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &models.ShareClaims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("invalid token signing method")
+		}
+		return getJWTSecret(), nil
+	}, jwt.WithAudience(models.ShareTokenAudience))
+	if errors.Is(err, jwt.ErrTokenExpired) {
+		return nil, ErrShareTokenExpired
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(*models.ShareClaims)
+	if !ok || !token.Valid || claims.AnnotationID == "" {
+		return nil, errors.New("invalid share token")
+	}
+
+	return claims, nil
+}
+
 // ExtractUserIDFromToken extracts user ID from token string
 func ExtractUserIDFromToken(tokenString string) (string, error) {
 	claims, err := ValidateToken(tokenString)
@@ -0,0 +1,362 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Span kinds, as numbered by OTLP
+const (
+	SpanKindInternal = 1
+	SpanKindServer   = 2
+	SpanKindClient   = 3
+)
+
+const (
+	traceBatchSize     = 256
+	traceQueueSize     = 4096
+	traceFlushInterval = 5 * time.Second
+)
+
+// Span times one operation of a trace. A nil *Span is valid and does nothing, which is what
+// StartSpan returns while tracing is disabled.
+type Span struct {
+	traceID  [16]byte
+	spanID   [8]byte
+	parentID [8]byte
+	name     string
+	kind     int
+	start    time.Time
+	mu       sync.Mutex
+	attrs    map[string]any
+	err      error
+	ended    bool
+}
+
+type spanContextKey struct{}
+
+// traceExporter batches finished spans and sends them to an OTLP/HTTP collector as JSON
+type traceExporter struct {
+	endpoint string
+	headers  map[string]string
+	service  string
+	client   *http.Client
+	queue    chan map[string]any
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+var tracer *traceExporter
+
+// InitTracing sends spans to the OTLP/HTTP collector at endpoint, e.g. http://localhost:4318.
+// headers are key=value pairs separated by commas, like OTEL_EXPORTER_OTLP_HEADERS. Without an
+// endpoint tracing stays disabled and spans cost nothing.
+func InitTracing(endpoint, headers, serviceName string) {
+	endpoint = strings.TrimRight(strings.TrimSpace(endpoint), "/")
+	if endpoint == "" {
+		return
+	}
+	if !strings.HasSuffix(endpoint, "/v1/traces") {
+		endpoint += "/v1/traces"
+	}
+
+	parsed := make(map[string]string)
+	for _, pair := range strings.Split(headers, ",") {
+		key, value, ok := strings.Cut(pair, "=")
+		if ok && strings.TrimSpace(key) != "" {
+			parsed[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+
+	tracer = &traceExporter{
+		endpoint: endpoint,
+		headers:  parsed,
+		service:  serviceName,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		queue:    make(chan map[string]any, traceQueueSize),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go tracer.run()
+	log.Printf("Tracing enabled, exporting spans to %s", endpoint)
+}
+
+// TracingEnabled reports whether spans are exported
+func TracingEnabled() bool {
+	return tracer != nil
+}
+
+// ShutdownTracing exports the spans that are still queued, waiting at most until ctx is done
+func ShutdownTracing(ctx context.Context) {
+	if tracer == nil {
+		return
+	}
+	close(tracer.stop)
+	select {
+	case <-tracer.done:
+	case <-ctx.Done():
+		log.Printf("Warning: gave up exporting queued spans: %v", ctx.Err())
+	}
+}
+
+// StartSpan starts a span as a child of the span in ctx, or as the root of a new trace. The
+// returned context carries the new span; End must be called on it.
+func StartSpan(ctx context.Context, name string) (context.Context, *Span) {
+	return startSpan(ctx, name, SpanKindInternal)
+}
+
+// StartClientSpan starts a span for a call to another service, such as the database or AWS
+func StartClientSpan(ctx context.Context, name string) (context.Context, *Span) {
+	return startSpan(ctx, name, SpanKindClient)
+}
+
+// StartServerSpan starts the span of an incoming request. traceparent is the W3C header of the
+// caller; when valid, the span continues the caller's trace.
+func StartServerSpan(ctx context.Context, name, traceparent string) (context.Context, *Span) {
+	if tracer == nil {
+		return ctx, nil
+	}
+	span := newSpan(name, SpanKindServer)
+	if traceID, parentID, ok := parseTraceParent(traceparent); ok {
+		span.traceID, span.parentID = traceID, parentID
+	} else {
+		rand.Read(span.traceID[:])
+	}
+	return context.WithValue(ctx, spanContextKey{}, span), span
+}
+
+// SpanFromContext returns the current span of ctx, or nil
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanContextKey{}).(*Span)
+	return span
+}
+
+func startSpan(ctx context.Context, name string, kind int) (context.Context, *Span) {
+	if tracer == nil {
+		return ctx, nil
+	}
+	span := newSpan(name, kind)
+	if parent := SpanFromContext(ctx); parent != nil {
+		span.traceID, span.parentID = parent.traceID, parent.spanID
+	} else {
+		rand.Read(span.traceID[:])
+	}
+	return context.WithValue(ctx, spanContextKey{}, span), span
+}
+
+func newSpan(name string, kind int) *Span {
+	span := &Span{name: name, kind: kind, start: time.Now(), attrs: make(map[string]any)}
+	rand.Read(span.spanID[:])
+	return span
+}
+
+// SetName renames the span, e.g. once the matched route is known
+func (s *Span) SetName(name string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.name = name
+	s.mu.Unlock()
+}
+
+// SetAttribute records a string, bool, integer or float attribute on the span
+func (s *Span) SetAttribute(key string, value any) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.attrs[key] = value
+	s.mu.Unlock()
+}
+
+// RecordError marks the span as failed; a nil err is ignored
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	s.err = err
+	s.mu.Unlock()
+}
+
+// TraceParent returns the W3C traceparent header for propagating the span to another service
+func (s *Span) TraceParent() string {
+	if s == nil {
+		return ""
+	}
+	return fmt.Sprintf("00-%s-%s-01", hex.EncodeToString(s.traceID[:]), hex.EncodeToString(s.spanID[:]))
+}
+
+// End finishes the span and queues it for export. Spans are dropped rather than slowing down
+// requests when the collector can't keep up.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	end := time.Now()
+
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	payload := s.otlpJSON(end)
+	s.mu.Unlock()
+
+	select {
+	case tracer.queue <- payload:
+	default:
+	}
+}
+
+// parseTraceParent reads the trace and parent span IDs of a version 00 traceparent header
+func parseTraceParent(header string) ([16]byte, [8]byte, bool) {
+	var traceID [16]byte
+	var parentID [8]byte
+
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) != 4 || parts[0] != "00" || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return traceID, parentID, false
+	}
+	if _, err := hex.Decode(traceID[:], []byte(parts[1])); err != nil {
+		return traceID, parentID, false
+	}
+	if _, err := hex.Decode(parentID[:], []byte(parts[2])); err != nil {
+		return traceID, parentID, false
+	}
+	if traceID == [16]byte{} || parentID == [8]byte{} {
+		return traceID, parentID, false
+	}
+	return traceID, parentID, true
+}
+
+func (e *traceExporter) run() {
+	defer close(e.done)
+	ticker := time.NewTicker(traceFlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]map[string]any, 0, traceBatchSize)
+	for {
+		select {
+		case span := <-e.queue:
+			batch = append(batch, span)
+			if len(batch) >= traceBatchSize {
+				e.export(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				e.export(batch)
+				batch = batch[:0]
+			}
+		case <-e.stop:
+			for {
+				select {
+				case span := <-e.queue:
+					batch = append(batch, span)
+				default:
+					if len(batch) > 0 {
+						e.export(batch)
+					}
+					return
+				}
+			}
+		}
+	}
+}
+
+// export posts a batch in the OTLP/HTTP JSON encoding. Failed batches are dropped, tracing must
+// never hold up the API.
+func (e *traceExporter) export(spans []map[string]any) {
+	body, err := json.Marshal(map[string]any{
+		"resourceSpans": []any{map[string]any{
+			"resource": map[string]any{
+				"attributes": []any{otlpAttribute("service.name", e.service)},
+			},
+			"scopeSpans": []any{map[string]any{
+				"scope": map[string]any{"name": "auto-annotation-api"},
+				"spans": spans,
+			}},
+		}},
+	})
+	if err != nil {
+		log.Printf("Warning: failed to encode spans: %v", err)
+		return
+	}
+
+	req, err := http.NewRequest(http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		log.Printf("Warning: failed to export spans: %v", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range e.headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		log.Printf("Warning: failed to export %d spans: %v", len(spans), err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		log.Printf("Warning: trace collector rejected %d spans with status %d", len(spans), resp.StatusCode)
+	}
+}
+
+// otlpJSON encodes the span for export; s.mu must be held
+func (s *Span) otlpJSON(end time.Time) map[string]any {
+	attributes := make([]any, 0, len(s.attrs))
+	for key, value := range s.attrs {
+		attributes = append(attributes, otlpAttribute(key, value))
+	}
+
+	span := map[string]any{
+		"traceId":           hex.EncodeToString(s.traceID[:]),
+		"spanId":            hex.EncodeToString(s.spanID[:]),
+		"name":              s.name,
+		"kind":              s.kind,
+		"startTimeUnixNano": strconv.FormatInt(s.start.UnixNano(), 10),
+		"endTimeUnixNano":   strconv.FormatInt(end.UnixNano(), 10),
+		"attributes":        attributes,
+	}
+	if s.parentID != [8]byte{} {
+		span["parentSpanId"] = hex.EncodeToString(s.parentID[:])
+	}
+	if s.err != nil {
+		span["status"] = map[string]any{"code": 2, "message": s.err.Error()}
+	}
+	return span
+}
+
+func otlpAttribute(key string, value any) map[string]any {
+	var v map[string]any
+	switch value := value.(type) {
+	case string:
+		v = map[string]any{"stringValue": value}
+	case bool:
+		v = map[string]any{"boolValue": value}
+	case int:
+		v = map[string]any{"intValue": strconv.Itoa(value)}
+	case int64:
+		v = map[string]any{"intValue": strconv.FormatInt(value, 10)}
+	case float64:
+		v = map[string]any{"doubleValue": value}
+	default:
+		v = map[string]any{"stringValue": fmt.Sprint(value)}
+	}
+	return map[string]any{"key": key, "value": v}
+}
@@ -0,0 +1,31 @@
+package utils
+
+import (
+	"context"
+
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/smithy-go/middleware"
+)
+
+// AWSTracingOptions adds a client span around every AWS operation, e.g. S3.PutObject or
+// Polly.SynthesizeSpeech, to clients configured with them as APIOptions. Calls made without a
+// traced context start their own trace.
+func AWSTracingOptions() []func(*middleware.Stack) error {
+	return []func(*middleware.Stack) error{
+		func(stack *middleware.Stack) error {
+			// Added after the SDK's own initialize steps, which put the service and operation in ctx
+			return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("Tracing", func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+				service, operation := awsmiddleware.GetServiceID(ctx), awsmiddleware.GetOperationName(ctx)
+				ctx, span := StartClientSpan(ctx, service+"."+operation)
+				span.SetAttribute("rpc.system", "aws-api")
+				span.SetAttribute("rpc.service", service)
+				span.SetAttribute("rpc.method", operation)
+
+				out, metadata, err := next.HandleInitialize(ctx, in)
+				span.RecordError(err)
+				span.End()
+				return out, metadata, err
+			}), middleware.After)
+		},
+	}
+}
@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/event"
+)
+
+// MongoTracingMonitor returns a command monitor that records a client span for every MongoDB
+// command run with a traced context, as a child of its span. Commands outside a trace, such as
+// those of background jobs and the driver's own housekeeping, are not recorded.
+func MongoTracingMonitor(databaseName string) *event.CommandMonitor {
+	var spans sync.Map // Request ID -> *Span
+
+	finish := func(requestID int64, err error) {
+		if span, ok := spans.LoadAndDelete(requestID); ok {
+			span.(*Span).RecordError(err)
+			span.(*Span).End()
+		}
+	}
+
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, evt *event.CommandStartedEvent) {
+			if SpanFromContext(ctx) == nil {
+				return
+			}
+			_, span := StartClientSpan(ctx, "mongodb."+evt.CommandName)
+			span.SetAttribute("db.system", "mongodb")
+			span.SetAttribute("db.name", databaseName)
+			span.SetAttribute("db.operation", evt.CommandName)
+			if collection, ok := evt.Command.Lookup(evt.CommandName).StringValueOK(); ok {
+				span.SetAttribute("db.mongodb.collection", collection)
+			}
+			spans.Store(evt.RequestID, span)
+		},
+		Succeeded: func(ctx context.Context, evt *event.CommandSucceededEvent) {
+			finish(evt.RequestID, nil)
+		},
+		Failed: func(ctx context.Context, evt *event.CommandFailedEvent) {
+			finish(evt.RequestID, errors.New(evt.Failure))
+		},
+	}
+}